@@ -0,0 +1,113 @@
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Albert-tru/DanceMirror/types"
+)
+
+// ErrNotLoggedIn 调用需要登录的接口前没有 Login 也没有 WithToken
+var ErrNotLoggedIn = errors.New("dancemirror: not logged in")
+
+// LoginResult 登录结果
+type LoginResult struct {
+	Token string     `json:"token"`
+	User  types.User `json:"user"`
+}
+
+// Register 注册用户（不会自动登录）
+func (c *Client) Register(ctx context.Context, payload types.RegisterUserPayload) error {
+	return c.doJSON(ctx, http.MethodPost, "/register", false, payload, nil)
+}
+
+// Login 登录并保存令牌，之后的请求自动带上令牌；令牌快过期时自动刷新（不保存密码）
+func (c *Client) Login(ctx context.Context, phone, password string) (*LoginResult, error) {
+	var result LoginResult
+	payload := types.LoginUserPayload{Phone: phone, Password: password}
+	if err := c.doJSON(ctx, http.MethodPost, "/login", false, payload, &result); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.token = result.Token
+	c.mu.Unlock()
+	return &result, nil
+}
+
+// RefreshToken 用当前的令牌换一个新的令牌（POST /refresh）。令牌已过期时返回
+// CodeUnauthorized 的错误（见 IsUnauthorized），需要重新 Login
+func (c *Client) RefreshToken(ctx context.Context) error {
+	return c.refresh(ctx, c.Token())
+}
+
+// refresh 刷新令牌 old；并发调用时只有第一个发出请求，其他的发现令牌已经换过后直接返回
+func (c *Client) refresh(ctx context.Context, old string) error {
+	if old == "" {
+		return ErrNotLoggedIn
+	}
+
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+	if c.Token() != old {
+		return nil
+	}
+
+	var result struct {
+		Token string `json:"token"`
+	}
+	req, err := c.newRequest(ctx, http.MethodPost, "/refresh", old, nil)
+	if err != nil {
+		return err
+	}
+	if err := c.do(req, &result); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.token = result.Token
+	c.mu.Unlock()
+	return nil
+}
+
+// validToken 返回当前令牌；令牌在 refreshBefore 内过期时先刷新。
+// 已经过期的令牌不能刷新，原样返回，由服务器返回 401
+func (c *Client) validToken(ctx context.Context) (string, error) {
+	token := c.Token()
+	if token == "" {
+		return "", ErrNotLoggedIn
+	}
+
+	expiresAt, ok := tokenExpiry(token)
+	if !ok || time.Until(expiresAt) > c.refreshBefore || !time.Now().Before(expiresAt) {
+		return token, nil
+	}
+	if err := c.refresh(ctx, token); err != nil {
+		return "", err
+	}
+	return c.Token(), nil
+}
+
+// tokenExpiry 读取 JWT 中的 expiresAt（不校验签名，只用于判断什么时候刷新）
+func tokenExpiry(token string) (time.Time, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, false
+	}
+	data, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}, false
+	}
+	var claims struct {
+		ExpiresAt int64 `json:"expiresAt"`
+	}
+	if err := json.Unmarshal(data, &claims); err != nil || claims.ExpiresAt == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(claims.ExpiresAt, 0), true
+}
@@ -0,0 +1,156 @@
+// Package client DanceMirror API 的 Go 客户端（内部工具使用：批量导入、练功房终端等）。
+// 请求走 /api/v2，错误为 *Error（和服务器的响应结构一致，按 Code 判断错误类型）
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const apiPrefix = "/api/v2"
+
+// Client API 客户端，可以并发使用
+type Client struct {
+	baseURL       string
+	httpClient    *http.Client
+	locale        string
+	refreshBefore time.Duration
+
+	mu    sync.Mutex
+	token string
+
+	refreshMu sync.Mutex // 同一时间只刷新一次令牌
+}
+
+// Option 客户端选项
+type Option func(*Client)
+
+// WithHTTPClient 使用指定的 http.Client（默认为 http.DefaultClient）
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.httpClient = hc }
+}
+
+// WithToken 使用已有的令牌（和 Login 得到的令牌一样会在过期前自动刷新）
+func WithToken(token string) Option {
+	return func(c *Client) { c.token = token }
+}
+
+// WithLocale 错误信息的语言（zh-CN 或 en），作为 Accept-Language 发送
+func WithLocale(locale string) Option {
+	return func(c *Client) { c.locale = locale }
+}
+
+// WithRefreshBefore 令牌在过期前多久自动刷新（默认 5 分钟）
+func WithRefreshBefore(d time.Duration) Option {
+	return func(c *Client) { c.refreshBefore = d }
+}
+
+// New 创建客户端，baseURL 为服务器地址，例如 http://localhost:8080
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:       strings.TrimRight(baseURL, "/"),
+		httpClient:    http.DefaultClient,
+		refreshBefore: 5 * time.Minute,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Token 当前的令牌，没有登录时为空
+func (c *Client) Token() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.token
+}
+
+// doJSON 发送 JSON 请求，成功时把响应的 data 解码到 out（out 为 nil 时忽略）
+func (c *Client) doJSON(ctx context.Context, method, path string, auth bool, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	var err error
+
+	var token string
+	if auth {
+		if token, err = c.validToken(ctx); err != nil {
+			return err
+		}
+	}
+
+	req, err := c.newRequest(ctx, method, path, token, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return c.do(req, out)
+}
+
+// newRequest 创建请求；token 不为空时带上令牌
+func (c *Client) newRequest(ctx context.Context, method, path, token string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+apiPrefix+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if c.locale != "" {
+		req.Header.Set("Accept-Language", c.locale)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req, nil
+}
+
+func (c *Client) do(req *http.Request, out any) error {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return newError(resp, data)
+	}
+
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return fmt.Errorf("dancemirror: decode response: %w", err)
+	}
+	if out == nil || len(env.Data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(env.Data, out); err != nil {
+		return fmt.Errorf("dancemirror: decode response data: %w", err)
+	}
+	return nil
+}
+
+// envelope /api/v2 的响应结构，data 按接口解码
+type envelope struct {
+	Code      string          `json:"code"`
+	Message   string          `json:"message"`
+	Data      json.RawMessage `json:"data"`
+	Details   []FieldError    `json:"details"`
+	RequestID string          `json:"requestId"`
+}
@@ -0,0 +1,254 @@
+package client_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Albert-tru/DanceMirror/client"
+	"github.com/Albert-tru/DanceMirror/cmd/api/apitest"
+	"github.com/Albert-tru/DanceMirror/config"
+	"github.com/Albert-tru/DanceMirror/types"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const password = "password123"
+
+// login 在存储中创建用户，返回通过 Login 登录的客户端
+func login(t *testing.T, s *apitest.Server, phone string) (*client.Client, *types.User) {
+	t.Helper()
+	u, _, err := s.CreateUser(phone, password, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := client.New(s.URL, client.WithLocale("en"))
+	if _, err := c.Login(context.Background(), phone, password); err != nil {
+		t.Fatal(err)
+	}
+	return c, u
+}
+
+// signToken 签发指定过期时间的令牌（服务器签发的令牌有效期由配置决定）
+func signToken(t *testing.T, userID string, expiresAt time.Time) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"userID":    userID,
+		"expiresAt": expiresAt.Unix(),
+	}).SignedString([]byte(config.Envs.JWTSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestRegisterAndLogin(t *testing.T) {
+	s := apitest.Start(t)
+	ctx := context.Background()
+	c := client.New(s.URL, client.WithLocale("en"))
+
+	if _, err := c.ListVideos(ctx); !errors.Is(err, client.ErrNotLoggedIn) {
+		t.Fatalf("ListVideos before login: err = %v, want ErrNotLoggedIn", err)
+	}
+
+	payload := types.RegisterUserPayload{Phone: "13800000001", Password: password, FirstName: "Test", LastName: "User"}
+	if err := c.Register(ctx, payload); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	result, err := c.Login(ctx, payload.Phone, password)
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if result.Token == "" || c.Token() != result.Token {
+		t.Fatalf("Login token = %q, client token = %q", result.Token, c.Token())
+	}
+	if result.User.Phone != payload.Phone {
+		t.Errorf("Login user phone = %q, want %q", result.User.Phone, payload.Phone)
+	}
+
+	videos, err := c.ListVideos(ctx)
+	if err != nil {
+		t.Fatalf("ListVideos: %v", err)
+	}
+	if len(videos) != 0 {
+		t.Errorf("ListVideos = %d videos, want 0", len(videos))
+	}
+}
+
+func TestErrors(t *testing.T) {
+	s := apitest.Start(t)
+	ctx := context.Background()
+	owner, _ := login(t, s, "13800000001")
+	other, _ := login(t, s, "13800000002")
+
+	video, err := owner.UploadVideo(ctx, client.Upload{Title: "owner", FileName: "a.mp4", File: bytes.NewReader(apitest.FakeMP4(1024))})
+	if err != nil {
+		t.Fatalf("UploadVideo: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		call   func() error
+		status int
+		code   string
+	}{
+		{
+			name: "validation",
+			call: func() error {
+				return owner.Register(ctx, types.RegisterUserPayload{Phone: "1", Password: password, FirstName: "a", LastName: "b"})
+			},
+			status: 400, code: client.CodeValidationFailed,
+		},
+		{
+			name: "wrong password",
+			call: func() error {
+				_, err := client.New(s.URL).Login(ctx, "13800000001", "wrong-password")
+				return err
+			},
+			status: 400, code: client.CodeBadRequest,
+		},
+		{
+			name:   "not found",
+			call:   func() error { _, err := owner.GetVideo(ctx, video.ID+1000); return err },
+			status: 404, code: client.CodeNotFound,
+		},
+		{
+			name:   "other user's video",
+			call:   func() error { _, err := other.GetVideo(ctx, video.ID); return err },
+			status: 403, code: client.CodePermissionDenied,
+		},
+		{
+			name: "invalid token",
+			call: func() error {
+				_, err := client.New(s.URL, client.WithToken("not-a-token")).ListVideos(ctx)
+				return err
+			},
+			status: 401, code: client.CodeUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call()
+			var apiErr *client.Error
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("err = %v, want *client.Error", err)
+			}
+			if apiErr.StatusCode != tt.status || apiErr.Code != tt.code {
+				t.Errorf("err = %d %s, want %d %s", apiErr.StatusCode, apiErr.Code, tt.status, tt.code)
+			}
+			if apiErr.RequestID == "" || apiErr.Message == "" {
+				t.Errorf("err = %+v, want a message and a request id", apiErr)
+			}
+			if !client.IsCode(err, tt.code) {
+				t.Errorf("IsCode(err, %q) = false", tt.code)
+			}
+		})
+	}
+
+	// 校验错误带有字段详情
+	var apiErr *client.Error
+	if !errors.As(owner.Register(ctx, types.RegisterUserPayload{Phone: "1", Password: password, FirstName: "a", LastName: "b"}), &apiErr) ||
+		len(apiErr.Details) == 0 || apiErr.Details[0].Field != "phone" {
+		t.Errorf("validation error details = %+v, want the phone field", apiErr)
+	}
+}
+
+func TestRefreshToken(t *testing.T) {
+	s := apitest.Start(t)
+	ctx := context.Background()
+	_, u := login(t, s, "13800000001")
+	userID := strconv.Itoa(u.ID)
+
+	// 令牌在 refreshBefore（默认 5 分钟）内过期，请求前自动换成新的令牌
+	expiring := signToken(t, userID, time.Now().Add(time.Minute))
+	c := client.New(s.URL, client.WithToken(expiring))
+	if _, err := c.ListVideos(ctx); err != nil {
+		t.Fatalf("ListVideos with an expiring token: %v", err)
+	}
+	if c.Token() == expiring {
+		t.Fatal("token was not refreshed")
+	}
+
+	// 手动刷新
+	before := c.Token()
+	time.Sleep(time.Second) // 令牌中的过期时间精确到秒
+	if err := c.RefreshToken(ctx); err != nil {
+		t.Fatalf("RefreshToken: %v", err)
+	}
+	if c.Token() == before {
+		t.Error("RefreshToken did not change the token")
+	}
+
+	// 已过期的令牌不能刷新，需要重新登录
+	expired := client.New(s.URL, client.WithToken(signToken(t, userID, time.Now().Add(-time.Minute))))
+	if _, err := expired.ListVideos(ctx); !client.IsUnauthorized(err) {
+		t.Errorf("ListVideos with an expired token: err = %v, want unauthorized", err)
+	}
+	if err := expired.RefreshToken(ctx); !client.IsUnauthorized(err) {
+		t.Errorf("RefreshToken with an expired token: err = %v, want unauthorized", err)
+	}
+
+	if err := client.New(s.URL).RefreshToken(ctx); !errors.Is(err, client.ErrNotLoggedIn) {
+		t.Errorf("RefreshToken without a token: err = %v, want ErrNotLoggedIn", err)
+	}
+}
+
+func TestVideos(t *testing.T) {
+	s := apitest.Start(t)
+	ctx := context.Background()
+	c, u := login(t, s, "13800000001")
+
+	content := apitest.FakeMP4(256 << 10)
+	var sent, total int64
+	video, err := c.UploadVideo(ctx, client.Upload{
+		Title:       "基本功",
+		Description: "第一节",
+		FileName:    "basics.mp4",
+		File:        bytes.NewReader(content),
+		ContentType: "video/mp4",
+		Size:        int64(len(content)),
+		Progress:    func(s, t int64) { sent, total = s, t },
+	})
+	if err != nil {
+		t.Fatalf("UploadVideo: %v", err)
+	}
+	if sent != int64(len(content)) || total != int64(len(content)) {
+		t.Errorf("progress = %d/%d, want %d/%d", sent, total, len(content), len(content))
+	}
+	if video.Title != "基本功" || video.UserID != u.ID || video.FileSize != int64(len(content)) {
+		t.Errorf("uploaded video = %+v", video)
+	}
+
+	got, err := c.GetVideo(ctx, video.ID)
+	if err != nil {
+		t.Fatalf("GetVideo: %v", err)
+	}
+	if got.ID != video.ID || got.Description != "第一节" {
+		t.Errorf("GetVideo = %+v, want %+v", got, video)
+	}
+
+	videos, err := c.ListVideos(ctx)
+	if err != nil {
+		t.Fatalf("ListVideos: %v", err)
+	}
+	if len(videos) != 1 || videos[0].ID != video.ID {
+		t.Fatalf("ListVideos = %+v, want the uploaded video", videos)
+	}
+
+	if err := c.DeleteVideo(ctx, video.ID); err != nil {
+		t.Fatalf("DeleteVideo: %v", err)
+	}
+	if _, err := c.GetVideo(ctx, video.ID); !client.IsNotFound(err) {
+		t.Errorf("GetVideo after delete: err = %v, want not found", err)
+	}
+	if videos, err := c.ListVideos(ctx); err != nil || len(videos) != 0 {
+		t.Errorf("ListVideos after delete = %d videos, %v; want none", len(videos), err)
+	}
+
+	if _, err := c.UploadVideo(ctx, client.Upload{Title: "empty"}); err == nil {
+		t.Error("UploadVideo without a file: want an error")
+	}
+}
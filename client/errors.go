@@ -0,0 +1,122 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// 错误码，和服务器 /api/v2 响应中的 code 相同（已发布的错误码不会修改）
+const (
+	CodeBadRequest       = "bad_request"
+	CodeValidationFailed = "validation_failed"
+	CodeInvalidID        = "invalid_id"
+	CodeUnauthorized     = "unauthorized"
+	CodePermissionDenied = "permission_denied"
+	CodeNotFound         = "not_found"
+	CodeConflict         = "conflict"
+	CodePayloadTooLarge  = "payload_too_large"
+	CodeUnsupportedMedia = "unsupported_media"
+	CodeQuotaExceeded    = "quota_exceeded"
+	CodeTooManyRequests  = "too_many_requests"
+	CodeUnavailable      = "unavailable"
+	CodeInternalError    = "internal_error"
+)
+
+// requestIDHeader 服务器返回请求 ID 的响应头
+const requestIDHeader = "X-Request-Id"
+
+// FieldError 单个字段的校验错误（code 为 validation_failed 时）
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Error 服务器返回的错误。Message 按 WithLocale 的语言返回，只用于展示，判断错误类型用 Code
+type Error struct {
+	StatusCode int
+	Code       string
+	Message    string
+	Details    []FieldError
+	RequestID  string // 向服务端排查问题时提供
+}
+
+func (e *Error) Error() string {
+	if e.RequestID == "" {
+		return fmt.Sprintf("dancemirror: %s: %s (%d)", e.Code, e.Message, e.StatusCode)
+	}
+	return fmt.Sprintf("dancemirror: %s: %s (%d, request %s)", e.Code, e.Message, e.StatusCode, e.RequestID)
+}
+
+// IsCode 判断 err 是否为指定错误码的 *Error
+func IsCode(err error, code string) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.Code == code
+}
+
+// IsNotFound 记录不存在（包括已移入回收站的视频）。没有权限查看时是 CodePermissionDenied
+func IsNotFound(err error) bool {
+	return IsCode(err, CodeNotFound)
+}
+
+// IsUnauthorized 令牌无效或已过期，需要重新 Login
+func IsUnauthorized(err error) bool {
+	return IsCode(err, CodeUnauthorized)
+}
+
+// newError 解析错误响应；不是 /api/v2 的响应结构时（比如路由不存在、代理返回的错误）按状态码推断错误码
+func newError(resp *http.Response, body []byte) *Error {
+	e := &Error{
+		StatusCode: resp.StatusCode,
+		RequestID:  resp.Header.Get(requestIDHeader),
+	}
+
+	var env envelope
+	if err := json.Unmarshal(body, &env); err == nil && env.Code != "" {
+		e.Code = env.Code
+		e.Message = env.Message
+		e.Details = env.Details
+		if env.RequestID != "" {
+			e.RequestID = env.RequestID
+		}
+		return e
+	}
+
+	e.Code = statusCode(resp.StatusCode)
+	e.Message = strings.TrimSpace(string(body))
+	if e.Message == "" {
+		e.Message = http.StatusText(resp.StatusCode)
+	}
+	return e
+}
+
+// statusCode 按状态码推断错误码（和服务器对没有指定错误码的错误的处理相同）
+func statusCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeBadRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodePermissionDenied
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusRequestEntityTooLarge:
+		return CodePayloadTooLarge
+	case http.StatusUnsupportedMediaType:
+		return CodeUnsupportedMedia
+	case http.StatusTooManyRequests:
+		return CodeTooManyRequests
+	case http.StatusServiceUnavailable:
+		return CodeUnavailable
+	}
+	if status >= 500 {
+		return CodeInternalError
+	}
+	return CodeBadRequest
+}
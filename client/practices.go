@@ -0,0 +1,41 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/Albert-tru/DanceMirror/types"
+)
+
+// ListPractices 我的练习记录
+func (c *Client) ListPractices(ctx context.Context) ([]*types.Practice, error) {
+	var practices []*types.Practice
+	if err := c.doJSON(ctx, http.MethodGet, "/practices", true, nil, &practices); err != nil {
+		return nil, err
+	}
+	return practices, nil
+}
+
+// GetPractice 练习记录详情
+func (c *Client) GetPractice(ctx context.Context, id int) (*types.Practice, error) {
+	var practice types.Practice
+	if err := c.doJSON(ctx, http.MethodGet, fmt.Sprintf("/practices/%d", id), true, nil, &practice); err != nil {
+		return nil, err
+	}
+	return &practice, nil
+}
+
+// CreatePractice 创建练习记录
+func (c *Client) CreatePractice(ctx context.Context, payload types.CreatePracticePayload) (*types.Practice, error) {
+	var practice types.Practice
+	if err := c.doJSON(ctx, http.MethodPost, "/practices", true, payload, &practice); err != nil {
+		return nil, err
+	}
+	return &practice, nil
+}
+
+// DeletePractice 删除练习记录
+func (c *Client) DeletePractice(ctx context.Context, id int) error {
+	return c.doJSON(ctx, http.MethodDelete, fmt.Sprintf("/practices/%d", id), true, nil, nil)
+}
@@ -0,0 +1,137 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+
+	"github.com/Albert-tru/DanceMirror/types"
+)
+
+// Upload 上传视频的参数
+type Upload struct {
+	Title       string
+	Description string
+	ReferenceID int // 练习录像对应的参考视频，0 表示不是练习录像
+
+	FileName    string
+	File        io.Reader // 边读边上传，不会整个读入内存
+	ContentType string    // 文件的 MIME 类型，为空时由服务器按文件头识别
+	Size        int64     // 文件大小，只用于 Progress 的 total，未知时为 0
+
+	// Progress 每写出一块文件内容后调用，sent 为已写出的字节数（写入请求体，不代表服务器已收到）
+	Progress func(sent, total int64)
+}
+
+// ListVideos 我的视频（不含回收站中的）
+func (c *Client) ListVideos(ctx context.Context) ([]*types.Video, error) {
+	var videos []*types.Video
+	if err := c.doJSON(ctx, http.MethodGet, "/videos", true, nil, &videos); err != nil {
+		return nil, err
+	}
+	return videos, nil
+}
+
+// GetVideo 视频详情（含转码输出）
+func (c *Client) GetVideo(ctx context.Context, id int) (*types.Video, error) {
+	var video types.Video
+	if err := c.doJSON(ctx, http.MethodGet, fmt.Sprintf("/videos/%d", id), true, nil, &video); err != nil {
+		return nil, err
+	}
+	return &video, nil
+}
+
+// DeleteVideo 把视频移入回收站
+func (c *Client) DeleteVideo(ctx context.Context, id int) error {
+	return c.doJSON(ctx, http.MethodDelete, fmt.Sprintf("/videos/%d", id), true, nil, nil)
+}
+
+// UploadVideo 以 multipart/form-data 流式上传视频。相同内容的视频已存在时返回已有的视频
+func (c *Client) UploadVideo(ctx context.Context, up Upload) (*types.Video, error) {
+	if up.File == nil {
+		return nil, errors.New("dancemirror: upload has no file")
+	}
+
+	pr, pw := io.Pipe()
+	defer pr.Close()
+	form := multipart.NewWriter(pw)
+	go func() {
+		pw.CloseWithError(writeUploadForm(form, up))
+	}()
+
+	token, err := c.validToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	req, err := c.newRequest(ctx, http.MethodPost, "/videos", token, pr)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+
+	var video types.Video
+	if err := c.do(req, &video); err != nil {
+		return nil, err
+	}
+	return &video, nil
+}
+
+// writeUploadForm 写出表单字段和文件；请求结束后 pipe 被关闭，写入返回错误，协程退出
+func writeUploadForm(form *multipart.Writer, up Upload) error {
+	fields := [][2]string{{"title", up.Title}, {"description", up.Description}}
+	if up.ReferenceID != 0 {
+		fields = append(fields, [2]string{"referenceId", strconv.Itoa(up.ReferenceID)})
+	}
+	for _, f := range fields {
+		if err := form.WriteField(f[0], f[1]); err != nil {
+			return err
+		}
+	}
+
+	contentType := up.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="video"; filename="%s"`, quoteEscaper.Replace(up.FileName)))
+	header.Set("Content-Type", contentType)
+	part, err := form.CreatePart(header)
+	if err != nil {
+		return err
+	}
+
+	var file io.Reader = up.File
+	if up.Progress != nil {
+		file = &progressReader{r: up.File, total: up.Size, progress: up.Progress}
+	}
+	if _, err := io.Copy(part, file); err != nil {
+		return err
+	}
+	return form.Close()
+}
+
+// quoteEscaper 转义 Content-Disposition 中的文件名
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// progressReader 读取时回调已读出的字节数
+type progressReader struct {
+	r        io.Reader
+	sent     int64
+	total    int64
+	progress func(sent, total int64)
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 {
+		p.sent += int64(n)
+		p.progress(p.sent, p.total)
+	}
+	return n, err
+}
@@ -3,31 +3,85 @@ package api
 // 负责处理所有的 API 请求
 
 import (
+"context"
 "database/sql"
 "log"
 "net/http"
+"time"
 
+"github.com/Albert-tru/DanceMirror/config"
+"github.com/Albert-tru/DanceMirror/service/beat"
+"github.com/Albert-tru/DanceMirror/service/class"
+"github.com/Albert-tru/DanceMirror/service/comment"
+"github.com/Albert-tru/DanceMirror/service/event"
+"github.com/Albert-tru/DanceMirror/service/job"
+"github.com/Albert-tru/DanceMirror/service/openapi"
+"github.com/Albert-tru/DanceMirror/service/practice"
+"github.com/Albert-tru/DanceMirror/service/quota"
+"github.com/Albert-tru/DanceMirror/service/room"
+"github.com/Albert-tru/DanceMirror/service/segment"
+"github.com/Albert-tru/DanceMirror/service/storage"
+"github.com/Albert-tru/DanceMirror/service/transcode"
 "github.com/Albert-tru/DanceMirror/service/user"
 "github.com/Albert-tru/DanceMirror/service/video"
+"github.com/Albert-tru/DanceMirror/service/webhook"
+"github.com/Albert-tru/DanceMirror/types"
+"github.com/Albert-tru/DanceMirror/utils"
+"github.com/Albert-tru/DanceMirror/utils/i18n"
 "github.com/gorilla/mux"
 )
 
 // APIServer 结构体：保存服务器需要的信息
 type APIServer struct {
-addr string  // 服务器地址，比如 ":8080"
-db   *sql.DB // 数据库连接
+addr       string            // 服务器地址，比如 ":8080"
+db         *sql.DB           // 数据库连接（只用于 /readyz 检查，可以为 nil）
+stores     *Stores           // 数据存储
+transcoder types.Transcoder // 为 nil 时按配置查找 ffmpeg
+
+spec    *openapi.Document // Handler 生成的 OpenAPI 文档
+specErr error             // 文档检查的结果（有路由没有登记文档等）
 }
 
 // NewAPIServer 创建一个新的服务器实例
 func NewAPIServer(addr string, db *sql.DB) *APIServer {
+return NewAPIServerWithStores(addr, db, NewStores(db))
+}
+
+// NewAPIServerWithStores 使用指定的数据存储创建服务器实例（测试时传入内存实现）
+func NewAPIServerWithStores(addr string, db *sql.DB, stores *Stores) *APIServer {
 return &APIServer{
-addr: addr,
-db:   db,
+addr:   addr,
+db:     db,
+stores: stores,
 }
 }
 
+// SetTranscoder 指定转码器（比如测试用的 transcode.Fake），需要在 Handler 之前调用
+func (s *APIServer) SetTranscoder(transcoder types.Transcoder) {
+s.transcoder = transcoder
+}
+
 // Run 启动服务器的主函数
 func (s *APIServer) Run() error {
+handler := s.Handler(context.Background())
+
+log.Println("🚀 Server is running on", s.addr)
+return http.ListenAndServe(s.addr, handler)
+}
+
+// OpenAPI 返回 Handler 生成的 OpenAPI 文档，以及有路由没有登记文档（或登记的文档没有路由）时的错误
+func (s *APIServer) OpenAPI() (*openapi.Document, error) {
+return s.spec, s.specErr
+}
+
+// Handler 创建完整的路由并启动后台任务（任务执行器、回收站清理、文件删除、Webhook 投递），
+// ctx 结束时后台任务退出
+func (s *APIServer) Handler(ctx context.Context) http.Handler {
+stores := s.stores
+
+// 客户端没有指定语言时错误信息使用的语言
+i18n.Init(config.Envs.DefaultLocale)
+
 // 1. 创建路由器（负责管理所有的 URL 路径）
 router := mux.NewRouter()
 
@@ -54,29 +108,119 @@ router.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 http.Redirect(w, r, "/static/", http.StatusMovedPermanently)
 }).Methods("GET")
 
-// 2. 创建 API 路由组（所有 API 都以 /api/v1 开头）
-subrouter := router.PathPrefix("/api/v1").Subrouter()
-
-// 3. 设置静态文件服务（让浏览器可以访问上传的视频）
-// 访问 /uploads/xxx.mp4 就能看到视频文件
-router.PathPrefix("/uploads/").Handler(
-http.StripPrefix("/uploads/", http.FileServer(http.Dir("./uploads"))))
+// 2. 创建 API 路由组：同一套接口同时挂在 /api/v1（原来的响应格式，兼容旧客户端）
+// 和 /api/v2（统一的响应结构：错误码、字段校验详情、请求 ID）下
+v1 := router.PathPrefix("/api/v1").Subrouter()
+v2 := router.PathPrefix("/api/v2").Subrouter()
+v2.Use(utils.WithEnvelope)
+register := func(h interface{ RegisterRoutes(*mux.Router) }) {
+h.RegisterRoutes(v1)
+h.RegisterRoutes(v2)
+}
 
+// 3. 设置静态文件服务（前端页面）。上传的视频不直接公开，只能通过带权限检查的 /videos/{id}/stream 播放
 // 访问 /static/xxx.html 就能看到前端页面
 router.PathPrefix("/static/").Handler(
 http.StripPrefix("/static/", http.FileServer(http.Dir("./static"))))
 
 // 4. 注册用户相关的路由（注册、登录）
-userStore := user.NewStore(s.db)          // 创建用户数据库操作对象
+userStore := stores.Users                 // 用户数据存储
 userHandler := user.NewHandler(userStore) // 创建用户处理器
-userHandler.RegisterRoutes(subrouter)     // 注册路由
+register(userHandler)     // 注册路由
 
-// 5. 注册视频相关的路由（上传、查询、删除）
-videoStore := video.NewStore(s.db)
-videoHandler := video.NewHandler(videoStore, userStore)
-videoHandler.RegisterRoutes(subrouter)
+// 5. 创建事件总线，并注册实时事件推送路由（SSE）
+eventBus := event.NewBus(stores.Events, int(config.Envs.EventLogSize))
+eventHandler := event.NewHandler(eventBus, userStore)
+register(eventHandler)
 
-// 6. 启动服务器，开始监听请求
-log.Println("🚀 Server is running on", s.addr)
-return http.ListenAndServe(s.addr, corsMiddleware(router))
+// 6. 注册存储配额相关的路由
+quotaManager := quota.NewManager(stores.Quotas, userStore)
+quotaHandler := quota.NewHandler(quotaManager, stores.Quotas, userStore)
+register(quotaHandler)
+
+// 7. 创建后台任务执行器，注册转码和文件清理任务（找不到 ffmpeg 时只记录日志，不转码）
+videoStore := stores.Videos
+jobRunner := job.NewRunner(stores.Jobs, int(config.Envs.JobWorkers), int(config.Envs.JobMaxAttempts))
+renditionStore := stores.Renditions
+
+transcoder := s.transcoder
+if transcoder == nil {
+if ffmpeg, err := transcode.NewFFmpeg(config.Envs.FFmpegPath, config.Envs.FFprobePath); err != nil {
+log.Printf("⚠️  %v, transcoding disabled", err)
+} else {
+transcoder = ffmpeg
+}
+}
+fileStorage := storage.NewLocal(config.Envs.UploadDir)
+pipeline := transcode.NewPipeline(videoStore, renditionStore, transcoder, fileStorage, jobRunner, eventBus)
+jobRunner.Handle(types.JobTranscode, pipeline.HandleTranscode)
+jobRunner.Handle(types.JobHLS, pipeline.HandleHLS)
+jobRunner.Handle(types.JobThumbnails, pipeline.HandleThumbnails)
+beatStore := stores.TempoMaps
+analyzer := beat.NewAnalyzer(videoStore, beatStore, transcoder)
+jobRunner.Handle(types.JobBeats, analyzer.HandleBeats)
+jobRunner.Handle(types.JobSync, analyzer.HandleSync)
+jobRunner.Handle(types.JobDerive, pipeline.HandleDerive)
+jobRunner.Handle(types.JobDeleteStorage, storage.DeleteHandler(fileStorage))
+jobHandler := job.NewHandler(stores.Jobs, userStore)
+register(jobHandler)
+
+// 8. 注册视频相关的路由（上传、查询、删除）
+classStore := stores.Classes
+videoHandler := video.NewHandler(videoStore, userStore, classStore, eventBus, quotaManager, jobRunner, renditionStore, fileStorage, transcoder)
+register(videoHandler)
+beatHandler := beat.NewHandler(beatStore, videoStore, userStore, classStore)
+register(beatHandler)
+segmentHandler := segment.NewHandler(stores.Segments, beatStore, videoStore, userStore, classStore)
+register(segmentHandler)
+
+// 剪辑任务生成的视频按上传流程登记，所有任务类型注册完后再启动执行器
+jobRunner.Handle(types.JobClip, videoHandler.HandleClip)
+go jobRunner.Run(ctx)
+
+// 回收站中超过保留期的视频由后台任务彻底删除
+purger := video.NewPurger(videoStore, quotaManager, time.Duration(config.Envs.TrashRetentionDays)*24*time.Hour)
+go purger.Run(ctx)
+
+// 删除记录时登记的文件由后台任务删除，失败会重试
+fileDeleter := video.NewFileDeleter(stores.Files)
+go fileDeleter.Run(ctx)
+
+// 9. 注册练习记录和班级相关的路由
+practiceStore := stores.Practices
+practiceHandler := practice.NewHandler(practiceStore, videoStore, userStore, classStore, eventBus)
+register(practiceHandler)
+
+classHandler := class.NewHandler(classStore, videoStore, practiceStore, userStore, eventBus)
+register(classHandler)
+
+// 10. 注册视频评论相关的路由
+commentHandler := comment.NewHandler(stores.Comments, videoStore, userStore, classStore, eventBus)
+register(commentHandler)
+
+// 11. 注册同步练习房间相关的路由（WebSocket），后台定期清理没人的房间
+roomHub := room.NewHub()
+go roomHub.Run(ctx)
+roomHandler := room.NewHandler(roomHub, videoStore, userStore, classStore)
+register(roomHandler)
+
+// 12. 注册 Webhook 相关的路由，并启动后台投递任务（监听事件总线）
+webhookStore := stores.Webhooks
+dispatcher := webhook.NewDispatcher(webhookStore, int(config.Envs.WebhookMaxAttempts), config.Envs.WebhookAllowPrivate)
+eventBus.AddListener(dispatcher.HandleEvent)
+go dispatcher.Run(ctx)
+
+webhookHandler := webhook.NewHandler(webhookStore, dispatcher, userStore)
+register(webhookHandler)
+
+// 13. OpenAPI 文档（/api/v1/openapi.json）：由 /api/v1 下的路由和 types 中的请求/响应结构生成，
+// 只挂在 v1 下（文档本身不使用统一的响应结构）；需要在其他路由注册之后生成
+docsHandler := openapi.NewHandler()
+docsHandler.RegisterRoutes(v1)
+s.spec, s.specErr = docsHandler.Build(v1, "/api/v1")
+if s.specErr != nil {
+log.Printf("⚠️ %v", s.specErr)
+}
+
+return utils.WithRequestID(utils.WithLocale(corsMiddleware(router)))
 }
@@ -0,0 +1,62 @@
+package api
+
+import (
+"context"
+"encoding/json"
+"net/http"
+"net/http/httptest"
+"testing"
+
+"github.com/Albert-tru/DanceMirror/db"
+"github.com/Albert-tru/DanceMirror/service/openapi"
+)
+
+// newTestServer 用 SQLite 内存数据库创建服务器并生成路由；ctx 已结束，后台任务直接退出
+func newTestServer(t *testing.T) (*APIServer, http.Handler) {
+t.Helper()
+
+database, err := db.NewSQLiteStorage(":memory:")
+if err != nil {
+t.Fatal(err)
+}
+t.Cleanup(func() { database.Close() })
+if err := db.Migrate(database); err != nil {
+t.Fatal(err)
+}
+
+ctx, cancel := context.WithCancel(context.Background())
+cancel()
+server := NewAPIServer("", database)
+return server, server.Handler(ctx)
+}
+
+// 每个路由都要在 service/openapi/endpoints.go 中登记文档，登记的文档也都要有路由
+func TestOpenAPIMatchesRoutes(t *testing.T) {
+server, _ := newTestServer(t)
+
+spec, err := server.OpenAPI()
+if err != nil {
+t.Fatalf("OpenAPI() error: %v", err)
+}
+if spec == nil || len(spec.Paths) == 0 {
+t.Fatal("OpenAPI() returned an empty document")
+}
+}
+
+func TestOpenAPIServed(t *testing.T) {
+_, handler := newTestServer(t)
+
+rec := httptest.NewRecorder()
+handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/openapi.json", nil))
+if rec.Code != http.StatusOK {
+t.Fatalf("GET /api/v1/openapi.json = %d, want %d", rec.Code, http.StatusOK)
+}
+
+var doc openapi.Document
+if err := json.NewDecoder(rec.Body).Decode(&doc); err != nil {
+t.Fatalf("decode openapi.json: %v", err)
+}
+if _, ok := doc.Paths["/videos/{id}/stream"]; !ok {
+t.Error("openapi.json has no /videos/{id}/stream")
+}
+}
@@ -0,0 +1,341 @@
+// Package apitest 用 httptest 启动完整的 API 路由（和 APIServer 使用同一套路由），
+// 供处理器的表驱动测试使用。
+//
+// 默认所有数据都在同一个 SQLite 内存数据库中（使用和线上相同的数据库存储，外键检查开启），
+// 测试可以通过 Stores 直接读写。WithMemoryStores 把用户、视频和练习记录换成内存存储
+package apitest
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Albert-tru/DanceMirror/cmd/api"
+	"github.com/Albert-tru/DanceMirror/config"
+	"github.com/Albert-tru/DanceMirror/db"
+	"github.com/Albert-tru/DanceMirror/service/auth"
+	"github.com/Albert-tru/DanceMirror/service/practice"
+	"github.com/Albert-tru/DanceMirror/service/transcode"
+	"github.com/Albert-tru/DanceMirror/service/user"
+	"github.com/Albert-tru/DanceMirror/service/video"
+	"github.com/Albert-tru/DanceMirror/types"
+)
+
+// Server 测试服务器，URL 为服务器地址（接口在 /api/v1 下）
+type Server struct {
+	*httptest.Server
+
+	Stores      *api.Stores // 服务器使用的存储
+	DB          *sql.DB
+	Transcoder  *transcode.Fake
+	UploadDir   string
+	memory      *user.MemoryStore // WithMemoryStores 时的用户存储
+	cancel      context.CancelFunc
+	prevUploads string
+}
+
+// Option 测试服务器选项
+type Option func(*Server)
+
+// WithMemoryStores 用户、视频和练习记录使用内存存储（user.NewMemoryStore 等），其他数据仍在
+// SQLite 中。内存中的用户和视频不在数据库里，所以这时数据库的外键检查是关闭的，
+// 需要联表查询用户的接口（评论、班级成员列表）只能用默认的数据库存储测试
+func WithMemoryStores() Option {
+	return func(s *Server) {
+		s.memory = user.NewMemoryStore()
+		videos := video.NewMemoryStore()
+		s.Stores.Users = s.memory
+		s.Stores.Videos = videos
+		s.Stores.Files = videos
+		s.Stores.Practices = practice.NewMemoryStore()
+	}
+}
+
+// New 启动测试服务器。上传目录是新建的临时目录（会修改 config.Envs.UploadDir，
+// 所以同一时间只能运行一个测试服务器），Close 时删除并恢复原来的配置
+func New(opts ...Option) (*Server, error) {
+	database, err := db.NewSQLiteStorage(":memory:")
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Migrate(database); err != nil {
+		database.Close()
+		return nil, err
+	}
+
+	uploadDir, err := os.MkdirTemp("", "dancemirror-apitest-*")
+	if err != nil {
+		database.Close()
+		return nil, err
+	}
+
+	s := &Server{
+		Stores:      api.NewStores(database),
+		DB:          database,
+		Transcoder:  transcode.NewFake(),
+		UploadDir:   uploadDir,
+		prevUploads: config.Envs.UploadDir,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.memory != nil {
+		if _, err := database.Exec("PRAGMA foreign_keys = OFF"); err != nil {
+			database.Close()
+			os.RemoveAll(uploadDir)
+			return nil, err
+		}
+	}
+	config.Envs.UploadDir = uploadDir
+
+	server := api.NewAPIServerWithStores("", database, s.Stores)
+	server.SetTranscoder(s.Transcoder)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.Server = httptest.NewServer(server.Handler(ctx))
+	return s, nil
+}
+
+// Start 和 New 相同，失败时结束测试，测试结束时自动 Close
+func Start(t testing.TB, opts ...Option) *Server {
+	t.Helper()
+
+	s, err := New(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.Close)
+	return s
+}
+
+// EachStore 分别用数据库存储和内存存储（WithMemoryStores）启动测试服务器，在子测试中运行 f
+func EachStore(t *testing.T, f func(t *testing.T, s *Server)) {
+	t.Helper()
+
+	variants := []struct {
+		name string
+		opts []Option
+	}{
+		{"sql", nil},
+		{"memory", []Option{WithMemoryStores()}},
+	}
+	for _, v := range variants {
+		t.Run(v.name, func(t *testing.T) {
+			f(t, Start(t, v.opts...))
+		})
+	}
+}
+
+// Close 停止服务器和后台任务，删除上传目录
+func (s *Server) Close() {
+	s.Server.Close()
+	s.cancel()
+	s.DB.Close()
+	os.RemoveAll(s.UploadDir)
+	config.Envs.UploadDir = s.prevUploads
+}
+
+// CreateUser 直接在存储中创建用户（role 为空时是普通用户），返回用户和登录令牌
+func (s *Server) CreateUser(phone, password, role string) (*types.User, string, error) {
+	hashed, err := auth.HashPassword(password)
+	if err != nil {
+		return nil, "", err
+	}
+
+	ctx := context.Background()
+	err = s.Stores.Users.CreateUser(ctx, types.User{Phone: phone, Password: hashed, FirstName: "Test", LastName: phone})
+	if err != nil {
+		return nil, "", err
+	}
+	u, err := s.Stores.Users.GetUserByPhone(ctx, phone)
+	if err != nil {
+		return nil, "", err
+	}
+	if role != "" && role != u.Role {
+		// 存储接口没有修改角色的方法（角色只能在数据库中修改）
+		if s.memory != nil {
+			err = s.memory.SetRole(u.ID, role)
+		} else {
+			_, err = s.DB.ExecContext(ctx, "UPDATE users SET role = ? WHERE id = ?", role, u.ID)
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		u.Role = role
+	}
+
+	token, err := s.Token(u.ID)
+	if err != nil {
+		return nil, "", err
+	}
+	return u, token, nil
+}
+
+// Token 为用户签发登录令牌
+func (s *Server) Token(userID int) (string, error) {
+	return auth.CreateJWT([]byte(config.Envs.JWTSecret), userID)
+}
+
+// Request 发送请求。body 为 nil、[]byte、string 或按 JSON 编码的值；token 不为空时带上 Authorization
+func (s *Server) Request(method, path, token string, body any) (*http.Response, error) {
+	var reader io.Reader
+	switch b := body.(type) {
+	case nil:
+	case []byte:
+		reader = bytes.NewReader(b)
+	case string:
+		reader = strings.NewReader(b)
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, s.URL+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	return s.Client().Do(req)
+}
+
+// Do 发送请求并检查状态码，out 不为 nil 时把响应解析到 out
+func (s *Server) Do(t testing.TB, method, path, token string, body any, status int, out any) {
+	t.Helper()
+
+	resp, err := s.Request(method, path, token, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != status {
+		t.Fatalf("%s %s = %d %s, want %d", method, path, resp.StatusCode, data, status)
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			t.Fatalf("%s %s: decode %q: %v", method, path, data, err)
+		}
+	}
+}
+
+// Upload 以 multipart 表单上传视频（POST /api/v1/videos），fields 为 title 等其他表单字段
+func (s *Server) Upload(token string, fields map[string]string, fileName string, content []byte) (*http.Response, error) {
+	var buf bytes.Buffer
+	form := multipart.NewWriter(&buf)
+	for k, v := range fields {
+		if err := form.WriteField(k, v); err != nil {
+			return nil, err
+		}
+	}
+	part, err := form.CreateFormFile("video", fileName)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := part.Write(content); err != nil {
+		return nil, err
+	}
+	if err := form.Close(); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.URL+"/api/v1/videos", &buf)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	return s.Client().Do(req)
+}
+
+// FakeMP4 生成能通过上传格式检查的 MP4 内容（ftyp 文件头加 size 字节随机数据，每次内容都不同）
+func FakeMP4(size int) []byte {
+	header := []byte("\x00\x00\x00\x18ftypisom\x00\x00\x02\x00isomiso2")
+	data := make([]byte, size)
+	rand.Read(data)
+	return append(header, data...)
+}
+
+// DecodeJSON 读取并关闭响应体，按 JSON 解析到 v
+func DecodeJSON(resp *http.Response, v any) error {
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("decode %q: %v", data, err)
+	}
+	return nil
+}
+
+// Classroom 一个老师的班级：老师上传视频并布置给班级，学生已加入班级，Outsider 不在班级中。
+// Teacher、Student、Outsider 是各自的登录令牌
+type Classroom struct {
+	Class                      types.Class
+	Video                      types.Video
+	Teacher, Student, Outsider string
+	TeacherID, StudentID       int
+}
+
+// NewClassroom 创建老师（RoleTeacher）、学生和班级外的用户，建好班级并布置一个视频
+func (s *Server) NewClassroom(t testing.TB) *Classroom {
+	t.Helper()
+
+	c := &Classroom{}
+	teacher, token, err := s.CreateUser("13800000001", "password123", types.RoleTeacher)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Teacher, c.TeacherID = token, teacher.ID
+	student, token, err := s.CreateUser("13800000002", "password123", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Student, c.StudentID = token, student.ID
+	if _, c.Outsider, err = s.CreateUser("13800000003", "password123", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	s.Do(t, http.MethodPost, "/api/v1/classes", c.Teacher, types.CreateClassPayload{Name: "Jazz"}, http.StatusCreated, &c.Class)
+	s.Do(t, http.MethodPost, "/api/v1/classes/join", c.Student, types.JoinClassPayload{JoinCode: c.Class.JoinCode}, http.StatusOK, nil)
+
+	resp, err := s.Upload(c.Teacher, map[string]string{"title": "routine"}, "routine.mp4", FakeMP4(1024))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := DecodeJSON(resp, &c.Video); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("upload = %d", resp.StatusCode)
+	}
+	s.Do(t, http.MethodPost, fmt.Sprintf("/api/v1/classes/%d/assignments", c.Class.ID), c.Teacher, types.AssignVideoPayload{VideoID: c.Video.ID}, http.StatusCreated, nil)
+	return c
+}
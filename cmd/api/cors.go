@@ -10,7 +10,8 @@ return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 // 设置 CORS 头
 w.Header().Set("Access-Control-Allow-Origin", "*")
 w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Request-Id")
+w.Header().Set("Access-Control-Expose-Headers", "X-Request-Id")
 w.Header().Set("Access-Control-Max-Age", "3600")
 
 // 处理预检请求
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/Albert-tru/DanceMirror/utils"
 	"github.com/Albert-tru/DanceMirror/utils/logger"
 )
 
@@ -43,6 +44,7 @@ func RequestLogger(next http.Handler) http.Handler {
 
 		// 记录请求日志
 		logger.WithFields(map[string]interface{}{
+			"request_id":  utils.RequestID(r.Context()),
 			"method":      r.Method,
 			"path":        r.URL.Path,
 			"status_code": wrapped.statusCode,
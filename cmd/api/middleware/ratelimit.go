@@ -66,7 +66,7 @@ func RateLimit(limiter *IPRateLimiter) func(http.Handler) http.Handler {
 			// 获取该 IP 的限流器并检查
 			rateLimiter := limiter.GetLimiter(ip)
 			if !rateLimiter.Allow() {
-				utils.TooManyRequests(w, "")
+				utils.WriteError(w, http.StatusTooManyRequests, utils.NewError(utils.CodeTooManyRequests, "too many requests, please try again later"))
 				return
 			}
 
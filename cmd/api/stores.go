@@ -0,0 +1,57 @@
+package api
+
+import (
+"database/sql"
+
+"github.com/Albert-tru/DanceMirror/service/beat"
+"github.com/Albert-tru/DanceMirror/service/class"
+"github.com/Albert-tru/DanceMirror/service/comment"
+"github.com/Albert-tru/DanceMirror/service/event"
+"github.com/Albert-tru/DanceMirror/service/job"
+"github.com/Albert-tru/DanceMirror/service/practice"
+"github.com/Albert-tru/DanceMirror/service/quota"
+"github.com/Albert-tru/DanceMirror/service/segment"
+"github.com/Albert-tru/DanceMirror/service/transcode"
+"github.com/Albert-tru/DanceMirror/service/user"
+"github.com/Albert-tru/DanceMirror/service/video"
+"github.com/Albert-tru/DanceMirror/service/webhook"
+"github.com/Albert-tru/DanceMirror/types"
+)
+
+// Stores API 用到的所有数据存储。NewStores 创建数据库实现，
+// 测试时可以把其中一部分换成内存实现（比如 user.NewMemoryStore）
+type Stores struct {
+Users      types.UserStore
+Videos     types.VideoStore
+Files      types.FileDeletionStore // 待删除的文件，数据库实现和 Videos 是同一个对象
+Practices  types.PracticeStore
+Classes    types.ClassStore
+Comments   types.CommentStore
+Events     types.EventStore
+Quotas     types.QuotaStore
+Jobs       types.JobStore
+Renditions types.RenditionStore
+TempoMaps  types.TempoMapStore
+Segments   types.SegmentStore
+Webhooks   types.WebhookStore
+}
+
+// NewStores 创建基于数据库（MySQL 或 SQLite）的存储
+func NewStores(db *sql.DB) *Stores {
+videoStore := video.NewStore(db)
+return &Stores{
+Users:      user.NewStore(db),
+Videos:     videoStore,
+Files:      videoStore,
+Practices:  practice.NewStore(db),
+Classes:    class.NewStore(db),
+Comments:   comment.NewStore(db),
+Events:     event.NewStore(db),
+Quotas:     quota.NewStore(db),
+Jobs:       job.NewStore(db),
+Renditions: transcode.NewStore(db),
+TempoMaps:  beat.NewStore(db),
+Segments:   segment.NewStore(db),
+Webhooks:   webhook.NewStore(db),
+}
+}
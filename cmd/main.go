@@ -7,11 +7,12 @@ import (
 	"github.com/Albert-tru/DanceMirror/cmd/api"
 	"github.com/Albert-tru/DanceMirror/config"
 	"github.com/Albert-tru/DanceMirror/db"
+	"github.com/Albert-tru/DanceMirror/db/dialect"
 )
 
 func main() {
-	// 1. 连接数据库
-	database, err := db.NewMySQLStorage(config.Envs)
+	// 1. 连接数据库（按 DB_DRIVER 选择 MySQL 或 SQLite）
+	database, err := db.NewStorage(config.Envs)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -19,6 +20,13 @@ func main() {
 	// 2. 检查数据库连接
 	initStorage(database)
 
+	// SQLite 没有单独的迁移步骤（:memory: 每次启动都是空库），启动时自动迁移
+	if dialect.Of(database) == dialect.SQLite {
+		if err := db.Migrate(database); err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	// 3. 启动 Web 服务器
 	server := api.NewAPIServer(":"+config.Envs.Port, database)
 	if err := server.Run(); err != nil {
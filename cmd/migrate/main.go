@@ -7,36 +7,23 @@ import (
 
 	"github.com/Albert-tru/DanceMirror/config"
 	"github.com/Albert-tru/DanceMirror/db"
-	_ "github.com/go-sql-driver/mysql"
 	"github.com/golang-migrate/migrate/v4"
-	"github.com/golang-migrate/migrate/v4/database/mysql"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
 )
 
 func main() {
-	// 1. 连接数据库
-	database, err := db.NewMySQLStorage(config.Envs)
+	// 1. 连接数据库（按 DB_DRIVER 选择 MySQL 或 SQLite）
+	database, err := db.NewStorage(config.Envs)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	// 2. 创建迁移驱动
-	driver, err := mysql.WithInstance(database, &mysql.Config{})
+	// 2. 创建迁移实例（迁移文件内嵌在程序中，按数据库方言选择）
+	m, err := db.NewMigrate(database)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	// 3. 创建迁移实例（从 migrations 目录读取 SQL 文件）
-	m, err := migrate.NewWithDatabaseInstance(
-		"file://cmd/migrate/migrations",
-		"mysql",
-		driver,
-	)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	// 4. 执行命令（up 或 down）
+	// 3. 执行命令（up 或 down）
 	cmd := os.Args[len(os.Args)-1]
 
 	switch cmd {
@@ -0,0 +1,14 @@
+-- 创建练习记录表
+CREATE TABLE IF NOT EXISTS practices (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    userId INT NOT NULL,
+    videoId INT NOT NULL,
+    duration INT NOT NULL,
+    speed FLOAT NOT NULL DEFAULT 1.0,
+    notes TEXT,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_userId (userId),
+    INDEX idx_videoId (videoId),
+    FOREIGN KEY (userId) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (videoId) REFERENCES videos(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,11 @@
+-- 创建班级表（老师创建，学生通过邀请码加入）
+CREATE TABLE IF NOT EXISTS classes (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    teacherId INT NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    joinCode VARCHAR(16) NOT NULL UNIQUE,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_teacherId (teacherId),
+    FOREIGN KEY (teacherId) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
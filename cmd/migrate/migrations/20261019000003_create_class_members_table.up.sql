@@ -0,0 +1,10 @@
+-- 创建班级成员表
+CREATE TABLE IF NOT EXISTS class_members (
+    classId INT NOT NULL,
+    userId INT NOT NULL,
+    joinedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (classId, userId),
+    INDEX idx_userId (userId),
+    FOREIGN KEY (classId) REFERENCES classes(id) ON DELETE CASCADE,
+    FOREIGN KEY (userId) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS class_assignments;
@@ -0,0 +1,12 @@
+-- 创建班级作业表（老师布置给班级的参考视频）
+CREATE TABLE IF NOT EXISTS class_assignments (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    classId INT NOT NULL,
+    videoId INT NOT NULL,
+    note TEXT,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uniq_class_video (classId, videoId),
+    INDEX idx_videoId (videoId),
+    FOREIGN KEY (classId) REFERENCES classes(id) ON DELETE CASCADE,
+    FOREIGN KEY (videoId) REFERENCES videos(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,3 @@
+ALTER TABLE videos
+    DROP INDEX idx_referenceId,
+    DROP COLUMN referenceId;
@@ -0,0 +1,4 @@
+-- 练习录像关联的参考视频（学生上传录像时指定）
+ALTER TABLE videos
+    ADD COLUMN referenceId INT DEFAULT NULL,
+    ADD INDEX idx_referenceId (referenceId);
@@ -0,0 +1,15 @@
+-- 创建视频评论表（带时间点，支持回复）
+CREATE TABLE IF NOT EXISTS video_comments (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    videoId INT NOT NULL,
+    userId INT NOT NULL,
+    parentId INT DEFAULT NULL,
+    videoTime FLOAT NOT NULL DEFAULT 0,
+    body TEXT NOT NULL,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_videoId (videoId),
+    FOREIGN KEY (videoId) REFERENCES videos(id) ON DELETE CASCADE,
+    FOREIGN KEY (userId) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (parentId) REFERENCES video_comments(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,11 @@
+-- 创建 Webhook 投递尝试记录表
+CREATE TABLE IF NOT EXISTS webhook_attempts (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    deliveryId INT NOT NULL,
+    responseStatus INT NOT NULL DEFAULT 0,
+    error TEXT,
+    durationMs INT NOT NULL DEFAULT 0,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_deliveryId (deliveryId),
+    FOREIGN KEY (deliveryId) REFERENCES webhook_deliveries(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
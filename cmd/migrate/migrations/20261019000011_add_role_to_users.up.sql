@@ -0,0 +1,2 @@
+-- 用户角色：user / teacher / admin（决定默认存储配额和管理权限）
+ALTER TABLE users ADD COLUMN role VARCHAR(16) NOT NULL DEFAULT 'user';
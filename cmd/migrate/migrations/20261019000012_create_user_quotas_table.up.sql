@@ -0,0 +1,8 @@
+-- 管理员为单个用户设置的配额（覆盖角色默认值，NULL 表示沿用默认值）
+CREATE TABLE IF NOT EXISTS user_quotas (
+    userId INT PRIMARY KEY,
+    maxBytes BIGINT DEFAULT NULL,
+    maxVideos INT DEFAULT NULL,
+    updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (userId) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,7 @@
+-- 用户存储用量（上传前通过条件 UPDATE 原子地预占）
+CREATE TABLE IF NOT EXISTS storage_usage (
+    userId INT PRIMARY KEY,
+    usedBytes BIGINT NOT NULL DEFAULT 0,
+    videoCount INT NOT NULL DEFAULT 0,
+    FOREIGN KEY (userId) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1 @@
+DELETE FROM storage_usage;
@@ -0,0 +1,3 @@
+-- 根据已有视频初始化存储用量
+INSERT INTO storage_usage (userId, usedBytes, videoCount)
+SELECT userId, SUM(fileSize), COUNT(*) FROM videos GROUP BY userId;
@@ -0,0 +1,3 @@
+ALTER TABLE videos
+    DROP INDEX idx_userId_contentHash,
+    DROP COLUMN contentHash;
@@ -0,0 +1,4 @@
+-- 视频内容的 SHA-256，用于去重
+ALTER TABLE videos
+    ADD COLUMN contentHash CHAR(64) DEFAULT NULL,
+    ADD INDEX idx_userId_contentHash (userId, contentHash);
@@ -0,0 +1,8 @@
+-- 创建视频文件表（相同内容只存一份，按引用计数删除）
+CREATE TABLE IF NOT EXISTS video_blobs (
+    hash CHAR(64) PRIMARY KEY,
+    filePath VARCHAR(500) NOT NULL,
+    fileSize BIGINT NOT NULL,
+    refCount INT NOT NULL DEFAULT 1,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
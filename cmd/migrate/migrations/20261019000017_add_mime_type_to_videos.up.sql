@@ -0,0 +1,2 @@
+-- 服务端根据文件头识别出的 MIME 类型
+ALTER TABLE videos ADD COLUMN mimeType VARCHAR(64) DEFAULT NULL;
@@ -0,0 +1,4 @@
+-- 视频软删除：deletedAt 不为空表示在回收站中
+ALTER TABLE videos
+    ADD COLUMN deletedAt TIMESTAMP NULL DEFAULT NULL,
+    ADD INDEX idx_deletedAt (deletedAt);
@@ -0,0 +1,10 @@
+-- 创建待删除文件表（与数据库记录的删除在同一事务中写入，后台任务负责删除文件并重试）
+CREATE TABLE IF NOT EXISTS file_deletions (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    filePath VARCHAR(500) NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    lastError TEXT,
+    nextAttemptAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_nextAttemptAt (nextAttemptAt)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
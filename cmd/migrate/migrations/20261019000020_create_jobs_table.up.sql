@@ -0,0 +1,16 @@
+-- 创建后台任务表（转码等耗时操作，worker 按 runAt 领取，失败后重试）
+CREATE TABLE IF NOT EXISTS jobs (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    type VARCHAR(32) NOT NULL,
+    videoId INT DEFAULT NULL,
+    payload JSON DEFAULT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    maxAttempts INT NOT NULL DEFAULT 3,
+    lastError TEXT,
+    runAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_status_runAt (status, runAt),
+    INDEX idx_videoId (videoId)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,18 @@
+-- 创建视频转码输出表（每个视频每种规格一条）
+CREATE TABLE IF NOT EXISTS video_renditions (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    videoId INT NOT NULL,
+    kind VARCHAR(32) NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    filePath VARCHAR(500) DEFAULT NULL,
+    mimeType VARCHAR(64) DEFAULT NULL,
+    width INT NOT NULL DEFAULT 0,
+    height INT NOT NULL DEFAULT 0,
+    duration FLOAT NOT NULL DEFAULT 0,
+    fileSize BIGINT NOT NULL DEFAULT 0,
+    error TEXT,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uniq_video_kind (videoId, kind),
+    FOREIGN KEY (videoId) REFERENCES videos(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,4 @@
+ALTER TABLE jobs
+    DROP INDEX idx_userId,
+    DROP COLUMN result,
+    DROP COLUMN userId;
@@ -0,0 +1,5 @@
+-- 任务所属用户和执行结果（例如剪辑生成的视频 ID）
+ALTER TABLE jobs
+    ADD COLUMN userId INT DEFAULT NULL,
+    ADD COLUMN result JSON DEFAULT NULL,
+    ADD INDEX idx_userId (userId);
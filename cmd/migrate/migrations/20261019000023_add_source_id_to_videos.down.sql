@@ -0,0 +1,3 @@
+ALTER TABLE videos
+    DROP INDEX idx_sourceId,
+    DROP COLUMN sourceId;
@@ -0,0 +1,4 @@
+-- 剪辑生成的视频对应的源视频
+ALTER TABLE videos
+    ADD COLUMN sourceId INT DEFAULT NULL,
+    ADD INDEX idx_sourceId (sourceId);
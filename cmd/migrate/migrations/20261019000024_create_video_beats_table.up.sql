@@ -0,0 +1,12 @@
+-- 创建视频节拍表（每个视频一条，节拍时间以 JSON 数组保存）
+CREATE TABLE IF NOT EXISTS video_beats (
+    videoId INT PRIMARY KEY,
+    bpm FLOAT NOT NULL DEFAULT 0,
+    beatsPerBar INT NOT NULL DEFAULT 4,
+    beats JSON NOT NULL,
+    downbeats JSON NOT NULL,
+    confidence FLOAT NOT NULL DEFAULT 0,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (videoId) REFERENCES videos(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,14 @@
+-- 创建视频片段表（用户保存的 AB 循环区间）
+CREATE TABLE IF NOT EXISTS video_segments (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    videoId INT NOT NULL,
+    userId INT NOT NULL,
+    name VARCHAR(100) NOT NULL DEFAULT '',
+    startTime FLOAT NOT NULL,
+    endTime FLOAT NOT NULL,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_userId_videoId (userId, videoId),
+    FOREIGN KEY (videoId) REFERENCES videos(id) ON DELETE CASCADE,
+    FOREIGN KEY (userId) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,3 @@
+ALTER TABLE videos
+    DROP COLUMN syncConfidence,
+    DROP COLUMN syncOffset;
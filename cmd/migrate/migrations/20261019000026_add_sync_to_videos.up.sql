@@ -0,0 +1,4 @@
+-- 练习录像与参考视频的时间偏移（由音频互相关计算）和可信度
+ALTER TABLE videos
+    ADD COLUMN syncOffset FLOAT DEFAULT NULL,
+    ADD COLUMN syncConfidence FLOAT DEFAULT NULL;
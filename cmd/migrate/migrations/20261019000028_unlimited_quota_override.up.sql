@@ -0,0 +1,3 @@
+-- 配额覆盖中的 0 改为表示不允许上传，不限改用 -1；已有的 0（原来表示不限）改为 -1
+UPDATE user_quotas SET maxBytes = -1 WHERE maxBytes = 0;
+UPDATE user_quotas SET maxVideos = -1 WHERE maxVideos = 0;
@@ -0,0 +1,8 @@
+// Package migrations 内嵌数据库迁移文件：本目录是 MySQL 的迁移，
+// sqlite/ 是同一版本的 SQLite 表结构。新增迁移时两边都要加上
+package migrations
+
+import "embed"
+
+//go:embed *.sql sqlite/*.sql
+var FS embed.FS
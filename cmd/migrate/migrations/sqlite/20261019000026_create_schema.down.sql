@@ -0,0 +1,19 @@
+DROP TABLE IF EXISTS video_segments;
+DROP TABLE IF EXISTS video_beats;
+DROP TABLE IF EXISTS video_renditions;
+DROP TABLE IF EXISTS jobs;
+DROP TABLE IF EXISTS file_deletions;
+DROP TABLE IF EXISTS video_blobs;
+DROP TABLE IF EXISTS storage_usage;
+DROP TABLE IF EXISTS user_quotas;
+DROP TABLE IF EXISTS webhook_attempts;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhooks;
+DROP TABLE IF EXISTS events;
+DROP TABLE IF EXISTS video_comments;
+DROP TABLE IF EXISTS class_assignments;
+DROP TABLE IF EXISTS class_members;
+DROP TABLE IF EXISTS classes;
+DROP TABLE IF EXISTS practices;
+DROP TABLE IF EXISTS videos;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,275 @@
+-- SQLite 表结构，对应 MySQL 迁移到 20261019000026 为止的结果（列顺序相同）
+-- 供本地开发和 CI 使用；MySQL 的 ON UPDATE CURRENT_TIMESTAMP 用触发器实现
+
+CREATE TABLE users (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    email VARCHAR(255) COLLATE NOCASE,
+    phone VARCHAR(20) NOT NULL UNIQUE,
+    password VARCHAR(255) NOT NULL,
+    firstName VARCHAR(100) NOT NULL,
+    lastName VARCHAR(100) NOT NULL,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    role VARCHAR(16) NOT NULL DEFAULT 'user'
+);
+CREATE INDEX idx_users_email ON users (email);
+
+CREATE TABLE videos (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    userId INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    title VARCHAR(255) NOT NULL,
+    description TEXT,
+    filePath VARCHAR(500) NOT NULL,
+    fileName VARCHAR(255) NOT NULL,
+    fileSize BIGINT NOT NULL,
+    duration FLOAT DEFAULT NULL,
+    thumbnail VARCHAR(500) DEFAULT NULL,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    referenceId INT DEFAULT NULL,
+    contentHash CHAR(64) DEFAULT NULL,
+    mimeType VARCHAR(64) DEFAULT NULL,
+    deletedAt TIMESTAMP NULL DEFAULT NULL,
+    sourceId INT DEFAULT NULL,
+    syncOffset FLOAT DEFAULT NULL,
+    syncConfidence FLOAT DEFAULT NULL
+);
+CREATE INDEX idx_videos_userId ON videos (userId);
+CREATE INDEX idx_videos_referenceId ON videos (referenceId);
+CREATE INDEX idx_videos_userId_contentHash ON videos (userId, contentHash);
+CREATE INDEX idx_videos_deletedAt ON videos (deletedAt);
+CREATE INDEX idx_videos_sourceId ON videos (sourceId);
+
+CREATE TABLE practices (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    userId INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    videoId INT NOT NULL REFERENCES videos(id) ON DELETE CASCADE,
+    duration INT NOT NULL,
+    speed FLOAT NOT NULL DEFAULT 1.0,
+    notes TEXT,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_practices_userId ON practices (userId);
+CREATE INDEX idx_practices_videoId ON practices (videoId);
+
+CREATE TABLE classes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    teacherId INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    joinCode VARCHAR(16) NOT NULL UNIQUE COLLATE NOCASE,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_classes_teacherId ON classes (teacherId);
+
+CREATE TABLE class_members (
+    classId INT NOT NULL REFERENCES classes(id) ON DELETE CASCADE,
+    userId INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    joinedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (classId, userId)
+);
+CREATE INDEX idx_class_members_userId ON class_members (userId);
+
+CREATE TABLE class_assignments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    classId INT NOT NULL REFERENCES classes(id) ON DELETE CASCADE,
+    videoId INT NOT NULL REFERENCES videos(id) ON DELETE CASCADE,
+    note TEXT,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (classId, videoId)
+);
+CREATE INDEX idx_class_assignments_videoId ON class_assignments (videoId);
+
+CREATE TABLE video_comments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    videoId INT NOT NULL REFERENCES videos(id) ON DELETE CASCADE,
+    userId INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    parentId INT DEFAULT NULL REFERENCES video_comments(id) ON DELETE CASCADE,
+    videoTime FLOAT NOT NULL DEFAULT 0,
+    body TEXT NOT NULL,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_video_comments_videoId ON video_comments (videoId);
+
+CREATE TABLE events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    userId INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type VARCHAR(64) NOT NULL,
+    data TEXT NOT NULL,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_events_userId_id ON events (userId, id);
+
+CREATE TABLE webhooks (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    userId INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    url VARCHAR(2048) NOT NULL,
+    secret VARCHAR(128) NOT NULL,
+    events VARCHAR(512) NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_webhooks_userId ON webhooks (userId);
+
+CREATE TABLE webhook_deliveries (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    webhookId INT NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    event VARCHAR(64) NOT NULL,
+    payload TEXT NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    nextAttemptAt TIMESTAMP NULL DEFAULT NULL,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_webhook_deliveries_webhookId ON webhook_deliveries (webhookId);
+CREATE INDEX idx_webhook_deliveries_status_next ON webhook_deliveries (status, nextAttemptAt);
+
+CREATE TABLE webhook_attempts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    deliveryId INT NOT NULL REFERENCES webhook_deliveries(id) ON DELETE CASCADE,
+    responseStatus INT NOT NULL DEFAULT 0,
+    error TEXT,
+    durationMs INT NOT NULL DEFAULT 0,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_webhook_attempts_deliveryId ON webhook_attempts (deliveryId);
+
+CREATE TABLE user_quotas (
+    userId INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    maxBytes BIGINT DEFAULT NULL,
+    maxVideos INT DEFAULT NULL,
+    updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE storage_usage (
+    userId INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    usedBytes BIGINT NOT NULL DEFAULT 0,
+    videoCount INT NOT NULL DEFAULT 0
+);
+
+CREATE TABLE video_blobs (
+    hash CHAR(64) PRIMARY KEY,
+    filePath VARCHAR(500) NOT NULL,
+    fileSize BIGINT NOT NULL,
+    refCount INT NOT NULL DEFAULT 1,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE file_deletions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    filePath VARCHAR(500) NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    lastError TEXT,
+    nextAttemptAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_file_deletions_nextAttemptAt ON file_deletions (nextAttemptAt);
+
+CREATE TABLE jobs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    type VARCHAR(32) NOT NULL,
+    videoId INT DEFAULT NULL,
+    payload TEXT DEFAULT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    maxAttempts INT NOT NULL DEFAULT 3,
+    lastError TEXT,
+    runAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    userId INT DEFAULT NULL,
+    result TEXT DEFAULT NULL
+);
+CREATE INDEX idx_jobs_status_runAt ON jobs (status, runAt);
+CREATE INDEX idx_jobs_videoId ON jobs (videoId);
+CREATE INDEX idx_jobs_userId ON jobs (userId);
+
+CREATE TABLE video_renditions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    videoId INT NOT NULL REFERENCES videos(id) ON DELETE CASCADE,
+    kind VARCHAR(32) NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    filePath VARCHAR(500) DEFAULT NULL,
+    mimeType VARCHAR(64) DEFAULT NULL,
+    width INT NOT NULL DEFAULT 0,
+    height INT NOT NULL DEFAULT 0,
+    duration FLOAT NOT NULL DEFAULT 0,
+    fileSize BIGINT NOT NULL DEFAULT 0,
+    error TEXT,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (videoId, kind)
+);
+
+CREATE TABLE video_beats (
+    videoId INT PRIMARY KEY REFERENCES videos(id) ON DELETE CASCADE,
+    bpm FLOAT NOT NULL DEFAULT 0,
+    beatsPerBar INT NOT NULL DEFAULT 4,
+    beats TEXT NOT NULL,
+    downbeats TEXT NOT NULL,
+    confidence FLOAT NOT NULL DEFAULT 0,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE video_segments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    videoId INT NOT NULL REFERENCES videos(id) ON DELETE CASCADE,
+    userId INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL DEFAULT '',
+    startTime FLOAT NOT NULL,
+    endTime FLOAT NOT NULL,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_video_segments_userId_videoId ON video_segments (userId, videoId);
+
+-- 更新时刷新 updatedAt（语句里显式设置了 updatedAt 时保留设置的值）
+CREATE TRIGGER videos_updatedAt AFTER UPDATE ON videos
+FOR EACH ROW WHEN NEW.updatedAt IS OLD.updatedAt
+BEGIN
+    UPDATE videos SET updatedAt = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
+
+CREATE TRIGGER video_comments_updatedAt AFTER UPDATE ON video_comments
+FOR EACH ROW WHEN NEW.updatedAt IS OLD.updatedAt
+BEGIN
+    UPDATE video_comments SET updatedAt = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
+
+CREATE TRIGGER webhook_deliveries_updatedAt AFTER UPDATE ON webhook_deliveries
+FOR EACH ROW WHEN NEW.updatedAt IS OLD.updatedAt
+BEGIN
+    UPDATE webhook_deliveries SET updatedAt = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
+
+CREATE TRIGGER user_quotas_updatedAt AFTER UPDATE ON user_quotas
+FOR EACH ROW WHEN NEW.updatedAt IS OLD.updatedAt
+BEGIN
+    UPDATE user_quotas SET updatedAt = CURRENT_TIMESTAMP WHERE userId = NEW.userId;
+END;
+
+CREATE TRIGGER jobs_updatedAt AFTER UPDATE ON jobs
+FOR EACH ROW WHEN NEW.updatedAt IS OLD.updatedAt
+BEGIN
+    UPDATE jobs SET updatedAt = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
+
+CREATE TRIGGER video_renditions_updatedAt AFTER UPDATE ON video_renditions
+FOR EACH ROW WHEN NEW.updatedAt IS OLD.updatedAt
+BEGIN
+    UPDATE video_renditions SET updatedAt = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
+
+CREATE TRIGGER video_beats_updatedAt AFTER UPDATE ON video_beats
+FOR EACH ROW WHEN NEW.updatedAt IS OLD.updatedAt
+BEGIN
+    UPDATE video_beats SET updatedAt = CURRENT_TIMESTAMP WHERE videoId = NEW.videoId;
+END;
+
+CREATE TRIGGER video_segments_updatedAt AFTER UPDATE ON video_segments
+FOR EACH ROW WHEN NEW.updatedAt IS OLD.updatedAt
+BEGIN
+    UPDATE video_segments SET updatedAt = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
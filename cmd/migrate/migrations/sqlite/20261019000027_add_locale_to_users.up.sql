@@ -0,0 +1,2 @@
+-- 用户设置的接口返回信息语言（zh-CN、en），空字符串表示按请求的 Accept-Language
+ALTER TABLE users ADD COLUMN locale VARCHAR(16) NOT NULL DEFAULT '';
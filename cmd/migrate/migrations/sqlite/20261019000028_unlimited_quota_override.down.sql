@@ -0,0 +1,2 @@
+UPDATE user_quotas SET maxBytes = 0 WHERE maxBytes < 0;
+UPDATE user_quotas SET maxVideos = 0 WHERE maxVideos < 0;
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/Albert-tru/DanceMirror/cmd/api"
+	"github.com/Albert-tru/DanceMirror/db"
+)
+
+// 输出 OpenAPI 文档，或检查每个路由都登记了文档（service/openapi/endpoints.go）：
+//
+//	go run cmd/openapi/main.go          输出 /api/v1/openapi.json 的内容
+//	go run cmd/openapi/main.go -check   有路由没有登记文档、或登记的文档没有对应路由时退出码为 1
+func main() {
+	check := flag.Bool("check", false, "只检查路由和文档是否一一对应")
+	flag.Parse()
+
+	// 1. 用 SQLite 内存数据库创建服务器，只需要注册路由；ctx 已结束，后台任务直接退出
+	database, err := db.NewSQLiteStorage(":memory:")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer database.Close()
+	if err := db.Migrate(database); err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	server := api.NewAPIServer("", database)
+	server.Handler(ctx)
+
+	// 2. 检查或输出文档
+	spec, err := server.OpenAPI()
+	if *check {
+		if err != nil {
+			fmt.Fprintln(os.Stderr, strings.ReplaceAll(err.Error(), "; ", "\n"))
+			os.Exit(1)
+		}
+		fmt.Printf("✅ %d 个路径都登记了文档\n", len(spec.Paths))
+		return
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(spec); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Albert-tru/DanceMirror/config"
+	"github.com/Albert-tru/DanceMirror/db"
+	"github.com/Albert-tru/DanceMirror/service/quota"
+	"github.com/Albert-tru/DanceMirror/service/user"
+	"github.com/Albert-tru/DanceMirror/service/video"
+)
+
+// 检查上传目录和 videos 表是否一致：
+//
+//	go run cmd/reconcile/main.go          只报告
+//	go run cmd/reconcile/main.go -repair  删除孤儿文件和文件已丢失的视频记录
+func main() {
+	repair := flag.Bool("repair", false, "删除孤儿文件和文件已丢失的视频记录")
+	grace := flag.Duration("grace", time.Hour, "最近修改过的文件可能仍在上传中，不算孤儿文件")
+	flag.Parse()
+
+	// 1. 连接数据库
+	database, err := db.NewStorage(config.Envs)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// 2. 对比上传目录和数据库记录
+	videoStore := video.NewStore(database)
+	quotaManager := quota.NewManager(quota.NewStore(database), user.NewStore(database))
+	reconciler := video.NewReconciler(videoStore, videoStore, quotaManager, config.Envs.UploadDir, *grace)
+
+	report, err := reconciler.Run(context.Background(), *repair)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// 3. 输出结果
+	fmt.Printf("孤儿文件: %d\n", len(report.OrphanFiles))
+	for _, path := range report.OrphanFiles {
+		fmt.Printf("  %s\n", path)
+	}
+	fmt.Printf("文件丢失的视频: %d\n", len(report.MissingFiles))
+	for _, v := range report.MissingFiles {
+		fmt.Printf("  #%d (user %d) %s\n", v.ID, v.UserID, v.FilePath)
+	}
+	if report.SkippedRecent > 0 {
+		fmt.Printf("跳过最近修改的文件: %d\n", report.SkippedRecent)
+	}
+
+	if !report.Repaired {
+		if len(report.OrphanFiles) > 0 || len(report.MissingFiles) > 0 {
+			fmt.Println("使用 -repair 修复")
+		}
+		return
+	}
+
+	// 4. 立即删除登记的文件（API 服务的后台任务也会处理剩余的）
+	video.NewFileDeleter(videoStore).DeleteDue(context.Background())
+	fmt.Println("✅ 修复完成！")
+}
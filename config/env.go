@@ -15,10 +15,34 @@ type Config struct {
 	DBPassword    string
 	DBAddress     string
 	DBName        string
+	DBDriver      string // mysql 或 sqlite
+	DBPath        string // SQLite 数据库文件路径，:memory: 为内存数据库
 	JWTSecret     string
 	JWTExpiration string
 	UploadDir     string
 	MaxUploadSize int64
+	EventLogSize  int64 // 每个用户保留的事件条数（用于断线重连补发）
+
+	WebhookMaxAttempts  int64 // Webhook 最多投递次数（含首次）
+	WebhookAllowPrivate bool  // 允许 Webhook 投递到本机和内网地址（只用于本地开发和测试）
+
+	// 各角色的默认存储配额（0 表示不限，管理员不限）
+	QuotaUserBytes     int64
+	QuotaUserVideos    int64
+	QuotaTeacherBytes  int64
+	QuotaTeacherVideos int64
+
+	TrashRetentionDays int64 // 回收站中的视频保留多少天后彻底删除
+
+	// 后台任务和转码（找不到 ffmpeg 时不转码）
+	JobWorkers     int64
+	JobMaxAttempts int64
+	FFmpegPath     string
+	FFprobePath    string
+
+	SignedURLTTL int64 // 签名链接（HLS 分片等）的有效期（秒）
+
+	DefaultLocale string // 客户端没有指定语言时接口返回信息的语言（zh-CN 或 en）
 }
 
 var Envs = initConfig()
@@ -45,10 +69,32 @@ func initConfig() Config {
 		DBPassword:    getEnv("DB_PASSWORD", ""),
 		DBAddress:     dbAddress,
 		DBName:        getEnv("DB_NAME", "dancemirror"),
+		DBDriver:      getEnv("DB_DRIVER", "mysql"),
+		DBPath:        getEnv("DB_PATH", "dancemirror.db"),
 		JWTSecret:     getEnv("JWT_SECRET", "super-secret-jwt-key"),
 		JWTExpiration: getEnv("JWT_EXPIRATION", "72h"),
 		UploadDir:     getEnv("UPLOAD_DIR", "./uploads"),
 		MaxUploadSize: getEnvAsInt64("MAX_UPLOAD_SIZE", 524288000),
+		EventLogSize:  getEnvAsInt64("EVENT_LOG_SIZE", 200),
+
+		WebhookMaxAttempts:  getEnvAsInt64("WEBHOOK_MAX_ATTEMPTS", 8),
+		WebhookAllowPrivate: getEnvAsBool("WEBHOOK_ALLOW_PRIVATE", false),
+
+		QuotaUserBytes:     getEnvAsInt64("QUOTA_USER_BYTES", 5*1024*1024*1024),
+		QuotaUserVideos:    getEnvAsInt64("QUOTA_USER_VIDEOS", 100),
+		QuotaTeacherBytes:  getEnvAsInt64("QUOTA_TEACHER_BYTES", 50*1024*1024*1024),
+		QuotaTeacherVideos: getEnvAsInt64("QUOTA_TEACHER_VIDEOS", 1000),
+
+		TrashRetentionDays: getEnvAsInt64("TRASH_RETENTION_DAYS", 30),
+
+		JobWorkers:     getEnvAsInt64("JOB_WORKERS", 2),
+		JobMaxAttempts: getEnvAsInt64("JOB_MAX_ATTEMPTS", 3),
+		FFmpegPath:     getEnv("FFMPEG_PATH", "ffmpeg"),
+		FFprobePath:    getEnv("FFPROBE_PATH", "ffprobe"),
+
+		SignedURLTTL: getEnvAsInt64("SIGNED_URL_TTL", 4*60*60),
+
+		DefaultLocale: getEnv("DEFAULT_LOCALE", "zh-CN"),
 	}
 }
 
@@ -59,6 +105,15 @@ func getEnv(key, fallback string) string {
 	return fallback
 }
 
+func getEnvAsBool(key string, fallback bool) bool {
+	if value, ok := os.LookupEnv(key); ok {
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return fallback
+}
+
 func getEnvAsInt64(key string, fallback int64) int64 {
 	if value, ok := os.LookupEnv(key); ok {
 		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
@@ -0,0 +1,58 @@
+// Package dbtest 为测试创建已执行迁移的 SQLite 内存数据库（外键检查开启，和 db.NewSQLiteStorage 相同）
+package dbtest
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/Albert-tru/DanceMirror/db"
+)
+
+// New 创建数据库，测试结束时关闭
+func New(t testing.TB) *sql.DB {
+	t.Helper()
+
+	database, err := db.NewSQLiteStorage(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.Close() })
+	if err := db.Migrate(database); err != nil {
+		t.Fatal(err)
+	}
+	return database
+}
+
+// CreateUser 直接插入一个用户（满足其他表的外键），返回用户 ID
+func CreateUser(t testing.TB, database *sql.DB, phone string) int {
+	t.Helper()
+
+	res, err := database.ExecContext(context.Background(),
+		"INSERT INTO users (email, phone, password, firstName, lastName) VALUES (?, ?, ?, ?, ?)", "", phone, "x", "Test", phone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return int(id)
+}
+
+// CreateVideo 直接插入一个视频，返回视频 ID
+func CreateVideo(t testing.TB, database *sql.DB, userID int, title string) int {
+	t.Helper()
+
+	res, err := database.ExecContext(context.Background(),
+		"INSERT INTO videos (userId, title, filePath, fileName, fileSize) VALUES (?, ?, ?, ?, ?)",
+		userID, title, "uploads/"+title+".mp4", title+".mp4", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return int(id)
+}
@@ -0,0 +1,96 @@
+// Package dialect 屏蔽 MySQL 和 SQLite 之间的 SQL 差异，
+// 存储层用它拼出两种数据库都能执行的语句
+package dialect
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Albert-tru/DanceMirror/types"
+	"github.com/go-sql-driver/mysql"
+	"github.com/mattn/go-sqlite3"
+)
+
+type Dialect string
+
+const (
+	MySQL  Dialect = "mysql"
+	SQLite Dialect = "sqlite3"
+)
+
+// Of 根据连接使用的驱动判断方言
+func Of(db *sql.DB) Dialect {
+	if _, ok := db.Driver().(*sqlite3.SQLiteDriver); ok {
+		return SQLite
+	}
+	return MySQL
+}
+
+// InsertIgnore 插入时忽略唯一键冲突
+func (d Dialect) InsertIgnore() string {
+	if d == SQLite {
+		return "INSERT OR IGNORE"
+	}
+	return "INSERT IGNORE"
+}
+
+// OnConflict 唯一键 keys 冲突时改为执行 set（set 中的列名指已有的行）
+func (d Dialect) OnConflict(keys string, set string) string {
+	if d == SQLite {
+		return fmt.Sprintf("ON CONFLICT(%s) DO UPDATE SET %s", keys, set)
+	}
+	return "ON DUPLICATE KEY UPDATE " + set
+}
+
+// Upsert 唯一键 keys 冲突时把 cols 更新为要插入的值
+func (d Dialect) Upsert(keys string, cols ...string) string {
+	set := make([]string, len(cols))
+	for i, col := range cols {
+		if d == SQLite {
+			set[i] = fmt.Sprintf("%s = excluded.%s", col, col)
+		} else {
+			set[i] = fmt.Sprintf("%s = VALUES(%s)", col, col)
+		}
+	}
+	return d.OnConflict(keys, strings.Join(set, ", "))
+}
+
+// ForUpdate 在事务中锁定读到的行。SQLite 同一时间只有一个写事务，不需要行锁
+func (d Dialect) ForUpdate() string {
+	if d == SQLite {
+		return ""
+	}
+	return " FOR UPDATE"
+}
+
+// ForUpdateSkipLocked 锁定读到的行并跳过其他事务已锁定的行
+func (d Dialect) ForUpdateSkipLocked() string {
+	if d == SQLite {
+		return ""
+	}
+	return " FOR UPDATE SKIP LOCKED"
+}
+
+// Time 转换要写入或比较的时间参数。SQLite 把时间存成文本，
+// 和 CURRENT_TIMESTAMP 默认值一样统一用 UTC，按字符串比较才是按时间先后
+func (d Dialect) Time(t time.Time) time.Time {
+	if d == SQLite {
+		return t.UTC()
+	}
+	return t
+}
+
+// Conflict 把违反唯一约束的驱动错误包装成 types.ErrConflict，其他错误原样返回
+func Conflict(err error) error {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
+		return fmt.Errorf("%w: %v", types.ErrConflict, err)
+	}
+	if isSQLiteConflict(err) {
+		return fmt.Errorf("%w: %v", types.ErrConflict, err)
+	}
+	return err
+}
@@ -0,0 +1,8 @@
+//go:build !cgo
+
+package dialect
+
+// isSQLiteConflict 不启用 cgo 时 SQLite 驱动不可用（生产镜像只用 MySQL），不会有 SQLite 的错误
+func isSQLiteConflict(err error) bool {
+	return false
+}
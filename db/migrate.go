@@ -0,0 +1,54 @@
+package db
+
+import (
+	"database/sql"
+
+	"github.com/Albert-tru/DanceMirror/cmd/migrate/migrations"
+	"github.com/Albert-tru/DanceMirror/db/dialect"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/database/mysql"
+	"github.com/golang-migrate/migrate/v4/database/sqlite3"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+// NewMigrate 创建迁移实例，按连接的方言使用对应的迁移文件。
+// 迁移实例的 Close 会关闭 db，和 API 共用连接时不要调用
+func NewMigrate(db *sql.DB) (*migrate.Migrate, error) {
+	var driver database.Driver
+	var dir string
+	var err error
+
+	d := dialect.Of(db)
+	switch d {
+	case dialect.SQLite:
+		driver, err = sqlite3.WithInstance(db, &sqlite3.Config{})
+		dir = "sqlite"
+	default:
+		driver, err = mysql.WithInstance(db, &mysql.Config{})
+		dir = "."
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	source, err := iofs.New(migrations.FS, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return migrate.NewWithInstance("iofs", source, string(d), driver)
+}
+
+// Migrate 执行所有待执行的迁移
+func Migrate(db *sql.DB) error {
+	m, err := NewMigrate(db)
+	if err != nil {
+		return err
+	}
+
+	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// NewSQLiteStorage 打开 SQLite 数据库，path 为文件路径或 :memory:（需要 cgo）
+func NewSQLiteStorage(path string) (*sql.DB, error) {
+	log.Printf("Using SQLite database: %s", path)
+
+	// 开启外键约束（级联删除依赖它），读取的时间转换为本地时区，和 MySQL 的 loc=Local 一致
+	dsn := fmt.Sprintf("file:%s?_foreign_keys=on&_busy_timeout=5000&_loc=auto", path)
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite 同一时间只允许一个写入者，:memory: 数据库又是每个连接各自一份，
+	// 所以只用一个连接（不会过期关闭）
+	db.SetMaxOpenConns(1)
+	db.SetConnMaxLifetime(0)
+
+	if err := db.Ping(); err != nil {
+		return nil, err
+	}
+
+	log.Println("✅ Database connected successfully!")
+	return db, nil
+}
@@ -2,6 +2,7 @@ package auth
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -44,33 +45,44 @@ func WithJWTAuth(handlerFunc http.HandlerFunc, store types.UserStore) http.Handl
 		token, err := validateJWT(tokenString)
 		if err != nil {
 			log.Printf("failed to validate token: %v", err)
-			permissionDenied(w)
+			utils.WriteUnauthorized(w)
 			return
 		}
 
 		if !token.Valid {
 			log.Println("invalid token")
-			permissionDenied(w)
+			utils.WriteUnauthorized(w)
 			return
 		}
 
 		claims := token.Claims.(jwt.MapClaims)
-		str := claims["userID"].(string)
+		if expired(claims) {
+			utils.WriteUnauthorized(w)
+			return
+		}
+		str, _ := claims["userID"].(string)
 
 		userID, err := strconv.Atoi(str)
 		if err != nil {
 			log.Printf("failed to convert userID to int: %v", err)
-			permissionDenied(w)
+			utils.WriteUnauthorized(w)
 			return
 		}
 
-		u, err := store.GetUserByID(userID)
-		if err != nil {
+		u, err := store.GetUserByID(r.Context(), userID)
+		if errors.Is(err, types.ErrNotFound) {
 			log.Printf("failed to get user by id: %v", err)
-			permissionDenied(w)
+			utils.WriteUnauthorized(w)
+			return
+		}
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err)
 			return
 		}
 
+		// 用户设置过语言时优先于 Accept-Language
+		utils.SetLocale(w, u.Locale)
+
 		ctx := r.Context()
 		ctx = context.WithValue(ctx, UserKey, u.ID)
 		r = r.WithContext(ctx)
@@ -79,6 +91,26 @@ func WithJWTAuth(handlerFunc http.HandlerFunc, store types.UserStore) http.Handl
 	}
 }
 
+// expired 令牌是否已过期。过期时间在自定义的 expiresAt 中（jwt 库只检查标准的 exp），没有时视为过期
+func expired(claims jwt.MapClaims) bool {
+	expiresAt, ok := claims["expiresAt"].(float64)
+	return !ok || time.Now().Unix() >= int64(expiresAt)
+}
+
+// WithJWTAuthFromQuery 与 WithJWTAuth 相同，但在没有 Authorization 请求头时
+// 允许通过 ?token= 传递令牌（EventSource 和 WebSocket 无法设置请求头）
+func WithJWTAuthFromQuery(handlerFunc http.HandlerFunc, store types.UserStore) http.HandlerFunc {
+	next := WithJWTAuth(handlerFunc, store)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			if token := r.URL.Query().Get("token"); token != "" {
+				r.Header.Set("Authorization", "Bearer "+token)
+			}
+		}
+		next(w, r)
+	}
+}
+
 func validateJWT(tokenString string) (*jwt.Token, error) {
 	return jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
@@ -88,10 +120,6 @@ func validateJWT(tokenString string) (*jwt.Token, error) {
 	})
 }
 
-func permissionDenied(w http.ResponseWriter) {
-	utils.WriteError(w, http.StatusForbidden, fmt.Errorf("permission denied"))
-}
-
 func GetUserIDFromContext(ctx context.Context) int {
 	userID, ok := ctx.Value(UserKey).(int)
 	if !ok {
@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/Albert-tru/DanceMirror/config"
+	"github.com/Albert-tru/DanceMirror/utils"
+)
+
+// SignURL 给路径加上过期时间和签名，持有链接的人在过期前无需令牌即可访问
+// （HLS 播放器请求分片时不会带 Authorization 请求头）
+func SignURL(path string, expires time.Time) string {
+	exp := strconv.FormatInt(expires.Unix(), 10)
+	q := url.Values{}
+	q.Set("expires", exp)
+	q.Set("signature", urlSignature(path, exp))
+	return path + "?" + q.Encode()
+}
+
+// VerifySignedURL 检查请求路径的签名是否有效且未过期
+func VerifySignedURL(r *http.Request) bool {
+	exp := r.URL.Query().Get("expires")
+	sig := r.URL.Query().Get("signature")
+
+	expires, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return false
+	}
+	return hmac.Equal([]byte(sig), []byte(urlSignature(r.URL.Path, exp)))
+}
+
+// WithSignedURL 只允许签名有效的请求访问
+func WithSignedURL(handlerFunc http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !VerifySignedURL(r) {
+			utils.WriteError(w, http.StatusForbidden, utils.NewError(utils.CodePermissionDenied, "permission denied"))
+			return
+		}
+		handlerFunc(w, r)
+	}
+}
+
+func urlSignature(path, expires string) string {
+	mac := hmac.New(sha256.New, []byte(config.Envs.JWTSecret))
+	fmt.Fprintf(mac, "%s\n%s", path, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
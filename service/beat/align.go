@@ -0,0 +1,80 @@
+package beat
+
+import "math"
+
+// Align 用两段音频起音曲线的互相关估计时间偏移：录像中 t 秒的画面对应参考视频的 t+offset 秒
+// （录像比参考视频晚开始时 offset 为正）。confidence 为重叠部分的归一化相关系数，0~1
+func Align(recording, reference []float32, sampleRate int) (offset, confidence float64) {
+	a := onsetEnvelope(recording)
+	b := onsetEnvelope(reference)
+	if len(a) == 0 || len(b) == 0 {
+		return 0, 0
+	}
+	center(a)
+	center(b)
+
+	// 频域计算 c[k] = Σ a[t]·b[t+k]，负的 k 位于数组末尾
+	n := 1
+	for n < len(a)+len(b) {
+		n <<= 1
+	}
+	aRe, aIm := make([]float64, n), make([]float64, n)
+	bRe, bIm := make([]float64, n), make([]float64, n)
+	copy(aRe, a)
+	copy(bRe, b)
+	fft(aRe, aIm)
+	fft(bRe, bIm)
+	for i := 0; i < n; i++ {
+		// conj(A)·B，再取共轭后正变换得到逆变换（结果是实数，只差 1/n 的系数）
+		re := aRe[i]*bRe[i] + aIm[i]*bIm[i]
+		im := aRe[i]*bIm[i] - aIm[i]*bRe[i]
+		aRe[i], aIm[i] = re, -im
+	}
+	fft(aRe, aIm)
+
+	corr := func(k int) float64 {
+		if k < 0 {
+			k += n
+		}
+		return aRe[k] / float64(n)
+	}
+
+	best, bestCorr := 0, math.Inf(-1)
+	for k := -(len(a) - 1); k < len(b); k++ {
+		if c := corr(k); c > bestCorr {
+			best, bestCorr = k, c
+		}
+	}
+
+	// 重叠部分的归一化相关系数
+	var energyA, energyB float64
+	for t := max(0, -best); t < len(a) && t+best < len(b); t++ {
+		energyA += a[t] * a[t]
+		energyB += b[t+best] * b[t+best]
+	}
+	if energyA > 0 && energyB > 0 {
+		confidence = math.Max(0, math.Min(1, bestCorr/math.Sqrt(energyA*energyB)))
+	}
+
+	// 抛物线插值得到小数帧的偏移
+	frames := float64(best)
+	l, m, r := corr(best-1), bestCorr, corr(best+1)
+	if d := l - 2*m + r; d < 0 {
+		frames += 0.5 * (l - r) / d
+	}
+
+	fps := float64(sampleRate) / hopSize
+	return roundMillis(frames / fps), math.Round(confidence*100) / 100
+}
+
+// center 减去均值
+func center(x []float64) {
+	var mean float64
+	for _, v := range x {
+		mean += v
+	}
+	mean /= float64(len(x))
+	for i := range x {
+		x[i] -= mean
+	}
+}
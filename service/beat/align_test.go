@@ -0,0 +1,85 @@
+package beat
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// noise 白噪声加上随机位置、随机强弱的短音（有起音但没有节奏）
+func noise(duration float64, seed int64) []float32 {
+	r := rand.New(rand.NewSource(seed))
+	samples := make([]float32, int(duration*SampleRate))
+	for i := range samples {
+		samples[i] = float32(r.Float64()*2-1) * 0.05
+	}
+	for n := 0; n < int(duration*3); n++ {
+		first := r.Intn(len(samples))
+		gain := 0.2 + 0.8*r.Float64()
+		for j := 0; j < SampleRate/20 && first+j < len(samples); j++ {
+			t := float64(j) / SampleRate
+			samples[first+j] += float32(gain * math.Exp(-t*60) * math.Sin(2*math.Pi*700*t))
+		}
+	}
+	return samples
+}
+
+// cut 截取 samples 中从 start 秒开始的 duration 秒
+func cut(samples []float32, start, duration float64) []float32 {
+	first := int(math.Round(start * SampleRate))
+	return samples[first : first+int(duration*SampleRate)]
+}
+
+func TestAlign(t *testing.T) {
+	// 参考视频和录像是同一段表演的不同截取；表演没有规律的节奏，偏移只有一个最佳值
+	performance := noise(40, 1)
+	reference := cut(performance, 5, 30)
+
+	tests := []struct {
+		name   string
+		offset float64
+	}{
+		{"recording starts later", 3.3},
+		{"recording starts earlier", -1.7},
+		{"in sync", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 录像中 t 秒对应参考视频的 t+offset 秒
+			recording := cut(performance, 5+tt.offset, 20)
+			offset, confidence := Align(recording, reference, SampleRate)
+			if math.Abs(offset-tt.offset) > 0.006 {
+				t.Errorf("offset = %.3fs, want %.3fs", offset, tt.offset)
+			}
+			// 偏移不是整数帧时两条起音曲线的采样位置不同，相关系数略低于 1
+			if confidence < 0.8 {
+				t.Errorf("confidence = %v, want close to 1", confidence)
+			}
+		})
+	}
+}
+
+// 互不相关的音频相关系数很低
+func TestAlignUncorrelated(t *testing.T) {
+	_, confidence := Align(noise(20, 2), noise(30, 3), SampleRate)
+	if confidence > 0.3 {
+		t.Errorf("confidence = %v, want low for unrelated audio", confidence)
+	}
+}
+
+func TestAlignEmpty(t *testing.T) {
+	tests := []struct {
+		name                 string
+		recording, reference []float32
+	}{
+		{"empty recording", nil, noise(5, 4)},
+		{"empty reference", noise(5, 4), nil},
+		{"shorter than a frame", make([]float32, frameSize-1), noise(5, 4)},
+	}
+	for _, tt := range tests {
+		offset, confidence := Align(tt.recording, tt.reference, SampleRate)
+		if offset != 0 || confidence != 0 {
+			t.Errorf("%s: Align = %v, %v, want 0, 0", tt.name, offset, confidence)
+		}
+	}
+}
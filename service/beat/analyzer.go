@@ -0,0 +1,105 @@
+package beat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Albert-tru/DanceMirror/service/job"
+	"github.com/Albert-tru/DanceMirror/types"
+)
+
+// Analyzer 执行音频分析任务（节拍检测、录像与参考视频对齐），音轨通过 Transcoder 解码为 PCM
+type Analyzer struct {
+	videos     types.VideoStore
+	store      types.TempoMapStore
+	transcoder types.Transcoder // 为 nil 时（没有 ffmpeg）跳过分析
+}
+
+func NewAnalyzer(videos types.VideoStore, store types.TempoMapStore, transcoder types.Transcoder) *Analyzer {
+	return &Analyzer{
+		videos:     videos,
+		store:      store,
+		transcoder: transcoder,
+	}
+}
+
+// HandleBeats 执行 types.JobBeats 任务
+func (a *Analyzer) HandleBeats(ctx context.Context, j *types.Job) error {
+	video, err := a.videos.GetVideoByID(ctx, j.VideoID)
+	if errors.Is(err, types.ErrNotFound) {
+		return job.Permanent(err)
+	}
+	if err != nil {
+		return err
+	}
+	if a.transcoder == nil {
+		return nil
+	}
+
+	info, err := a.transcoder.Probe(ctx, video.FilePath)
+	if err != nil {
+		return err
+	}
+
+	// 没有音轨时保存空的节拍信息，和"尚未分析"区分开
+	tempo := &types.TempoMap{BeatsPerBar: beatsPerBar, Beats: []float64{}, Downbeats: []float64{}}
+	if info.AudioCodec != "" {
+		samples, err := a.transcoder.DecodeAudio(ctx, video.FilePath, SampleRate)
+		if err != nil {
+			return err
+		}
+		tempo = Detect(samples, SampleRate)
+	}
+
+	tempo.VideoID = video.ID
+	return a.store.SaveTempoMap(ctx, tempo)
+}
+
+// HandleSync 执行 types.JobSync 任务：对齐练习录像和参考视频的音轨，保存时间偏移
+func (a *Analyzer) HandleSync(ctx context.Context, j *types.Job) error {
+	recording, err := a.videos.GetVideoByID(ctx, j.VideoID)
+	if errors.Is(err, types.ErrNotFound) {
+		return job.Permanent(err)
+	}
+	if err != nil {
+		return err
+	}
+	if recording.ReferenceID == 0 {
+		return job.Permanent(fmt.Errorf("video %d has no reference video", recording.ID))
+	}
+	reference, err := a.videos.GetVideoByID(ctx, recording.ReferenceID)
+	if errors.Is(err, types.ErrNotFound) {
+		return job.Permanent(err)
+	}
+	if err != nil {
+		return err
+	}
+	if a.transcoder == nil {
+		return nil
+	}
+
+	recordingAudio, err := a.decode(ctx, recording)
+	if err != nil {
+		return err
+	}
+	referenceAudio, err := a.decode(ctx, reference)
+	if err != nil {
+		return err
+	}
+
+	offset, confidence := Align(recordingAudio, referenceAudio, SampleRate)
+	return a.videos.UpdateSync(ctx, recording.ID, offset, confidence)
+}
+
+// decode 解码视频的音轨，没有音轨时不再重试
+func (a *Analyzer) decode(ctx context.Context, video *types.Video) ([]float32, error) {
+	info, err := a.transcoder.Probe(ctx, video.FilePath)
+	if err != nil {
+		return nil, err
+	}
+	if info.AudioCodec == "" {
+		return nil, job.Permanent(fmt.Errorf("video %d has no audio track", video.ID))
+	}
+	return a.transcoder.DecodeAudio(ctx, video.FilePath, SampleRate)
+}
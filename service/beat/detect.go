@@ -0,0 +1,250 @@
+package beat
+
+import (
+	"math"
+
+	"github.com/Albert-tru/DanceMirror/types"
+)
+
+const (
+	// SampleRate 分析用的采样率，节拍检测不需要高频部分
+	SampleRate = 11025
+
+	frameSize   = 1024
+	hopSize     = 128 // 约 11.6 毫秒一帧
+	minBPM      = 60
+	maxBPM      = 200
+	beatsPerBar = 4
+	tightness   = 100 // 节拍间隔偏离估计速度的惩罚系数
+)
+
+// Detect 分析单声道 PCM，估计速度并跟踪每一拍的位置（Ellis 2007 的动态规划方法），
+// 再按重音找出每小节的第一拍。没有明显节奏时 Beats 为空
+func Detect(samples []float32, sampleRate int) *types.TempoMap {
+	tempo := &types.TempoMap{
+		BeatsPerBar: beatsPerBar,
+		Beats:       []float64{},
+		Downbeats:   []float64{},
+	}
+
+	fps := float64(sampleRate) / hopSize
+	onset := onsetEnvelope(samples)
+	period, confidence := estimatePeriod(onset, fps)
+	if period == 0 {
+		return tempo
+	}
+	tempo.BPM = math.Round(60*fps/period*10) / 10
+	tempo.Confidence = math.Round(confidence*100) / 100
+
+	// 频谱取了对数，起音刚进入窗口末端（窗函数还很小）时变化最大，帧时间取窗口 7/8 处
+	offset := float64(frameSize) * 7 / 8 / float64(sampleRate)
+	frames := trackBeats(onset, period)
+	for _, f := range frames {
+		tempo.Beats = append(tempo.Beats, roundMillis(float64(f)/fps+offset))
+	}
+
+	phase := downbeatPhase(onset, frames)
+	for i := phase; i < len(tempo.Beats); i += beatsPerBar {
+		tempo.Downbeats = append(tempo.Downbeats, tempo.Beats[i])
+	}
+	return tempo
+}
+
+// onsetEnvelope 起音强度曲线：相邻帧对数频谱的正向变化之和（spectral flux），
+// 减去局部均值后归一化
+func onsetEnvelope(samples []float32) []float64 {
+	if len(samples) < frameSize {
+		return nil
+	}
+	n := (len(samples)-frameSize)/hopSize + 1
+
+	window := hann(frameSize)
+	re := make([]float64, frameSize)
+	im := make([]float64, frameSize)
+	prev := make([]float64, frameSize/2+1)
+	flux := make([]float64, n)
+	for i := 0; i < n; i++ {
+		frame := samples[i*hopSize : i*hopSize+frameSize]
+		for k := range re {
+			re[k] = float64(frame[k]) * window[k]
+			im[k] = 0
+		}
+		fft(re, im)
+
+		var sum float64
+		for k := range prev {
+			mag := math.Log1p(1000 * math.Hypot(re[k], im[k]))
+			if i > 0 && mag > prev[k] {
+				sum += mag - prev[k]
+			}
+			prev[k] = mag
+		}
+		flux[i] = sum
+	}
+
+	// 减去前后约 0.1 秒的均值，只保留突出的起音
+	const radius = 10
+	env := make([]float64, n)
+	var total, squares float64
+	for i := range flux {
+		lo, hi := max(0, i-radius), min(n, i+radius+1)
+		var mean float64
+		for _, v := range flux[lo:hi] {
+			mean += v
+		}
+		mean /= float64(hi - lo)
+		if v := flux[i] - mean; v > 0 {
+			env[i] = v
+			total += v
+			squares += v * v
+		}
+	}
+
+	mean := total / float64(n)
+	std := math.Sqrt(squares/float64(n) - mean*mean)
+	if std > 0 {
+		for i := range env {
+			env[i] /= std
+		}
+	}
+	return env
+}
+
+// estimatePeriod 用起音曲线的自相关估计一拍的帧数；按对数速度的高斯分布偏向 120 BPM，
+// 减少估成一半或两倍速度的情况。返回 0 表示没有节奏
+func estimatePeriod(onset []float64, fps float64) (period, confidence float64) {
+	minLag := int(fps * 60 / maxBPM)
+	maxLag := min(int(math.Ceil(fps*60/minBPM)), len(onset)/2)
+	if maxLag <= minLag+1 {
+		return 0, 0
+	}
+
+	autocorr := func(lag int) float64 {
+		var sum float64
+		for t := lag; t < len(onset); t++ {
+			sum += onset[t] * onset[t-lag]
+		}
+		return sum / float64(len(onset)-lag)
+	}
+
+	energy := autocorr(0)
+	if energy == 0 {
+		return 0, 0
+	}
+
+	ac := make([]float64, maxLag+2)
+	best, bestScore := 0, 0.0
+	for lag := minLag - 1; lag <= maxLag+1 && lag < len(onset); lag++ {
+		ac[lag] = autocorr(lag)
+		if lag < minLag || lag > maxLag {
+			continue
+		}
+		bpm := 60 * fps / float64(lag)
+		weight := math.Exp(-0.5 * math.Pow(math.Log2(bpm/120), 2))
+		if score := ac[lag] * weight; score > bestScore {
+			best, bestScore = lag, score
+		}
+	}
+	if best == 0 {
+		return 0, 0
+	}
+
+	// 抛物线插值得到小数帧的周期
+	period = float64(best)
+	a, b, c := ac[best-1], ac[best], ac[best+1]
+	if d := a - 2*b + c; d < 0 {
+		period += 0.5 * (a - c) / d
+	}
+	return period, math.Min(ac[best]/energy, 1)
+}
+
+// trackBeats 动态规划选择节拍帧：每一拍的得分是起音强度加上前一拍的最佳得分，
+// 两拍间隔偏离 period 时扣分
+func trackBeats(onset []float64, period float64) []int {
+	n := len(onset)
+	score := make([]float64, n)
+	back := make([]int, n)
+	lo, hi := int(math.Round(period/2)), int(math.Round(2*period))
+	for i := range onset {
+		back[i] = -1
+		best := math.Inf(-1)
+		for prev := max(0, i-hi); prev <= i-lo; prev++ {
+			s := score[prev] - tightness*math.Pow(math.Log(float64(i-prev)/period), 2)
+			if s > best {
+				best, back[i] = s, prev
+			}
+		}
+		score[i] = onset[i]
+		if back[i] >= 0 {
+			score[i] += best
+		}
+	}
+
+	// 最后一拍取最后一个周期内得分最高的帧，再沿 back 回溯
+	last := max(0, n-int(math.Ceil(period)))
+	for i := last; i < n; i++ {
+		if score[i] > score[last] {
+			last = i
+		}
+	}
+	var frames []int
+	for i := last; i >= 0; i = back[i] {
+		frames = append(frames, i)
+	}
+	for i, j := 0, len(frames)-1; i < j; i, j = i+1, j-1 {
+		frames[i], frames[j] = frames[j], frames[i]
+	}
+
+	return trimWeakBeats(onset, frames)
+}
+
+// trimWeakBeats 去掉开头和结尾没有起音的拍子（片头片尾的静音段）
+func trimWeakBeats(onset []float64, frames []int) []int {
+	var squares float64
+	for _, f := range frames {
+		squares += onset[f] * onset[f]
+	}
+	if len(frames) == 0 {
+		return frames
+	}
+	threshold := 0.5 * math.Sqrt(squares/float64(len(frames)))
+
+	start, end := 0, len(frames)
+	for start < end && localPeak(onset, frames[start]) < threshold {
+		start++
+	}
+	for end > start && localPeak(onset, frames[end-1]) < threshold {
+		end--
+	}
+	return frames[start:end]
+}
+
+// downbeatPhase 小节第一拍通常最重：取平均起音强度最大的相位
+func downbeatPhase(onset []float64, frames []int) int {
+	best, bestStrength := 0, -1.0
+	for phase := 0; phase < beatsPerBar && phase < len(frames); phase++ {
+		var sum float64
+		var count int
+		for i := phase; i < len(frames); i += beatsPerBar {
+			sum += localPeak(onset, frames[i])
+			count++
+		}
+		if strength := sum / float64(count); strength > bestStrength {
+			best, bestStrength = phase, strength
+		}
+	}
+	return best
+}
+
+// localPeak 帧附近（前后 2 帧）的最大起音强度，容忍节拍位置的少量误差
+func localPeak(onset []float64, frame int) float64 {
+	var peak float64
+	for i := max(0, frame-2); i <= frame+2 && i < len(onset); i++ {
+		peak = math.Max(peak, onset[i])
+	}
+	return peak
+}
+
+func roundMillis(seconds float64) float64 {
+	return math.Round(seconds*1000) / 1000
+}
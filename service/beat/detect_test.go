@@ -0,0 +1,118 @@
+package beat
+
+import (
+	"math"
+	"testing"
+)
+
+// clickTrack 生成节拍音：从 start 秒开始每拍一个衰减的 1kHz 短音，
+// 下标 i%4 == accent 的拍子更响（小节第一拍）
+func clickTrack(bpm, start, duration float64, accent int) (samples []float32, clicks []float64) {
+	samples = make([]float32, int(duration*SampleRate))
+	period := 60 / bpm
+	for i := 0; start+float64(i)*period < duration-0.1; i++ {
+		at := start + float64(i)*period
+		clicks = append(clicks, at)
+		gain := 0.4
+		if i%beatsPerBar == accent {
+			gain = 1
+		}
+		first := int(at * SampleRate)
+		for j := 0; j < SampleRate/20 && first+j < len(samples); j++ {
+			t := float64(j) / SampleRate
+			samples[first+j] = float32(gain * math.Exp(-t*60) * math.Sin(2*math.Pi*1000*t))
+		}
+	}
+	return samples, clicks
+}
+
+func TestDetect(t *testing.T) {
+	hop := float64(hopSize) / SampleRate
+
+	tests := []struct {
+		name   string
+		bpm    float64
+		start  float64
+		accent int
+	}{
+		{"120 bpm", 120, 0.5, 0},
+		{"90 bpm", 90, 0.3, 1},
+		{"100 bpm with a late downbeat", 100, 1, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			samples, clicks := clickTrack(tt.bpm, tt.start, 20, tt.accent)
+			tempo := Detect(samples, SampleRate)
+
+			if math.Abs(tempo.BPM-tt.bpm) > 1 {
+				t.Fatalf("BPM = %v, want %v", tempo.BPM, tt.bpm)
+			}
+			if tempo.Confidence <= 0.3 {
+				t.Errorf("confidence = %v, want a clear rhythm", tempo.Confidence)
+			}
+			if len(tempo.Beats) < len(clicks)-2 {
+				t.Errorf("found %d beats, want about %d", len(tempo.Beats), len(clicks))
+			}
+
+			// 每一拍都落在某个节拍音一帧之内
+			for _, b := range tempo.Beats {
+				i := nearest(clicks, b)
+				if math.Abs(clicks[i]-b) > hop {
+					t.Errorf("beat at %.3fs is %.1fms from the nearest click", b, 1000*math.Abs(clicks[i]-b))
+				}
+			}
+
+			// 小节第一拍是加重的节拍音
+			if len(tempo.Downbeats) == 0 {
+				t.Fatal("no downbeats")
+			}
+			for _, d := range tempo.Downbeats {
+				if i := nearest(clicks, d); i%beatsPerBar != tt.accent {
+					t.Errorf("downbeat at %.3fs is click %d, want an accented click (i%%4 == %d)", d, i, tt.accent)
+				}
+			}
+		})
+	}
+}
+
+// 没有节奏时不报错，返回空的网格
+func TestDetectNoRhythm(t *testing.T) {
+	tests := []struct {
+		name    string
+		samples []float32
+	}{
+		{"empty", nil},
+		{"shorter than a frame", make([]float32, frameSize-1)},
+		{"silence", make([]float32, 10*SampleRate)},
+		{"too short for a tempo", clickTrackSamples(120, 0.2)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempo := Detect(tt.samples, SampleRate)
+			if tempo.BPM != 0 || len(tempo.Beats) != 0 || len(tempo.Downbeats) != 0 {
+				t.Errorf("Detect = %+v, want an empty grid", tempo)
+			}
+			if tempo.Beats == nil || tempo.Downbeats == nil {
+				t.Error("Beats and Downbeats must be empty slices, not nil")
+			}
+		})
+	}
+}
+
+func TestEstimatePeriodPrefersModerateTempo(t *testing.T) {
+	fps := float64(SampleRate) / hopSize
+	samples, _ := clickTrack(100, 0.5, 20, 0)
+	period, confidence := estimatePeriod(onsetEnvelope(samples), fps)
+	// 200 BPM 和 50 BPM 的周期也有相关，应该选择 100 BPM
+	if bpm := 60 * fps / period; math.Abs(bpm-100) > 1 {
+		t.Errorf("tempo = %.1f BPM, want 100", bpm)
+	}
+	if confidence <= 0 || confidence > 1 {
+		t.Errorf("confidence = %v, want (0, 1]", confidence)
+	}
+}
+
+func clickTrackSamples(bpm, duration float64) []float32 {
+	samples, _ := clickTrack(bpm, 0, duration, 0)
+	return samples
+}
@@ -0,0 +1,46 @@
+package beat
+
+import "math"
+
+// fft 原地计算复数序列的快速傅里叶变换，长度必须是 2 的幂
+func fft(re, im []float64) {
+	n := len(re)
+
+	// 位反转重排
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j |= bit
+		if i < j {
+			re[i], re[j] = re[j], re[i]
+			im[i], im[j] = im[j], im[i]
+		}
+	}
+
+	for size := 2; size <= n; size <<= 1 {
+		angle := -2 * math.Pi / float64(size)
+		wRe, wIm := math.Cos(angle), math.Sin(angle)
+		for start := 0; start < n; start += size {
+			uRe, uIm := 1.0, 0.0
+			for k := 0; k < size/2; k++ {
+				a, b := start+k, start+k+size/2
+				tRe := re[b]*uRe - im[b]*uIm
+				tIm := re[b]*uIm + im[b]*uRe
+				re[b], im[b] = re[a]-tRe, im[a]-tIm
+				re[a], im[a] = re[a]+tRe, im[a]+tIm
+				uRe, uIm = uRe*wRe-uIm*wIm, uRe*wIm+uIm*wRe
+			}
+		}
+	}
+}
+
+// hann 汉宁窗
+func hann(n int) []float64 {
+	w := make([]float64, n)
+	for i := range w {
+		w[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(n))
+	}
+	return w
+}
@@ -0,0 +1,123 @@
+package beat
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/Albert-tru/DanceMirror/types"
+)
+
+// 终点与某一拍相差不到这么多秒时，认为片段在这一拍之前结束
+const boundaryTolerance = 0.05
+
+// HasGrid 是否有可用的节拍网格
+func HasGrid(tempo *types.TempoMap) bool {
+	return tempo != nil && len(tempo.Beats) > 0
+}
+
+// Snap 把起止时间对齐到最近的拍子、小节或八拍；对齐后区间为空时终点取起点后的下一个对齐点，
+// 起点已是最后一个对齐点时改为前一个对齐点到它。只有一个对齐点时不对齐
+func Snap(tempo *types.TempoMap, start, end float64, mode string) (float64, float64) {
+	points := gridPoints(tempo, mode)
+	if len(points) < 2 {
+		return start, end
+	}
+
+	s, e := nearest(points, start), nearest(points, end)
+	if points[e] <= points[s] {
+		if s+1 < len(points) {
+			e = s + 1
+		} else {
+			s, e = s-1, s
+		}
+	}
+	return points[s], points[e]
+}
+
+// Counts 计算片段覆盖的拍子，没有节拍网格时返回 nil
+func Counts(tempo *types.TempoMap, start, end float64) *types.SegmentCounts {
+	if !HasGrid(tempo) {
+		return nil
+	}
+
+	first := nearest(tempo.Beats, start)
+	// 最后一拍是终点之前的那一拍
+	last := sort.SearchFloat64s(tempo.Beats, end-boundaryTolerance) - 1
+	if last < first {
+		last = first
+	}
+
+	counts := &types.SegmentCounts{
+		Start: position(tempo, first),
+		End:   position(tempo, last),
+		Beats: last - first + 1,
+	}
+	counts.Label = fmt.Sprintf("%d-%d ~ %d-%d", counts.Start.Eight, counts.Start.Count, counts.End.Eight, counts.End.Count)
+	return counts
+}
+
+// position 第 i 拍的位置，小节和八拍从第一个小节第一拍开始计数
+func position(tempo *types.TempoMap, i int) types.BeatPosition {
+	perBar := tempo.BeatsPerBar
+	if perBar <= 0 {
+		perBar = beatsPerBar
+	}
+
+	rel := i - firstDownbeat(tempo)
+	return types.BeatPosition{
+		Time:  tempo.Beats[i],
+		Bar:   floorDiv(rel, perBar) + 1,
+		Beat:  rel - floorDiv(rel, perBar)*perBar + 1,
+		Eight: floorDiv(rel, 8) + 1,
+		Count: rel - floorDiv(rel, 8)*8 + 1,
+	}
+}
+
+// gridPoints 对齐方式对应的时间点
+func gridPoints(tempo *types.TempoMap, mode string) []float64 {
+	if !HasGrid(tempo) {
+		return nil
+	}
+
+	switch mode {
+	case types.SnapBeat:
+		return tempo.Beats
+	case types.SnapBar:
+		if len(tempo.Downbeats) > 0 {
+			return tempo.Downbeats
+		}
+		return tempo.Beats
+	case types.SnapEight:
+		points := []float64{}
+		for i := firstDownbeat(tempo); i < len(tempo.Beats); i += 8 {
+			points = append(points, tempo.Beats[i])
+		}
+		return points
+	}
+	return nil
+}
+
+// firstDownbeat 第一个小节第一拍在 Beats 中的下标
+func firstDownbeat(tempo *types.TempoMap) int {
+	if len(tempo.Downbeats) == 0 {
+		return 0
+	}
+	return nearest(tempo.Beats, tempo.Downbeats[0])
+}
+
+// nearest 有序时间点中离 t 最近的下标
+func nearest(points []float64, t float64) int {
+	i := sort.SearchFloat64s(points, t)
+	if i == len(points) {
+		return i - 1
+	}
+	if i > 0 && t-points[i-1] <= points[i]-t {
+		return i - 1
+	}
+	return i
+}
+
+func floorDiv(a, b int) int {
+	return int(math.Floor(float64(a) / float64(b)))
+}
@@ -0,0 +1,109 @@
+package beat
+
+import (
+	"testing"
+
+	"github.com/Albert-tru/DanceMirror/types"
+)
+
+// grid 12 拍，每拍 0.5 秒（1.0~6.5 秒），第一拍是弱起，小节从第二拍（1.5 秒）开始
+func grid() *types.TempoMap {
+	tempo := &types.TempoMap{BPM: 120, BeatsPerBar: 4, Downbeats: []float64{1.5, 3.5, 5.5}}
+	for i := 0; i < 12; i++ {
+		tempo.Beats = append(tempo.Beats, 1+0.5*float64(i))
+	}
+	return tempo
+}
+
+func TestSnap(t *testing.T) {
+	oneBar := grid()
+	oneBar.Downbeats = []float64{1.5}
+	oneEight := &types.TempoMap{Beats: grid().Beats[:8], Downbeats: []float64{1.5}}
+
+	tests := []struct {
+		name       string
+		tempo      *types.TempoMap
+		start, end float64
+		mode       string
+		wantStart  float64
+		wantEnd    float64
+	}{
+		{"nearest beats", grid(), 1.1, 2.4, types.SnapBeat, 1, 2.5},
+		{"ties go to the earlier beat", grid(), 1.25, 2.75, types.SnapBeat, 1, 2.5},
+		{"before the first and after the last beat", grid(), 0, 9, types.SnapBeat, 1, 6.5},
+		{"empty interval takes the next beat", grid(), 1.6, 1.7, types.SnapBeat, 1.5, 2},
+		{"end before start", grid(), 3, 2, types.SnapBeat, 3, 3.5},
+		{"empty interval at the last beat takes the previous one", grid(), 6.4, 6.6, types.SnapBeat, 6, 6.5},
+		{"bars", grid(), 1.4, 4.4, types.SnapBar, 1.5, 3.5},
+		{"empty bar interval takes the next bar", grid(), 1.4, 2, types.SnapBar, 1.5, 3.5},
+		{"empty bar interval at the last bar", grid(), 5.2, 6.5, types.SnapBar, 3.5, 5.5},
+		{"bars without downbeats use beats", &types.TempoMap{Beats: []float64{1, 1.5, 2}}, 1.1, 1.9, types.SnapBar, 1, 2},
+		{"eights", grid(), 2, 5, types.SnapEight, 1.5, 5.5},
+		{"single bar is not snapped", oneBar, 1.4, 2.2, types.SnapBar, 1.4, 2.2},
+		{"single eight is not snapped", oneEight, 2, 3, types.SnapEight, 2, 3},
+		{"single beat is not snapped", &types.TempoMap{Beats: []float64{1}}, 0.2, 0.8, types.SnapBeat, 0.2, 0.8},
+		{"no grid", &types.TempoMap{}, 1.1, 2.4, types.SnapBeat, 1.1, 2.4},
+		{"nil tempo map", nil, 1.1, 2.4, types.SnapBeat, 1.1, 2.4},
+		{"unknown mode", grid(), 1.1, 2.4, "measure", 1.1, 2.4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end := Snap(tt.tempo, tt.start, tt.end, tt.mode)
+			if start != tt.wantStart || end != tt.wantEnd {
+				t.Errorf("Snap(%v, %v, %q) = %v, %v; want %v, %v", tt.start, tt.end, tt.mode, start, end, tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestCounts(t *testing.T) {
+	tests := []struct {
+		name       string
+		start, end float64
+		label      string
+		beats      int
+		first      types.BeatPosition
+		last       types.BeatPosition
+	}{
+		// 弱起的一拍属于第 0 个八拍的第 8 拍、第 0 小节的第 4 拍
+		{"anacrusis", 1, 2.5, "0-8 ~ 1-2", 3,
+			types.BeatPosition{Time: 1, Bar: 0, Beat: 4, Eight: 0, Count: 8},
+			types.BeatPosition{Time: 2, Bar: 1, Beat: 2, Eight: 1, Count: 2}},
+		{"one eight", 1.5, 5.5, "1-1 ~ 1-8", 8,
+			types.BeatPosition{Time: 1.5, Bar: 1, Beat: 1, Eight: 1, Count: 1},
+			types.BeatPosition{Time: 5, Bar: 2, Beat: 4, Eight: 1, Count: 8}},
+		{"into the second eight", 1.5, 6, "1-1 ~ 2-1", 9,
+			types.BeatPosition{Time: 1.5, Bar: 1, Beat: 1, Eight: 1, Count: 1},
+			types.BeatPosition{Time: 5.5, Bar: 3, Beat: 1, Eight: 2, Count: 1}},
+		{"end just after a beat", 1.5, 3.52, "1-1 ~ 1-4", 4,
+			types.BeatPosition{Time: 1.5, Bar: 1, Beat: 1, Eight: 1, Count: 1},
+			types.BeatPosition{Time: 3, Bar: 1, Beat: 4, Eight: 1, Count: 4}},
+		{"shorter than a beat", 2.9, 3.1, "1-4 ~ 1-4", 1,
+			types.BeatPosition{Time: 3, Bar: 1, Beat: 4, Eight: 1, Count: 4},
+			types.BeatPosition{Time: 3, Bar: 1, Beat: 4, Eight: 1, Count: 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counts := Counts(grid(), tt.start, tt.end)
+			if counts == nil {
+				t.Fatal("Counts = nil")
+			}
+			if counts.Label != tt.label || counts.Beats != tt.beats {
+				t.Errorf("Counts(%v, %v) = %q (%d beats), want %q (%d beats)", tt.start, tt.end, counts.Label, counts.Beats, tt.label, tt.beats)
+			}
+			if counts.Start != tt.first || counts.End != tt.last {
+				t.Errorf("Counts(%v, %v) = %+v ~ %+v, want %+v ~ %+v", tt.start, tt.end, counts.Start, counts.End, tt.first, tt.last)
+			}
+		})
+	}
+
+	if counts := Counts(&types.TempoMap{}, 1, 2); counts != nil {
+		t.Errorf("Counts without a grid = %+v, want nil", counts)
+	}
+
+	// 没有小节信息时从第一拍开始计数
+	counts := Counts(&types.TempoMap{Beats: []float64{1, 1.5, 2}}, 1, 2.5)
+	if counts == nil || counts.Label != "1-1 ~ 1-3" {
+		t.Errorf("Counts without downbeats = %+v, want 1-1 ~ 1-3", counts)
+	}
+}
@@ -0,0 +1,71 @@
+package beat
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Albert-tru/DanceMirror/service/auth"
+	"github.com/Albert-tru/DanceMirror/service/video"
+	"github.com/Albert-tru/DanceMirror/types"
+	"github.com/Albert-tru/DanceMirror/utils"
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	store      types.TempoMapStore
+	videoStore types.VideoStore
+	userStore  types.UserStore
+	classStore types.ClassStore
+}
+
+func NewHandler(store types.TempoMapStore, videoStore types.VideoStore, userStore types.UserStore, classStore types.ClassStore) *Handler {
+	return &Handler{
+		store:      store,
+		videoStore: videoStore,
+		userStore:  userStore,
+		classStore: classStore,
+	}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/videos/{id}/beats", auth.WithJWTAuth(h.handleGetBeats, h.userStore)).Methods(http.MethodGet)
+}
+
+// handleGetBeats 返回视频的节拍信息，上传后由后台任务分析，尚未完成时返回 404
+func (h *Handler) handleGetBeats(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, utils.NewError(utils.CodeInvalidID, "invalid video id"))
+		return
+	}
+
+	v, err := h.videoStore.GetVideoByID(r.Context(), id)
+	if err != nil {
+		utils.WriteStoreError(w, err)
+		return
+	}
+
+	userID := auth.GetUserIDFromContext(r.Context())
+	ok, err := video.CanView(r.Context(), h.classStore, v, userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if !ok {
+		utils.WriteError(w, http.StatusForbidden, utils.NewError(utils.CodePermissionDenied, "permission denied"))
+		return
+	}
+
+	tempo, err := h.store.GetTempoMap(r.Context(), v.ID)
+	if errors.Is(err, types.ErrNotFound) {
+		utils.WriteError(w, http.StatusNotFound, utils.NewError(utils.CodeNotFound, "beats not analyzed yet"))
+		return
+	}
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, tempo)
+}
@@ -0,0 +1,91 @@
+package beat
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Albert-tru/DanceMirror/db/dialect"
+	"github.com/Albert-tru/DanceMirror/types"
+)
+
+// tempoColumns 和 scanRowIntoTempoMap 的顺序一致
+const tempoColumns = "videoId, bpm, beatsPerBar, beats, downbeats, confidence, createdAt, updatedAt"
+
+type Store struct {
+	db      *sql.DB
+	dialect dialect.Dialect
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db, dialect: dialect.Of(db)}
+}
+
+func (s *Store) SaveTempoMap(ctx context.Context, tempo *types.TempoMap) error {
+	beats, err := json.Marshal(tempo.Beats)
+	if err != nil {
+		return err
+	}
+	downbeats, err := json.Marshal(tempo.Downbeats)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+INSERT INTO video_beats (videoId, bpm, beatsPerBar, beats, downbeats, confidence)
+VALUES (?, ?, ?, ?, ?, ?)
+`+s.dialect.Upsert("videoId", "bpm", "beatsPerBar", "beats", "downbeats", "confidence"),
+		tempo.VideoID, tempo.BPM, tempo.BeatsPerBar, beats, downbeats, tempo.Confidence)
+	return err
+}
+
+func (s *Store) GetTempoMap(ctx context.Context, videoID int) (*types.TempoMap, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+tempoColumns+" FROM video_beats WHERE videoId = ?", videoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tempo *types.TempoMap
+	for rows.Next() {
+		tempo, err = scanRowIntoTempoMap(rows)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if tempo == nil {
+		return nil, fmt.Errorf("tempo map %w", types.ErrNotFound)
+	}
+
+	return tempo, nil
+}
+
+func scanRowIntoTempoMap(rows *sql.Rows) (*types.TempoMap, error) {
+	tempo := new(types.TempoMap)
+
+	var beats, downbeats []byte
+	err := rows.Scan(
+		&tempo.VideoID,
+		&tempo.BPM,
+		&tempo.BeatsPerBar,
+		&beats,
+		&downbeats,
+		&tempo.Confidence,
+		&tempo.CreatedAt,
+		&tempo.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(beats, &tempo.Beats); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(downbeats, &tempo.Downbeats); err != nil {
+		return nil, err
+	}
+
+	return tempo, nil
+}
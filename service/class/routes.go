@@ -0,0 +1,477 @@
+package class
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Albert-tru/DanceMirror/service/auth"
+	"github.com/Albert-tru/DanceMirror/types"
+	"github.com/Albert-tru/DanceMirror/utils"
+	"github.com/gorilla/mux"
+)
+
+// 邀请码字符集（去掉了容易混淆的 0/O、1/I/L）
+const joinCodeAlphabet = "23456789ABCDEFGHJKMNPQRSTUVWXYZ"
+
+const joinCodeLength = 8
+
+type Handler struct {
+	store         types.ClassStore
+	videoStore    types.VideoStore
+	practiceStore types.PracticeStore
+	userStore     types.UserStore
+	events        types.EventPublisher
+}
+
+func NewHandler(store types.ClassStore, videoStore types.VideoStore, practiceStore types.PracticeStore, userStore types.UserStore, events types.EventPublisher) *Handler {
+	return &Handler{
+		store:         store,
+		videoStore:    videoStore,
+		practiceStore: practiceStore,
+		userStore:     userStore,
+		events:        events,
+	}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/classes", auth.WithJWTAuth(h.handleGetClasses, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/classes", auth.WithJWTAuth(h.handleCreateClass, h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/classes/join", auth.WithJWTAuth(h.handleJoinClass, h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/classes/{id}", auth.WithJWTAuth(h.handleGetClass, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/classes/{id}", auth.WithJWTAuth(h.handleDeleteClass, h.userStore)).Methods(http.MethodDelete)
+
+	// 成员管理
+	router.HandleFunc("/classes/{id}/members", auth.WithJWTAuth(h.handleGetMembers, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/classes/{id}/members/{userId}", auth.WithJWTAuth(h.handleRemoveMember, h.userStore)).Methods(http.MethodDelete)
+	router.HandleFunc("/classes/{id}/members/{userId}/activity", auth.WithJWTAuth(h.handleGetStudentActivity, h.userStore)).Methods(http.MethodGet)
+
+	// 作业（布置的参考视频）
+	router.HandleFunc("/classes/{id}/assignments", auth.WithJWTAuth(h.handleGetAssignments, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/classes/{id}/assignments", auth.WithJWTAuth(h.handleCreateAssignment, h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/classes/{id}/assignments/{videoId}", auth.WithJWTAuth(h.handleDeleteAssignment, h.userStore)).Methods(http.MethodDelete)
+}
+
+func (h *Handler) handleGetClasses(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == -1 {
+		utils.WriteError(w, http.StatusUnauthorized, utils.NewError(utils.CodeUnauthorized, "unauthorized"))
+		return
+	}
+
+	classes, err := h.store.GetClassesForUser(r.Context(), userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	for _, c := range classes {
+		hideJoinCode(c, userID)
+	}
+
+	utils.WriteJSON(w, http.StatusOK, classes)
+}
+
+func (h *Handler) handleCreateClass(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == -1 {
+		utils.WriteError(w, http.StatusUnauthorized, utils.NewError(utils.CodeUnauthorized, "unauthorized"))
+		return
+	}
+
+	var payload types.CreateClassPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, utils.InvalidPayload(err))
+		return
+	}
+
+	code, err := h.newJoinCode(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	class := &types.Class{
+		TeacherID:   userID,
+		Name:        payload.Name,
+		Description: payload.Description,
+		JoinCode:    code,
+	}
+	if err := h.store.CreateClass(r.Context(), class); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, class)
+}
+
+func (h *Handler) handleJoinClass(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == -1 {
+		utils.WriteError(w, http.StatusUnauthorized, utils.NewError(utils.CodeUnauthorized, "unauthorized"))
+		return
+	}
+
+	var payload types.JoinClassPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, utils.InvalidPayload(err))
+		return
+	}
+
+	class, err := h.store.GetClassByJoinCode(r.Context(), strings.ToUpper(strings.TrimSpace(payload.JoinCode)))
+	if errors.Is(err, types.ErrNotFound) {
+		utils.WriteError(w, http.StatusNotFound, utils.NewError(utils.CodeNotFound, "invalid join code"))
+		return
+	}
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if class.TeacherID == userID {
+		utils.WriteError(w, http.StatusBadRequest, utils.NewError(utils.CodeBadRequest, "teacher cannot join own class"))
+		return
+	}
+
+	member, err := h.store.IsMember(r.Context(), class.ID, userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if member {
+		utils.WriteError(w, http.StatusConflict, utils.NewError(utils.CodeConflict, "already a member of this class"))
+		return
+	}
+
+	err = h.store.AddMember(r.Context(), class.ID, userID)
+	if errors.Is(err, types.ErrConflict) {
+		utils.WriteError(w, http.StatusConflict, utils.NewError(utils.CodeConflict, "already a member of this class"))
+		return
+	}
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	hideJoinCode(class, userID)
+	utils.WriteJSON(w, http.StatusOK, class)
+}
+
+func (h *Handler) handleGetClass(w http.ResponseWriter, r *http.Request) {
+	class, ok := h.loadClass(w, r, false)
+	if !ok {
+		return
+	}
+
+	hideJoinCode(class, auth.GetUserIDFromContext(r.Context()))
+	utils.WriteJSON(w, http.StatusOK, class)
+}
+
+func (h *Handler) handleDeleteClass(w http.ResponseWriter, r *http.Request) {
+	class, ok := h.loadClass(w, r, true)
+	if !ok {
+		return
+	}
+
+	if err := h.store.DeleteClass(r.Context(), class.ID); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"message": "class deleted successfully"})
+}
+
+func (h *Handler) handleGetMembers(w http.ResponseWriter, r *http.Request) {
+	class, ok := h.loadClass(w, r, false)
+	if !ok {
+		return
+	}
+
+	members, err := h.store.GetMembers(r.Context(), class.ID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, members)
+}
+
+// handleRemoveMember 老师移除学生，或学生自己退出班级
+func (h *Handler) handleRemoveMember(w http.ResponseWriter, r *http.Request) {
+	class, ok := h.loadClass(w, r, false)
+	if !ok {
+		return
+	}
+
+	memberID, err := strconv.Atoi(mux.Vars(r)["userId"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, utils.NewError(utils.CodeInvalidID, "invalid user id"))
+		return
+	}
+
+	userID := auth.GetUserIDFromContext(r.Context())
+	if class.TeacherID != userID && memberID != userID {
+		utils.WriteError(w, http.StatusForbidden, utils.NewError(utils.CodePermissionDenied, "permission denied"))
+		return
+	}
+
+	if err := h.store.RemoveMember(r.Context(), class.ID, memberID); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"message": "member removed successfully"})
+}
+
+// handleGetStudentActivity 老师查看学生在每个作业视频上的练习记录和录像
+func (h *Handler) handleGetStudentActivity(w http.ResponseWriter, r *http.Request) {
+	class, ok := h.loadClass(w, r, true)
+	if !ok {
+		return
+	}
+
+	studentID, err := strconv.Atoi(mux.Vars(r)["userId"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, utils.NewError(utils.CodeInvalidID, "invalid user id"))
+		return
+	}
+
+	member, err := h.store.IsMember(r.Context(), class.ID, studentID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if !member {
+		utils.WriteError(w, http.StatusNotFound, utils.NewError(utils.CodeNotFound, "student not found in class"))
+		return
+	}
+
+	assignments, err := h.store.GetAssignments(r.Context(), class.ID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	activity := []*types.StudentActivity{}
+	for _, a := range assignments {
+		practices, err := h.practiceStore.GetPracticesByVideo(r.Context(), studentID, a.VideoID)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		recordings, err := h.videoStore.GetRecordings(r.Context(), studentID, a.VideoID)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		activity = append(activity, &types.StudentActivity{
+			VideoID:    a.VideoID,
+			Practices:  practices,
+			Recordings: recordings,
+		})
+	}
+
+	utils.WriteJSON(w, http.StatusOK, activity)
+}
+
+func (h *Handler) handleGetAssignments(w http.ResponseWriter, r *http.Request) {
+	class, ok := h.loadClass(w, r, false)
+	if !ok {
+		return
+	}
+
+	assignments, err := h.store.GetAssignments(r.Context(), class.ID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	for _, a := range assignments {
+		// 视频可能已被删除，只返回作业本身
+		v, err := h.videoStore.GetVideoByID(r.Context(), a.VideoID)
+		if err != nil && !errors.Is(err, types.ErrNotFound) {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+		a.Video = v
+	}
+
+	utils.WriteJSON(w, http.StatusOK, assignments)
+}
+
+func (h *Handler) handleCreateAssignment(w http.ResponseWriter, r *http.Request) {
+	class, ok := h.loadClass(w, r, true)
+	if !ok {
+		return
+	}
+
+	var payload types.AssignVideoPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, utils.InvalidPayload(err))
+		return
+	}
+
+	// 只能布置自己上传的视频
+	video, err := h.videoStore.GetVideoByID(r.Context(), payload.VideoID)
+	if err != nil {
+		utils.WriteStoreError(w, err)
+		return
+	}
+	if video.UserID != class.TeacherID {
+		utils.WriteError(w, http.StatusForbidden, utils.NewError(utils.CodePermissionDenied, "permission denied"))
+		return
+	}
+
+	assigned, err := h.store.IsAssigned(r.Context(), class.ID, video.ID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if assigned {
+		utils.WriteError(w, http.StatusConflict, utils.NewError(utils.CodeConflict, "video already assigned to this class"))
+		return
+	}
+
+	assignment := &types.ClassAssignment{
+		ClassID: class.ID,
+		VideoID: video.ID,
+		Note:    payload.Note,
+		Video:   video,
+	}
+	err = h.store.CreateAssignment(r.Context(), assignment)
+	if errors.Is(err, types.ErrConflict) {
+		utils.WriteError(w, http.StatusConflict, utils.NewError(utils.CodeConflict, "video already assigned to this class"))
+		return
+	}
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// 通知班级里的所有学生
+	members, err := h.store.GetMembers(r.Context(), class.ID)
+	if err != nil {
+		log.Printf("failed to load members of class %d: %v", class.ID, err)
+	}
+	for _, m := range members {
+		h.events.Publish(m.UserID, types.EventAssignmentCreated, assignment)
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, assignment)
+}
+
+func (h *Handler) handleDeleteAssignment(w http.ResponseWriter, r *http.Request) {
+	class, ok := h.loadClass(w, r, true)
+	if !ok {
+		return
+	}
+
+	videoID, err := strconv.Atoi(mux.Vars(r)["videoId"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, utils.NewError(utils.CodeInvalidID, "invalid video id"))
+		return
+	}
+
+	if err := h.store.DeleteAssignment(r.Context(), class.ID, videoID); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"message": "assignment deleted successfully"})
+}
+
+// loadClass 读取路径中的班级并校验权限，teacherOnly 为 true 时仅老师可访问。
+// 失败时已写入响应。
+func (h *Handler) loadClass(w http.ResponseWriter, r *http.Request, teacherOnly bool) (*types.Class, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, utils.NewError(utils.CodeInvalidID, "invalid class id"))
+		return nil, false
+	}
+
+	class, err := h.store.GetClassByID(r.Context(), id)
+	if err != nil {
+		utils.WriteStoreError(w, err)
+		return nil, false
+	}
+
+	userID := auth.GetUserIDFromContext(r.Context())
+	if class.TeacherID == userID {
+		return class, true
+	}
+
+	if !teacherOnly {
+		member, err := h.store.IsMember(r.Context(), class.ID, userID)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+			return nil, false
+		}
+		if member {
+			return class, true
+		}
+	}
+
+	utils.WriteError(w, http.StatusForbidden, utils.NewError(utils.CodePermissionDenied, "permission denied"))
+	return nil, false
+}
+
+// newJoinCode 生成一个未被占用的邀请码
+func (h *Handler) newJoinCode(ctx context.Context) (string, error) {
+	for i := 0; i < 5; i++ {
+		code, err := generateJoinCode()
+		if err != nil {
+			return "", err
+		}
+		_, err = h.store.GetClassByJoinCode(ctx, code)
+		if errors.Is(err, types.ErrNotFound) {
+			return code, nil
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return "", fmt.Errorf("failed to generate join code")
+}
+
+func generateJoinCode() (string, error) {
+	max := big.NewInt(int64(len(joinCodeAlphabet)))
+	b := make([]byte, joinCodeLength)
+	for i := range b {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = joinCodeAlphabet[n.Int64()]
+	}
+	return string(b), nil
+}
+
+// hideJoinCode 邀请码只对老师可见
+func hideJoinCode(class *types.Class, userID int) {
+	if class.TeacherID != userID {
+		class.JoinCode = ""
+	}
+}
@@ -0,0 +1,101 @@
+package class_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/Albert-tru/DanceMirror/cmd/api/apitest"
+	"github.com/Albert-tru/DanceMirror/types"
+)
+
+func TestJoin(t *testing.T) {
+	s := apitest.Start(t)
+	c := s.NewClassroom(t)
+
+	tests := []struct {
+		name   string
+		token  string
+		code   string
+		status int
+	}{
+		{"already a member", c.Student, c.Class.JoinCode, http.StatusConflict},
+		{"teacher joins own class", c.Teacher, c.Class.JoinCode, http.StatusBadRequest},
+		{"unknown code", c.Outsider, "NOPE1234", http.StatusNotFound},
+		{"empty code", c.Outsider, "", http.StatusBadRequest},
+		{"not logged in", "", c.Class.JoinCode, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s.Do(t, http.MethodPost, "/api/v1/classes/join", tt.token, types.JoinClassPayload{JoinCode: tt.code}, tt.status, nil)
+		})
+	}
+}
+
+// 成员列表联表查询用户的姓名
+func TestMembers(t *testing.T) {
+	s := apitest.Start(t)
+	c := s.NewClassroom(t)
+	path := fmt.Sprintf("/api/v1/classes/%d/members", c.Class.ID)
+
+	var members []types.ClassMember
+	s.Do(t, http.MethodGet, path, c.Teacher, nil, http.StatusOK, &members)
+	if len(members) != 1 || members[0].UserID != c.StudentID || members[0].FirstName != "Test" || members[0].LastName != "13800000002" {
+		t.Errorf("members = %+v, want the student", members)
+	}
+	s.Do(t, http.MethodGet, path, c.Student, nil, http.StatusOK, nil)
+	s.Do(t, http.MethodGet, path, c.Outsider, nil, http.StatusForbidden, nil)
+}
+
+// 班级成员可以查看布置给班级的视频，老师可以管理班级，其他人都不行
+func TestAccess(t *testing.T) {
+	s := apitest.Start(t)
+	c := s.NewClassroom(t)
+	class := fmt.Sprintf("/api/v1/classes/%d", c.Class.ID)
+	video := fmt.Sprintf("/api/v1/videos/%d", c.Video.ID)
+	stream := video + "/stream?token="
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		status int
+	}{
+		{"student gets class", http.MethodGet, class, c.Student, http.StatusOK},
+		{"outsider gets class", http.MethodGet, class, c.Outsider, http.StatusForbidden},
+		{"student gets assigned video", http.MethodGet, video, c.Student, http.StatusOK},
+		{"student streams assigned video", http.MethodGet, stream + c.Student, "", http.StatusOK},
+		{"outsider gets assigned video", http.MethodGet, video, c.Outsider, http.StatusForbidden},
+		{"outsider streams assigned video", http.MethodGet, stream + c.Outsider, "", http.StatusForbidden},
+		{"student deletes assigned video", http.MethodDelete, video, c.Student, http.StatusForbidden},
+		{"student views activity", http.MethodGet, fmt.Sprintf("%s/members/%d/activity", class, c.StudentID), c.Student, http.StatusForbidden},
+		{"teacher views activity", http.MethodGet, fmt.Sprintf("%s/members/%d/activity", class, c.StudentID), c.Teacher, http.StatusOK},
+		{"student deletes class", http.MethodDelete, class, c.Student, http.StatusForbidden},
+		{"outsider removes student", http.MethodDelete, fmt.Sprintf("%s/members/%d", class, c.StudentID), c.Outsider, http.StatusForbidden},
+		{"missing class", http.MethodGet, "/api/v1/classes/999", c.Teacher, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := s.Request(tt.method, tt.path, tt.token, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Errorf("%s %s = %d, want %d", tt.method, tt.path, resp.StatusCode, tt.status)
+			}
+		})
+	}
+}
+
+// 学生被移出班级后不能再查看班级的视频
+func TestRemoveMember(t *testing.T) {
+	s := apitest.Start(t)
+	c := s.NewClassroom(t)
+	video := fmt.Sprintf("/api/v1/videos/%d", c.Video.ID)
+
+	s.Do(t, http.MethodDelete, fmt.Sprintf("/api/v1/classes/%d/members/%d", c.Class.ID, c.StudentID), c.Teacher, nil, http.StatusOK, nil)
+	s.Do(t, http.MethodGet, video, c.Student, nil, http.StatusForbidden, nil)
+	s.Do(t, http.MethodGet, fmt.Sprintf("/api/v1/classes/%d", c.Class.ID), c.Student, nil, http.StatusForbidden, nil)
+}
@@ -0,0 +1,228 @@
+package class
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/Albert-tru/DanceMirror/db/dialect"
+	"github.com/Albert-tru/DanceMirror/types"
+)
+
+// classColumns 和 scanRowIntoClass 的顺序一致
+const classColumns = "id, teacherId, name, description, joinCode, createdAt"
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) CreateClass(ctx context.Context, class *types.Class) error {
+	result, err := s.db.ExecContext(ctx, "INSERT INTO classes (teacherId, name, description, joinCode) VALUES (?, ?, ?, ?)",
+		class.TeacherID, class.Name, class.Description, class.JoinCode)
+	if err != nil {
+		return dialect.Conflict(err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	class.ID = int(id)
+	return nil
+}
+
+func (s *Store) GetClassByID(ctx context.Context, id int) (*types.Class, error) {
+	return s.getClass(ctx, "SELECT "+classColumns+" FROM classes WHERE id = ?", id)
+}
+
+func (s *Store) GetClassByJoinCode(ctx context.Context, code string) (*types.Class, error) {
+	return s.getClass(ctx, "SELECT "+classColumns+" FROM classes WHERE joinCode = ?", code)
+}
+
+// GetClassesForUser 获取用户任教或加入的所有班级
+func (s *Store) GetClassesForUser(ctx context.Context, userID int) ([]*types.Class, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT `+classColumns+` FROM classes c
+WHERE c.teacherId = ?
+   OR EXISTS (SELECT 1 FROM class_members m WHERE m.classId = c.id AND m.userId = ?)
+ORDER BY c.createdAt DESC`, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	classes := []*types.Class{}
+	for rows.Next() {
+		c, err := scanRowIntoClass(rows)
+		if err != nil {
+			return nil, err
+		}
+		classes = append(classes, c)
+	}
+
+	return classes, nil
+}
+
+func (s *Store) DeleteClass(ctx context.Context, id int) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM classes WHERE id = ?", id)
+	return err
+}
+
+func (s *Store) AddMember(ctx context.Context, classID, userID int) error {
+	_, err := s.db.ExecContext(ctx, "INSERT INTO class_members (classId, userId) VALUES (?, ?)", classID, userID)
+	return dialect.Conflict(err)
+}
+
+func (s *Store) RemoveMember(ctx context.Context, classID, userID int) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM class_members WHERE classId = ? AND userId = ?", classID, userID)
+	return err
+}
+
+func (s *Store) GetMembers(ctx context.Context, classID int) ([]*types.ClassMember, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT m.classId, m.userId, u.firstName, u.lastName, m.joinedAt
+FROM class_members m
+JOIN users u ON u.id = m.userId
+WHERE m.classId = ?
+ORDER BY m.joinedAt`, classID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []*types.ClassMember{}
+	for rows.Next() {
+		m := new(types.ClassMember)
+		if err := rows.Scan(&m.ClassID, &m.UserID, &m.FirstName, &m.LastName, &m.JoinedAt); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+
+	return members, nil
+}
+
+func (s *Store) IsMember(ctx context.Context, classID, userID int) (bool, error) {
+	return s.exists(ctx, "SELECT 1 FROM class_members WHERE classId = ? AND userId = ?", classID, userID)
+}
+
+func (s *Store) CreateAssignment(ctx context.Context, assignment *types.ClassAssignment) error {
+	result, err := s.db.ExecContext(ctx, "INSERT INTO class_assignments (classId, videoId, note) VALUES (?, ?, ?)",
+		assignment.ClassID, assignment.VideoID, assignment.Note)
+	if err != nil {
+		return dialect.Conflict(err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	assignment.ID = int(id)
+	return nil
+}
+
+func (s *Store) GetAssignments(ctx context.Context, classID int) ([]*types.ClassAssignment, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT id, classId, videoId, note, createdAt FROM class_assignments WHERE classId = ? ORDER BY createdAt DESC", classID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	assignments := []*types.ClassAssignment{}
+	for rows.Next() {
+		a := new(types.ClassAssignment)
+		var note sql.NullString
+		if err := rows.Scan(&a.ID, &a.ClassID, &a.VideoID, &note, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		a.Note = note.String
+		assignments = append(assignments, a)
+	}
+
+	return assignments, nil
+}
+
+func (s *Store) IsAssigned(ctx context.Context, classID, videoID int) (bool, error) {
+	return s.exists(ctx, "SELECT 1 FROM class_assignments WHERE classId = ? AND videoId = ?", classID, videoID)
+}
+
+func (s *Store) DeleteAssignment(ctx context.Context, classID, videoID int) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM class_assignments WHERE classId = ? AND videoId = ?", classID, videoID)
+	return err
+}
+
+func (s *Store) IsVideoAssignedToUser(ctx context.Context, userID, videoID int) (bool, error) {
+	return s.exists(ctx, `
+SELECT 1 FROM class_assignments a
+JOIN class_members m ON m.classId = a.classId
+WHERE m.userId = ? AND a.videoId = ?`, userID, videoID)
+}
+
+func (s *Store) IsTeacherOfAssignment(ctx context.Context, teacherID, studentID, videoID int) (bool, error) {
+	return s.exists(ctx, `
+SELECT 1 FROM classes c
+JOIN class_members m ON m.classId = c.id
+JOIN class_assignments a ON a.classId = c.id
+WHERE c.teacherId = ? AND m.userId = ? AND a.videoId = ?`, teacherID, studentID, videoID)
+}
+
+func (s *Store) getClass(ctx context.Context, query string, args ...any) (*types.Class, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	c := new(types.Class)
+	for rows.Next() {
+		c, err = scanRowIntoClass(rows)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if c.ID == 0 {
+		return nil, fmt.Errorf("class %w", types.ErrNotFound)
+	}
+
+	return c, nil
+}
+
+func (s *Store) exists(ctx context.Context, query string, args ...any) (bool, error) {
+	rows, err := s.db.QueryContext(ctx, query+" LIMIT 1", args...)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	return rows.Next(), rows.Err()
+}
+
+func scanRowIntoClass(rows *sql.Rows) (*types.Class, error) {
+	class := new(types.Class)
+
+	var description sql.NullString
+	err := rows.Scan(
+		&class.ID,
+		&class.TeacherID,
+		&class.Name,
+		&description,
+		&class.JoinCode,
+		&class.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if description.Valid {
+		class.Description = description.String
+	}
+
+	return class, nil
+}
@@ -0,0 +1,261 @@
+package comment
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/Albert-tru/DanceMirror/service/auth"
+	"github.com/Albert-tru/DanceMirror/service/video"
+	"github.com/Albert-tru/DanceMirror/types"
+	"github.com/Albert-tru/DanceMirror/utils"
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	store      types.CommentStore
+	videoStore types.VideoStore
+	userStore  types.UserStore
+	classStore types.ClassStore
+	events     types.EventPublisher
+}
+
+func NewHandler(store types.CommentStore, videoStore types.VideoStore, userStore types.UserStore, classStore types.ClassStore, events types.EventPublisher) *Handler {
+	return &Handler{
+		store:      store,
+		videoStore: videoStore,
+		userStore:  userStore,
+		classStore: classStore,
+		events:     events,
+	}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/videos/{id}/comments", auth.WithJWTAuth(h.handleGetComments, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/videos/{id}/comments", auth.WithJWTAuth(h.handleCreateComment, h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/videos/{id}/comments/{commentId}", auth.WithJWTAuth(h.handleUpdateComment, h.userStore)).Methods(http.MethodPut)
+	router.HandleFunc("/videos/{id}/comments/{commentId}", auth.WithJWTAuth(h.handleDeleteComment, h.userStore)).Methods(http.MethodDelete)
+}
+
+func (h *Handler) handleGetComments(w http.ResponseWriter, r *http.Request) {
+	v, ok := h.loadVideo(w, r)
+	if !ok {
+		return
+	}
+
+	comments, err := h.store.GetComments(r.Context(), v.ID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, buildThreads(comments))
+}
+
+func (h *Handler) handleCreateComment(w http.ResponseWriter, r *http.Request) {
+	v, ok := h.loadVideo(w, r)
+	if !ok {
+		return
+	}
+
+	var payload types.CreateCommentPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, utils.InvalidPayload(err))
+		return
+	}
+
+	comment := &types.Comment{
+		VideoID:   v.ID,
+		UserID:    auth.GetUserIDFromContext(r.Context()),
+		VideoTime: payload.VideoTime,
+		Body:      payload.Body,
+	}
+
+	// 通知视频所有者和被回复的人（不通知自己）
+	recipients := []int{v.UserID}
+
+	// 回复：父评论必须属于同一个视频，时间点沿用父评论
+	if payload.ParentID != 0 {
+		parent, err := h.store.GetCommentByID(r.Context(), payload.ParentID)
+		if err != nil && !errors.Is(err, types.ErrNotFound) {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if err != nil || parent.VideoID != v.ID {
+			utils.WriteError(w, http.StatusBadRequest, utils.NewError(utils.CodeBadRequest, "parent comment not found"))
+			return
+		}
+		comment.ParentID = parent.ID
+		comment.VideoTime = parent.VideoTime
+		recipients = append(recipients, parent.UserID)
+	}
+
+	if !withinDuration(v, comment.VideoTime) {
+		utils.WriteError(w, http.StatusBadRequest, utils.NewError(utils.CodeBadRequest, "videoTime exceeds video duration"))
+		return
+	}
+
+	if err := h.store.CreateComment(r.Context(), comment); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	created, err := h.store.GetCommentByID(r.Context(), comment.ID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	notified := map[int]bool{comment.UserID: true}
+	for _, id := range recipients {
+		if !notified[id] {
+			notified[id] = true
+			h.events.Publish(id, types.EventCommentCreated, created)
+		}
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, created)
+}
+
+func (h *Handler) handleUpdateComment(w http.ResponseWriter, r *http.Request) {
+	v, comment, ok := h.loadOwnComment(w, r)
+	if !ok {
+		return
+	}
+
+	var payload types.UpdateCommentPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, utils.InvalidPayload(err))
+		return
+	}
+
+	comment.Body = payload.Body
+	// 回复的时间点跟随父评论，不允许单独修改
+	if payload.VideoTime != nil && comment.ParentID == 0 {
+		if !withinDuration(v, *payload.VideoTime) {
+			utils.WriteError(w, http.StatusBadRequest, utils.NewError(utils.CodeBadRequest, "videoTime exceeds video duration"))
+			return
+		}
+		comment.VideoTime = *payload.VideoTime
+	}
+
+	if err := h.store.UpdateComment(r.Context(), comment); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	updated, err := h.store.GetCommentByID(r.Context(), comment.ID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, updated)
+}
+
+func (h *Handler) handleDeleteComment(w http.ResponseWriter, r *http.Request) {
+	_, comment, ok := h.loadOwnComment(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.store.DeleteComment(r.Context(), comment.ID); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"message": "comment deleted successfully"})
+}
+
+// loadVideo 读取路径中的视频并校验查看权限，失败时已写入响应
+func (h *Handler) loadVideo(w http.ResponseWriter, r *http.Request) (*types.Video, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, utils.NewError(utils.CodeInvalidID, "invalid video id"))
+		return nil, false
+	}
+
+	v, err := h.videoStore.GetVideoByID(r.Context(), id)
+	if err != nil {
+		utils.WriteStoreError(w, err)
+		return nil, false
+	}
+
+	userID := auth.GetUserIDFromContext(r.Context())
+	allowed, err := video.CanView(r.Context(), h.classStore, v, userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return nil, false
+	}
+	if !allowed {
+		utils.WriteError(w, http.StatusForbidden, utils.NewError(utils.CodePermissionDenied, "permission denied"))
+		return nil, false
+	}
+
+	return v, true
+}
+
+// loadOwnComment 读取路径中的评论，只有作者本人可以修改或删除
+func (h *Handler) loadOwnComment(w http.ResponseWriter, r *http.Request) (*types.Video, *types.Comment, bool) {
+	v, ok := h.loadVideo(w, r)
+	if !ok {
+		return nil, nil, false
+	}
+
+	commentID, err := strconv.Atoi(mux.Vars(r)["commentId"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, utils.NewError(utils.CodeInvalidID, "invalid comment id"))
+		return nil, nil, false
+	}
+
+	comment, err := h.store.GetCommentByID(r.Context(), commentID)
+	if err == nil && comment.VideoID != v.ID {
+		err = fmt.Errorf("comment %w", types.ErrNotFound)
+	}
+	if err != nil {
+		utils.WriteStoreError(w, err)
+		return nil, nil, false
+	}
+
+	if comment.UserID != auth.GetUserIDFromContext(r.Context()) {
+		utils.WriteError(w, http.StatusForbidden, utils.NewError(utils.CodePermissionDenied, "permission denied"))
+		return nil, nil, false
+	}
+
+	return v, comment, true
+}
+
+// buildThreads 把平铺的评论列表组装成树，顶层评论按时间点排序
+func buildThreads(comments []*types.Comment) []*types.Comment {
+	byID := make(map[int]*types.Comment, len(comments))
+	for _, c := range comments {
+		byID[c.ID] = c
+	}
+
+	threads := []*types.Comment{}
+	for _, c := range comments {
+		if parent, ok := byID[c.ParentID]; ok && c.ParentID != 0 {
+			parent.Replies = append(parent.Replies, c)
+			continue
+		}
+		threads = append(threads, c)
+	}
+
+	return threads
+}
+
+// withinDuration 视频时长已知时，时间点不能超过时长
+func withinDuration(v *types.Video, t float64) bool {
+	return v.Duration <= 0 || t <= v.Duration
+}
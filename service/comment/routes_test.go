@@ -0,0 +1,96 @@
+package comment_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/Albert-tru/DanceMirror/cmd/api/apitest"
+	"github.com/Albert-tru/DanceMirror/types"
+)
+
+func TestCreate(t *testing.T) {
+	s := apitest.Start(t)
+	c := s.NewClassroom(t)
+	path := fmt.Sprintf("/api/v1/videos/%d/comments", c.Video.ID)
+
+	tests := []struct {
+		name    string
+		token   string
+		payload any
+		status  int
+	}{
+		{"owner", c.Teacher, types.CreateCommentPayload{VideoTime: 1.5, Body: "watch the arms"}, http.StatusCreated},
+		{"class member", c.Student, types.CreateCommentPayload{VideoTime: 2, Body: "thanks"}, http.StatusCreated},
+		{"outsider", c.Outsider, types.CreateCommentPayload{VideoTime: 2, Body: "hi"}, http.StatusForbidden},
+		{"empty body", c.Student, types.CreateCommentPayload{VideoTime: 2}, http.StatusBadRequest},
+		{"negative time", c.Student, types.CreateCommentPayload{VideoTime: -1, Body: "x"}, http.StatusBadRequest},
+		{"unknown parent", c.Student, types.CreateCommentPayload{Body: "x", ParentID: 999}, http.StatusBadRequest},
+		{"not logged in", "", types.CreateCommentPayload{Body: "x"}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s.Do(t, http.MethodPost, path, tt.token, tt.payload, tt.status, nil)
+		})
+	}
+
+	s.Do(t, http.MethodGet, path, c.Outsider, nil, http.StatusForbidden, nil)
+	s.Do(t, http.MethodGet, "/api/v1/videos/999/comments", c.Teacher, nil, http.StatusNotFound, nil)
+}
+
+// 回复挂在父评论下，评论带有作者的姓名（联表查询用户）
+func TestThreads(t *testing.T) {
+	s := apitest.Start(t)
+	c := s.NewClassroom(t)
+	path := fmt.Sprintf("/api/v1/videos/%d/comments", c.Video.ID)
+
+	var parent types.Comment
+	s.Do(t, http.MethodPost, path, c.Teacher, types.CreateCommentPayload{VideoTime: 3, Body: "watch the arms"}, http.StatusCreated, &parent)
+	s.Do(t, http.MethodPost, path, c.Student, types.CreateCommentPayload{Body: "ok", ParentID: parent.ID}, http.StatusCreated, nil)
+	s.Do(t, http.MethodPost, path, c.Student, types.CreateCommentPayload{VideoTime: 1, Body: "earlier"}, http.StatusCreated, nil)
+
+	var threads []types.Comment
+	s.Do(t, http.MethodGet, path, c.Student, nil, http.StatusOK, &threads)
+	if len(threads) != 2 || threads[0].Body != "earlier" || threads[1].ID != parent.ID {
+		t.Fatalf("threads = %+v, want two top-level comments ordered by video time", threads)
+	}
+	if threads[1].FirstName != "Test" || threads[1].LastName != "13800000001" {
+		t.Errorf("comment author = %q %q, want the teacher's name", threads[1].FirstName, threads[1].LastName)
+	}
+	replies := threads[1].Replies
+	if len(replies) != 1 || replies[0].Body != "ok" || replies[0].VideoTime != 3 || replies[0].LastName != "13800000002" {
+		t.Errorf("replies = %+v, want the student's reply at the parent's time", replies)
+	}
+}
+
+// 只有作者本人可以修改和删除评论（视频所有者也不行）
+func TestAuthorOnly(t *testing.T) {
+	s := apitest.Start(t)
+	c := s.NewClassroom(t)
+	var comment types.Comment
+	s.Do(t, http.MethodPost, fmt.Sprintf("/api/v1/videos/%d/comments", c.Video.ID), c.Student, types.CreateCommentPayload{Body: "question"}, http.StatusCreated, &comment)
+	path := fmt.Sprintf("/api/v1/videos/%d/comments/%d", c.Video.ID, comment.ID)
+
+	tests := []struct {
+		name   string
+		method string
+		token  string
+		status int
+	}{
+		{"owner of the video edits", http.MethodPut, c.Teacher, http.StatusForbidden},
+		{"outsider edits", http.MethodPut, c.Outsider, http.StatusForbidden},
+		{"owner of the video deletes", http.MethodDelete, c.Teacher, http.StatusForbidden},
+		{"author edits", http.MethodPut, c.Student, http.StatusOK},
+		{"author deletes", http.MethodDelete, c.Student, http.StatusOK},
+		{"deleted comment", http.MethodPut, c.Student, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body any
+			if tt.method == http.MethodPut {
+				body = types.UpdateCommentPayload{Body: "edited"}
+			}
+			s.Do(t, tt.method, path, tt.token, body, tt.status, nil)
+		})
+	}
+}
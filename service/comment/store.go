@@ -0,0 +1,124 @@
+package comment
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/Albert-tru/DanceMirror/types"
+)
+
+const selectComment = `
+SELECT c.id, c.videoId, c.userId, c.parentId, c.videoTime, c.body, u.firstName, u.lastName, c.createdAt, c.updatedAt
+FROM video_comments c
+JOIN users u ON u.id = c.userId`
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// GetComments 获取视频的所有评论（按时间点排序的平铺列表）
+func (s *Store) GetComments(ctx context.Context, videoID int) ([]*types.Comment, error) {
+	rows, err := s.db.QueryContext(ctx, selectComment+" WHERE c.videoId = ? ORDER BY c.videoTime, c.createdAt", videoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := []*types.Comment{}
+	for rows.Next() {
+		c, err := scanRowIntoComment(rows)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, c)
+	}
+
+	return comments, nil
+}
+
+func (s *Store) GetCommentByID(ctx context.Context, id int) (*types.Comment, error) {
+	rows, err := s.db.QueryContext(ctx, selectComment+" WHERE c.id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	c := new(types.Comment)
+	for rows.Next() {
+		c, err = scanRowIntoComment(rows)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if c.ID == 0 {
+		return nil, fmt.Errorf("comment %w", types.ErrNotFound)
+	}
+
+	return c, nil
+}
+
+func (s *Store) CreateComment(ctx context.Context, comment *types.Comment) error {
+	var parentID sql.NullInt64
+	if comment.ParentID != 0 {
+		parentID = sql.NullInt64{Int64: int64(comment.ParentID), Valid: true}
+	}
+
+	result, err := s.db.ExecContext(ctx, "INSERT INTO video_comments (videoId, userId, parentId, videoTime, body) VALUES (?, ?, ?, ?, ?)",
+		comment.VideoID, comment.UserID, parentID, comment.VideoTime, comment.Body)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	comment.ID = int(id)
+	return nil
+}
+
+func (s *Store) UpdateComment(ctx context.Context, comment *types.Comment) error {
+	_, err := s.db.ExecContext(ctx, "UPDATE video_comments SET videoTime = ?, body = ?, updatedAt = CURRENT_TIMESTAMP WHERE id = ?",
+		comment.VideoTime, comment.Body, comment.ID)
+	return err
+}
+
+// DeleteComment 删除评论，回复会被级联删除
+func (s *Store) DeleteComment(ctx context.Context, id int) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM video_comments WHERE id = ?", id)
+	return err
+}
+
+func scanRowIntoComment(rows *sql.Rows) (*types.Comment, error) {
+	comment := new(types.Comment)
+
+	var parentID sql.NullInt64
+	err := rows.Scan(
+		&comment.ID,
+		&comment.VideoID,
+		&comment.UserID,
+		&parentID,
+		&comment.VideoTime,
+		&comment.Body,
+		&comment.FirstName,
+		&comment.LastName,
+		&comment.CreatedAt,
+		&comment.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if parentID.Valid {
+		comment.ParentID = int(parentID.Int64)
+	}
+
+	return comment, nil
+}
@@ -0,0 +1,113 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+
+	"github.com/Albert-tru/DanceMirror/types"
+)
+
+// 每个订阅者的缓冲区大小，消费跟不上时丢弃事件（客户端可用 Last-Event-ID 重连补发）
+const subscriberBuffer = 32
+
+// Bus 进程内事件总线：先写入事件日志，再推送给该用户的所有在线订阅者
+type Bus struct {
+	store types.EventStore
+	keep  int
+	// publishMu 保证事件按 ID 顺序推送，订阅者据此去重
+	publishMu sync.Mutex
+	mu        sync.RWMutex
+	subs      map[int]map[chan *types.Event]struct{}
+	listeners []func(*types.Event)
+}
+
+// NewBus 创建事件总线，keep 为每个用户保留的事件条数
+func NewBus(store types.EventStore, keep int) *Bus {
+	return &Bus{
+		store: store,
+		keep:  keep,
+		subs:  make(map[int]map[chan *types.Event]struct{}),
+	}
+}
+
+// Publish 发布事件给指定用户，失败只记录日志，不影响业务请求。
+// 事件在请求结束后也要写入，所以不接收请求的 ctx
+func (b *Bus) Publish(userID int, eventType string, data any) {
+	ctx := context.Background()
+	payload, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("event: failed to marshal %s: %v", eventType, err)
+		return
+	}
+
+	b.publishMu.Lock()
+	defer b.publishMu.Unlock()
+
+	e := &types.Event{
+		UserID: userID,
+		Type:   eventType,
+		Data:   payload,
+	}
+	if err := b.store.CreateEvent(ctx, e); err != nil {
+		log.Printf("event: failed to persist %s for user %d: %v", eventType, userID, err)
+		return
+	}
+	if b.keep > 0 {
+		if err := b.store.PruneEvents(ctx, userID, b.keep); err != nil {
+			log.Printf("event: failed to prune events for user %d: %v", userID, err)
+		}
+	}
+
+	for _, listener := range b.listeners {
+		listener(e)
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for ch := range b.subs[userID] {
+		select {
+		case ch <- e:
+		default:
+			log.Printf("event: subscriber of user %d is too slow, dropping event %d", userID, e.ID)
+		}
+	}
+}
+
+// AddListener 注册一个接收所有用户事件的监听器（如 Webhook 投递），
+// 监听器在发布方的 goroutine 中同步调用，应尽快返回。需在开始发布前注册。
+func (b *Bus) AddListener(listener func(*types.Event)) {
+	b.listeners = append(b.listeners, listener)
+}
+
+// Subscribe 订阅用户的事件，返回的 cancel 必须调用以释放订阅
+func (b *Bus) Subscribe(userID int) (<-chan *types.Event, func()) {
+	ch := make(chan *types.Event, subscriberBuffer)
+
+	b.mu.Lock()
+	if b.subs[userID] == nil {
+		b.subs[userID] = make(map[chan *types.Event]struct{})
+	}
+	b.subs[userID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs[userID], ch)
+			if len(b.subs[userID]) == 0 {
+				delete(b.subs, userID)
+			}
+			b.mu.Unlock()
+		})
+	}
+
+	return ch, cancel
+}
+
+// History 获取 afterID 之后的历史事件，用于断线重连补发
+func (b *Bus) History(ctx context.Context, userID int, afterID int64) ([]*types.Event, error) {
+	return b.store.GetEventsAfter(ctx, userID, afterID)
+}
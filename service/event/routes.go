@@ -0,0 +1,122 @@
+package event
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Albert-tru/DanceMirror/service/auth"
+	"github.com/Albert-tru/DanceMirror/types"
+	"github.com/Albert-tru/DanceMirror/utils"
+	"github.com/gorilla/mux"
+)
+
+// 心跳间隔，防止代理因连接空闲而断开
+const heartbeatInterval = 25 * time.Second
+
+type Handler struct {
+	bus       *Bus
+	userStore types.UserStore
+}
+
+func NewHandler(bus *Bus, userStore types.UserStore) *Handler {
+	return &Handler{
+		bus:       bus,
+		userStore: userStore,
+	}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	// EventSource 不能设置请求头，允许通过 ?token= 认证
+	router.HandleFunc("/events", auth.WithJWTAuthFromQuery(h.handleEvents, h.userStore)).Methods(http.MethodGet)
+}
+
+// handleEvents 以 Server-Sent Events 推送当前用户的事件，
+// 支持 Last-Event-ID 请求头（或 ?lastEventId=）从事件日志补发
+func (h *Handler) handleEvents(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == -1 {
+		utils.WriteError(w, http.StatusUnauthorized, utils.NewError(utils.CodeUnauthorized, "unauthorized"))
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		utils.WriteError(w, http.StatusInternalServerError, utils.NewError(utils.CodeInternalError, "streaming unsupported"))
+		return
+	}
+
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = r.URL.Query().Get("lastEventId")
+	}
+	var lastSent int64
+	if lastID != "" {
+		id, err := strconv.ParseInt(lastID, 10, 64)
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, utils.NewError(utils.CodeBadRequest, "invalid Last-Event-ID"))
+			return
+		}
+		lastSent = id
+	}
+
+	// 先订阅再补发，避免两者之间产生的事件丢失
+	ch, cancel := h.bus.Subscribe(userID)
+	defer cancel()
+
+	var history []*types.Event
+	if lastID != "" {
+		var err error
+		history, err = h.bus.History(r.Context(), userID, lastSent)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "retry: 3000\n\n")
+
+	for _, e := range history {
+		if err := writeEvent(w, e); err != nil {
+			return
+		}
+		lastSent = e.ID
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e := <-ch:
+			// 已经在补发中发送过的事件跳过
+			if e.ID <= lastSent {
+				continue
+			}
+			if err := writeEvent(w, e); err != nil {
+				return
+			}
+			lastSent = e.ID
+			flusher.Flush()
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, e *types.Event) error {
+	_, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, e.Data)
+	return err
+}
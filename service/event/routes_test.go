@@ -0,0 +1,256 @@
+package event_test
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Albert-tru/DanceMirror/cmd/api/apitest"
+	"github.com/Albert-tru/DanceMirror/types"
+)
+
+// received 从事件流中读到的事件
+type received struct {
+	id   int64
+	kind string
+}
+
+// open 打开当前用户的事件流（和 EventSource 一样用 ?token= 认证），lastID 不为空时带上 Last-Event-ID，
+// 读到的事件写入返回的通道。测试结束时断开
+func open(t *testing.T, s *apitest.Server, token, lastID string) <-chan received {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.URL+"/api/v1/events?token="+token, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lastID != "" {
+		req.Header.Set("Last-Event-ID", lastID)
+	}
+	resp, err := s.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("GET /events = %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	events := make(chan received, 64)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer resp.Body.Close()
+		var e received
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case strings.HasPrefix(line, "id: "):
+				e.id, _ = strconv.ParseInt(strings.TrimPrefix(line, "id: "), 10, 64)
+			case strings.HasPrefix(line, "event: "):
+				e.kind = strings.TrimPrefix(line, "event: ")
+			case line == "" && e.id != 0:
+				events <- e
+				e = received{}
+			}
+		}
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return events
+}
+
+// collect 读取事件，直到收到 want 的最后一个事件
+func collect(t *testing.T, events <-chan received, want []*types.Event) []received {
+	t.Helper()
+
+	var got []received
+	for len(want) > 0 && (len(got) == 0 || got[len(got)-1].id < want[len(want)-1].ID) {
+		select {
+		case e := <-events:
+			got = append(got, e)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("received %v, still waiting for event %d", got, want[len(want)-1].ID)
+		}
+	}
+	return got
+}
+
+// check 收到的事件和事件日志中的完全一致：按顺序，没有遗漏也没有重复
+func check(t *testing.T, got []received, want []*types.Event) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("received %v, want %d events", got, len(want))
+	}
+	for i := range want {
+		if got[i].id != want[i].ID || got[i].kind != want[i].Type {
+			t.Errorf("event %d = %d %s, want %d %s", i, got[i].id, got[i].kind, want[i].ID, want[i].Type)
+		}
+	}
+}
+
+type fixture struct {
+	s       *apitest.Server
+	userID  int
+	token   string
+	videoID int
+}
+
+func setup(t *testing.T) *fixture {
+	t.Helper()
+
+	f := &fixture{s: apitest.Start(t)}
+	u, token, err := f.s.CreateUser("13800000001", "password123", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.userID, f.token = u.ID, token
+
+	resp, err := f.s.Upload(token, map[string]string{"title": "routine"}, "routine.mp4", apitest.FakeMP4(1024))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var video types.Video
+	if err := apitest.DecodeJSON(resp, &video); err != nil {
+		t.Fatal(err)
+	}
+	f.videoID = video.ID
+
+	// 等后台转码发布 video.processed，之后的事件都由测试产生
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		events := f.history(t, 0)
+		if events[len(events)-1].Type == types.EventVideoProcessed {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("events = %d, video.processed was not published", len(events))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return f
+}
+
+// practice 创建一条练习记录，发布 practice.created
+func (f *fixture) practice(t *testing.T) {
+	t.Helper()
+	f.s.Do(t, http.MethodPost, "/api/v1/practices", f.token, types.CreatePracticePayload{VideoID: f.videoID, Duration: 60, Speed: 1}, http.StatusCreated, nil)
+}
+
+// history 事件日志中的事件（转码完成等后台事件可能随时加入，所以在收完之后再读）
+func (f *fixture) history(t *testing.T, afterID int64) []*types.Event {
+	t.Helper()
+	events, err := f.s.Stores.Events.GetEventsAfter(context.Background(), f.userID, afterID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return events
+}
+
+// 连接时一边补发历史事件一边有新事件发布，历史和实时事件之间不遗漏也不重复
+func TestReplay(t *testing.T) {
+	f := setup(t)
+	for i := 0; i < 3; i++ {
+		f.practice(t)
+	}
+
+	// 其他用户的事件不会推送
+	_, other, err := f.s.CreateUser("13800000002", "password123", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := f.s.Upload(other, map[string]string{"title": "other"}, "other.mp4", apitest.FakeMP4(1024))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 5; i++ {
+			resp, err := f.s.Request(http.MethodPost, "/api/v1/practices", f.token, types.CreatePracticePayload{VideoID: f.videoID, Duration: 60, Speed: 1})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			resp.Body.Close()
+		}
+	}()
+	events := open(t, f.s, f.token, "0")
+	wg.Wait()
+
+	want := f.history(t, 0)
+	check(t, collect(t, events, want), want)
+}
+
+// 重连时只补发 Last-Event-ID 之后的事件，之后继续推送新事件
+func TestReconnect(t *testing.T) {
+	f := setup(t)
+	for i := 0; i < 4; i++ {
+		f.practice(t)
+	}
+	first := open(t, f.s, f.token, "0")
+	seen := collect(t, first, f.history(t, 0))
+	last := seen[1].id
+
+	events := open(t, f.s, f.token, strconv.FormatInt(last, 10))
+	f.practice(t)
+	want := f.history(t, last)
+	check(t, collect(t, events, want), want)
+	if want[len(want)-1].Type != types.EventPracticeCreated {
+		t.Errorf("last event = %s, want the new practice", want[len(want)-1].Type)
+	}
+
+	// 不带 Last-Event-ID 时只推送新事件
+	live := open(t, f.s, f.token, "")
+	before := f.history(t, 0)
+	f.practice(t)
+	want = f.history(t, before[len(before)-1].ID)
+	check(t, collect(t, live, want), want)
+}
+
+func TestInvalidRequest(t *testing.T) {
+	f := setup(t)
+
+	tests := []struct {
+		name   string
+		path   string
+		lastID string
+		status int
+	}{
+		{"invalid header", "/api/v1/events?token=" + f.token, "abc", http.StatusBadRequest},
+		{"invalid query", "/api/v1/events?lastEventId=1.5&token=" + f.token, "", http.StatusBadRequest},
+		{"no token", "/api/v1/events", "", http.StatusForbidden},
+		{"no token v2", "/api/v2/events", "", http.StatusUnauthorized},
+		{"invalid token", "/api/v1/events?token=abc", "", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, f.s.URL+tt.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.lastID != "" {
+				req.Header.Set("Last-Event-ID", tt.lastID)
+			}
+			resp, err := f.s.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Errorf("GET %s = %d, want %d", tt.path, resp.StatusCode, tt.status)
+			}
+		})
+	}
+}
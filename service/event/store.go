@@ -0,0 +1,83 @@
+package event
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/Albert-tru/DanceMirror/types"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) CreateEvent(ctx context.Context, event *types.Event) error {
+	result, err := s.db.ExecContext(ctx, "INSERT INTO events (userId, type, data) VALUES (?, ?, ?)",
+		event.UserID, event.Type, string(event.Data))
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	event.ID = id
+	return nil
+}
+
+// GetEventsAfter 获取用户 ID 大于 afterID 的事件（按 ID 升序）
+func (s *Store) GetEventsAfter(ctx context.Context, userID int, afterID int64) ([]*types.Event, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT id, userId, type, data, createdAt FROM events WHERE userId = ? AND id > ? ORDER BY id", userID, afterID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []*types.Event{}
+	for rows.Next() {
+		e, err := scanRowIntoEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+
+	return events, nil
+}
+
+// PruneEvents 只保留用户最近的 keep 条事件
+func (s *Store) PruneEvents(ctx context.Context, userID int, keep int) error {
+	_, err := s.db.ExecContext(ctx, `
+DELETE FROM events
+WHERE userId = ? AND id <= (
+    SELECT id FROM (
+        SELECT id FROM events WHERE userId = ? ORDER BY id DESC LIMIT 1 OFFSET ?
+    ) AS boundary
+)`, userID, userID, keep)
+	return err
+}
+
+func scanRowIntoEvent(rows *sql.Rows) (*types.Event, error) {
+	event := new(types.Event)
+
+	var data []byte
+	err := rows.Scan(
+		&event.ID,
+		&event.UserID,
+		&event.Type,
+		&data,
+		&event.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	event.Data = data
+	return event, nil
+}
@@ -0,0 +1,50 @@
+package job
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Albert-tru/DanceMirror/service/auth"
+	"github.com/Albert-tru/DanceMirror/types"
+	"github.com/Albert-tru/DanceMirror/utils"
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	store     types.JobStore
+	userStore types.UserStore
+}
+
+func NewHandler(store types.JobStore, userStore types.UserStore) *Handler {
+	return &Handler{
+		store:     store,
+		userStore: userStore,
+	}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	// 查询用户发起的后台任务（例如剪辑）的进度和结果
+	router.HandleFunc("/jobs/{id}", auth.WithJWTAuth(h.handleGetJob, h.userStore)).Methods(http.MethodGet)
+}
+
+func (h *Handler) handleGetJob(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, utils.NewError(utils.CodeInvalidID, "invalid job id"))
+		return
+	}
+
+	job, err := h.store.GetJobByID(r.Context(), id)
+	if err != nil {
+		utils.WriteStoreError(w, err)
+		return
+	}
+
+	userID := auth.GetUserIDFromContext(r.Context())
+	if job.UserID != userID {
+		utils.WriteError(w, http.StatusForbidden, utils.NewError(utils.CodePermissionDenied, "permission denied"))
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, job)
+}
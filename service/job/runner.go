@@ -0,0 +1,199 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Albert-tru/DanceMirror/types"
+)
+
+const (
+	pollInterval = 5 * time.Second
+	retryBase    = 30 * time.Second
+	retryMax     = time.Hour
+)
+
+// HandlerFunc 执行一个任务，返回错误时按退避重试；需要返回结果时设置 job.Result
+type HandlerFunc func(ctx context.Context, job *types.Job) error
+
+// permanentError 不需要重试的错误（例如视频已被删除）
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent 包装不需要重试的错误，任务会直接标记为失败
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// Runner 后台任务执行器：多个 worker 从 jobs 表领取到期的任务，
+// 按类型交给注册的 HandlerFunc 执行
+type Runner struct {
+	store       types.JobStore
+	workers     int
+	maxAttempts int
+	handlers    map[string]HandlerFunc
+	wake        chan struct{}
+}
+
+func NewRunner(store types.JobStore, workers, maxAttempts int) *Runner {
+	if workers < 1 {
+		workers = 1
+	}
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	return &Runner{
+		store:       store,
+		workers:     workers,
+		maxAttempts: maxAttempts,
+		handlers:    make(map[string]HandlerFunc),
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+// Handle 注册任务类型的处理函数，需要在 Run 之前调用
+func (r *Runner) Handle(jobType string, fn HandlerFunc) {
+	r.handlers[jobType] = fn
+}
+
+// Enqueue 提交任务并唤醒 worker
+func (r *Runner) Enqueue(ctx context.Context, jobType string, userID, videoID int, payload any) (*types.Job, error) {
+	job := &types.Job{
+		Type:        jobType,
+		UserID:      userID,
+		VideoID:     videoID,
+		MaxAttempts: r.maxAttempts,
+		RunAt:       time.Now(),
+	}
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		job.Payload = data
+	}
+
+	if err := r.store.CreateJob(ctx, job); err != nil {
+		return nil, err
+	}
+
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+	return job, nil
+}
+
+// Run 启动 worker，直到 ctx 被取消
+func (r *Runner) Run(ctx context.Context) {
+	// 上次进程退出时正在执行的任务重新排队
+	if err := r.store.ResetRunningJobs(ctx); err != nil {
+		log.Printf("jobs: failed to reset running jobs: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < r.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.work(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (r *Runner) work(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		// 连续执行到没有到期任务为止
+		for ctx.Err() == nil && r.runNext(ctx) {
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-r.wake:
+		}
+	}
+}
+
+// runNext 领取并执行一个任务，没有任务时返回 false
+func (r *Runner) runNext(ctx context.Context) bool {
+	job, err := r.store.ClaimJob(ctx, time.Now())
+	if err != nil {
+		log.Printf("jobs: failed to claim job: %v", err)
+		return false
+	}
+	if job == nil {
+		return false
+	}
+
+	// 退出时正在执行的任务也要记录结果
+	done := context.WithoutCancel(ctx)
+	err = r.execute(ctx, job)
+	if err == nil {
+		if err := r.store.CompleteJob(done, job.ID, job.Result); err != nil {
+			log.Printf("jobs: failed to complete job %d: %v", job.ID, err)
+		}
+		return true
+	}
+
+	var permanent *permanentError
+	if errors.As(err, &permanent) || job.Attempts >= job.MaxAttempts {
+		log.Printf("jobs: %s job %d failed: %v", job.Type, job.ID, err)
+		if err := r.store.FailJob(done, job.ID, err.Error()); err != nil {
+			log.Printf("jobs: failed to mark job %d as failed: %v", job.ID, err)
+		}
+		return true
+	}
+
+	log.Printf("jobs: %s job %d failed (attempt %d/%d), will retry: %v", job.Type, job.ID, job.Attempts, job.MaxAttempts, err)
+	if err := r.store.RetryJob(done, job.ID, err.Error(), time.Now().Add(backoff(job.Attempts))); err != nil {
+		log.Printf("jobs: failed to reschedule job %d: %v", job.ID, err)
+	}
+	return true
+}
+
+// execute 执行任务，处理函数 panic 时当作失败
+func (r *Runner) execute(ctx context.Context, job *types.Job) (err error) {
+	fn, ok := r.handlers[job.Type]
+	if !ok {
+		return Permanent(fmt.Errorf("unknown job type: %s", job.Type))
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+	return fn(ctx, job)
+}
+
+// backoff 第 n 次失败后的等待时间：30 秒起每次翻倍，最多 1 小时
+func backoff(attempts int) time.Duration {
+	delay := retryBase
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= retryMax {
+			return retryMax
+		}
+	}
+	return delay
+}
+
+// IsFinalAttempt 任务这次失败后是否不再重试
+func IsFinalAttempt(job *types.Job) bool {
+	return job.Attempts >= job.MaxAttempts
+}
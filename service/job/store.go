@@ -0,0 +1,188 @@
+package job
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Albert-tru/DanceMirror/db/dialect"
+	"github.com/Albert-tru/DanceMirror/types"
+)
+
+// jobColumns 和 scanRowIntoJob 的顺序一致
+const jobColumns = "id, type, videoId, payload, status, attempts, maxAttempts, lastError, runAt, createdAt, updatedAt, userId, result"
+
+type Store struct {
+	db      *sql.DB
+	dialect dialect.Dialect
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db, dialect: dialect.Of(db)}
+}
+
+func (s *Store) CreateJob(ctx context.Context, job *types.Job) error {
+	var userID, videoID sql.NullInt64
+	if job.UserID != 0 {
+		userID = sql.NullInt64{Int64: int64(job.UserID), Valid: true}
+	}
+	if job.VideoID != 0 {
+		videoID = sql.NullInt64{Int64: int64(job.VideoID), Valid: true}
+	}
+	var payload any
+	if len(job.Payload) > 0 {
+		payload = []byte(job.Payload)
+	}
+
+	result, err := s.db.ExecContext(ctx, "INSERT INTO jobs (type, userId, videoId, payload, maxAttempts, runAt) VALUES (?, ?, ?, ?, ?, ?)",
+		job.Type, userID, videoID, payload, job.MaxAttempts, s.dialect.Time(job.RunAt))
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	job.ID = int(id)
+	job.Status = types.JobPending
+	return nil
+}
+
+func (s *Store) ClaimJob(ctx context.Context, now time.Time) (*types.Job, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// SKIP LOCKED 让多个 worker 同时领取时互不阻塞
+	rows, err := tx.QueryContext(ctx, "SELECT "+jobColumns+" FROM jobs WHERE status = ? AND runAt <= ? ORDER BY runAt, id LIMIT 1"+s.dialect.ForUpdateSkipLocked(),
+		types.JobPending, s.dialect.Time(now))
+	if err != nil {
+		return nil, err
+	}
+	var job *types.Job
+	for rows.Next() {
+		job, err = scanRowIntoJob(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+	}
+	rows.Close()
+
+	if job == nil {
+		return nil, nil
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE jobs SET status = ?, attempts = attempts + 1 WHERE id = ?", types.JobRunning, job.ID); err != nil {
+		return nil, err
+	}
+	job.Status = types.JobRunning
+	job.Attempts++
+
+	return job, tx.Commit()
+}
+
+func (s *Store) CompleteJob(ctx context.Context, id int, result json.RawMessage) error {
+	var value any
+	if len(result) > 0 {
+		value = []byte(result)
+	}
+	_, err := s.db.ExecContext(ctx, "UPDATE jobs SET status = ?, lastError = NULL, result = ? WHERE id = ?", types.JobSucceeded, value, id)
+	return err
+}
+
+func (s *Store) RetryJob(ctx context.Context, id int, lastError string, runAt time.Time) error {
+	_, err := s.db.ExecContext(ctx, "UPDATE jobs SET status = ?, lastError = ?, runAt = ? WHERE id = ?", types.JobPending, lastError, s.dialect.Time(runAt), id)
+	return err
+}
+
+func (s *Store) FailJob(ctx context.Context, id int, lastError string) error {
+	_, err := s.db.ExecContext(ctx, "UPDATE jobs SET status = ?, lastError = ? WHERE id = ?", types.JobFailed, lastError, id)
+	return err
+}
+
+func (s *Store) ResetRunningJobs(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, "UPDATE jobs SET status = ? WHERE status = ?", types.JobPending, types.JobRunning)
+	return err
+}
+
+func (s *Store) GetJobByID(ctx context.Context, id int) (*types.Job, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+jobColumns+" FROM jobs WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var job *types.Job
+	for rows.Next() {
+		job, err = scanRowIntoJob(rows)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if job == nil {
+		return nil, fmt.Errorf("job %w", types.ErrNotFound)
+	}
+
+	return job, nil
+}
+
+func (s *Store) GetJobsByVideo(ctx context.Context, videoID int) ([]*types.Job, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+jobColumns+" FROM jobs WHERE videoId = ? ORDER BY id DESC", videoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []*types.Job{}
+	for rows.Next() {
+		job, err := scanRowIntoJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, nil
+}
+
+func scanRowIntoJob(rows *sql.Rows) (*types.Job, error) {
+	job := new(types.Job)
+
+	var videoID, userID sql.NullInt64
+	var payload, result []byte
+	var lastError sql.NullString
+	err := rows.Scan(
+		&job.ID,
+		&job.Type,
+		&videoID,
+		&payload,
+		&job.Status,
+		&job.Attempts,
+		&job.MaxAttempts,
+		&lastError,
+		&job.RunAt,
+		&job.CreatedAt,
+		&job.UpdatedAt,
+		&userID,
+		&result,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	job.UserID = int(userID.Int64)
+	job.VideoID = int(videoID.Int64)
+	job.Payload = payload
+	job.Result = result
+	job.LastError = lastError.String
+
+	return job, nil
+}
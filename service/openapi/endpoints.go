@@ -0,0 +1,182 @@
+package openapi
+
+import (
+	"net/http"
+
+	"github.com/Albert-tru/DanceMirror/service/room"
+	"github.com/Albert-tru/DanceMirror/types"
+)
+
+// Message 只有一条提示信息的响应
+type Message struct {
+	Message string `json:"message"`
+}
+
+// LoginUser 登录响应中的用户信息
+type LoginUser struct {
+	ID        int    `json:"id"`
+	Phone     string `json:"phone"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+	Role      string `json:"role"`
+	Locale    string `json:"locale"`
+}
+
+// LoginResponse 登录响应
+type LoginResponse struct {
+	Token string    `json:"token"`
+	User  LoginUser `json:"user"`
+}
+
+// TokenResponse 刷新令牌的响应
+type TokenResponse struct {
+	Token string `json:"token"`
+}
+
+// UploadForm 上传视频的表单（multipart/form-data），文件字段也可以叫 file（录制上传）
+type UploadForm struct {
+	Title       string `json:"title" validate:"required"`
+	Description string `json:"description"`
+	ReferenceID int    `json:"referenceId"`
+	Video       File   `json:"video" validate:"required"`
+}
+
+// endpoints 接口说明，键为 "方法 路径"（路径相对于 /api/v1）。新增路由时在这里登记
+var endpoints = map[string]Endpoint{
+	// 用户
+	"POST /register": {Summary: "注册", Tag: "users", Request: types.RegisterUserPayload{}, Status: http.StatusCreated, Response: Message{}},
+	"POST /login":    {Summary: "登录", Tag: "users", Request: types.LoginUserPayload{}, Response: LoginResponse{}},
+	"POST /refresh": {
+		Summary:     "刷新令牌",
+		Description: "用未过期的令牌换一个新的令牌（有效期重新计算）",
+		Tag:         "users", Auth: AuthBearer, Response: TokenResponse{},
+	},
+	"PUT /me/locale": {
+		Summary:     "设置错误信息的语言",
+		Description: "空字符串表示按 Accept-Language 选择",
+		Tag:         "users", Auth: AuthBearer, Request: types.UpdateLocalePayload{}, Response: types.UpdateLocalePayload{},
+	},
+
+	// 视频
+	"GET /videos": {Summary: "我的视频列表", Tag: "videos", Auth: AuthBearer, Response: []types.Video{}},
+	"POST /videos": {
+		Summary:     "上传视频",
+		Description: "相同内容的视频已存在时返回已有的视频（200）",
+		Tag:         "videos", Auth: AuthBearer, Request: UploadForm{}, RequestType: "multipart/form-data",
+		Status: http.StatusCreated, Response: types.Video{},
+	},
+	"GET /videos/{id}":          {Summary: "视频详情（含转码输出）", Tag: "videos", Auth: AuthBearer, Response: types.Video{}},
+	"DELETE /videos/{id}":       {Summary: "把视频移入回收站", Tag: "videos", Auth: AuthBearer, Response: Message{}},
+	"POST /videos/{id}/restore": {Summary: "从回收站恢复视频", Tag: "videos", Auth: AuthBearer, Response: types.Video{}},
+	"GET /trash":                {Summary: "回收站中的视频", Tag: "videos", Auth: AuthBearer, Response: []types.Video{}},
+	"GET /videos/{id}/stream": {
+		Summary: "播放视频（支持 Range）", Tag: "videos", Auth: AuthQuery, Content: "video/*",
+		Params: []Param{{Name: "download", In: "query", Description: "不为空时作为附件下载"}},
+	},
+	"GET /videos/{id}/renditions":        {Summary: "转码输出列表", Tag: "videos", Auth: AuthBearer, Response: []types.Rendition{}},
+	"GET /videos/{id}/renditions/{kind}": {Summary: "播放转码输出", Tag: "videos", Auth: AuthQuery, Content: "video/mp4"},
+	"GET /videos/{id}/thumbnail":         {Summary: "封面图", Tag: "videos", Auth: AuthQuery, Content: "image/jpeg"},
+	"GET /videos/{id}/thumbnails.vtt":    {Summary: "拖动预览图的 WebVTT 轨道（图片为签名链接）", Tag: "videos", Auth: AuthQuery, Content: "text/vtt"},
+	"GET /videos/{id}/thumbnails/{file}": {Summary: "拖动预览图", Tag: "videos", Auth: AuthSigned, Content: "image/jpeg"},
+	"POST /videos/{id}/sync": {
+		Summary: "计算练习录像和参考视频的时间偏移", Tag: "videos", Auth: AuthBearer,
+		Status: http.StatusAccepted, Response: types.Job{},
+	},
+	"POST /videos/{id}/derived": {
+		Summary:     "生成镜像/变速版本",
+		Description: "已经生成过时返回已有的转码输出（200）",
+		Tag:         "videos", Auth: AuthBearer, Request: types.CreateDerivedPayload{},
+		Status: http.StatusAccepted, Response: types.Rendition{},
+	},
+	"POST /videos/{id}/clips": {
+		Summary: "剪辑视频（后台任务，完成后生成新视频）", Tag: "videos", Auth: AuthBearer, Request: types.CreateClipPayload{},
+		Status: http.StatusAccepted, Response: types.Job{},
+	},
+	"GET /videos/{id}/clips":                {Summary: "从视频剪辑出的视频", Tag: "videos", Auth: AuthBearer, Response: []types.Video{}},
+	"GET /videos/{id}/hls/master.m3u8":      {Summary: "HLS 主播放列表（子播放列表为签名链接）", Tag: "videos", Auth: AuthQuery, Content: "application/vnd.apple.mpegurl"},
+	"GET /videos/{id}/hls/{variant}/{file}": {Summary: "HLS 子播放列表和分片", Tag: "videos", Auth: AuthSigned, Content: "application/octet-stream"},
+	"GET /videos/{id}/beats":                {Summary: "节拍信息", Tag: "videos", Auth: AuthBearer, Response: types.TempoMap{}},
+
+	// 练习片段
+	"GET /videos/{id}/segments":  {Summary: "练习片段列表", Tag: "segments", Auth: AuthBearer, Response: []types.Segment{}},
+	"POST /videos/{id}/segments": {Summary: "保存练习片段", Tag: "segments", Auth: AuthBearer, Request: types.CreateSegmentPayload{}, Status: http.StatusCreated, Response: types.Segment{}},
+	"PUT /videos/{id}/segments/{segmentId}": {
+		Summary: "修改练习片段", Tag: "segments", Auth: AuthBearer, Request: types.UpdateSegmentPayload{}, Response: types.Segment{},
+	},
+	"DELETE /videos/{id}/segments/{segmentId}": {Summary: "删除练习片段", Tag: "segments", Auth: AuthBearer, Response: Message{}},
+
+	// 评论
+	"GET /videos/{id}/comments":  {Summary: "评论列表（回复在 replies 中）", Tag: "comments", Auth: AuthBearer, Response: []types.Comment{}},
+	"POST /videos/{id}/comments": {Summary: "发表评论", Tag: "comments", Auth: AuthBearer, Request: types.CreateCommentPayload{}, Status: http.StatusCreated, Response: types.Comment{}},
+	"PUT /videos/{id}/comments/{commentId}": {
+		Summary: "修改评论", Tag: "comments", Auth: AuthBearer, Request: types.UpdateCommentPayload{}, Response: types.Comment{},
+	},
+	"DELETE /videos/{id}/comments/{commentId}": {Summary: "删除评论", Tag: "comments", Auth: AuthBearer, Response: Message{}},
+
+	// 练习记录
+	"GET /practices":         {Summary: "练习记录列表", Tag: "practices", Auth: AuthBearer, Response: []types.Practice{}},
+	"POST /practices":        {Summary: "创建练习记录", Tag: "practices", Auth: AuthBearer, Request: types.CreatePracticePayload{}, Status: http.StatusCreated, Response: types.Practice{}},
+	"GET /practices/{id}":    {Summary: "练习记录详情", Tag: "practices", Auth: AuthBearer, Response: types.Practice{}},
+	"DELETE /practices/{id}": {Summary: "删除练习记录", Tag: "practices", Auth: AuthBearer, Response: Message{}},
+
+	// 班级
+	"GET /classes":         {Summary: "我创建和加入的班级", Tag: "classes", Auth: AuthBearer, Response: []types.Class{}},
+	"POST /classes":        {Summary: "创建班级（老师）", Tag: "classes", Auth: AuthBearer, Request: types.CreateClassPayload{}, Status: http.StatusCreated, Response: types.Class{}},
+	"POST /classes/join":   {Summary: "通过邀请码加入班级", Tag: "classes", Auth: AuthBearer, Request: types.JoinClassPayload{}, Response: types.Class{}},
+	"GET /classes/{id}":    {Summary: "班级详情", Tag: "classes", Auth: AuthBearer, Response: types.Class{}},
+	"DELETE /classes/{id}": {Summary: "删除班级（老师）", Tag: "classes", Auth: AuthBearer, Response: Message{}},
+	"GET /classes/{id}/members": {
+		Summary: "班级成员", Tag: "classes", Auth: AuthBearer, Response: []types.ClassMember{},
+	},
+	"DELETE /classes/{id}/members/{userId}": {Summary: "移除成员（老师）或退出班级", Tag: "classes", Auth: AuthBearer, Response: Message{}},
+	"GET /classes/{id}/members/{userId}/activity": {
+		Summary: "学生在各个作业上的练习情况（老师）", Tag: "classes", Auth: AuthBearer, Response: []types.StudentActivity{},
+	},
+	"GET /classes/{id}/assignments": {Summary: "班级作业", Tag: "classes", Auth: AuthBearer, Response: []types.ClassAssignment{}},
+	"POST /classes/{id}/assignments": {
+		Summary: "布置视频（老师）", Tag: "classes", Auth: AuthBearer, Request: types.AssignVideoPayload{},
+		Status: http.StatusCreated, Response: types.ClassAssignment{},
+	},
+	"DELETE /classes/{id}/assignments/{videoId}": {Summary: "取消布置（老师）", Tag: "classes", Auth: AuthBearer, Response: Message{}},
+
+	// 存储配额
+	"GET /me/usage":               {Summary: "我的存储用量和配额", Tag: "quota", Auth: AuthBearer, Response: types.StorageUsage{}},
+	"GET /admin/users/{id}/usage": {Summary: "用户的存储用量和配额（管理员）", Tag: "quota", Auth: AuthBearer, Response: types.StorageUsage{}},
+	"PUT /admin/users/{id}/quota": {
+		Summary: "设置用户配额（管理员）", Tag: "quota", Auth: AuthBearer, Request: types.SetQuotaPayload{}, Response: types.StorageUsage{},
+		Description: "字段为 null 或不传时沿用角色默认值，0 表示不允许再上传，-1 表示不限",
+	},
+
+	// 实时事件和后台任务
+	"GET /events": {
+		Summary: "实时事件（SSE）", Tag: "events", Auth: AuthQuery, Content: "text/event-stream",
+		Params: []Param{
+			{Name: "Last-Event-ID", In: "header", Type: "integer", Description: "断线重连时补发这个 ID 之后的事件"},
+			{Name: "lastEventId", In: "query", Type: "integer", Description: "同 Last-Event-ID 请求头"},
+		},
+	},
+	"GET /jobs/{id}": {Summary: "后台任务状态", Tag: "jobs", Auth: AuthBearer, Response: types.Job{}},
+
+	// 同步练习房间
+	"POST /rooms":        {Summary: "创建同步练习房间", Tag: "rooms", Auth: AuthBearer, Request: types.CreateRoomPayload{}, Status: http.StatusCreated, Response: room.Snapshot{}},
+	"GET /rooms/{id}":    {Summary: "房间信息", Tag: "rooms", Auth: AuthBearer, Params: []Param{{Name: "id", In: "path"}}, Response: room.Snapshot{}},
+	"GET /rooms/{id}/ws": {Summary: "加入房间（WebSocket）", Tag: "rooms", Auth: AuthQuery, Params: []Param{{Name: "id", In: "path"}}, Status: http.StatusSwitchingProtocols},
+
+	// Webhook
+	"GET /webhooks":         {Summary: "Webhook 列表", Tag: "webhooks", Auth: AuthBearer, Response: []types.Webhook{}},
+	"POST /webhooks":        {Summary: "创建 Webhook（响应中包含签名密钥）", Tag: "webhooks", Auth: AuthBearer, Request: types.CreateWebhookPayload{}, Status: http.StatusCreated, Response: types.Webhook{}},
+	"GET /webhooks/{id}":    {Summary: "Webhook 详情", Tag: "webhooks", Auth: AuthBearer, Response: types.Webhook{}},
+	"DELETE /webhooks/{id}": {Summary: "删除 Webhook", Tag: "webhooks", Auth: AuthBearer, Response: Message{}},
+	"GET /webhooks/{id}/deliveries": {
+		Summary: "投递记录", Tag: "webhooks", Auth: AuthBearer, Response: []types.WebhookDelivery{},
+	},
+	"GET /webhooks/{id}/deliveries/{deliveryId}": {
+		Summary: "投递详情（含每次尝试的结果）", Tag: "webhooks", Auth: AuthBearer, Response: types.WebhookDelivery{},
+	},
+	"POST /webhooks/{id}/deliveries/{deliveryId}/redeliver": {
+		Summary: "重新投递", Tag: "webhooks", Auth: AuthBearer, Status: http.StatusAccepted, Response: types.WebhookDelivery{},
+	},
+
+	// 文档
+	"GET /openapi.json": {Summary: "OpenAPI 文档", Tag: "docs", Response: map[string]any{}},
+}
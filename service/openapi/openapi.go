@@ -0,0 +1,317 @@
+// Package openapi 由注册的路由和 types 包中的请求/响应结构生成 OpenAPI 3 文档。
+// 每个接口在 endpoints 中登记一条说明，Build 会检查路由和登记的说明是否一一对应
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Albert-tru/DanceMirror/utils"
+	"github.com/gorilla/mux"
+)
+
+// Document OpenAPI 3.0 文档
+type Document struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       Info                             `json:"info"`
+	Servers    []Server                         `json:"servers"`
+	Tags       []Tag                            `json:"tags,omitempty"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components Components                       `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type Server struct {
+	URL         string `json:"url"`
+	Description string `json:"description,omitempty"`
+}
+
+type Tag struct {
+	Name string `json:"name"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	Name         string `json:"name,omitempty"`
+	In           string `json:"in,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+// Operation OpenAPI 的 Operation Object
+type Operation struct {
+	Summary     string                `json:"summary"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []*Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                  `json:"required"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Auth 接口的认证方式
+type Auth int
+
+const (
+	AuthNone   Auth = iota
+	AuthBearer      // Authorization: Bearer <token>
+	AuthQuery       // 同 AuthBearer，也可以用 ?token=（<video>、EventSource、WebSocket 无法设置请求头）
+	AuthSigned      // 签名链接（?expires=&signature=），由其他接口的响应给出
+)
+
+// Param 路径参数之外的参数；和路径参数同名时覆盖自动生成的路径参数
+type Param struct {
+	Name        string
+	In          string // query、header 或 path
+	Type        string // 默认为 string
+	Description string
+	Required    bool
+}
+
+// Endpoint 一个接口的说明。Request/Response 为类型的零值，nil 表示没有请求体或响应内容是 Content 指定的文件
+type Endpoint struct {
+	Summary     string
+	Description string
+	Tag         string
+	Auth        Auth
+	Params      []Param
+
+	Request     any
+	RequestType string // 默认为 application/json
+
+	Status   int // 成功时的状态码，默认 200
+	Response any
+	Content  string // 非 JSON 响应的 Content-Type
+}
+
+// ErrorResponse /api/v1 的错误响应
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+var pathParam = regexp.MustCompile(`\{([^}:]+)(?::[^}]*)?\}`)
+
+// Build 遍历 router 中 prefix 下的路由生成文档（路径相对于 prefix）。
+// 有路由没有登记说明、或登记的说明没有对应的路由时返回错误，文档中只包含两边都有的接口
+func Build(router *mux.Router, prefix string) (*Document, error) {
+	doc := &Document{
+		OpenAPI: "3.0.3",
+		Info: Info{
+			Title:   "DanceMirror API",
+			Version: "1.0.0",
+			Description: "同一套接口也挂在 /api/v2 下：响应统一为 {code, message, data, details, requestId}，" +
+				"客户端按 code 判断错误类型（见 README）。本文档描述 /api/v1 的响应格式。",
+		},
+		Servers: []Server{{URL: prefix}},
+		Paths:   map[string]map[string]*Operation{},
+		Components: Components{
+			SecuritySchemes: map[string]*SecurityScheme{
+				"bearer": {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+				"token":  {Type: "apiKey", In: "query", Name: "token", Description: "同 bearer 的 JWT，用于无法设置请求头的场景"},
+			},
+		},
+	}
+	s := &schemas{components: map[string]*Schema{}}
+	errSchema := s.of(reflect.TypeOf(ErrorResponse{}))
+
+	var problems []string
+	seen := map[string]bool{}
+	tags := map[string]bool{}
+	err := router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		tpl, err := route.GetPathTemplate()
+		if err != nil || !strings.HasPrefix(tpl, prefix) {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			// 没有限制方法的是 PathPrefix 挂载的静态文件
+			return nil
+		}
+
+		path := strings.TrimPrefix(tpl, prefix)
+		for _, method := range methods {
+			key := method + " " + path
+			seen[key] = true
+			e, ok := endpoints[key]
+			if !ok {
+				problems = append(problems, "路由没有登记文档: "+key)
+				continue
+			}
+			if doc.Paths[path] == nil {
+				doc.Paths[path] = map[string]*Operation{}
+			}
+			doc.Paths[path][strings.ToLower(method)] = e.operation(s, path, errSchema)
+			tags[e.Tag] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for key := range endpoints {
+		if !seen[key] {
+			problems = append(problems, "登记的文档没有对应的路由: "+key)
+		}
+	}
+	for tag := range tags {
+		doc.Tags = append(doc.Tags, Tag{Name: tag})
+	}
+	sort.Slice(doc.Tags, func(i, j int) bool { return doc.Tags[i].Name < doc.Tags[j].Name })
+	doc.Components.Schemas = s.components
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return doc, fmt.Errorf("openapi: %s", strings.Join(problems, "; "))
+	}
+	return doc, nil
+}
+
+func (e Endpoint) operation(s *schemas, path string, errSchema *Schema) *Operation {
+	op := &Operation{
+		Summary:     e.Summary,
+		Description: e.Description,
+		Responses:   map[string]*Response{},
+	}
+	if e.Tag != "" {
+		op.Tags = []string{e.Tag}
+	}
+
+	// 路径参数：名字为 id 或以 Id 结尾的是整数
+	overrides := map[string]bool{}
+	for _, p := range e.Params {
+		if p.In == "path" {
+			overrides[p.Name] = true
+		}
+	}
+	for _, m := range pathParam.FindAllStringSubmatch(path, -1) {
+		name := m[1]
+		if overrides[name] {
+			continue
+		}
+		typ := "string"
+		if name == "id" || strings.HasSuffix(name, "Id") {
+			typ = "integer"
+		}
+		op.Parameters = append(op.Parameters, &Parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: typ}})
+	}
+	for _, p := range e.Params {
+		typ := p.Type
+		if typ == "" {
+			typ = "string"
+		}
+		op.Parameters = append(op.Parameters, &Parameter{
+			Name:        p.Name,
+			In:          p.In,
+			Description: p.Description,
+			Required:    p.Required || p.In == "path",
+			Schema:      &Schema{Type: typ},
+		})
+	}
+
+	switch e.Auth {
+	case AuthBearer:
+		op.Security = []map[string][]string{{"bearer": {}}}
+	case AuthQuery:
+		op.Security = []map[string][]string{{"bearer": {}}, {"token": {}}}
+	case AuthSigned:
+		op.Parameters = append(op.Parameters,
+			&Parameter{Name: "expires", In: "query", Required: true, Schema: &Schema{Type: "integer"}},
+			&Parameter{Name: "signature", In: "query", Required: true, Schema: &Schema{Type: "string"}},
+		)
+	}
+
+	if e.Request != nil {
+		contentType := e.RequestType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]*MediaType{contentType: {Schema: s.of(reflect.TypeOf(e.Request))}},
+		}
+	}
+
+	status := e.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	ok := &Response{Description: http.StatusText(status)}
+	switch {
+	case e.Response != nil:
+		ok.Content = map[string]*MediaType{"application/json": {Schema: s.of(reflect.TypeOf(e.Response))}}
+	case e.Content != "":
+		ok.Content = map[string]*MediaType{e.Content: {Schema: &Schema{Type: "string", Format: "binary"}}}
+	}
+	op.Responses[fmt.Sprint(status)] = ok
+	op.Responses["default"] = &Response{
+		Description: "错误",
+		Content:     map[string]*MediaType{"application/json": {Schema: errSchema}},
+	}
+	return op
+}
+
+// Handler 返回生成的文档
+type Handler struct {
+	doc *Document
+}
+
+func NewHandler() *Handler {
+	return &Handler{}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/openapi.json", h.handleSpec).Methods(http.MethodGet)
+}
+
+// Build 生成文档，需要在所有路由注册之后调用（见 openapi.Build）
+func (h *Handler) Build(router *mux.Router, prefix string) (*Document, error) {
+	doc, err := Build(router, prefix)
+	h.doc = doc
+	return doc, err
+}
+
+func (h *Handler) handleSpec(w http.ResponseWriter, r *http.Request) {
+	if h.doc == nil {
+		utils.WriteError(w, http.StatusServiceUnavailable, utils.NewError(utils.CodeUnavailable, "openapi document is not ready"))
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, h.doc)
+}
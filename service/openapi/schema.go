@@ -0,0 +1,210 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Schema OpenAPI 3.0 的 Schema Object（只包含用到的字段）
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+}
+
+// File multipart 表单中的文件字段
+type File struct{}
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+	rawType  = reflect.TypeOf(json.RawMessage{})
+	fileType = reflect.TypeOf(File{})
+)
+
+// schemas 按 Go 类型生成 Schema，具名结构体放到 components 中用 $ref 引用
+type schemas struct {
+	components map[string]*Schema
+}
+
+func (s *schemas) of(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case rawType:
+		return &Schema{Description: "任意 JSON"}
+	case fileType:
+		return &Schema{Type: "string", Format: "binary"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: s.of(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.of(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		name := schemaName(t)
+		if _, ok := s.components[name]; !ok {
+			// 先占位，支持递归类型（比如评论的回复）
+			s.components[name] = nil
+			s.components[name] = s.object(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	default:
+		return &Schema{}
+	}
+}
+
+// object 结构体按 JSON 字段名生成属性，validate 标签转换为约束
+func (s *schemas) object(t reflect.Type) *Schema {
+	obj := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		prop := s.of(f.Type)
+		if f.Type.Kind() == reflect.Pointer && prop.Ref == "" {
+			prop.Nullable = true
+		}
+		if rules := f.Tag.Get("validate"); rules != "" {
+			if prop.Ref != "" {
+				// $ref 不能和其他关键字并列
+				prop = &Schema{Ref: prop.Ref}
+			} else {
+				applyRules(prop, rules, t)
+			}
+			if hasRule(rules, "required") {
+				obj.Required = append(obj.Required, name)
+			}
+		}
+		obj.Properties[name] = prop
+	}
+	return obj
+}
+
+// applyRules 把 validate 标签转换为 Schema 约束，dive 之后的规则作用于数组元素
+func applyRules(prop *Schema, rules string, parent reflect.Type) {
+	target := prop
+	for _, rule := range strings.Split(rules, ",") {
+		tag, param, _ := strings.Cut(rule, "=")
+		switch tag {
+		case "dive":
+			if target.Items == nil {
+				return
+			}
+			target = target.Items
+		case "min", "max", "len":
+			setBound(target, tag, param)
+		case "oneof":
+			target.Enum = strings.Fields(param)
+		case "url":
+			target.Format = "uri"
+		case "email":
+			target.Format = "email"
+		case "gtfield":
+			if f, ok := parent.FieldByName(param); ok {
+				name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+				target.Description = "大于 " + name
+			}
+		}
+	}
+}
+
+// setBound min、max、len 按类型对应长度、元素个数或数值范围
+func setBound(prop *Schema, tag, param string) {
+	n, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return
+	}
+	i := int(n)
+
+	switch prop.Type {
+	case "string":
+		if tag != "max" {
+			prop.MinLength = &i
+		}
+		if tag != "min" {
+			prop.MaxLength = &i
+		}
+	case "array":
+		if tag != "max" {
+			prop.MinItems = &i
+		}
+		if tag != "min" {
+			prop.MaxItems = &i
+		}
+	case "integer", "number":
+		if tag != "max" {
+			prop.Minimum = &n
+		}
+		if tag != "min" {
+			prop.Maximum = &n
+		}
+	}
+}
+
+func hasRule(rules, name string) bool {
+	for _, rule := range strings.Split(rules, ",") {
+		if rule == "dive" {
+			return false
+		}
+		if rule == name {
+			return true
+		}
+	}
+	return false
+}
+
+// schemaName types 包和本包的类型直接用类型名，其他包加上包名（比如 room.Snapshot 为 RoomSnapshot）
+func schemaName(t reflect.Type) string {
+	pkg := t.PkgPath()
+	pkg = pkg[strings.LastIndex(pkg, "/")+1:]
+	if pkg == "types" || pkg == "openapi" {
+		return t.Name()
+	}
+	r := []rune(pkg)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r) + t.Name()
+}
@@ -0,0 +1,81 @@
+package practice
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Albert-tru/DanceMirror/types"
+)
+
+// MemoryStore 内存中的 types.PracticeStore 实现（用于测试），
+// 查询结果和错误与数据库实现一致，返回的都是副本
+type MemoryStore struct {
+	mu        sync.RWMutex
+	nextID    int
+	practices map[int]*types.Practice
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{nextID: 1, practices: map[int]*types.Practice{}}
+}
+
+func (s *MemoryStore) GetPractices(ctx context.Context, userID int) ([]*types.Practice, error) {
+	return s.filter(func(p *types.Practice) bool { return p.UserID == userID }), nil
+}
+
+func (s *MemoryStore) GetPracticeByID(ctx context.Context, id int) (*types.Practice, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	p, ok := s.practices[id]
+	if !ok {
+		return nil, fmt.Errorf("practice %w", types.ErrNotFound)
+	}
+	copied := *p
+	return &copied, nil
+}
+
+func (s *MemoryStore) GetPracticesByVideo(ctx context.Context, userID, videoID int) ([]*types.Practice, error) {
+	return s.filter(func(p *types.Practice) bool { return p.UserID == userID && p.VideoID == videoID }), nil
+}
+
+func (s *MemoryStore) CreatePractice(ctx context.Context, practice *types.Practice) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 和数据库一样只回填 ID，创建时间只保存在记录中
+	practice.ID = s.nextID
+	s.nextID++
+
+	stored := *practice
+	stored.CreatedAt = time.Now().Truncate(time.Second)
+	s.practices[stored.ID] = &stored
+	return nil
+}
+
+func (s *MemoryStore) DeletePractice(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.practices, id)
+	return nil
+}
+
+// filter 返回符合条件的记录，最新的在前
+func (s *MemoryStore) filter(match func(*types.Practice) bool) []*types.Practice {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	practices := []*types.Practice{}
+	for _, p := range s.practices {
+		if match(p) {
+			copied := *p
+			practices = append(practices, &copied)
+		}
+	}
+	sort.Slice(practices, func(i, j int) bool { return practices[i].ID > practices[j].ID })
+	return practices
+}
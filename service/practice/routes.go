@@ -0,0 +1,150 @@
+package practice
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Albert-tru/DanceMirror/service/auth"
+	"github.com/Albert-tru/DanceMirror/service/video"
+	"github.com/Albert-tru/DanceMirror/types"
+	"github.com/Albert-tru/DanceMirror/utils"
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	store      types.PracticeStore
+	videoStore types.VideoStore
+	userStore  types.UserStore
+	classStore types.ClassStore
+	events     types.EventPublisher
+}
+
+func NewHandler(store types.PracticeStore, videoStore types.VideoStore, userStore types.UserStore, classStore types.ClassStore, events types.EventPublisher) *Handler {
+	return &Handler{
+		store:      store,
+		videoStore: videoStore,
+		userStore:  userStore,
+		classStore: classStore,
+		events:     events,
+	}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/practices", auth.WithJWTAuth(h.handleGetPractices, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/practices", auth.WithJWTAuth(h.handleCreatePractice, h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/practices/{id}", auth.WithJWTAuth(h.handleGetPractice, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/practices/{id}", auth.WithJWTAuth(h.handleDeletePractice, h.userStore)).Methods(http.MethodDelete)
+}
+
+func (h *Handler) handleGetPractices(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == -1 {
+		utils.WriteError(w, http.StatusUnauthorized, utils.NewError(utils.CodeUnauthorized, "unauthorized"))
+		return
+	}
+
+	practices, err := h.store.GetPractices(r.Context(), userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, practices)
+}
+
+func (h *Handler) handleCreatePractice(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == -1 {
+		utils.WriteError(w, http.StatusUnauthorized, utils.NewError(utils.CodeUnauthorized, "unauthorized"))
+		return
+	}
+
+	var payload types.CreatePracticePayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, utils.InvalidPayload(err))
+		return
+	}
+
+	// 只能为有权查看的视频（自己的或班级布置的）记录练习
+	v, err := h.videoStore.GetVideoByID(r.Context(), payload.VideoID)
+	if err != nil {
+		utils.WriteStoreError(w, err)
+		return
+	}
+	ok, err := video.CanView(r.Context(), h.classStore, v, userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if !ok {
+		utils.WriteError(w, http.StatusForbidden, utils.NewError(utils.CodePermissionDenied, "permission denied"))
+		return
+	}
+
+	practice := &types.Practice{
+		UserID:   userID,
+		VideoID:  payload.VideoID,
+		Duration: payload.Duration,
+		Speed:    payload.Speed,
+		Notes:    payload.Notes,
+	}
+	if err := h.store.CreatePractice(r.Context(), practice); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	h.events.Publish(userID, types.EventPracticeCreated, practice)
+
+	utils.WriteJSON(w, http.StatusCreated, practice)
+}
+
+func (h *Handler) handleGetPractice(w http.ResponseWriter, r *http.Request) {
+	practice, ok := h.loadOwnPractice(w, r)
+	if !ok {
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, practice)
+}
+
+func (h *Handler) handleDeletePractice(w http.ResponseWriter, r *http.Request) {
+	practice, ok := h.loadOwnPractice(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.store.DeletePractice(r.Context(), practice.ID); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"message": "practice deleted successfully"})
+}
+
+// loadOwnPractice 读取路径中的练习记录并校验归属，失败时已写入响应
+func (h *Handler) loadOwnPractice(w http.ResponseWriter, r *http.Request) (*types.Practice, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, utils.NewError(utils.CodeInvalidID, "invalid practice id"))
+		return nil, false
+	}
+
+	practice, err := h.store.GetPracticeByID(r.Context(), id)
+	if err != nil {
+		utils.WriteStoreError(w, err)
+		return nil, false
+	}
+
+	userID := auth.GetUserIDFromContext(r.Context())
+	if practice.UserID != userID {
+		utils.WriteError(w, http.StatusForbidden, utils.NewError(utils.CodePermissionDenied, "permission denied"))
+		return nil, false
+	}
+
+	return practice, true
+}
@@ -0,0 +1,93 @@
+package practice_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/Albert-tru/DanceMirror/cmd/api/apitest"
+	"github.com/Albert-tru/DanceMirror/types"
+)
+
+func createUser(t *testing.T, s *apitest.Server, phone string) string {
+	t.Helper()
+	_, token, err := s.CreateUser(phone, "password123", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func uploadVideo(t *testing.T, s *apitest.Server, token string) int {
+	t.Helper()
+	resp, err := s.Upload(token, map[string]string{"title": "routine"}, "routine.mp4", apitest.FakeMP4(1024))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var video types.Video
+	if err := apitest.DecodeJSON(resp, &video); err != nil {
+		t.Fatal(err)
+	}
+	return video.ID
+}
+
+func TestCreate(t *testing.T) {
+	apitest.EachStore(t, func(t *testing.T, s *apitest.Server) {
+		owner := createUser(t, s, "13800000001")
+		other := createUser(t, s, "13800000002")
+		own := uploadVideo(t, s, owner)
+		others := uploadVideo(t, s, other)
+
+		tests := []struct {
+			name    string
+			token   string
+			payload types.CreatePracticePayload
+			status  int
+		}{
+			{"own video", owner, types.CreatePracticePayload{VideoID: own, Duration: 60, Speed: 0.75}, http.StatusCreated},
+			{"another user's video", owner, types.CreatePracticePayload{VideoID: others, Duration: 60, Speed: 1}, http.StatusForbidden},
+			{"missing video", owner, types.CreatePracticePayload{VideoID: 999, Duration: 60, Speed: 1}, http.StatusNotFound},
+			{"speed too high", owner, types.CreatePracticePayload{VideoID: own, Duration: 60, Speed: 3}, http.StatusBadRequest},
+			{"no duration", owner, types.CreatePracticePayload{VideoID: own, Speed: 1}, http.StatusBadRequest},
+			{"not logged in", "", types.CreatePracticePayload{VideoID: own, Duration: 60, Speed: 1}, http.StatusForbidden},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				s.Do(t, http.MethodPost, "/api/v1/practices", tt.token, tt.payload, tt.status, nil)
+			})
+		}
+	})
+}
+
+// 练习记录只有本人可以查看和删除
+func TestOwnership(t *testing.T) {
+	apitest.EachStore(t, func(t *testing.T, s *apitest.Server) {
+		owner := createUser(t, s, "13800000001")
+		other := createUser(t, s, "13800000002")
+		var practice types.Practice
+		s.Do(t, http.MethodPost, "/api/v1/practices", owner, types.CreatePracticePayload{VideoID: uploadVideo(t, s, owner), Duration: 60, Speed: 1}, http.StatusCreated, &practice)
+		path := fmt.Sprintf("/api/v1/practices/%d", practice.ID)
+
+		var practices []types.Practice
+		s.Do(t, http.MethodGet, "/api/v1/practices", other, nil, http.StatusOK, &practices)
+		if len(practices) != 0 {
+			t.Errorf("another user's practice list = %+v, want empty", practices)
+		}
+
+		steps := []struct {
+			method string
+			token  string
+			status int
+		}{
+			{http.MethodGet, other, http.StatusForbidden},
+			{http.MethodDelete, other, http.StatusForbidden},
+			{http.MethodGet, owner, http.StatusOK},
+			{http.MethodDelete, owner, http.StatusOK},
+			{http.MethodGet, owner, http.StatusNotFound},
+		}
+		for _, step := range steps {
+			s.Do(t, step.method, path, step.token, nil, step.status, nil)
+		}
+		s.Do(t, http.MethodGet, "/api/v1/practices/abc", owner, nil, http.StatusBadRequest, nil)
+	})
+}
@@ -0,0 +1,126 @@
+package practice
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/Albert-tru/DanceMirror/types"
+)
+
+// practiceColumns 和 scanRowIntoPractice 的顺序一致
+const practiceColumns = "id, userId, videoId, duration, speed, notes, createdAt"
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) GetPractices(ctx context.Context, userID int) ([]*types.Practice, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+practiceColumns+" FROM practices WHERE userId = ? ORDER BY createdAt DESC", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	practices := []*types.Practice{}
+	for rows.Next() {
+		p, err := scanRowIntoPractice(rows)
+		if err != nil {
+			return nil, err
+		}
+		practices = append(practices, p)
+	}
+
+	return practices, nil
+}
+
+func (s *Store) GetPracticeByID(ctx context.Context, id int) (*types.Practice, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+practiceColumns+" FROM practices WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	p := new(types.Practice)
+	for rows.Next() {
+		p, err = scanRowIntoPractice(rows)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if p.ID == 0 {
+		return nil, fmt.Errorf("practice %w", types.ErrNotFound)
+	}
+
+	return p, nil
+}
+
+// GetPracticesByVideo 获取用户针对某个视频的练习记录
+func (s *Store) GetPracticesByVideo(ctx context.Context, userID, videoID int) ([]*types.Practice, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+practiceColumns+" FROM practices WHERE userId = ? AND videoId = ? ORDER BY createdAt DESC", userID, videoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	practices := []*types.Practice{}
+	for rows.Next() {
+		p, err := scanRowIntoPractice(rows)
+		if err != nil {
+			return nil, err
+		}
+		practices = append(practices, p)
+	}
+
+	return practices, nil
+}
+
+func (s *Store) CreatePractice(ctx context.Context, practice *types.Practice) error {
+	result, err := s.db.ExecContext(ctx, "INSERT INTO practices (userId, videoId, duration, speed, notes) VALUES (?, ?, ?, ?, ?)",
+		practice.UserID, practice.VideoID, practice.Duration, practice.Speed, practice.Notes)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	practice.ID = int(id)
+	return nil
+}
+
+func (s *Store) DeletePractice(ctx context.Context, id int) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM practices WHERE id = ?", id)
+	return err
+}
+
+func scanRowIntoPractice(rows *sql.Rows) (*types.Practice, error) {
+	practice := new(types.Practice)
+
+	var notes sql.NullString
+	err := rows.Scan(
+		&practice.ID,
+		&practice.UserID,
+		&practice.VideoID,
+		&practice.Duration,
+		&practice.Speed,
+		&notes,
+		&practice.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if notes.Valid {
+		practice.Notes = notes.String
+	}
+
+	return practice, nil
+}
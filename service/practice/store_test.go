@@ -0,0 +1,111 @@
+package practice_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/Albert-tru/DanceMirror/db/dbtest"
+	"github.com/Albert-tru/DanceMirror/service/practice"
+	"github.com/Albert-tru/DanceMirror/types"
+)
+
+// fixture 数据库中的两个用户和各自的视频（满足练习记录的外键，内存存储不检查）
+type fixture struct {
+	alice, bob           int
+	aliceVideo, bobVideo int
+}
+
+// 内存存储和数据库存储执行同样的操作，结果和错误应该一致
+func forEachStore(t *testing.T, test func(t *testing.T, store types.PracticeStore, f fixture)) {
+	impls := []struct {
+		name  string
+		store func(database *sql.DB) types.PracticeStore
+	}{
+		{"memory", func(*sql.DB) types.PracticeStore { return practice.NewMemoryStore() }},
+		{"sqlite", func(database *sql.DB) types.PracticeStore { return practice.NewStore(database) }},
+	}
+	for _, impl := range impls {
+		t.Run(impl.name, func(t *testing.T) {
+			database := dbtest.New(t)
+			f := fixture{alice: dbtest.CreateUser(t, database, "13800000001"), bob: dbtest.CreateUser(t, database, "13800000002")}
+			f.aliceVideo = dbtest.CreateVideo(t, database, f.alice, "alice")
+			f.bobVideo = dbtest.CreateVideo(t, database, f.bob, "bob")
+			test(t, impl.store(database), f)
+		})
+	}
+}
+
+func ids(practices []*types.Practice) []int {
+	ids := []int{}
+	for _, p := range practices {
+		ids = append(ids, p.ID)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+func TestStoreCreateAndQuery(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store types.PracticeStore, f fixture) {
+		ctx := context.Background()
+		create := func(userID, videoID int) *types.Practice {
+			p := &types.Practice{ID: 99, UserID: userID, VideoID: videoID, Duration: 60, Speed: 0.75, Notes: "slow"}
+			if err := store.CreatePractice(ctx, p); err != nil {
+				t.Fatalf("CreatePractice: %v", err)
+			}
+			return p
+		}
+		first := create(f.alice, f.aliceVideo)
+		second := create(f.alice, f.bobVideo)
+		create(f.bob, f.bobVideo)
+
+		// 回填的 ID 从 1 开始递增，不使用传入的值
+		if first.ID != 1 || second.ID != 2 {
+			t.Fatalf("IDs = %d, %d; want 1, 2", first.ID, second.ID)
+		}
+
+		got, err := store.GetPracticeByID(ctx, first.ID)
+		if err != nil {
+			t.Fatalf("GetPracticeByID: %v", err)
+		}
+		if got.UserID != f.alice || got.VideoID != f.aliceVideo || got.Duration != 60 || got.Speed != 0.75 || got.Notes != "slow" || got.CreatedAt.IsZero() {
+			t.Errorf("GetPracticeByID = %+v", got)
+		}
+
+		all, err := store.GetPractices(ctx, f.alice)
+		if err != nil || len(ids(all)) != 2 || ids(all)[0] != 1 || ids(all)[1] != 2 {
+			t.Errorf("GetPractices = %v, %v; want [1 2]", ids(all), err)
+		}
+		byVideo, err := store.GetPracticesByVideo(ctx, f.alice, f.bobVideo)
+		if err != nil || len(byVideo) != 1 || byVideo[0].ID != 2 {
+			t.Errorf("GetPracticesByVideo = %v, %v; want [2]", ids(byVideo), err)
+		}
+		none, err := store.GetPractices(ctx, 42)
+		if err != nil || none == nil || len(none) != 0 {
+			t.Errorf("GetPractices of a user without practices = %#v, %v; want an empty slice", none, err)
+		}
+	})
+}
+
+func TestStoreDelete(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store types.PracticeStore, f fixture) {
+		ctx := context.Background()
+		p := &types.Practice{UserID: f.alice, VideoID: f.aliceVideo, Duration: 30, Speed: 1}
+		if err := store.CreatePractice(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := store.DeletePractice(ctx, p.ID); err != nil {
+			t.Fatalf("DeletePractice: %v", err)
+		}
+		if _, err := store.GetPracticeByID(ctx, p.ID); !errors.Is(err, types.ErrNotFound) || err.Error() != "practice not found" {
+			t.Errorf("GetPracticeByID after delete: err = %v, want %q", err, "practice not found")
+		}
+		// 删除不存在的记录不报错
+		if err := store.DeletePractice(ctx, p.ID); err != nil {
+			t.Errorf("DeletePractice of a missing practice: %v", err)
+		}
+	})
+}
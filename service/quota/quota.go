@@ -0,0 +1,128 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/Albert-tru/DanceMirror/config"
+	"github.com/Albert-tru/DanceMirror/types"
+)
+
+// ErrExceeded 上传会超出存储配额
+var ErrExceeded = errors.New("storage quota exceeded")
+
+// Manager 计算用户配额（单用户覆盖 > 角色默认值）并预占/释放用量
+type Manager struct {
+	store     types.QuotaStore
+	userStore types.UserStore
+}
+
+func NewManager(store types.QuotaStore, userStore types.UserStore) *Manager {
+	return &Manager{
+		store:     store,
+		userStore: userStore,
+	}
+}
+
+// Unlimited 不限制用量
+const Unlimited = -1
+
+// Limits 返回用户的字节数和视频数上限，Unlimited（负数）表示不限，0 表示不允许上传
+func (m *Manager) Limits(ctx context.Context, u *types.User) (int64, int, error) {
+	maxBytes, maxVideos := roleDefaults(u.Role)
+
+	override, err := m.store.GetQuotaOverride(ctx, u.ID)
+	if err != nil {
+		return 0, 0, err
+	}
+	if override.MaxBytes != nil {
+		maxBytes = *override.MaxBytes
+	}
+	if override.MaxVideos != nil {
+		maxVideos = *override.MaxVideos
+	}
+
+	return maxBytes, maxVideos, nil
+}
+
+// Reserve 为一个即将写入的视频预占用量，超出配额时返回 ErrExceeded
+func (m *Manager) Reserve(ctx context.Context, userID int, bytes int64) error {
+	u, err := m.userStore.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	maxBytes, maxVideos, err := m.Limits(ctx, u)
+	if err != nil {
+		return err
+	}
+
+	ok, err := m.store.Reserve(ctx, userID, bytes, maxBytes, maxVideos)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrExceeded
+	}
+	return nil
+}
+
+// Release 释放一个视频占用的用量（上传失败或删除视频时调用）。
+// 请求被取消时也要释放，所以不随 ctx 取消
+func (m *Manager) Release(ctx context.Context, userID int, bytes int64) {
+	if err := m.store.Release(context.WithoutCancel(ctx), userID, bytes); err != nil {
+		log.Printf("quota: failed to release %d bytes for user %d: %v", bytes, userID, err)
+	}
+}
+
+// Usage 获取用户的用量和剩余配额
+func (m *Manager) Usage(ctx context.Context, u *types.User) (*types.StorageUsage, error) {
+	usedBytes, videoCount, err := m.store.GetUsage(ctx, u.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	maxBytes, maxVideos, err := m.Limits(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &types.StorageUsage{
+		UserID:     u.ID,
+		Role:       u.Role,
+		UsedBytes:  usedBytes,
+		VideoCount: videoCount,
+	}
+	if maxBytes >= 0 {
+		remaining := max(maxBytes-usedBytes, 0)
+		usage.MaxBytes = &maxBytes
+		usage.RemainingBytes = &remaining
+	}
+	if maxVideos >= 0 {
+		remaining := max(maxVideos-videoCount, 0)
+		usage.MaxVideos = &maxVideos
+		usage.RemainingVideos = &remaining
+	}
+
+	return usage, nil
+}
+
+// roleDefaults 角色默认配额，管理员不限（配置中的 0 表示不限）
+func roleDefaults(role string) (int64, int) {
+	switch role {
+	case types.RoleAdmin:
+		return Unlimited, Unlimited
+	case types.RoleTeacher:
+		return configLimit(config.Envs.QuotaTeacherBytes), int(configLimit(config.Envs.QuotaTeacherVideos))
+	default:
+		return configLimit(config.Envs.QuotaUserBytes), int(configLimit(config.Envs.QuotaUserVideos))
+	}
+}
+
+func configLimit(limit int64) int64 {
+	if limit <= 0 {
+		return Unlimited
+	}
+	return limit
+}
@@ -0,0 +1,119 @@
+package quota_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/Albert-tru/DanceMirror/config"
+	"github.com/Albert-tru/DanceMirror/db/dbtest"
+	"github.com/Albert-tru/DanceMirror/service/quota"
+	"github.com/Albert-tru/DanceMirror/service/user"
+	"github.com/Albert-tru/DanceMirror/types"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+// 并发预占时条件 UPDATE 只放行配额内的请求
+func TestReserveConcurrent(t *testing.T) {
+	tests := []struct {
+		name      string
+		maxBytes  int64
+		maxVideos int
+		bytes     int64
+		want      int
+	}{
+		{"byte limit", 1000, quota.Unlimited, 300, 3},
+		{"video limit", quota.Unlimited, 5, 300, 5},
+		{"both limits", 1000, 2, 300, 2},
+		{"unlimited", quota.Unlimited, quota.Unlimited, 300, 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database := dbtest.New(t)
+			store := quota.NewStore(database)
+			userID := dbtest.CreateUser(t, database, "13800000001")
+			ctx := context.Background()
+
+			const n = 20
+			var wg sync.WaitGroup
+			var mu sync.Mutex
+			reserved := 0
+			for i := 0; i < n; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					ok, err := store.Reserve(ctx, userID, tt.bytes, tt.maxBytes, tt.maxVideos)
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					if ok {
+						mu.Lock()
+						reserved++
+						mu.Unlock()
+					}
+				}()
+			}
+			wg.Wait()
+
+			if reserved != tt.want {
+				t.Errorf("%d of %d reservations succeeded, want %d", reserved, n, tt.want)
+			}
+			usedBytes, videoCount, err := store.GetUsage(ctx, userID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if usedBytes != int64(tt.want)*tt.bytes || videoCount != tt.want {
+				t.Errorf("usage = %d bytes, %d videos; want %d bytes, %d videos", usedBytes, videoCount, int64(tt.want)*tt.bytes, tt.want)
+			}
+		})
+	}
+}
+
+// 覆盖配额优先于角色默认值：0 表示不允许上传，-1 表示不限
+func TestOverride(t *testing.T) {
+	prevBytes, prevVideos := config.Envs.QuotaUserBytes, config.Envs.QuotaUserVideos
+	config.Envs.QuotaUserBytes, config.Envs.QuotaUserVideos = 1000, 2
+	t.Cleanup(func() { config.Envs.QuotaUserBytes, config.Envs.QuotaUserVideos = prevBytes, prevVideos })
+
+	tests := []struct {
+		name     string
+		override types.QuotaOverride
+		bytes    int64
+		want     int // 连续 10 次预占中成功的次数
+	}{
+		{"role default", types.QuotaOverride{}, 300, 2},
+		{"no bytes", types.QuotaOverride{MaxBytes: ptr[int64](0)}, 1, 0},
+		{"no videos", types.QuotaOverride{MaxVideos: ptr(0)}, 1, 0},
+		{"unlimited bytes", types.QuotaOverride{MaxBytes: ptr[int64](-1), MaxVideos: ptr(-1)}, 1 << 40, 10},
+		{"unlimited videos, default bytes", types.QuotaOverride{MaxVideos: ptr(-1)}, 300, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database := dbtest.New(t)
+			store := quota.NewStore(database)
+			manager := quota.NewManager(store, user.NewStore(database))
+			ctx := context.Background()
+
+			tt.override.UserID = dbtest.CreateUser(t, database, "13800000001")
+			if err := store.SetQuotaOverride(ctx, &tt.override); err != nil {
+				t.Fatal(err)
+			}
+
+			reserved := 0
+			for i := 0; i < 10; i++ {
+				err := manager.Reserve(ctx, tt.override.UserID, tt.bytes)
+				if err == nil {
+					reserved++
+				} else if !errors.Is(err, quota.ErrExceeded) {
+					t.Fatal(err)
+				}
+			}
+			if reserved != tt.want {
+				t.Errorf("%d reservations succeeded, want %d", reserved, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package quota
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Albert-tru/DanceMirror/service/auth"
+	"github.com/Albert-tru/DanceMirror/types"
+	"github.com/Albert-tru/DanceMirror/utils"
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	manager   *Manager
+	store     types.QuotaStore
+	userStore types.UserStore
+}
+
+func NewHandler(manager *Manager, store types.QuotaStore, userStore types.UserStore) *Handler {
+	return &Handler{
+		manager:   manager,
+		store:     store,
+		userStore: userStore,
+	}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/me/usage", auth.WithJWTAuth(h.handleGetMyUsage, h.userStore)).Methods(http.MethodGet)
+
+	// 管理员查看和覆盖用户配额
+	router.HandleFunc("/admin/users/{id}/usage", auth.WithJWTAuth(h.handleGetUserUsage, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/admin/users/{id}/quota", auth.WithJWTAuth(h.handleSetUserQuota, h.userStore)).Methods(http.MethodPut)
+}
+
+func (h *Handler) handleGetMyUsage(w http.ResponseWriter, r *http.Request) {
+	u, err := h.userStore.GetUserByID(r.Context(), auth.GetUserIDFromContext(r.Context()))
+	if errors.Is(err, types.ErrNotFound) {
+		utils.WriteError(w, http.StatusUnauthorized, utils.NewError(utils.CodeUnauthorized, "unauthorized"))
+		return
+	}
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	usage, err := h.manager.Usage(r.Context(), u)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, usage)
+}
+
+func (h *Handler) handleGetUserUsage(w http.ResponseWriter, r *http.Request) {
+	target, ok := h.loadTargetUser(w, r)
+	if !ok {
+		return
+	}
+
+	usage, err := h.manager.Usage(r.Context(), target)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, usage)
+}
+
+// handleSetUserQuota 覆盖用户配额，字段为 null 时恢复角色默认值
+func (h *Handler) handleSetUserQuota(w http.ResponseWriter, r *http.Request) {
+	target, ok := h.loadTargetUser(w, r)
+	if !ok {
+		return
+	}
+
+	var payload types.SetQuotaPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, utils.InvalidPayload(err))
+		return
+	}
+
+	override := &types.QuotaOverride{
+		UserID:    target.ID,
+		MaxBytes:  payload.MaxBytes,
+		MaxVideos: payload.MaxVideos,
+	}
+	if err := h.store.SetQuotaOverride(r.Context(), override); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	usage, err := h.manager.Usage(r.Context(), target)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, usage)
+}
+
+// loadTargetUser 校验当前用户是管理员，并读取路径中的目标用户，失败时已写入响应
+func (h *Handler) loadTargetUser(w http.ResponseWriter, r *http.Request) (*types.User, bool) {
+	admin, err := h.userStore.GetUserByID(r.Context(), auth.GetUserIDFromContext(r.Context()))
+	if errors.Is(err, types.ErrNotFound) {
+		utils.WriteError(w, http.StatusUnauthorized, utils.NewError(utils.CodeUnauthorized, "unauthorized"))
+		return nil, false
+	}
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return nil, false
+	}
+	if admin.Role != types.RoleAdmin {
+		utils.WriteError(w, http.StatusForbidden, utils.NewError(utils.CodePermissionDenied, "permission denied"))
+		return nil, false
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, utils.NewError(utils.CodeInvalidID, "invalid user id"))
+		return nil, false
+	}
+
+	target, err := h.userStore.GetUserByID(r.Context(), id)
+	if err != nil {
+		utils.WriteStoreError(w, err)
+		return nil, false
+	}
+
+	return target, true
+}
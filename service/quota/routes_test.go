@@ -0,0 +1,60 @@
+package quota_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/Albert-tru/DanceMirror/cmd/api/apitest"
+	"github.com/Albert-tru/DanceMirror/types"
+)
+
+// 只有管理员可以查看和覆盖其他用户的配额；覆盖为 0 后上传被拒绝，-1 不限
+func TestSetUserQuota(t *testing.T) {
+	s := apitest.Start(t)
+	_, admin, err := s.CreateUser("13800000001", "password123", types.RoleAdmin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, token, err := s.CreateUser("13800000002", "password123", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	quota := fmt.Sprintf("/api/v1/admin/users/%d/quota", u.ID)
+
+	s.Do(t, http.MethodGet, fmt.Sprintf("/api/v1/admin/users/%d/usage", u.ID), token, nil, http.StatusForbidden, nil)
+	s.Do(t, http.MethodPut, quota, token, map[string]any{"maxVideos": -1}, http.StatusForbidden, nil)
+	s.Do(t, http.MethodPut, "/api/v1/admin/users/999/quota", admin, map[string]any{"maxVideos": 0}, http.StatusNotFound, nil)
+	s.Do(t, http.MethodPut, quota, admin, map[string]any{"maxVideos": -2}, http.StatusBadRequest, nil)
+
+	upload := func(status int) {
+		t.Helper()
+		resp, err := s.Upload(token, map[string]string{"title": "routine"}, "routine.mp4", apitest.FakeMP4(1024))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != status {
+			t.Fatalf("upload = %d, want %d", resp.StatusCode, status)
+		}
+	}
+
+	var usage types.StorageUsage
+	s.Do(t, http.MethodPut, quota, admin, map[string]any{"maxBytes": 0}, http.StatusOK, &usage)
+	if usage.MaxBytes == nil || *usage.MaxBytes != 0 || usage.RemainingBytes == nil || *usage.RemainingBytes != 0 {
+		t.Errorf("usage = %+v, want no bytes left", usage)
+	}
+	upload(http.StatusRequestEntityTooLarge)
+
+	s.Do(t, http.MethodPut, quota, admin, map[string]any{"maxBytes": -1, "maxVideos": -1}, http.StatusOK, &usage)
+	if usage.MaxBytes != nil || usage.MaxVideos != nil {
+		t.Errorf("usage = %+v, want unlimited", usage)
+	}
+	upload(http.StatusCreated)
+	upload(http.StatusCreated)
+
+	s.Do(t, http.MethodGet, "/api/v1/me/usage", token, nil, http.StatusOK, &usage)
+	if usage.VideoCount != 2 {
+		t.Errorf("video count = %d, want 2", usage.VideoCount)
+	}
+}
@@ -0,0 +1,105 @@
+package quota
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/Albert-tru/DanceMirror/db/dialect"
+	"github.com/Albert-tru/DanceMirror/types"
+)
+
+type Store struct {
+	db      *sql.DB
+	dialect dialect.Dialect
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db, dialect: dialect.Of(db)}
+}
+
+func (s *Store) GetUsage(ctx context.Context, userID int) (int64, int, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT usedBytes, videoCount FROM storage_usage WHERE userId = ?", userID)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer rows.Close()
+
+	var usedBytes int64
+	var videoCount int
+	for rows.Next() {
+		if err := rows.Scan(&usedBytes, &videoCount); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	return usedBytes, videoCount, rows.Err()
+}
+
+// Reserve 用一条条件 UPDATE 检查并增加用量，并发上传时不会超出配额
+func (s *Store) Reserve(ctx context.Context, userID int, bytes int64, maxBytes int64, maxVideos int) (bool, error) {
+	if _, err := s.db.ExecContext(ctx, s.dialect.InsertIgnore()+" INTO storage_usage (userId) VALUES (?)", userID); err != nil {
+		return false, err
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+UPDATE storage_usage
+SET usedBytes = usedBytes + ?, videoCount = videoCount + 1
+WHERE userId = ?
+  AND (? < 0 OR usedBytes + ? <= ?)
+  AND (? < 0 OR videoCount < ?)`,
+		bytes, userID, maxBytes, bytes, maxBytes, maxVideos, maxVideos)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected == 1, nil
+}
+
+func (s *Store) Release(ctx context.Context, userID int, bytes int64) error {
+	_, err := s.db.ExecContext(ctx, `
+UPDATE storage_usage
+SET usedBytes = CASE WHEN usedBytes > ? THEN usedBytes - ? ELSE 0 END,
+    videoCount = CASE WHEN videoCount > 1 THEN videoCount - 1 ELSE 0 END
+WHERE userId = ?`, bytes, bytes, userID)
+	return err
+}
+
+// GetQuotaOverride 获取用户的配额覆盖，没有设置时字段为空
+func (s *Store) GetQuotaOverride(ctx context.Context, userID int) (*types.QuotaOverride, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT maxBytes, maxVideos FROM user_quotas WHERE userId = ?", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	override := &types.QuotaOverride{UserID: userID}
+	for rows.Next() {
+		var maxBytes sql.NullInt64
+		var maxVideos sql.NullInt32
+		if err := rows.Scan(&maxBytes, &maxVideos); err != nil {
+			return nil, err
+		}
+		if maxBytes.Valid {
+			override.MaxBytes = &maxBytes.Int64
+		}
+		if maxVideos.Valid {
+			v := int(maxVideos.Int32)
+			override.MaxVideos = &v
+		}
+	}
+
+	return override, rows.Err()
+}
+
+func (s *Store) SetQuotaOverride(ctx context.Context, override *types.QuotaOverride) error {
+	_, err := s.db.ExecContext(ctx, `
+INSERT INTO user_quotas (userId, maxBytes, maxVideos) VALUES (?, ?, ?)
+`+s.dialect.Upsert("userId", "maxBytes", "maxVideos"),
+		override.UserID, override.MaxBytes, override.MaxVideos)
+	return err
+}
@@ -0,0 +1,409 @@
+package room
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 没有人连接的房间保留多久后被清理，以及多久检查一次
+const (
+	emptyRoomTTL  = 10 * time.Minute
+	sweepInterval = time.Minute
+)
+
+// 客户端发送给服务器的消息类型（只有主持人可以发送控制命令）
+const (
+	MsgPlay     = "play"
+	MsgPause    = "pause"
+	MsgSeek     = "seek"
+	MsgSpeed    = "speed"
+	MsgLoop     = "loop"
+	MsgHandover = "handover"
+	MsgPing     = "ping"
+)
+
+// 服务器推送给客户端的消息类型
+const (
+	MsgState    = "state"
+	MsgPresence = "presence"
+	MsgPong     = "pong"
+	MsgError    = "error"
+)
+
+// Message 客户端发来的消息
+type Message struct {
+	Type       string   `json:"type"`
+	Position   *float64 `json:"position,omitempty"`   // 播放位置（秒）
+	Rate       *float64 `json:"rate,omitempty"`       // 播放速度
+	LoopStart  *float64 `json:"loopStart,omitempty"`  // AB 循环起点（秒），与 loopEnd 同时为空表示取消循环
+	LoopEnd    *float64 `json:"loopEnd,omitempty"`    // AB 循环终点（秒）
+	UserID     int      `json:"userId,omitempty"`     // handover 的目标用户
+	ClientTime int64    `json:"clientTime,omitempty"` // ping 时客户端的毫秒时间戳
+}
+
+// PlaybackState 播放状态。Position 是 ServerTime 时刻的位置，
+// 正在播放时客户端应按 position + (now - serverTime) * rate 推算当前位置，
+// now 需要用 ping/pong 估算出的时钟偏差换算成服务器时间。
+type PlaybackState struct {
+	Playing    bool     `json:"playing"`
+	Position   float64  `json:"position"`
+	Rate       float64  `json:"rate"`
+	LoopStart  *float64 `json:"loopStart,omitempty"`
+	LoopEnd    *float64 `json:"loopEnd,omitempty"`
+	ServerTime int64    `json:"serverTime"` // 毫秒时间戳
+}
+
+// Participant 在线成员
+type Participant struct {
+	UserID    int    `json:"userId"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+	IsHost    bool   `json:"isHost"`
+}
+
+// Outgoing 服务器推送的消息
+type Outgoing struct {
+	Type         string         `json:"type"`
+	State        *PlaybackState `json:"state,omitempty"`
+	HostID       int            `json:"hostId,omitempty"`
+	Participants []Participant  `json:"participants,omitempty"`
+	ClientTime   int64          `json:"clientTime,omitempty"`
+	ServerTime   int64          `json:"serverTime,omitempty"`
+	Message      string         `json:"message,omitempty"`
+}
+
+// Snapshot 房间信息（REST 接口返回）
+type Snapshot struct {
+	ID           string        `json:"id"`
+	VideoID      int           `json:"videoId"`
+	HostID       int           `json:"hostId"`
+	State        PlaybackState `json:"state"`
+	Participants []Participant `json:"participants"`
+	CreatedAt    time.Time     `json:"createdAt"`
+}
+
+// client 一个 WebSocket 连接，同一用户可以有多个连接
+type client struct {
+	participant Participant
+	joinedAt    time.Time
+	send        chan Outgoing
+}
+
+// Room 一个同步练习房间，只存在于内存中
+type Room struct {
+	ID        string
+	VideoID   int
+	CreatedAt time.Time
+
+	mu         sync.Mutex
+	hostID     int
+	state      PlaybackState
+	clients    map[*client]struct{}
+	emptySince time.Time
+}
+
+func newRoom(videoID, hostID int) (*Room, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	return &Room{
+		ID:         hex.EncodeToString(b),
+		VideoID:    videoID,
+		CreatedAt:  now,
+		hostID:     hostID,
+		state:      PlaybackState{Rate: 1, ServerTime: now.UnixMilli()},
+		clients:    make(map[*client]struct{}),
+		emptySince: now,
+	}, nil
+}
+
+// HostID 当前主持人
+func (r *Room) HostID() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.hostID
+}
+
+// Snapshot 获取房间当前信息
+func (r *Room) Snapshot() Snapshot {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return Snapshot{
+		ID:           r.ID,
+		VideoID:      r.VideoID,
+		HostID:       r.hostID,
+		State:        r.currentState(time.Now()),
+		Participants: r.participants(),
+		CreatedAt:    r.CreatedAt,
+	}
+}
+
+// join 加入房间：给新连接发送当前状态，并向所有人广播在线成员
+func (r *Room) join(c *client) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.clients[c] = struct{}{}
+	r.emptySince = time.Time{}
+
+	state := r.currentState(time.Now())
+	r.sendLocked(c, Outgoing{Type: MsgState, State: &state, HostID: r.hostID})
+	r.broadcastPresenceLocked()
+}
+
+// leave 离开房间：主持人的最后一个连接断开时，把主持人交给最早加入的在线成员
+func (r *Room) leave(c *client) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// 连接可能已经因为发送缓冲区满被移除
+	if _, ok := r.clients[c]; ok {
+		delete(r.clients, c)
+		close(c.send)
+	}
+
+	if len(r.clients) == 0 {
+		if r.emptySince.IsZero() {
+			r.emptySince = time.Now()
+		}
+		return
+	}
+
+	if !r.connectedLocked(r.hostID) {
+		var next *client
+		for other := range r.clients {
+			if next == nil || other.joinedAt.Before(next.joinedAt) {
+				next = other
+			}
+		}
+		r.hostID = next.participant.UserID
+	}
+	r.broadcastPresenceLocked()
+}
+
+// handle 处理客户端消息
+func (r *Room) handle(c *client, msg Message) {
+	now := time.Now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// 因为发送缓冲区满被移除的连接在断开前还可能读到消息，直接丢弃
+	if _, ok := r.clients[c]; !ok {
+		return
+	}
+
+	if msg.Type == MsgPing {
+		r.sendLocked(c, Outgoing{Type: MsgPong, ClientTime: msg.ClientTime, ServerTime: now.UnixMilli()})
+		return
+	}
+
+	if c.participant.UserID != r.hostID {
+		r.sendLocked(c, Outgoing{Type: MsgError, Message: "only the host can control playback"})
+		return
+	}
+
+	// 先把位置推进到当前时刻，再应用命令
+	state := r.currentState(now)
+
+	switch msg.Type {
+	case MsgPlay:
+		state.Playing = true
+		if msg.Position != nil {
+			state.Position = *msg.Position
+		}
+	case MsgPause:
+		state.Playing = false
+		if msg.Position != nil {
+			state.Position = *msg.Position
+		}
+	case MsgSeek:
+		if msg.Position == nil || *msg.Position < 0 {
+			r.sendLocked(c, Outgoing{Type: MsgError, Message: "seek requires a non-negative position"})
+			return
+		}
+		state.Position = *msg.Position
+	case MsgSpeed:
+		if msg.Rate == nil || *msg.Rate < 0.25 || *msg.Rate > 2 {
+			r.sendLocked(c, Outgoing{Type: MsgError, Message: "rate must be between 0.25 and 2"})
+			return
+		}
+		state.Rate = *msg.Rate
+	case MsgLoop:
+		if msg.LoopStart == nil && msg.LoopEnd == nil {
+			state.LoopStart, state.LoopEnd = nil, nil
+			break
+		}
+		if msg.LoopStart == nil || msg.LoopEnd == nil || *msg.LoopStart < 0 || *msg.LoopEnd <= *msg.LoopStart {
+			r.sendLocked(c, Outgoing{Type: MsgError, Message: "loop requires 0 <= loopStart < loopEnd"})
+			return
+		}
+		state.LoopStart, state.LoopEnd = msg.LoopStart, msg.LoopEnd
+		if state.Position < *state.LoopStart || state.Position > *state.LoopEnd {
+			state.Position = *state.LoopStart
+		}
+	case MsgHandover:
+		if !r.connectedLocked(msg.UserID) {
+			r.sendLocked(c, Outgoing{Type: MsgError, Message: "handover target is not connected"})
+			return
+		}
+		r.hostID = msg.UserID
+		r.broadcastPresenceLocked()
+		return
+	default:
+		r.sendLocked(c, Outgoing{Type: MsgError, Message: fmt.Sprintf("unknown message type: %s", msg.Type)})
+		return
+	}
+
+	r.state = state
+	r.broadcastLocked(Outgoing{Type: MsgState, State: &state, HostID: r.hostID})
+}
+
+// currentState 把播放位置推进到 now（考虑播放速度和 AB 循环）
+func (r *Room) currentState(now time.Time) PlaybackState {
+	state := r.state
+	if state.Playing {
+		elapsed := float64(now.UnixMilli()-state.ServerTime) / 1000
+		state.Position += elapsed * state.Rate
+		if state.LoopStart != nil && state.LoopEnd != nil && state.Position > *state.LoopEnd {
+			span := *state.LoopEnd - *state.LoopStart
+			state.Position = *state.LoopStart + math.Mod(state.Position-*state.LoopEnd, span)
+		}
+	}
+	state.ServerTime = now.UnixMilli()
+	return state
+}
+
+func (r *Room) connectedLocked(userID int) bool {
+	for c := range r.clients {
+		if c.participant.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// participants 在线成员列表（同一用户多个连接只算一次），按加入时间排序
+func (r *Room) participants() []Participant {
+	first := make(map[int]*client)
+	for c := range r.clients {
+		if prev, ok := first[c.participant.UserID]; !ok || c.joinedAt.Before(prev.joinedAt) {
+			first[c.participant.UserID] = c
+		}
+	}
+
+	ordered := make([]*client, 0, len(first))
+	for _, c := range first {
+		ordered = append(ordered, c)
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		return ordered[i].joinedAt.Before(ordered[j].joinedAt)
+	})
+
+	list := make([]Participant, 0, len(ordered))
+	for _, c := range ordered {
+		p := c.participant
+		p.IsHost = p.UserID == r.hostID
+		list = append(list, p)
+	}
+	return list
+}
+
+func (r *Room) broadcastPresenceLocked() {
+	r.broadcastLocked(Outgoing{Type: MsgPresence, HostID: r.hostID, Participants: r.participants()})
+}
+
+func (r *Room) broadcastLocked(msg Outgoing) {
+	msg.ServerTime = time.Now().UnixMilli()
+	for c := range r.clients {
+		r.sendLocked(c, msg)
+	}
+}
+
+// sendLocked 非阻塞发送，缓冲区满说明客户端卡住了，直接断开让它重连
+func (r *Room) sendLocked(c *client, msg Outgoing) {
+	if msg.ServerTime == 0 {
+		msg.ServerTime = time.Now().UnixMilli()
+	}
+	select {
+	case c.send <- msg:
+	default:
+		delete(r.clients, c)
+		close(c.send)
+		if len(r.clients) == 0 && r.emptySince.IsZero() {
+			r.emptySince = time.Now()
+		}
+	}
+}
+
+// Hub 管理所有房间
+type Hub struct {
+	mu    sync.Mutex
+	rooms map[string]*Room
+}
+
+func NewHub() *Hub {
+	return &Hub{rooms: make(map[string]*Room)}
+}
+
+// Create 创建房间
+func (h *Hub) Create(videoID, hostID int) (*Room, error) {
+	room, err := newRoom(videoID, hostID)
+	if err != nil {
+		return nil, err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.rooms[room.ID] = room
+	return room, nil
+}
+
+// Get 获取房间
+func (h *Hub) Get(id string) (*Room, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	room, ok := h.rooms[id]
+	return room, ok
+}
+
+// Run 定期清理长时间没人的房间，ctx 结束时退出
+func (h *Hub) Run(ctx context.Context) {
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			h.sweep(now)
+		}
+	}
+}
+
+func (h *Hub) sweep(now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for id, room := range h.rooms {
+		room.mu.Lock()
+		expired := len(room.clients) == 0 && now.Sub(room.emptySince) > emptyRoomTTL
+		room.mu.Unlock()
+		if expired {
+			delete(h.rooms, id)
+		}
+	}
+}
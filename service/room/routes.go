@@ -0,0 +1,221 @@
+package room
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Albert-tru/DanceMirror/service/auth"
+	"github.com/Albert-tru/DanceMirror/service/video"
+	"github.com/Albert-tru/DanceMirror/types"
+	"github.com/Albert-tru/DanceMirror/utils"
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	writeWait      = 10 * time.Second
+	pongWait       = 60 * time.Second
+	pingPeriod     = pongWait * 9 / 10
+	maxMessageSize = 4096
+	sendBuffer     = 64
+)
+
+// 与 corsMiddleware 一致，允许任意来源；身份由 token 校验
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin:     func(r *http.Request) bool { return true },
+}
+
+type Handler struct {
+	hub        *Hub
+	videoStore types.VideoStore
+	userStore  types.UserStore
+	classStore types.ClassStore
+}
+
+func NewHandler(hub *Hub, videoStore types.VideoStore, userStore types.UserStore, classStore types.ClassStore) *Handler {
+	return &Handler{
+		hub:        hub,
+		videoStore: videoStore,
+		userStore:  userStore,
+		classStore: classStore,
+	}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/rooms", auth.WithJWTAuth(h.handleCreateRoom, h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/rooms/{id}", auth.WithJWTAuth(h.handleGetRoom, h.userStore)).Methods(http.MethodGet)
+	// 浏览器的 WebSocket 不能设置请求头，允许通过 ?token= 认证
+	router.HandleFunc("/rooms/{id}/ws", auth.WithJWTAuthFromQuery(h.handleConnect, h.userStore)).Methods(http.MethodGet)
+}
+
+// handleCreateRoom 创建房间，创建者成为主持人
+func (h *Handler) handleCreateRoom(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == -1 {
+		utils.WriteError(w, http.StatusUnauthorized, utils.NewError(utils.CodeUnauthorized, "unauthorized"))
+		return
+	}
+
+	var payload types.CreateRoomPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, utils.InvalidPayload(err))
+		return
+	}
+
+	v, err := h.videoStore.GetVideoByID(r.Context(), payload.VideoID)
+	if err != nil {
+		utils.WriteStoreError(w, err)
+		return
+	}
+	if !h.canView(w, r, v, userID) {
+		return
+	}
+
+	room, err := h.hub.Create(v.ID, userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, room.Snapshot())
+}
+
+func (h *Handler) handleGetRoom(w http.ResponseWriter, r *http.Request) {
+	room, ok := h.loadRoom(w, r)
+	if !ok {
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, room.Snapshot())
+}
+
+// handleConnect 升级为 WebSocket 并加入房间
+func (h *Handler) handleConnect(w http.ResponseWriter, r *http.Request) {
+	room, ok := h.loadRoom(w, r)
+	if !ok {
+		return
+	}
+
+	u, err := h.userStore.GetUserByID(r.Context(), auth.GetUserIDFromContext(r.Context()))
+	if errors.Is(err, types.ErrNotFound) {
+		utils.WriteError(w, http.StatusUnauthorized, utils.NewError(utils.CodeUnauthorized, "unauthorized"))
+		return
+	}
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade 失败时已经写入了错误响应
+		log.Printf("room %s: websocket upgrade failed: %v", room.ID, err)
+		return
+	}
+
+	c := &client{
+		participant: Participant{UserID: u.ID, FirstName: u.FirstName, LastName: u.LastName},
+		joinedAt:    time.Now(),
+		send:        make(chan Outgoing, sendBuffer),
+	}
+
+	go writePump(conn, c)
+	room.join(c)
+	readPump(conn, room, c)
+}
+
+// readPump 读取客户端消息直到连接断开
+func readPump(conn *websocket.Conn, room *Room, c *client) {
+	defer func() {
+		room.leave(c)
+		conn.Close()
+	}()
+
+	conn.SetReadLimit(maxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		var msg Message
+		if err := conn.ReadJSON(&msg); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				log.Printf("room %s: read error: %v", room.ID, err)
+			}
+			return
+		}
+		room.handle(c, msg)
+	}
+}
+
+// writePump 把消息写到连接上，并定期发送 ping 保活
+func writePump(conn *websocket.Conn, c *client) {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		conn.Close()
+	}()
+
+	for {
+		select {
+		case msg, ok := <-c.send:
+			conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := conn.WriteJSON(msg); err != nil {
+				return
+			}
+		case <-ticker.C:
+			conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// loadRoom 读取路径中的房间并校验当前用户能否查看房间的视频，失败时已写入响应
+func (h *Handler) loadRoom(w http.ResponseWriter, r *http.Request) (*Room, bool) {
+	room, ok := h.hub.Get(mux.Vars(r)["id"])
+	if !ok {
+		utils.WriteError(w, http.StatusNotFound, utils.NewError(utils.CodeNotFound, "room not found"))
+		return nil, false
+	}
+
+	v, err := h.videoStore.GetVideoByID(r.Context(), room.VideoID)
+	if err != nil {
+		utils.WriteStoreError(w, err)
+		return nil, false
+	}
+
+	if !h.canView(w, r, v, auth.GetUserIDFromContext(r.Context())) {
+		return nil, false
+	}
+
+	return room, true
+}
+
+func (h *Handler) canView(w http.ResponseWriter, r *http.Request, v *types.Video, userID int) bool {
+	ok, err := video.CanView(r.Context(), h.classStore, v, userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return false
+	}
+	if !ok {
+		utils.WriteError(w, http.StatusForbidden, utils.NewError(utils.CodePermissionDenied, "permission denied"))
+		return false
+	}
+	return true
+}
@@ -0,0 +1,284 @@
+package room_test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Albert-tru/DanceMirror/cmd/api/apitest"
+	"github.com/Albert-tru/DanceMirror/service/room"
+	"github.com/Albert-tru/DanceMirror/types"
+	"github.com/gorilla/websocket"
+)
+
+// conn 测试用的房间连接
+type conn struct {
+	*websocket.Conn
+	name string
+}
+
+// dial 用 ?token= 连接房间的 WebSocket，握手失败时返回服务器的响应
+func dial(s *apitest.Server, roomID, token string) (*websocket.Conn, *http.Response, error) {
+	url := "ws" + strings.TrimPrefix(s.URL, "http") + "/api/v1/rooms/" + roomID + "/ws?token=" + token
+	return websocket.DefaultDialer.Dial(url, nil)
+}
+
+// connect 连接房间并读取加入时推送的播放状态，测试结束时断开
+func connect(t *testing.T, s *apitest.Server, roomID, token, name string) *conn {
+	t.Helper()
+
+	ws, resp, err := dial(s, roomID, token)
+	if err != nil {
+		status := 0
+		if resp != nil {
+			status = resp.StatusCode
+		}
+		t.Fatalf("%s: dial = %d %v", name, status, err)
+	}
+	c := &conn{Conn: ws, name: name}
+	t.Cleanup(func() { c.Close() })
+
+	c.until(t, func(msg room.Outgoing) bool { return msg.Type == room.MsgState })
+	return c
+}
+
+// until 读取消息直到 match 返回 true，返回匹配的消息
+func (c *conn) until(t *testing.T, match func(room.Outgoing) bool) room.Outgoing {
+	t.Helper()
+
+	c.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		var msg room.Outgoing
+		if err := c.ReadJSON(&msg); err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if match(msg) {
+			return msg
+		}
+	}
+}
+
+// host 等待主持人为 hostID、在线 n 人的成员列表
+func (c *conn) host(t *testing.T, hostID, n int) {
+	t.Helper()
+	c.until(t, func(msg room.Outgoing) bool {
+		return msg.Type == room.MsgPresence && msg.HostID == hostID && len(msg.Participants) == n
+	})
+}
+
+// reply 等待下一条 state 或 error 消息
+func (c *conn) reply(t *testing.T) room.Outgoing {
+	t.Helper()
+	return c.until(t, func(msg room.Outgoing) bool {
+		return msg.Type == room.MsgState || msg.Type == room.MsgError
+	})
+}
+
+func (c *conn) send(t *testing.T, msg room.Message) {
+	t.Helper()
+	if err := c.WriteJSON(msg); err != nil {
+		t.Fatalf("%s: %v", c.name, err)
+	}
+}
+
+func ptr(v float64) *float64 { return &v }
+
+// classroom 老师的班级再加一个学生，老师创建房间
+type classroom struct {
+	*apitest.Classroom
+	s           *apitest.Server
+	room        room.Snapshot
+	second      string // 第二个学生的令牌
+	secondID    int
+	teacherConn *conn
+	studentConn *conn
+	secondConn  *conn
+}
+
+func setup(t *testing.T) *classroom {
+	t.Helper()
+
+	s := apitest.Start(t)
+	c := &classroom{Classroom: s.NewClassroom(t), s: s}
+	u, token, err := s.CreateUser("13800000004", "password123", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.second, c.secondID = token, u.ID
+	s.Do(t, http.MethodPost, "/api/v1/classes/join", c.second, types.JoinClassPayload{JoinCode: c.Class.JoinCode}, http.StatusOK, nil)
+	s.Do(t, http.MethodPost, "/api/v1/rooms", c.Teacher, types.CreateRoomPayload{VideoID: c.Video.ID}, http.StatusCreated, &c.room)
+	return c
+}
+
+// join 老师、学生、第二个学生依次连接
+func (c *classroom) join(t *testing.T) {
+	t.Helper()
+
+	c.teacherConn = connect(t, c.s, c.room.ID, c.Teacher, "teacher")
+	c.teacherConn.host(t, c.TeacherID, 1)
+	c.studentConn = connect(t, c.s, c.room.ID, c.Student, "student")
+	c.teacherConn.host(t, c.TeacherID, 2)
+	c.secondConn = connect(t, c.s, c.room.ID, c.second, "second")
+	for _, conn := range []*conn{c.teacherConn, c.studentConn, c.secondConn} {
+		conn.host(t, c.TeacherID, 3)
+	}
+}
+
+func TestCreateRoom(t *testing.T) {
+	c := setup(t)
+	if c.room.HostID != c.TeacherID || c.room.VideoID != c.Video.ID || c.room.State.Rate != 1 {
+		t.Errorf("room = %+v, want hosted by the teacher at rate 1", c.room)
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		videoID int
+		status  int
+	}{
+		{"class member", c.Student, c.Video.ID, http.StatusCreated},
+		{"outsider", c.Outsider, c.Video.ID, http.StatusForbidden},
+		{"missing video", c.Teacher, 999, http.StatusNotFound},
+		{"invalid payload", c.Teacher, 0, http.StatusBadRequest},
+		{"no token", "", c.Video.ID, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c.s.Do(t, http.MethodPost, "/api/v1/rooms", tt.token, types.CreateRoomPayload{VideoID: tt.videoID}, tt.status, nil)
+		})
+	}
+
+	path := "/api/v1/rooms/" + c.room.ID
+	c.s.Do(t, http.MethodGet, path, c.Student, nil, http.StatusOK, nil)
+	c.s.Do(t, http.MethodGet, path, c.Outsider, nil, http.StatusForbidden, nil)
+	c.s.Do(t, http.MethodGet, "/api/v1/rooms/missing", c.Teacher, nil, http.StatusNotFound, nil)
+}
+
+// 不能查看房间视频的用户不能加入房间
+func TestJoinRequiresCanView(t *testing.T) {
+	c := setup(t)
+
+	tests := []struct {
+		name   string
+		roomID string
+		token  string
+		status int
+	}{
+		{"outsider", c.room.ID, c.Outsider, http.StatusForbidden},
+		{"no token", c.room.ID, "", http.StatusForbidden},
+		{"invalid token", c.room.ID, "abc", http.StatusForbidden},
+		{"missing room", "missing", c.Student, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ws, resp, err := dial(c.s, tt.roomID, tt.token)
+			if err == nil {
+				ws.Close()
+				t.Fatal("dial succeeded, want the handshake rejected")
+			}
+			if resp == nil || resp.StatusCode != tt.status {
+				t.Fatalf("dial = %v %v, want %d", resp, err, tt.status)
+			}
+		})
+	}
+
+	// 视频从班级移除后，之前能加入的学生也不能再加入
+	connect(t, c.s, c.room.ID, c.Student, "student")
+	c.s.Do(t, http.MethodDelete, fmt.Sprintf("/api/v1/classes/%d/assignments/%d", c.Class.ID, c.Video.ID), c.Teacher, nil, http.StatusOK, nil)
+	if ws, resp, err := dial(c.s, c.room.ID, c.second); err == nil {
+		ws.Close()
+		t.Error("dial after the video was unassigned succeeded")
+	} else if resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Errorf("dial after the video was unassigned = %v %v, want 403", resp, err)
+	}
+}
+
+// 只有主持人可以控制播放，其他人的控制命令只给自己返回错误
+func TestHostOnlyControls(t *testing.T) {
+	c := setup(t)
+	c.join(t)
+
+	controls := []room.Message{
+		{Type: room.MsgPlay},
+		{Type: room.MsgPause, Position: ptr(3)},
+		{Type: room.MsgSeek, Position: ptr(10)},
+		{Type: room.MsgSpeed, Rate: ptr(0.5)},
+		{Type: room.MsgLoop, LoopStart: ptr(1), LoopEnd: ptr(2)},
+		{Type: room.MsgHandover, UserID: c.StudentID},
+	}
+	for _, msg := range controls {
+		c.studentConn.send(t, msg)
+		if reply := c.studentConn.reply(t); reply.Type != room.MsgError {
+			t.Errorf("student %s = %+v, want an error", msg.Type, reply)
+		}
+	}
+
+	// 非主持人仍然可以 ping
+	c.studentConn.send(t, room.Message{Type: room.MsgPing, ClientTime: 42})
+	pong := c.studentConn.until(t, func(msg room.Outgoing) bool { return msg.Type == room.MsgPong })
+	if pong.ClientTime != 42 || pong.ServerTime == 0 {
+		t.Errorf("pong = %+v", pong)
+	}
+
+	// 主持人的命令广播给所有人，之前学生的命令没有生效
+	c.teacherConn.send(t, room.Message{Type: room.MsgSeek, Position: ptr(20)})
+	for _, conn := range []*conn{c.teacherConn, c.studentConn, c.secondConn} {
+		msg := conn.reply(t)
+		if msg.Type != room.MsgState || msg.State.Playing || msg.State.Position != 20 || msg.State.Rate != 1 || msg.State.LoopStart != nil {
+			t.Errorf("%s: %+v, want paused at 20s at rate 1 without a loop", conn.name, msg)
+		}
+	}
+
+	// 主持人的无效命令
+	invalid := []room.Message{
+		{Type: room.MsgSeek},
+		{Type: room.MsgSeek, Position: ptr(-1)},
+		{Type: room.MsgSpeed, Rate: ptr(3)},
+		{Type: room.MsgLoop, LoopStart: ptr(2), LoopEnd: ptr(1)},
+		{Type: room.MsgLoop, LoopStart: ptr(1)},
+		{Type: room.MsgHandover, UserID: 999},
+		{Type: "rewind"},
+	}
+	for _, msg := range invalid {
+		c.teacherConn.send(t, msg)
+		if reply := c.teacherConn.reply(t); reply.Type != room.MsgError {
+			t.Errorf("host %+v = %+v, want an error", msg, reply)
+		}
+	}
+}
+
+// 主持人离开后由最早加入的在线成员接任；主持人也可以主动移交
+func TestHostHandover(t *testing.T) {
+	c := setup(t)
+	c.join(t)
+
+	c.teacherConn.Close()
+	c.studentConn.host(t, c.StudentID, 2)
+	c.secondConn.host(t, c.StudentID, 2)
+
+	c.studentConn.send(t, room.Message{Type: room.MsgPlay, Position: ptr(5)})
+	if msg := c.secondConn.reply(t); msg.Type != room.MsgState || !msg.State.Playing {
+		t.Errorf("second = %+v, want playing", msg)
+	}
+
+	c.studentConn.send(t, room.Message{Type: room.MsgHandover, UserID: c.secondID})
+	c.studentConn.host(t, c.secondID, 2)
+	c.secondConn.host(t, c.secondID, 2)
+
+	c.studentConn.send(t, room.Message{Type: room.MsgPause})
+	if msg := c.studentConn.reply(t); msg.Type != room.MsgError {
+		t.Errorf("former host pause = %+v, want an error", msg)
+	}
+
+	// 原主持人重新加入后不会收回主持人
+	c.teacherConn = connect(t, c.s, c.room.ID, c.Teacher, "teacher")
+	c.secondConn.host(t, c.secondID, 3)
+
+	var snapshot room.Snapshot
+	c.s.Do(t, http.MethodGet, "/api/v1/rooms/"+c.room.ID, c.Teacher, nil, http.StatusOK, &snapshot)
+	if snapshot.HostID != c.secondID || !snapshot.State.Playing {
+		t.Errorf("room = %+v, want hosted by the second student and playing", snapshot)
+	}
+}
@@ -0,0 +1,250 @@
+package segment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/Albert-tru/DanceMirror/service/auth"
+	"github.com/Albert-tru/DanceMirror/service/beat"
+	"github.com/Albert-tru/DanceMirror/service/video"
+	"github.com/Albert-tru/DanceMirror/types"
+	"github.com/Albert-tru/DanceMirror/utils"
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	store      types.SegmentStore
+	tempoStore types.TempoMapStore
+	videoStore types.VideoStore
+	userStore  types.UserStore
+	classStore types.ClassStore
+}
+
+func NewHandler(store types.SegmentStore, tempoStore types.TempoMapStore, videoStore types.VideoStore, userStore types.UserStore, classStore types.ClassStore) *Handler {
+	return &Handler{
+		store:      store,
+		tempoStore: tempoStore,
+		videoStore: videoStore,
+		userStore:  userStore,
+		classStore: classStore,
+	}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/videos/{id}/segments", auth.WithJWTAuth(h.handleGetSegments, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/videos/{id}/segments", auth.WithJWTAuth(h.handleCreateSegment, h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/videos/{id}/segments/{segmentId}", auth.WithJWTAuth(h.handleUpdateSegment, h.userStore)).Methods(http.MethodPut)
+	router.HandleFunc("/videos/{id}/segments/{segmentId}", auth.WithJWTAuth(h.handleDeleteSegment, h.userStore)).Methods(http.MethodDelete)
+}
+
+func (h *Handler) handleGetSegments(w http.ResponseWriter, r *http.Request) {
+	v, ok := h.loadVideo(w, r)
+	if !ok {
+		return
+	}
+
+	segments, err := h.store.GetSegments(r.Context(), auth.GetUserIDFromContext(r.Context()), v.ID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	tempo, err := h.tempoMap(r.Context(), v.ID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	for _, segment := range segments {
+		segment.Counts = beat.Counts(tempo, segment.Start, segment.End)
+	}
+
+	utils.WriteJSON(w, http.StatusOK, segments)
+}
+
+func (h *Handler) handleCreateSegment(w http.ResponseWriter, r *http.Request) {
+	v, ok := h.loadVideo(w, r)
+	if !ok {
+		return
+	}
+
+	var payload types.CreateSegmentPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, utils.InvalidPayload(err))
+		return
+	}
+
+	segment := &types.Segment{
+		VideoID: v.ID,
+		UserID:  auth.GetUserIDFromContext(r.Context()),
+		Name:    payload.Name,
+	}
+	tempo, ok := h.applyRange(w, r, v, segment, payload.Start, payload.End, payload.Snap)
+	if !ok {
+		return
+	}
+
+	if err := h.store.CreateSegment(r.Context(), segment); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	segment.Counts = beat.Counts(tempo, segment.Start, segment.End)
+
+	utils.WriteJSON(w, http.StatusCreated, segment)
+}
+
+func (h *Handler) handleUpdateSegment(w http.ResponseWriter, r *http.Request) {
+	v, segment, ok := h.loadOwnSegment(w, r)
+	if !ok {
+		return
+	}
+
+	var payload types.UpdateSegmentPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, utils.InvalidPayload(err))
+		return
+	}
+
+	segment.Name = payload.Name
+	tempo, ok := h.applyRange(w, r, v, segment, payload.Start, payload.End, payload.Snap)
+	if !ok {
+		return
+	}
+
+	if err := h.store.UpdateSegment(r.Context(), segment); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	updated, err := h.store.GetSegmentByID(r.Context(), segment.ID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	updated.Counts = beat.Counts(tempo, updated.Start, updated.End)
+
+	utils.WriteJSON(w, http.StatusOK, updated)
+}
+
+func (h *Handler) handleDeleteSegment(w http.ResponseWriter, r *http.Request) {
+	_, segment, ok := h.loadOwnSegment(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.store.DeleteSegment(r.Context(), segment.ID); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"message": "segment deleted successfully"})
+}
+
+// applyRange 设置片段的起止时间，需要时对齐到节拍；返回节拍信息（没有时为 nil），
+// 失败时已写入响应
+func (h *Handler) applyRange(w http.ResponseWriter, r *http.Request, v *types.Video, segment *types.Segment, start, end float64, snap string) (*types.TempoMap, bool) {
+	tempo, err := h.tempoMap(r.Context(), v.ID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return nil, false
+	}
+	if snap != types.SnapNone {
+		if !beat.HasGrid(tempo) {
+			utils.WriteError(w, http.StatusConflict, utils.NewError(utils.CodeConflict, "video has no beat grid"))
+			return nil, false
+		}
+		start, end = beat.Snap(tempo, start, end, snap)
+		if end <= start {
+			utils.WriteError(w, http.StatusBadRequest, utils.NewError(utils.CodeBadRequest, "segment is empty after snapping"))
+			return nil, false
+		}
+	}
+
+	// 视频时长已知时，终点不能超过时长
+	if v.Duration > 0 && end > v.Duration {
+		utils.WriteError(w, http.StatusBadRequest, utils.NewError(utils.CodeBadRequest, "end exceeds video duration (%.3f)", v.Duration))
+		return nil, false
+	}
+
+	segment.Start, segment.End = start, end
+	return tempo, true
+}
+
+// tempoMap 读取视频的节拍信息，尚未分析时返回 nil
+func (h *Handler) tempoMap(ctx context.Context, videoID int) (*types.TempoMap, error) {
+	tempo, err := h.tempoStore.GetTempoMap(ctx, videoID)
+	if errors.Is(err, types.ErrNotFound) {
+		return nil, nil
+	}
+	return tempo, err
+}
+
+// loadVideo 读取路径中的视频并校验查看权限，失败时已写入响应
+func (h *Handler) loadVideo(w http.ResponseWriter, r *http.Request) (*types.Video, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, utils.NewError(utils.CodeInvalidID, "invalid video id"))
+		return nil, false
+	}
+
+	v, err := h.videoStore.GetVideoByID(r.Context(), id)
+	if err != nil {
+		utils.WriteStoreError(w, err)
+		return nil, false
+	}
+
+	userID := auth.GetUserIDFromContext(r.Context())
+	allowed, err := video.CanView(r.Context(), h.classStore, v, userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return nil, false
+	}
+	if !allowed {
+		utils.WriteError(w, http.StatusForbidden, utils.NewError(utils.CodePermissionDenied, "permission denied"))
+		return nil, false
+	}
+
+	return v, true
+}
+
+// loadOwnSegment 读取路径中的片段，只有创建者本人可以修改或删除
+func (h *Handler) loadOwnSegment(w http.ResponseWriter, r *http.Request) (*types.Video, *types.Segment, bool) {
+	v, ok := h.loadVideo(w, r)
+	if !ok {
+		return nil, nil, false
+	}
+
+	segmentID, err := strconv.Atoi(mux.Vars(r)["segmentId"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, utils.NewError(utils.CodeInvalidID, "invalid segment id"))
+		return nil, nil, false
+	}
+
+	segment, err := h.store.GetSegmentByID(r.Context(), segmentID)
+	if err == nil && segment.VideoID != v.ID {
+		err = fmt.Errorf("segment %w", types.ErrNotFound)
+	}
+	if err != nil {
+		utils.WriteStoreError(w, err)
+		return nil, nil, false
+	}
+
+	if segment.UserID != auth.GetUserIDFromContext(r.Context()) {
+		utils.WriteError(w, http.StatusForbidden, utils.NewError(utils.CodePermissionDenied, "permission denied"))
+		return nil, nil, false
+	}
+
+	return v, segment, true
+}
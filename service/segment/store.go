@@ -0,0 +1,109 @@
+package segment
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/Albert-tru/DanceMirror/types"
+)
+
+// segmentColumns 和 scanRowIntoSegment 的顺序一致
+const segmentColumns = "id, videoId, userId, name, startTime, endTime, createdAt, updatedAt"
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// GetSegments 获取用户在某个视频上保存的片段，按起点排序
+func (s *Store) GetSegments(ctx context.Context, userID, videoID int) ([]*types.Segment, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+segmentColumns+" FROM video_segments WHERE userId = ? AND videoId = ? ORDER BY startTime, id", userID, videoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	segments := []*types.Segment{}
+	for rows.Next() {
+		segment, err := scanRowIntoSegment(rows)
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, segment)
+	}
+
+	return segments, nil
+}
+
+func (s *Store) GetSegmentByID(ctx context.Context, id int) (*types.Segment, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+segmentColumns+" FROM video_segments WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var segment *types.Segment
+	for rows.Next() {
+		segment, err = scanRowIntoSegment(rows)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if segment == nil {
+		return nil, fmt.Errorf("segment %w", types.ErrNotFound)
+	}
+
+	return segment, nil
+}
+
+func (s *Store) CreateSegment(ctx context.Context, segment *types.Segment) error {
+	result, err := s.db.ExecContext(ctx, "INSERT INTO video_segments (videoId, userId, name, startTime, endTime) VALUES (?, ?, ?, ?, ?)",
+		segment.VideoID, segment.UserID, segment.Name, segment.Start, segment.End)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	segment.ID = int(id)
+	return nil
+}
+
+func (s *Store) UpdateSegment(ctx context.Context, segment *types.Segment) error {
+	_, err := s.db.ExecContext(ctx, "UPDATE video_segments SET name = ?, startTime = ?, endTime = ?, updatedAt = CURRENT_TIMESTAMP WHERE id = ?",
+		segment.Name, segment.Start, segment.End, segment.ID)
+	return err
+}
+
+func (s *Store) DeleteSegment(ctx context.Context, id int) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM video_segments WHERE id = ?", id)
+	return err
+}
+
+func scanRowIntoSegment(rows *sql.Rows) (*types.Segment, error) {
+	segment := new(types.Segment)
+
+	err := rows.Scan(
+		&segment.ID,
+		&segment.VideoID,
+		&segment.UserID,
+		&segment.Name,
+		&segment.Start,
+		&segment.End,
+		&segment.CreatedAt,
+		&segment.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return segment, nil
+}
@@ -0,0 +1,26 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Albert-tru/DanceMirror/service/job"
+	"github.com/Albert-tru/DanceMirror/types"
+)
+
+// DeletePayload types.JobDeleteStorage 任务的参数
+type DeletePayload struct {
+	Prefix string `json:"prefix"`
+}
+
+// DeleteHandler 执行 types.JobDeleteStorage 任务：删除前缀下的所有文件，失败后由任务队列重试
+func DeleteHandler(st types.Storage) job.HandlerFunc {
+	return func(ctx context.Context, j *types.Job) error {
+		var payload DeletePayload
+		if err := json.Unmarshal(j.Payload, &payload); err != nil || payload.Prefix == "" {
+			return job.Permanent(fmt.Errorf("invalid storage.delete payload: %s", j.Payload))
+		}
+		return st.Delete(ctx, payload.Prefix)
+	}
+}
@@ -0,0 +1,157 @@
+package transcode
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/Albert-tru/DanceMirror/types"
+)
+
+// Fake 不依赖 ffmpeg 的 Transcoder，用于测试：
+// 转码时直接复制源文件，Probe 返回固定的 Info，并记录每次调用
+type Fake struct {
+	Info *types.MediaInfo
+	BPM  float64 // DecodeAudio 生成的节拍音的速度
+	Err  error   // 不为空时所有操作都返回该错误
+
+	mu    sync.Mutex
+	calls []string
+}
+
+func NewFake() *Fake {
+	return &Fake{
+		Info: &types.MediaInfo{Duration: 10, Width: 1280, Height: 720, VideoCodec: "h264", AudioCodec: "aac"},
+		BPM:  120,
+	}
+}
+
+// Calls 已执行的操作，例如 "probe <path>"、"mp4 <src> <dst>"
+func (f *Fake) Calls() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.calls...)
+}
+
+func (f *Fake) record(call string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, call)
+}
+
+func (f *Fake) Probe(ctx context.Context, path string) (*types.MediaInfo, error) {
+	f.record("probe " + path)
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	info := *f.Info
+	return &info, nil
+}
+
+func (f *Fake) TranscodeMP4(ctx context.Context, src, dst string) error {
+	f.record("mp4 " + src + " " + dst)
+	if f.Err != nil {
+		return f.Err
+	}
+	return copyFile(src, dst)
+}
+
+// TranscodeHLS 生成两个分片，每个分片都是源文件的副本
+func (f *Fake) TranscodeHLS(ctx context.Context, src, dir string, variant types.HLSVariant) error {
+	f.record("hls " + variant.Name + " " + src + " " + dir)
+	if f.Err != nil {
+		return f.Err
+	}
+
+	playlist := "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:4\n#EXT-X-PLAYLIST-TYPE:VOD\n"
+	for i := 0; i < 2; i++ {
+		name := fmt.Sprintf("seg_%05d.ts", i)
+		if err := copyFile(src, filepath.Join(dir, name)); err != nil {
+			return err
+		}
+		playlist += "#EXTINF:4.000000,\n" + name + "\n"
+	}
+	playlist += "#EXT-X-ENDLIST\n"
+	return os.WriteFile(filepath.Join(dir, "index.m3u8"), []byte(playlist), 0644)
+}
+
+// Poster 和 Sprite 输出源文件的副本
+func (f *Fake) Poster(ctx context.Context, src, dst string, at float64, width int) error {
+	f.record(fmt.Sprintf("poster %s %s %.3f", src, dst, at))
+	if f.Err != nil {
+		return f.Err
+	}
+	return copyFile(src, dst)
+}
+
+func (f *Fake) Sprite(ctx context.Context, src, dst string, sheet types.SpriteSheet) error {
+	f.record(fmt.Sprintf("sprite %s %s %dx%d", src, dst, sheet.Columns, sheet.Rows))
+	if f.Err != nil {
+		return f.Err
+	}
+	return copyFile(src, dst)
+}
+
+// DecodeAudio 生成 Info.Duration 长的节拍音：每拍一个衰减的短音，每小节第一拍更响
+func (f *Fake) DecodeAudio(ctx context.Context, src string, sampleRate int) ([]float32, error) {
+	f.record("audio " + src)
+	if f.Err != nil {
+		return nil, f.Err
+	}
+
+	samples := make([]float32, int(f.Info.Duration*float64(sampleRate)))
+	if f.BPM <= 0 {
+		return samples, nil
+	}
+	period := 60 / f.BPM
+	for beat := 0; float64(beat)*period < f.Info.Duration; beat++ {
+		gain := 0.5
+		if beat%4 == 0 {
+			gain = 1
+		}
+		start := int(float64(beat) * period * float64(sampleRate))
+		for i := 0; i < sampleRate/20 && start+i < len(samples); i++ {
+			t := float64(i) / float64(sampleRate)
+			samples[start+i] = float32(gain * math.Exp(-t*60) * math.Sin(2*math.Pi*1000*t))
+		}
+	}
+	return samples, nil
+}
+
+func (f *Fake) Clip(ctx context.Context, src, dst string, opts types.ClipOptions) error {
+	f.record(fmt.Sprintf("clip %s %s %.3f-%.3f", src, dst, opts.Start, opts.End))
+	if f.Err != nil {
+		return f.Err
+	}
+	return copyFile(src, dst)
+}
+
+func (f *Fake) Derive(ctx context.Context, src, dst string, opts types.DerivedOptions) error {
+	f.record(fmt.Sprintf("derive %s %s mirror=%t speed=%g", src, dst, opts.Mirror, opts.Speed))
+	if f.Err != nil {
+		return f.Err
+	}
+	return copyFile(src, dst)
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,268 @@
+package transcode
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Albert-tru/DanceMirror/types"
+)
+
+// FFmpeg 调用本地 ffmpeg/ffprobe 可执行文件
+type FFmpeg struct {
+	ffmpegPath  string
+	ffprobePath string
+}
+
+// NewFFmpeg 在 PATH 中查找 ffmpeg 和 ffprobe，找不到时返回错误
+func NewFFmpeg(ffmpeg, ffprobe string) (*FFmpeg, error) {
+	ffmpegPath, err := exec.LookPath(ffmpeg)
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg not found: %v", err)
+	}
+	ffprobePath, err := exec.LookPath(ffprobe)
+	if err != nil {
+		return nil, fmt.Errorf("ffprobe not found: %v", err)
+	}
+
+	return &FFmpeg{
+		ffmpegPath:  ffmpegPath,
+		ffprobePath: ffprobePath,
+	}, nil
+}
+
+// ffprobe -print_format json 的输出（只取用到的字段）
+type probeOutput struct {
+	Streams []struct {
+		CodecType string `json:"codec_type"`
+		CodecName string `json:"codec_name"`
+		Width     int    `json:"width"`
+		Height    int    `json:"height"`
+	} `json:"streams"`
+	Format struct {
+		Duration string `json:"duration"`
+	} `json:"format"`
+}
+
+func (f *FFmpeg) Probe(ctx context.Context, path string) (*types.MediaInfo, error) {
+	out, err := f.run(ctx, f.ffprobePath,
+		"-v", "error",
+		"-print_format", "json",
+		"-show_format",
+		"-show_streams",
+		path,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var probe probeOutput
+	if err := json.Unmarshal(out, &probe); err != nil {
+		return nil, fmt.Errorf("invalid ffprobe output: %v", err)
+	}
+
+	info := &types.MediaInfo{}
+	info.Duration, _ = strconv.ParseFloat(probe.Format.Duration, 64)
+	for _, s := range probe.Streams {
+		switch s.CodecType {
+		case "video":
+			if info.VideoCodec == "" {
+				info.VideoCodec = s.CodecName
+				info.Width = s.Width
+				info.Height = s.Height
+			}
+		case "audio":
+			if info.AudioCodec == "" {
+				info.AudioCodec = s.CodecName
+			}
+		}
+	}
+	if info.VideoCodec == "" {
+		return nil, fmt.Errorf("no video stream in %s", path)
+	}
+
+	return info, nil
+}
+
+func (f *FFmpeg) TranscodeMP4(ctx context.Context, src, dst string) error {
+	_, err := f.run(ctx, f.ffmpegPath,
+		"-y", "-v", "error",
+		"-i", src,
+		"-map", "0:v:0", "-map", "0:a:0?",
+		// 最高 1080p，宽高取偶数（yuv420p 要求）
+		"-vf", "scale=-2:'min(1080,trunc(ih/2)*2)'",
+		"-c:v", "libx264", "-preset", "veryfast", "-crf", "23",
+		"-profile:v", "high", "-pix_fmt", "yuv420p",
+		"-c:a", "aac", "-b:a", "128k", "-ac", "2",
+		// moov 放在文件开头，边下边播
+		"-movflags", "+faststart",
+		"-f", "mp4",
+		dst,
+	)
+	return err
+}
+
+func (f *FFmpeg) TranscodeHLS(ctx context.Context, src, dir string, variant types.HLSVariant) error {
+	_, err := f.run(ctx, f.ffmpegPath,
+		"-y", "-v", "error",
+		"-i", src,
+		"-map", "0:v:0", "-map", "0:a:0?",
+		"-vf", fmt.Sprintf("scale=-2:%d", variant.Height),
+		"-c:v", "libx264", "-preset", "veryfast", "-profile:v", "main", "-pix_fmt", "yuv420p",
+		"-b:v", fmt.Sprintf("%dk", variant.VideoBitrate),
+		"-maxrate", fmt.Sprintf("%dk", variant.VideoBitrate*107/100),
+		"-bufsize", fmt.Sprintf("%dk", variant.VideoBitrate*3/2),
+		// 每个分片以关键帧开头，各码率的分片边界对齐，切换码率时不会卡顿
+		"-force_key_frames", fmt.Sprintf("expr:gte(t,n_forced*%d)", hlsSegmentSeconds),
+		"-sc_threshold", "0",
+		"-c:a", "aac", "-b:a", fmt.Sprintf("%dk", variant.AudioBitrate), "-ac", "2",
+		"-f", "hls",
+		"-hls_time", strconv.Itoa(hlsSegmentSeconds),
+		"-hls_playlist_type", "vod",
+		"-hls_segment_filename", filepath.Join(dir, "seg_%05d.ts"),
+		filepath.Join(dir, "index.m3u8"),
+	)
+	return err
+}
+
+func (f *FFmpeg) Poster(ctx context.Context, src, dst string, at float64, width int) error {
+	_, err := f.run(ctx, f.ffmpegPath,
+		"-y", "-v", "error",
+		"-ss", strconv.FormatFloat(at, 'f', 3, 64),
+		"-i", src,
+		"-frames:v", "1",
+		// 不放大小视频
+		"-vf", fmt.Sprintf("scale='min(%d,iw)':-2", width),
+		"-q:v", "3",
+		"-f", "image2", "-update", "1",
+		dst,
+	)
+	return err
+}
+
+func (f *FFmpeg) Sprite(ctx context.Context, src, dst string, sheet types.SpriteSheet) error {
+	_, err := f.run(ctx, f.ffmpegPath,
+		"-y", "-v", "error",
+		"-i", src,
+		"-vf", fmt.Sprintf("fps=1/%s,scale=%d:%d,tile=%dx%d",
+			strconv.FormatFloat(sheet.Interval, 'f', -1, 64), sheet.TileWidth, sheet.TileHeight, sheet.Columns, sheet.Rows),
+		"-frames:v", "1",
+		"-q:v", "5",
+		"-f", "image2", "-update", "1",
+		dst,
+	)
+	return err
+}
+
+// 节拍分析最多解码的时长（秒），避免超长视频占用过多内存
+const maxDecodeSeconds = 20 * 60
+
+func (f *FFmpeg) DecodeAudio(ctx context.Context, src string, sampleRate int) ([]float32, error) {
+	out, err := f.run(ctx, f.ffmpegPath,
+		"-v", "error",
+		"-i", src,
+		"-t", strconv.Itoa(maxDecodeSeconds),
+		"-vn", "-ac", "1", "-ar", strconv.Itoa(sampleRate),
+		"-f", "f32le", "-acodec", "pcm_f32le",
+		"pipe:1",
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	samples := make([]float32, len(out)/4)
+	for i := range samples {
+		samples[i] = math.Float32frombits(binary.LittleEndian.Uint32(out[i*4:]))
+	}
+	return samples, nil
+}
+
+func (f *FFmpeg) Clip(ctx context.Context, src, dst string, opts types.ClipOptions) error {
+	args := []string{
+		"-y", "-v", "error",
+		// -ss 放在 -i 前面快速定位，重新编码时仍是精确到帧的
+		"-ss", strconv.FormatFloat(opts.Start, 'f', 3, 64),
+		"-i", src,
+		"-t", strconv.FormatFloat(opts.End-opts.Start, 'f', 3, 64),
+		"-map", "0:v:0", "-map", "0:a:0?",
+	}
+	if c := opts.Crop; c != nil {
+		// yuv420p 要求宽高为偶数
+		args = append(args, "-vf", fmt.Sprintf("crop=%d:%d:%d:%d", c.Width-c.Width%2, c.Height-c.Height%2, c.X, c.Y))
+	}
+	args = append(args,
+		"-c:v", "libx264", "-preset", "veryfast", "-crf", "20", "-pix_fmt", "yuv420p",
+		"-c:a", "aac", "-b:a", "128k", "-ac", "2",
+		"-movflags", "+faststart",
+		"-f", "mp4",
+		dst,
+	)
+
+	_, err := f.run(ctx, f.ffmpegPath, args...)
+	return err
+}
+
+func (f *FFmpeg) Derive(ctx context.Context, src, dst string, opts types.DerivedOptions) error {
+	filters := []string{"scale=-2:'min(1080,trunc(ih/2)*2)'"}
+	if opts.Mirror {
+		filters = append(filters, "hflip")
+	}
+	args := []string{
+		"-y", "-v", "error",
+		"-i", src,
+		"-map", "0:v:0", "-map", "0:a:0?",
+	}
+	if speed := opts.Speed; speed > 0 && speed != 1 {
+		filters = append(filters, "setpts=PTS/"+strconv.FormatFloat(speed, 'f', -1, 64))
+		args = append(args, "-af", atempo(speed))
+	}
+	args = append(args,
+		"-vf", strings.Join(filters, ","),
+		"-c:v", "libx264", "-preset", "veryfast", "-crf", "23", "-pix_fmt", "yuv420p",
+		"-c:a", "aac", "-b:a", "128k", "-ac", "2",
+		"-movflags", "+faststart",
+		"-f", "mp4",
+		dst,
+	)
+
+	_, err := f.run(ctx, f.ffmpegPath, args...)
+	return err
+}
+
+// atempo 变速但保持音调；单个 atempo 滤镜只支持 0.5~2 倍，超出时串联多个
+func atempo(speed float64) string {
+	var filters []string
+	for speed < 0.5 {
+		filters = append(filters, "atempo=0.5")
+		speed /= 0.5
+	}
+	for speed > 2 {
+		filters = append(filters, "atempo=2")
+		speed /= 2
+	}
+	return strings.Join(append(filters, "atempo="+strconv.FormatFloat(speed, 'f', -1, 64)), ",")
+}
+
+// run 执行命令，失败时把 stderr 放进错误信息
+func (f *FFmpeg) run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if len(msg) > 500 {
+			msg = msg[len(msg)-500:]
+		}
+		return nil, fmt.Errorf("%s failed: %v: %s", name, err, msg)
+	}
+	return stdout.Bytes(), nil
+}
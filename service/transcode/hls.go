@@ -0,0 +1,54 @@
+package transcode
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Albert-tru/DanceMirror/types"
+)
+
+// 每个分片的时长（秒）
+const hlsSegmentSeconds = 4
+
+// hlsLadder 从低到高的码率阶梯
+var hlsLadder = []types.HLSVariant{
+	{Name: "360p", Height: 360, VideoBitrate: 800, AudioBitrate: 96},
+	{Name: "540p", Height: 540, VideoBitrate: 1400, AudioBitrate: 128},
+	{Name: "720p", Height: 720, VideoBitrate: 2800, AudioBitrate: 128},
+	{Name: "1080p", Height: 1080, VideoBitrate: 5000, AudioBitrate: 128},
+}
+
+// hlsVariants 不超过源视频高度的码率（至少保留最低一档，不放大）
+func hlsVariants(sourceHeight int) []types.HLSVariant {
+	variants := []types.HLSVariant{}
+	for _, v := range hlsLadder {
+		if v.Height <= sourceHeight {
+			variants = append(variants, v)
+		}
+	}
+	if len(variants) == 0 {
+		lowest := hlsLadder[0]
+		lowest.Height = sourceHeight - sourceHeight%2
+		variants = append(variants, lowest)
+	}
+	return variants
+}
+
+// masterPlaylist 生成 master 播放列表，各码率的播放列表位于 <name>/index.m3u8
+func masterPlaylist(variants []types.HLSVariant, sourceWidth, sourceHeight int) string {
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n#EXT-X-VERSION:3\n")
+	for _, v := range variants {
+		// 宽度按源视频比例取偶数，与 ffmpeg 的 scale=-2:h 一致
+		width := v.Height
+		if sourceHeight > 0 {
+			width = (sourceWidth*v.Height/sourceHeight + 1) / 2 * 2
+		}
+		// BANDWIDTH 是峰值码率，按平均码率加 10% 估算
+		bandwidth := (v.VideoBitrate + v.AudioBitrate) * 1100
+		fmt.Fprintf(&b, "#EXT-X-STREAM-INF:BANDWIDTH=%d,AVERAGE-BANDWIDTH=%d,RESOLUTION=%dx%d\n",
+			bandwidth, (v.VideoBitrate+v.AudioBitrate)*1000, width, v.Height)
+		fmt.Fprintf(&b, "%s/index.m3u8\n", v.Name)
+	}
+	return b.String()
+}
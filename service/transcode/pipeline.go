@@ -0,0 +1,431 @@
+package transcode
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Albert-tru/DanceMirror/service/job"
+	"github.com/Albert-tru/DanceMirror/service/storage"
+	"github.com/Albert-tru/DanceMirror/types"
+)
+
+// Pipeline 处理上传后的转码任务：先生成 MP4，再生成多码率 HLS，
+// 输出写入存储层并登记到 video_renditions
+type Pipeline struct {
+	videos     types.VideoStore
+	renditions types.RenditionStore
+	transcoder types.Transcoder // 为 nil 时（没有 ffmpeg）跳过转码
+	storage    types.Storage
+	jobs       types.JobQueue
+	events     types.EventPublisher
+}
+
+func NewPipeline(videos types.VideoStore, renditions types.RenditionStore, transcoder types.Transcoder, storage types.Storage, jobs types.JobQueue, events types.EventPublisher) *Pipeline {
+	return &Pipeline{
+		videos:     videos,
+		renditions: renditions,
+		transcoder: transcoder,
+		storage:    storage,
+		jobs:       jobs,
+		events:     events,
+	}
+}
+
+// RenditionKey 转码输出在存储层中的 key：renditions/<videoId>/<name>
+func RenditionKey(videoID int, name string) string {
+	return storage.RenditionPrefix(videoID) + "/" + name
+}
+
+// DerivedKind 派生输出的规格名，例如 "mirror"、"speed-0.75"、"mirror-speed-0.75"；
+// 相同参数得到相同的规格名，生成过的输出可以直接复用
+func DerivedKind(opts types.DerivedOptions) string {
+	var parts []string
+	if opts.Mirror {
+		parts = append(parts, "mirror")
+	}
+	if opts.Speed > 0 && opts.Speed != 1 {
+		parts = append(parts, "speed-"+strconv.FormatFloat(opts.Speed, 'f', -1, 64))
+	}
+	return strings.Join(parts, "-")
+}
+
+// IsDerived 是否为 DerivedKind 生成的派生输出（而不是上传后自动生成的 MP4、HLS 和预览图）
+func IsDerived(kind string) bool {
+	switch kind {
+	case types.RenditionMP4, types.RenditionHLS, types.RenditionThumbnails:
+		return false
+	}
+	return true
+}
+
+// HandleTranscode 执行 types.JobTranscode 任务，成功后提交 HLS 任务
+func (p *Pipeline) HandleTranscode(ctx context.Context, j *types.Job) error {
+	video, err := p.videos.GetVideoByID(ctx, j.VideoID)
+	if errors.Is(err, types.ErrNotFound) {
+		// 视频已被删除
+		return job.Permanent(err)
+	}
+	if err != nil {
+		return err
+	}
+
+	if p.transcoder == nil {
+		p.events.Publish(video.UserID, types.EventVideoProcessed, video)
+		return nil
+	}
+
+	err = p.run(ctx, j, video, types.RenditionMP4, p.transcodeMP4)
+	if err != nil {
+		return err
+	}
+
+	// 预览图先生成，拖动进度条时尽早可用
+	if _, err := p.jobs.Enqueue(ctx, types.JobThumbnails, video.UserID, video.ID, nil); err != nil {
+		log.Printf("transcode: failed to enqueue thumbnails for video %d: %v", video.ID, err)
+	}
+	if _, err := p.jobs.Enqueue(ctx, types.JobBeats, video.UserID, video.ID, nil); err != nil {
+		log.Printf("transcode: failed to enqueue beat detection for video %d: %v", video.ID, err)
+	}
+	if _, err := p.jobs.Enqueue(ctx, types.JobHLS, video.UserID, video.ID, nil); err != nil {
+		log.Printf("transcode: failed to enqueue hls for video %d: %v", video.ID, err)
+	}
+
+	// MP4 已经生成，读取失败时不重试（会重新转码），事件中只是没有输出列表
+	if video.Renditions, err = p.renditions.GetRenditions(ctx, video.ID); err != nil {
+		log.Printf("transcode: failed to load renditions of video %d: %v", video.ID, err)
+	}
+	p.events.Publish(video.UserID, types.EventVideoProcessed, video)
+	return nil
+}
+
+// HandleHLS 执行 types.JobHLS 任务
+func (p *Pipeline) HandleHLS(ctx context.Context, j *types.Job) error {
+	video, err := p.videos.GetVideoByID(ctx, j.VideoID)
+	if errors.Is(err, types.ErrNotFound) {
+		return job.Permanent(err)
+	}
+	if err != nil {
+		return err
+	}
+	if p.transcoder == nil {
+		return nil
+	}
+
+	return p.run(ctx, j, video, types.RenditionHLS, p.transcodeHLS)
+}
+
+// HandleThumbnails 执行 types.JobThumbnails 任务，生成封面图、预览图拼图和 WebVTT 缩略图轨道
+func (p *Pipeline) HandleThumbnails(ctx context.Context, j *types.Job) error {
+	video, err := p.videos.GetVideoByID(ctx, j.VideoID)
+	if errors.Is(err, types.ErrNotFound) {
+		return job.Permanent(err)
+	}
+	if err != nil {
+		return err
+	}
+	if p.transcoder == nil {
+		return nil
+	}
+
+	return p.run(ctx, j, video, types.RenditionThumbnails, p.generateThumbnails)
+}
+
+// HandleDerive 执行 types.JobDerive 任务，生成镜像/变速版本
+func (p *Pipeline) HandleDerive(ctx context.Context, j *types.Job) error {
+	video, err := p.videos.GetVideoByID(ctx, j.VideoID)
+	if errors.Is(err, types.ErrNotFound) {
+		return job.Permanent(err)
+	}
+	if err != nil {
+		return err
+	}
+	if p.transcoder == nil {
+		return job.Permanent(fmt.Errorf("transcoding is not available"))
+	}
+
+	var opts types.DerivedOptions
+	if err := json.Unmarshal(j.Payload, &opts); err != nil {
+		return job.Permanent(fmt.Errorf("invalid derive payload: %v", err))
+	}
+	kind := DerivedKind(opts)
+	if kind == "" {
+		return job.Permanent(fmt.Errorf("nothing to derive"))
+	}
+
+	err = p.run(ctx, j, video, kind, func(ctx context.Context, video *types.Video, rendition *types.Rendition) error {
+		return p.transcodeDerived(ctx, video, rendition, opts)
+	})
+	if err != nil {
+		return err
+	}
+
+	// 派生输出已经生成，读取失败时不重试，事件中只是没有输出列表。
+	// 派生输出可能是班级成员请求的，通知请求的人而不是视频所有者
+	if video.Renditions, err = p.renditions.GetRenditions(ctx, video.ID); err != nil {
+		log.Printf("transcode: failed to load renditions of video %d: %v", video.ID, err)
+	}
+	p.events.Publish(requester(j, video), types.EventVideoProcessed, video)
+	return nil
+}
+
+// requester 提交任务的用户，旧任务没有记录时为视频所有者
+func requester(j *types.Job, video *types.Video) int {
+	if j.UserID != 0 {
+		return j.UserID
+	}
+	return video.UserID
+}
+
+// run 执行一种输出的转码并记录状态；最后一次尝试失败时推送 video.failed
+func (p *Pipeline) run(ctx context.Context, j *types.Job, video *types.Video, kind string,
+	transcode func(ctx context.Context, video *types.Video, rendition *types.Rendition) error) error {
+	rendition := &types.Rendition{
+		VideoID: video.ID,
+		Kind:    kind,
+		Status:  types.RenditionProcessing,
+	}
+	if err := p.renditions.SaveRendition(ctx, rendition); err != nil {
+		return err
+	}
+
+	if err := transcode(ctx, video, rendition); err != nil {
+		rendition.Status = types.RenditionFailed
+		rendition.Error = err.Error()
+		if saveErr := p.renditions.SaveRendition(ctx, rendition); saveErr != nil {
+			log.Printf("transcode: failed to save %s rendition for video %d: %v", kind, video.ID, saveErr)
+		}
+		if job.IsFinalAttempt(j) {
+			p.events.Publish(requester(j, video), types.EventVideoFailed, rendition)
+		}
+		return err
+	}
+
+	rendition.Status = types.RenditionReady
+	rendition.Error = ""
+	if err := p.renditions.SaveRendition(ctx, rendition); err != nil {
+		// 视频在转码期间被彻底删除时外键约束失败，输出没有记录引用，直接删除
+		if _, getErr := p.videos.GetVideoByID(ctx, video.ID); getErr != nil {
+			if err := p.storage.Delete(ctx, storage.RenditionPrefix(video.ID)); err != nil {
+				log.Printf("transcode: failed to delete outputs of deleted video %d: %v", video.ID, err)
+			}
+			return job.Permanent(fmt.Errorf("video %d was deleted during transcoding", video.ID))
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (p *Pipeline) transcodeMP4(ctx context.Context, video *types.Video, rendition *types.Rendition) error {
+	// 补充上传时不知道的时长
+	source, err := p.transcoder.Probe(ctx, video.FilePath)
+	if err != nil {
+		return err
+	}
+	if video.Duration == 0 && source.Duration > 0 {
+		video.Duration = source.Duration
+		if err := p.videos.UpdateVideo(ctx, video); err != nil {
+			return err
+		}
+	}
+
+	workDir, err := os.MkdirTemp("", "transcode-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workDir)
+
+	tmp := filepath.Join(workDir, "video.mp4")
+	if err := p.transcoder.TranscodeMP4(ctx, video.FilePath, tmp); err != nil {
+		return err
+	}
+	output, err := p.transcoder.Probe(ctx, tmp)
+	if err != nil {
+		return err
+	}
+
+	key := RenditionKey(video.ID, "mp4.mp4")
+	size, err := p.putFile(ctx, key, tmp)
+	if err != nil {
+		return err
+	}
+
+	rendition.FilePath = key
+	rendition.MimeType = "video/mp4"
+	rendition.Width = output.Width
+	rendition.Height = output.Height
+	rendition.Duration = output.Duration
+	rendition.FileSize = size
+	return nil
+}
+
+func (p *Pipeline) transcodeDerived(ctx context.Context, video *types.Video, rendition *types.Rendition, opts types.DerivedOptions) error {
+	workDir, err := os.MkdirTemp("", "derive-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workDir)
+
+	tmp := filepath.Join(workDir, "video.mp4")
+	if err := p.transcoder.Derive(ctx, video.FilePath, tmp, opts); err != nil {
+		return err
+	}
+	output, err := p.transcoder.Probe(ctx, tmp)
+	if err != nil {
+		return err
+	}
+
+	key := RenditionKey(video.ID, rendition.Kind+".mp4")
+	size, err := p.putFile(ctx, key, tmp)
+	if err != nil {
+		return err
+	}
+
+	rendition.FilePath = key
+	rendition.MimeType = "video/mp4"
+	rendition.Width = output.Width
+	rendition.Height = output.Height
+	rendition.Duration = output.Duration
+	rendition.FileSize = size
+	return nil
+}
+
+func (p *Pipeline) generateThumbnails(ctx context.Context, video *types.Video, rendition *types.Rendition) error {
+	source, err := p.transcoder.Probe(ctx, video.FilePath)
+	if err != nil {
+		return err
+	}
+	if source.Duration <= 0 {
+		return fmt.Errorf("unknown video duration")
+	}
+
+	workDir, err := os.MkdirTemp("", "thumbnails-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workDir)
+
+	// 封面取 10% 处的一帧，避开片头的黑屏
+	poster := filepath.Join(workDir, "poster.jpg")
+	if err := p.transcoder.Poster(ctx, video.FilePath, poster, source.Duration/10, posterWidth); err != nil {
+		return err
+	}
+	sheet := spriteSheet(source.Duration, source.Width, source.Height)
+	sprite := filepath.Join(workDir, "sprite.jpg")
+	if err := p.transcoder.Sprite(ctx, video.FilePath, sprite, sheet); err != nil {
+		return err
+	}
+
+	prefix := RenditionKey(video.ID, "thumbnails")
+	posterSize, err := p.putFile(ctx, prefix+"/poster.jpg", poster)
+	if err != nil {
+		return err
+	}
+	spriteSize, err := p.putFile(ctx, prefix+"/sprite.jpg", sprite)
+	if err != nil {
+		return err
+	}
+	vtt := thumbnailsVTT(sheet, source.Duration, "sprite.jpg")
+	key := prefix + "/thumbnails.vtt"
+	if err := p.storage.Put(ctx, key, strings.NewReader(vtt)); err != nil {
+		return err
+	}
+
+	video.Thumbnail = prefix + "/poster.jpg"
+	if err := p.videos.UpdateVideo(ctx, video); err != nil {
+		return err
+	}
+
+	rendition.FilePath = key
+	rendition.MimeType = "text/vtt"
+	rendition.Width = sheet.TileWidth
+	rendition.Height = sheet.TileHeight
+	rendition.Duration = source.Duration
+	rendition.FileSize = posterSize + spriteSize + int64(len(vtt))
+	return nil
+}
+
+func (p *Pipeline) transcodeHLS(ctx context.Context, video *types.Video, rendition *types.Rendition) error {
+	source, err := p.transcoder.Probe(ctx, video.FilePath)
+	if err != nil {
+		return err
+	}
+
+	workDir, err := os.MkdirTemp("", "hls-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workDir)
+
+	// 重新生成时先清掉旧的分片（分片数量可能不同）
+	prefix := RenditionKey(video.ID, "hls")
+	if err := p.storage.Delete(ctx, prefix); err != nil {
+		return err
+	}
+
+	variants := hlsVariants(source.Height)
+	var total int64
+	for _, variant := range variants {
+		dir := filepath.Join(workDir, variant.Name)
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			return err
+		}
+		if err := p.transcoder.TranscodeHLS(ctx, video.FilePath, dir, variant); err != nil {
+			return fmt.Errorf("%s: %v", variant.Name, err)
+		}
+
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			size, err := p.putFile(ctx, prefix+"/"+variant.Name+"/"+entry.Name(), filepath.Join(dir, entry.Name()))
+			if err != nil {
+				return err
+			}
+			total += size
+		}
+	}
+
+	master := masterPlaylist(variants, source.Width, source.Height)
+	key := prefix + "/master.m3u8"
+	if err := p.storage.Put(ctx, key, strings.NewReader(master)); err != nil {
+		return err
+	}
+
+	top := variants[len(variants)-1]
+	rendition.FilePath = key
+	rendition.MimeType = "application/vnd.apple.mpegurl"
+	rendition.Height = top.Height
+	if source.Height > 0 {
+		rendition.Width = (source.Width*top.Height/source.Height + 1) / 2 * 2
+	}
+	rendition.Duration = source.Duration
+	rendition.FileSize = total + int64(len(master))
+	return nil
+}
+
+// putFile 把本地文件写入存储层，返回文件大小
+func (p *Pipeline) putFile(ctx context.Context, key, path string) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	if err := p.storage.Put(ctx, key, f); err != nil {
+		return 0, err
+	}
+	return stat.Size(), nil
+}
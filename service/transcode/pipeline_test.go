@@ -0,0 +1,280 @@
+package transcode_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/Albert-tru/DanceMirror/db/dbtest"
+	"github.com/Albert-tru/DanceMirror/service/storage"
+	"github.com/Albert-tru/DanceMirror/service/transcode"
+	"github.com/Albert-tru/DanceMirror/service/video"
+	"github.com/Albert-tru/DanceMirror/types"
+)
+
+// recorder 记录提交的任务和发布的事件
+type recorder struct {
+	mu     sync.Mutex
+	jobs   []string
+	events []event
+}
+
+type event struct {
+	userID int
+	kind   string
+	data   any
+}
+
+func (r *recorder) Enqueue(ctx context.Context, jobType string, userID, videoID int, payload any) (*types.Job, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.jobs = append(r.jobs, jobType)
+	return &types.Job{Type: jobType, UserID: userID, VideoID: videoID}, nil
+}
+
+func (r *recorder) Publish(userID int, eventType string, data any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event{userID, eventType, data})
+}
+
+// hooked 在转码 MP4 之前调用 before（模拟转码期间发生的事情）
+type hooked struct {
+	*transcode.Fake
+	before func()
+}
+
+func (h *hooked) TranscodeMP4(ctx context.Context, src, dst string) error {
+	h.before()
+	return h.Fake.TranscodeMP4(ctx, src, dst)
+}
+
+type fixture struct {
+	pipeline   *transcode.Pipeline
+	videos     *video.Store
+	renditions *transcode.Store
+	storage    *storage.Local
+	recorder   *recorder
+	video      *types.Video
+}
+
+func setup(t *testing.T, transcoder types.Transcoder) *fixture {
+	t.Helper()
+
+	database := dbtest.New(t)
+	dir := t.TempDir()
+	source := filepath.Join(dir, "source.mp4")
+	if err := os.WriteFile(source, []byte("not really a video"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	f := &fixture{
+		videos:     video.NewStore(database),
+		renditions: transcode.NewStore(database),
+		storage:    storage.NewLocal(filepath.Join(dir, "storage")),
+		recorder:   &recorder{},
+		video:      &types.Video{UserID: dbtest.CreateUser(t, database, "13800000001"), Title: "routine", FilePath: source, FileName: "source.mp4", FileSize: 18},
+	}
+	if err := f.videos.CreateVideo(context.Background(), f.video); err != nil {
+		t.Fatal(err)
+	}
+	f.pipeline = transcode.NewPipeline(f.videos, f.renditions, transcoder, f.storage, f.recorder, f.recorder)
+	return f
+}
+
+func (f *fixture) job(jobType string, attempts int) *types.Job {
+	return &types.Job{Type: jobType, VideoID: f.video.ID, Attempts: attempts, MaxAttempts: 3}
+}
+
+// exists 存储层中是否有 key（文件或目录）
+func (f *fixture) exists(t *testing.T, key string) bool {
+	t.Helper()
+	p, err := f.storage.Path(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = os.Stat(p)
+	return err == nil
+}
+
+func TestHandleTranscode(t *testing.T) {
+	fake := transcode.NewFake()
+	var during string
+	tc := &hooked{Fake: fake}
+	f := setup(t, tc)
+	ctx := context.Background()
+	tc.before = func() {
+		r, err := f.renditions.GetRendition(ctx, f.video.ID, types.RenditionMP4)
+		if err == nil {
+			during = r.Status
+		}
+	}
+
+	if err := f.renditions.SaveRendition(ctx, &types.Rendition{VideoID: f.video.ID, Kind: types.RenditionMP4, Status: types.RenditionPending}); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.pipeline.HandleTranscode(ctx, f.job(types.JobTranscode, 1)); err != nil {
+		t.Fatal(err)
+	}
+
+	if during != types.RenditionProcessing {
+		t.Errorf("status during transcoding = %q, want %q", during, types.RenditionProcessing)
+	}
+	r, err := f.renditions.GetRendition(ctx, f.video.ID, types.RenditionMP4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Status != types.RenditionReady || r.FilePath != transcode.RenditionKey(f.video.ID, "mp4.mp4") || r.Width != 1280 || r.Duration != 10 {
+		t.Errorf("rendition = %+v, want a ready 1280x720 mp4", r)
+	}
+	if !f.exists(t, r.FilePath) {
+		t.Errorf("%s was not written to storage", r.FilePath)
+	}
+
+	// 上传时不知道的时长由转码补充
+	v, err := f.videos.GetVideoByID(ctx, f.video.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Duration != 10 {
+		t.Errorf("video duration = %v, want 10", v.Duration)
+	}
+
+	wantJobs := []string{types.JobThumbnails, types.JobBeats, types.JobHLS}
+	if len(f.recorder.jobs) != len(wantJobs) {
+		t.Fatalf("jobs = %v, want %v", f.recorder.jobs, wantJobs)
+	}
+	for i := range wantJobs {
+		if f.recorder.jobs[i] != wantJobs[i] {
+			t.Errorf("jobs = %v, want %v", f.recorder.jobs, wantJobs)
+		}
+	}
+
+	if len(f.recorder.events) != 1 {
+		t.Fatalf("events = %+v, want video.processed", f.recorder.events)
+	}
+	e := f.recorder.events[0]
+	processed, ok := e.data.(*types.Video)
+	if e.kind != types.EventVideoProcessed || e.userID != f.video.UserID || !ok || len(processed.Renditions) != 1 {
+		t.Errorf("event = %+v, want video.processed for the owner with the mp4 rendition", e)
+	}
+}
+
+// 失败时记录错误，只有最后一次尝试失败才推送 video.failed
+func TestHandleTranscodeFailure(t *testing.T) {
+	tests := []struct {
+		name     string
+		attempts int
+		failed   bool
+	}{
+		{"retry left", 1, false},
+		{"last attempt", 3, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := transcode.NewFake()
+			fake.Err = errors.New("ffmpeg crashed")
+			f := setup(t, fake)
+			ctx := context.Background()
+
+			if err := f.pipeline.HandleTranscode(ctx, f.job(types.JobTranscode, tt.attempts)); err == nil {
+				t.Fatal("HandleTranscode succeeded, want the transcoder error")
+			}
+			r, err := f.renditions.GetRendition(ctx, f.video.ID, types.RenditionMP4)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r.Status != types.RenditionFailed || r.Error != "ffmpeg crashed" {
+				t.Errorf("rendition = %+v, want failed with the error", r)
+			}
+			if len(f.recorder.jobs) != 0 {
+				t.Errorf("jobs = %v, want none after a failure", f.recorder.jobs)
+			}
+
+			failed := len(f.recorder.events) == 1 && f.recorder.events[0].kind == types.EventVideoFailed
+			if failed != tt.failed || (!tt.failed && len(f.recorder.events) != 0) {
+				t.Errorf("events = %+v, want video.failed: %v", f.recorder.events, tt.failed)
+			}
+		})
+	}
+}
+
+// 转码期间视频被删除：输出没有记录引用，直接从存储层删除，任务不再重试
+func TestDeletedDuringTranscode(t *testing.T) {
+	tc := &hooked{Fake: transcode.NewFake()}
+	f := setup(t, tc)
+	ctx := context.Background()
+	tc.before = func() {
+		if err := f.videos.DeleteVideo(ctx, f.video.ID); err != nil {
+			t.Error(err)
+		}
+	}
+
+	if err := f.pipeline.HandleTranscode(ctx, f.job(types.JobTranscode, 1)); err == nil {
+		t.Fatal("HandleTranscode succeeded for a deleted video")
+	}
+	if f.exists(t, storage.RenditionPrefix(f.video.ID)) {
+		t.Errorf("outputs of the deleted video are still in storage")
+	}
+	if _, err := f.renditions.GetRendition(ctx, f.video.ID, types.RenditionMP4); !errors.Is(err, types.ErrNotFound) {
+		t.Errorf("rendition of the deleted video: %v, want not found", err)
+	}
+	if len(f.recorder.events) != 0 {
+		t.Errorf("events = %+v, want none", f.recorder.events)
+	}
+}
+
+func TestHandleHLS(t *testing.T) {
+	f := setup(t, transcode.NewFake())
+	ctx := context.Background()
+
+	if err := f.pipeline.HandleHLS(ctx, f.job(types.JobHLS, 1)); err != nil {
+		t.Fatal(err)
+	}
+	r, err := f.renditions.GetRendition(ctx, f.video.ID, types.RenditionHLS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Status != types.RenditionReady || r.FilePath != transcode.RenditionKey(f.video.ID, "hls/master.m3u8") {
+		t.Errorf("rendition = %+v, want a ready master playlist", r)
+	}
+	for _, key := range []string{r.FilePath, transcode.RenditionKey(f.video.ID, "hls/720p/index.m3u8"), transcode.RenditionKey(f.video.ID, "hls/360p/seg_00000.ts")} {
+		if !f.exists(t, key) {
+			t.Errorf("%s was not written to storage", key)
+		}
+	}
+}
+
+func TestHandleThumbnails(t *testing.T) {
+	f := setup(t, transcode.NewFake())
+	ctx := context.Background()
+
+	if err := f.pipeline.HandleThumbnails(ctx, f.job(types.JobThumbnails, 1)); err != nil {
+		t.Fatal(err)
+	}
+	r, err := f.renditions.GetRendition(ctx, f.video.ID, types.RenditionThumbnails)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Status != types.RenditionReady || r.MimeType != "text/vtt" {
+		t.Errorf("rendition = %+v, want a ready vtt track", r)
+	}
+	v, err := f.videos.GetVideoByID(ctx, f.video.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Thumbnail != transcode.RenditionKey(f.video.ID, "thumbnails/poster.jpg") || !f.exists(t, v.Thumbnail) {
+		t.Errorf("thumbnail = %q, want the stored poster", v.Thumbnail)
+	}
+
+	// 视频已删除时任务直接失败，不生成输出
+	if err := f.videos.DeleteVideo(ctx, f.video.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.pipeline.HandleThumbnails(ctx, f.job(types.JobThumbnails, 1)); err == nil {
+		t.Error("HandleThumbnails succeeded for a deleted video")
+	}
+}
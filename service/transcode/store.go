@@ -0,0 +1,158 @@
+package transcode
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/Albert-tru/DanceMirror/db/dialect"
+	"github.com/Albert-tru/DanceMirror/types"
+)
+
+// renditionColumns 和 scanRowIntoRendition 的顺序一致
+const renditionColumns = "id, videoId, kind, status, filePath, mimeType, width, height, duration, fileSize, error, createdAt, updatedAt"
+
+type Store struct {
+	db      *sql.DB
+	dialect dialect.Dialect
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db, dialect: dialect.Of(db)}
+}
+
+func (s *Store) SaveRendition(ctx context.Context, r *types.Rendition) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := s.saveRendition(ctx, tx, r); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SaveDerivedRendition 统计和保存在同一个事务中，并发请求不会超过 limit。
+// 锁住视频记录，同一视频的请求依次执行（SQLite 同一时间只有一个写事务）
+func (s *Store) SaveDerivedRendition(ctx context.Context, r *types.Rendition, limit int) (bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var id int
+	err = tx.QueryRowContext(ctx, "SELECT id FROM videos WHERE id = ?"+s.dialect.ForUpdate(), r.VideoID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return false, fmt.Errorf("video %w", types.ErrNotFound)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	// 和 IsDerived 一致：上传后自动生成的输出不算；失败的和要覆盖的同类型输出也不算
+	var derived int
+	err = tx.QueryRowContext(ctx, `
+SELECT COUNT(*) FROM video_renditions
+WHERE videoId = ? AND kind NOT IN (?, ?, ?) AND kind <> ? AND status <> ?`,
+		r.VideoID, types.RenditionMP4, types.RenditionHLS, types.RenditionThumbnails, r.Kind, types.RenditionFailed).Scan(&derived)
+	if err != nil {
+		return false, err
+	}
+	if derived >= limit {
+		return false, nil
+	}
+
+	if err := s.saveRendition(ctx, tx, r); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+func (s *Store) saveRendition(ctx context.Context, tx *sql.Tx, r *types.Rendition) error {
+	var filePath, mimeType, errMsg sql.NullString
+	if r.FilePath != "" {
+		filePath = sql.NullString{String: r.FilePath, Valid: true}
+	}
+	if r.MimeType != "" {
+		mimeType = sql.NullString{String: r.MimeType, Valid: true}
+	}
+	if r.Error != "" {
+		errMsg = sql.NullString{String: r.Error, Valid: true}
+	}
+
+	_, err := tx.ExecContext(ctx, `
+INSERT INTO video_renditions (videoId, kind, status, filePath, mimeType, width, height, duration, fileSize, error)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+`+s.dialect.Upsert("videoId, kind", "status", "filePath", "mimeType", "width", "height", "duration", "fileSize", "error"),
+		r.VideoID, r.Kind, r.Status, filePath, mimeType, r.Width, r.Height, r.Duration, r.FileSize, errMsg)
+	return err
+}
+
+func (s *Store) GetRendition(ctx context.Context, videoID int, kind string) (*types.Rendition, error) {
+	renditions, err := s.queryRenditions(ctx, "SELECT "+renditionColumns+" FROM video_renditions WHERE videoId = ? AND kind = ?", videoID, kind)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(renditions) == 0 {
+		return nil, fmt.Errorf("rendition %w", types.ErrNotFound)
+	}
+
+	return renditions[0], nil
+}
+
+func (s *Store) GetRenditions(ctx context.Context, videoID int) ([]*types.Rendition, error) {
+	return s.queryRenditions(ctx, "SELECT "+renditionColumns+" FROM video_renditions WHERE videoId = ? ORDER BY id", videoID)
+}
+
+func (s *Store) queryRenditions(ctx context.Context, query string, args ...any) ([]*types.Rendition, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	renditions := []*types.Rendition{}
+	for rows.Next() {
+		r, err := scanRowIntoRendition(rows)
+		if err != nil {
+			return nil, err
+		}
+		renditions = append(renditions, r)
+	}
+
+	return renditions, nil
+}
+
+func scanRowIntoRendition(rows *sql.Rows) (*types.Rendition, error) {
+	r := new(types.Rendition)
+
+	var filePath, mimeType, errMsg sql.NullString
+	err := rows.Scan(
+		&r.ID,
+		&r.VideoID,
+		&r.Kind,
+		&r.Status,
+		&filePath,
+		&mimeType,
+		&r.Width,
+		&r.Height,
+		&r.Duration,
+		&r.FileSize,
+		&errMsg,
+		&r.CreatedAt,
+		&r.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	r.FilePath = filePath.String
+	r.MimeType = mimeType.String
+	r.Error = errMsg.String
+
+	return r, nil
+}
@@ -0,0 +1,90 @@
+package transcode_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/Albert-tru/DanceMirror/db/dbtest"
+	"github.com/Albert-tru/DanceMirror/service/transcode"
+	"github.com/Albert-tru/DanceMirror/types"
+)
+
+// 上限只统计未失败的派生输出，重新生成同类型的输出不受上限影响
+func TestSaveDerivedRendition(t *testing.T) {
+	database := dbtest.New(t)
+	store := transcode.NewStore(database)
+	videoID := dbtest.CreateVideo(t, database, dbtest.CreateUser(t, database, "13800000001"), "routine")
+	ctx := context.Background()
+
+	save := func(kind, status string) bool {
+		t.Helper()
+		saved, err := store.SaveDerivedRendition(ctx, &types.Rendition{VideoID: videoID, Kind: kind, Status: status}, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return saved
+	}
+
+	// 自动生成的输出不算
+	for _, kind := range []string{types.RenditionMP4, types.RenditionHLS, types.RenditionThumbnails} {
+		if err := store.SaveRendition(ctx, &types.Rendition{VideoID: videoID, Kind: kind, Status: types.RenditionReady}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !save("mirror", types.RenditionReady) || !save("speed-0.5", types.RenditionFailed) || !save("speed-0.75", types.RenditionPending) {
+		t.Fatal("derived renditions under the limit were not saved")
+	}
+	if save("speed-1.5", types.RenditionPending) {
+		t.Error("third derived rendition was saved, want the limit of 2")
+	}
+	// 已有的同类型输出重新提交
+	if !save("speed-0.75", types.RenditionPending) {
+		t.Error("existing kind was not saved at the limit")
+	}
+	if save("speed-0.5", types.RenditionPending) {
+		t.Error("failed kind was retried over the limit")
+	}
+
+	if _, err := store.SaveDerivedRendition(ctx, &types.Rendition{VideoID: 999, Kind: "mirror", Status: types.RenditionPending}, 2); !errors.Is(err, types.ErrNotFound) {
+		t.Errorf("SaveDerivedRendition of a missing video: err = %v, want ErrNotFound", err)
+	}
+}
+
+// 并发请求不会超过上限
+func TestSaveDerivedRenditionConcurrent(t *testing.T) {
+	database := dbtest.New(t)
+	store := transcode.NewStore(database)
+	videoID := dbtest.CreateVideo(t, database, dbtest.CreateUser(t, database, "13800000001"), "routine")
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	saved := 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := store.SaveDerivedRendition(context.Background(), &types.Rendition{VideoID: videoID, Kind: fmt.Sprintf("speed-%d", i), Status: types.RenditionPending}, 6)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if ok {
+				mu.Lock()
+				saved++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	renditions, err := store.GetRenditions(context.Background(), videoID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved != 6 || len(renditions) != 6 {
+		t.Errorf("%d saved, %d stored; want 6", saved, len(renditions))
+	}
+}
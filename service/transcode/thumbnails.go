@@ -0,0 +1,69 @@
+package transcode
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/Albert-tru/DanceMirror/types"
+)
+
+const (
+	thumbnailInterval  = 5.0 // 预览图间隔（秒）
+	thumbnailMaxFrames = 100 // 长视频加大间隔，拼图不超过这么多帧
+	thumbnailColumns   = 10
+	thumbnailWidth     = 160 // 单张预览图宽度
+	posterWidth        = 640
+)
+
+// spriteSheet 按视频时长和分辨率计算拼图布局
+func spriteSheet(duration float64, width, height int) types.SpriteSheet {
+	interval := thumbnailInterval
+	if duration/interval > thumbnailMaxFrames {
+		interval = math.Ceil(duration / thumbnailMaxFrames)
+	}
+	count := int(math.Ceil(duration / interval))
+	if count < 1 {
+		count = 1
+	}
+
+	// 高度按源视频比例取偶数
+	tileHeight := thumbnailWidth * 9 / 16
+	if width > 0 && height > 0 {
+		tileHeight = (thumbnailWidth*height/width + 1) / 2 * 2
+	}
+
+	columns := min(count, thumbnailColumns)
+	return types.SpriteSheet{
+		Interval:   interval,
+		Count:      count,
+		Columns:    columns,
+		Rows:       (count + columns - 1) / columns,
+		TileWidth:  thumbnailWidth,
+		TileHeight: tileHeight,
+	}
+}
+
+// thumbnailsVTT 生成 WebVTT 缩略图轨道，每个时间段指向拼图中的一块（#xywh=）
+func thumbnailsVTT(sheet types.SpriteSheet, duration float64, spriteName string) string {
+	var b strings.Builder
+	b.WriteString("WEBVTT\n")
+	for i := 0; i < sheet.Count; i++ {
+		start := float64(i) * sheet.Interval
+		end := math.Min(start+sheet.Interval, duration)
+		if end <= start {
+			end = start + sheet.Interval
+		}
+		x := (i % sheet.Columns) * sheet.TileWidth
+		y := (i / sheet.Columns) * sheet.TileHeight
+		fmt.Fprintf(&b, "\n%s --> %s\n%s#xywh=%d,%d,%d,%d\n",
+			vttTimestamp(start), vttTimestamp(end), spriteName, x, y, sheet.TileWidth, sheet.TileHeight)
+	}
+	return b.String()
+}
+
+// vttTimestamp 格式化为 hh:mm:ss.mmm
+func vttTimestamp(seconds float64) string {
+	ms := int64(math.Round(seconds * 1000))
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
@@ -0,0 +1,95 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Albert-tru/DanceMirror/types"
+)
+
+// MemoryStore 内存中的 types.UserStore 实现（用于测试），
+// 查询结果和错误与数据库实现一致，返回的都是副本
+type MemoryStore struct {
+	mu     sync.RWMutex
+	nextID int
+	users  []*types.User
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{nextID: 1}
+}
+
+func (s *MemoryStore) GetUserByEmail(ctx context.Context, email string) (*types.User, error) {
+	// 和 MySQL 的 utf8mb4_unicode_ci 一样不区分大小写
+	return s.find(func(u *types.User) bool { return u.Email != "" && strings.EqualFold(u.Email, email) })
+}
+
+func (s *MemoryStore) GetUserByPhone(ctx context.Context, phone string) (*types.User, error) {
+	return s.find(func(u *types.User) bool { return u.Phone == phone })
+}
+
+func (s *MemoryStore) GetUserByID(ctx context.Context, id int) (*types.User, error) {
+	return s.find(func(u *types.User) bool { return u.ID == id })
+}
+
+func (s *MemoryStore) CreateUser(ctx context.Context, user types.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, u := range s.users {
+		if u.Phone == user.Phone {
+			return fmt.Errorf("%w: duplicate phone %s", types.ErrConflict, user.Phone)
+		}
+	}
+
+	// 和数据库一样忽略传入的 ID、创建时间、角色和语言
+	user.ID = s.nextID
+	user.CreatedAt = time.Now().Truncate(time.Second)
+	user.Role = types.RoleUser
+	user.Locale = ""
+	s.nextID++
+	s.users = append(s.users, &user)
+	return nil
+}
+
+func (s *MemoryStore) UpdateLocale(ctx context.Context, userID int, locale string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, u := range s.users {
+		if u.ID == userID {
+			u.Locale = locale
+		}
+	}
+	return nil
+}
+
+// SetRole 修改用户角色（数据库中由管理员直接修改，没有对应的接口）
+func (s *MemoryStore) SetRole(userID int, role string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, u := range s.users {
+		if u.ID == userID {
+			u.Role = role
+			return nil
+		}
+	}
+	return fmt.Errorf("user %w", types.ErrNotFound)
+}
+
+func (s *MemoryStore) find(match func(*types.User) bool) (*types.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, u := range s.users {
+		if match(u) {
+			copied := *u
+			return &copied, nil
+		}
+	}
+	return nil, fmt.Errorf("user %w", types.ErrNotFound)
+}
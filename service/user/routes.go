@@ -1,7 +1,7 @@
 package user
 
 import (
-	"fmt"
+	"errors"
 	"net/http"
 
 	"github.com/Albert-tru/DanceMirror/config"
@@ -22,6 +22,8 @@ func NewHandler(store types.UserStore) *Handler {
 func (h *Handler) RegisterRoutes(router *mux.Router) {
 	router.HandleFunc("/register", h.handleRegister).Methods(http.MethodPost)
 	router.HandleFunc("/login", h.handleLogin).Methods(http.MethodPost)
+	router.HandleFunc("/refresh", auth.WithJWTAuth(h.handleRefresh, h.store)).Methods(http.MethodPost)
+	router.HandleFunc("/me/locale", auth.WithJWTAuth(h.handleUpdateLocale, h.store)).Methods(http.MethodPut)
 }
 
 func (h *Handler) handleRegister(w http.ResponseWriter, r *http.Request) {
@@ -34,14 +36,18 @@ func (h *Handler) handleRegister(w http.ResponseWriter, r *http.Request) {
 
 	// 验证请求
 	if err := utils.Validate.Struct(payload); err != nil {
-		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", err))
+		utils.WriteError(w, http.StatusBadRequest, utils.InvalidPayload(err))
 		return
 	}
 
 	// 检查手机号是否已存在
-	_, err := h.store.GetUserByPhone(payload.Phone)
+	_, err := h.store.GetUserByPhone(r.Context(), payload.Phone)
 	if err == nil {
-		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("手机号 %s 已被注册", payload.Phone))
+		utils.WriteError(w, http.StatusBadRequest, utils.NewError(utils.CodeBadRequest, "phone %s is already registered", payload.Phone))
+		return
+	}
+	if !errors.Is(err, types.ErrNotFound) {
+		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -53,12 +59,17 @@ func (h *Handler) handleRegister(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 创建用户
-	err = h.store.CreateUser(types.User{
+	err = h.store.CreateUser(r.Context(), types.User{
 		Phone:     payload.Phone,
 		Password:  hashedPassword,
 		FirstName: payload.FirstName,
 		LastName:  payload.LastName,
 	})
+	if errors.Is(err, types.ErrConflict) {
+		// 并发注册同一个手机号
+		utils.WriteError(w, http.StatusConflict, utils.NewError(utils.CodeConflict, "phone %s is already registered", payload.Phone))
+		return
+	}
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
@@ -77,20 +88,24 @@ func (h *Handler) handleLogin(w http.ResponseWriter, r *http.Request) {
 
 	// 验证请求
 	if err := utils.Validate.Struct(payload); err != nil {
-		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", err))
+		utils.WriteError(w, http.StatusBadRequest, utils.InvalidPayload(err))
 		return
 	}
 
 	// 通过手机号查找用户
-	u, err := h.store.GetUserByPhone(payload.Phone)
+	u, err := h.store.GetUserByPhone(r.Context(), payload.Phone)
+	if errors.Is(err, types.ErrNotFound) {
+		utils.WriteError(w, http.StatusBadRequest, utils.NewError(utils.CodeBadRequest, "invalid phone or password"))
+		return
+	}
 	if err != nil {
-		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("手机号或密码错误"))
+		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
 	}
 
 	// 验证密码
 	if !auth.ComparePasswords(u.Password, []byte(payload.Password)) {
-		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("手机号或密码错误"))
+		utils.WriteError(w, http.StatusBadRequest, utils.NewError(utils.CodeBadRequest, "invalid phone or password"))
 		return
 	}
 
@@ -109,6 +124,39 @@ func (h *Handler) handleLogin(w http.ResponseWriter, r *http.Request) {
 			"phone":     u.Phone,
 			"firstName": u.FirstName,
 			"lastName":  u.LastName,
+			"role":      u.Role,
+			"locale":    u.Locale,
 		},
 	})
 }
+
+// handleRefresh 用未过期的令牌换一个新的令牌，客户端不需要保存密码
+func (h *Handler) handleRefresh(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.CreateJWT([]byte(config.Envs.JWTSecret), auth.GetUserIDFromContext(r.Context()))
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"token": token})
+}
+
+// handleUpdateLocale 设置接口返回信息的语言，空字符串恢复为按 Accept-Language
+func (h *Handler) handleUpdateLocale(w http.ResponseWriter, r *http.Request) {
+	var payload types.UpdateLocalePayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, utils.InvalidPayload(err))
+		return
+	}
+
+	if err := h.store.UpdateLocale(r.Context(), auth.GetUserIDFromContext(r.Context()), payload.Locale); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"locale": payload.Locale})
+}
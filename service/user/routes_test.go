@@ -0,0 +1,209 @@
+package user_test
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Albert-tru/DanceMirror/cmd/api/apitest"
+	"github.com/Albert-tru/DanceMirror/config"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// envelope /api/v2 的响应
+type envelope struct {
+	Code string `json:"code"`
+	Data struct {
+		Token string `json:"token"`
+	} `json:"data"`
+}
+
+func TestRegister(t *testing.T) {
+	s := apitest.Start(t)
+	if _, _, err := s.CreateUser("13800000001", "password123", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		body   any
+		status int
+		code   string
+	}{
+		{"ok", map[string]string{"phone": "13800000002", "password": "password123", "firstName": "a", "lastName": "b"}, http.StatusCreated, "ok"},
+		{"phone taken", map[string]string{"phone": "13800000001", "password": "password123", "firstName": "a", "lastName": "b"}, http.StatusBadRequest, "bad_request"},
+		{"short phone", map[string]string{"phone": "138", "password": "password123", "firstName": "a", "lastName": "b"}, http.StatusBadRequest, "validation_failed"},
+		{"short password", map[string]string{"phone": "13800000003", "password": "123", "firstName": "a", "lastName": "b"}, http.StatusBadRequest, "validation_failed"},
+		{"malformed json", "{", http.StatusBadRequest, "bad_request"},
+		{"no body", nil, http.StatusBadRequest, "bad_request"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := s.Request(http.MethodPost, "/api/v2/register", "", tt.body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var env envelope
+			if err := apitest.DecodeJSON(resp, &env); err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.status || env.Code != tt.code {
+				t.Errorf("POST /register = %d %s, want %d %s", resp.StatusCode, env.Code, tt.status, tt.code)
+			}
+		})
+	}
+}
+
+func TestLogin(t *testing.T) {
+	s := apitest.Start(t)
+	if _, _, err := s.CreateUser("13800000001", "password123", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		phone    string
+		password string
+		status   int
+	}{
+		{"ok", "13800000001", "password123", http.StatusOK},
+		{"wrong password", "13800000001", "password124", http.StatusBadRequest},
+		{"unknown phone", "13800000009", "password123", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := s.Request(http.MethodPost, "/api/v2/login", "", map[string]string{"phone": tt.phone, "password": tt.password})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var env envelope
+			if err := apitest.DecodeJSON(resp, &env); err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.status {
+				t.Errorf("POST /login = %d, want %d", resp.StatusCode, tt.status)
+			}
+			if (tt.status == http.StatusOK) != (env.Data.Token != "") {
+				t.Errorf("POST /login token = %q", env.Data.Token)
+			}
+		})
+	}
+}
+
+// 没有令牌、令牌无效或过期时 v2 返回 401，v1 为兼容旧客户端返回 403
+func TestAuthentication(t *testing.T) {
+	s := apitest.Start(t)
+	u, token, err := s.CreateUser("13800000001", "password123", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	signWith := func(secret string, claims jwt.MapClaims) string {
+		signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+	sign := func(claims jwt.MapClaims) string { return signWith(config.Envs.JWTSecret, claims) }
+	userID := strconv.Itoa(u.ID)
+
+	tests := []struct {
+		name   string
+		token  string
+		v1, v2 int
+	}{
+		{"valid", token, http.StatusOK, http.StatusOK},
+		{"missing", "", http.StatusForbidden, http.StatusUnauthorized},
+		{"malformed", "not-a-token", http.StatusForbidden, http.StatusUnauthorized},
+		{"expired", sign(jwt.MapClaims{"userID": userID, "expiresAt": time.Now().Add(-time.Minute).Unix()}), http.StatusForbidden, http.StatusUnauthorized},
+		{"no expiry", sign(jwt.MapClaims{"userID": userID}), http.StatusForbidden, http.StatusUnauthorized},
+		{"unknown user", sign(jwt.MapClaims{"userID": "42", "expiresAt": time.Now().Add(time.Hour).Unix()}), http.StatusForbidden, http.StatusUnauthorized},
+		{"wrong secret", signWith("other", jwt.MapClaims{"userID": userID, "expiresAt": time.Now().Add(time.Hour).Unix()}), http.StatusForbidden, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for prefix, want := range map[string]int{"/api/v1": tt.v1, "/api/v2": tt.v2} {
+				resp, err := s.Request(http.MethodGet, prefix+"/videos", tt.token, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				resp.Body.Close()
+				if resp.StatusCode != want {
+					t.Errorf("GET %s/videos = %d, want %d", prefix, resp.StatusCode, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	s := apitest.Start(t)
+	_, token, err := s.CreateUser("13800000001", "password123", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := s.Request(http.MethodPost, "/api/v2/refresh", token, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var env envelope
+	if err := apitest.DecodeJSON(resp, &env); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || env.Data.Token == "" {
+		t.Fatalf("POST /refresh = %d %+v", resp.StatusCode, env)
+	}
+
+	// 新令牌可以使用
+	resp, err = s.Request(http.MethodGet, "/api/v2/videos", env.Data.Token, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("GET /videos with the refreshed token = %d", resp.StatusCode)
+	}
+
+	resp, err = s.Request(http.MethodPost, "/api/v2/refresh", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("POST /refresh without a token = %d, want 401", resp.StatusCode)
+	}
+}
+
+func TestUpdateLocale(t *testing.T) {
+	s := apitest.Start(t)
+	u, token, err := s.CreateUser("13800000001", "password123", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		locale string
+		status int
+	}{
+		{"", http.StatusOK},
+		{"fr", http.StatusBadRequest},
+		{"en", http.StatusOK},
+	}
+	for _, tt := range tests {
+		resp, err := s.Request(http.MethodPut, "/api/v1/me/locale", token, map[string]string{"locale": tt.locale})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.status {
+			t.Errorf("PUT /me/locale %q = %d, want %d", tt.locale, resp.StatusCode, tt.status)
+		}
+	}
+
+	got, err := s.Stores.Users.GetUserByID(t.Context(), u.ID)
+	if err != nil || got.Locale != "en" {
+		t.Errorf("saved locale = %+v, %v; want en", got, err)
+	}
+}
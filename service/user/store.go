@@ -1,12 +1,17 @@
 package user
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 
+	"github.com/Albert-tru/DanceMirror/db/dialect"
 	"github.com/Albert-tru/DanceMirror/types"
 )
 
+// userColumns 和 scanRowIntoUser 的顺序一致
+const userColumns = "id, email, phone, password, firstName, lastName, createdAt, role, locale"
+
 type Store struct {
 	db *sql.DB
 }
@@ -15,8 +20,8 @@ func NewStore(db *sql.DB) *Store {
 	return &Store{db: db}
 }
 
-func (s *Store) GetUserByEmail(email string) (*types.User, error) {
-	rows, err := s.db.Query("SELECT * FROM users WHERE email = ?", email)
+func (s *Store) GetUserByEmail(ctx context.Context, email string) (*types.User, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+userColumns+" FROM users WHERE email = ?", email)
 	if err != nil {
 		return nil, err
 	}
@@ -31,14 +36,14 @@ func (s *Store) GetUserByEmail(email string) (*types.User, error) {
 	}
 
 	if u.ID == 0 {
-		return nil, fmt.Errorf("user not found")
+		return nil, fmt.Errorf("user %w", types.ErrNotFound)
 	}
 
 	return u, nil
 }
 
-func (s *Store) GetUserByPhone(phone string) (*types.User, error) {
-	rows, err := s.db.Query("SELECT * FROM users WHERE phone = ?", phone)
+func (s *Store) GetUserByPhone(ctx context.Context, phone string) (*types.User, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+userColumns+" FROM users WHERE phone = ?", phone)
 	if err != nil {
 		return nil, err
 	}
@@ -53,14 +58,14 @@ func (s *Store) GetUserByPhone(phone string) (*types.User, error) {
 	}
 
 	if u.ID == 0 {
-		return nil, fmt.Errorf("user not found")
+		return nil, fmt.Errorf("user %w", types.ErrNotFound)
 	}
 
 	return u, nil
 }
 
-func (s *Store) GetUserByID(id int) (*types.User, error) {
-	rows, err := s.db.Query("SELECT * FROM users WHERE id = ?", id)
+func (s *Store) GetUserByID(ctx context.Context, id int) (*types.User, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+userColumns+" FROM users WHERE id = ?", id)
 	if err != nil {
 		return nil, err
 	}
@@ -75,22 +80,28 @@ func (s *Store) GetUserByID(id int) (*types.User, error) {
 	}
 
 	if u.ID == 0 {
-		return nil, fmt.Errorf("user not found")
+		return nil, fmt.Errorf("user %w", types.ErrNotFound)
 	}
 
 	return u, nil
 }
 
-func (s *Store) CreateUser(user types.User) error {
-	_, err := s.db.Exec("INSERT INTO users (email, phone, password, firstName, lastName) VALUES (?, ?, ?, ?, ?)",
+func (s *Store) CreateUser(ctx context.Context, user types.User) error {
+	_, err := s.db.ExecContext(ctx, "INSERT INTO users (email, phone, password, firstName, lastName) VALUES (?, ?, ?, ?, ?)",
 		user.Email, user.Phone, user.Password, user.FirstName, user.LastName)
 	if err != nil {
-		return err
+		return dialect.Conflict(err)
 	}
 
 	return nil
 }
 
+func (s *Store) UpdateLocale(ctx context.Context, userID int, locale string) error {
+	// MySQL 中值没有变化时影响行数为 0，不能据此判断用户不存在（调用方已经查到了用户）
+	_, err := s.db.ExecContext(ctx, "UPDATE users SET locale = ? WHERE id = ?", locale, userID)
+	return err
+}
+
 func scanRowIntoUser(rows *sql.Rows) (*types.User, error) {
 	user := new(types.User)
 
@@ -102,6 +113,8 @@ func scanRowIntoUser(rows *sql.Rows) (*types.User, error) {
 		&user.FirstName,
 		&user.LastName,
 		&user.CreatedAt,
+		&user.Role,
+		&user.Locale,
 	)
 	if err != nil {
 		return nil, err
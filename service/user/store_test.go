@@ -0,0 +1,106 @@
+package user_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/Albert-tru/DanceMirror/db/dbtest"
+	"github.com/Albert-tru/DanceMirror/service/user"
+	"github.com/Albert-tru/DanceMirror/types"
+)
+
+// 内存存储和数据库存储执行同样的操作，结果和错误应该一致
+func forEachStore(t *testing.T, test func(t *testing.T, store types.UserStore)) {
+	impls := []struct {
+		name  string
+		store func(database *sql.DB) types.UserStore
+	}{
+		{"memory", func(*sql.DB) types.UserStore { return user.NewMemoryStore() }},
+		{"sqlite", func(database *sql.DB) types.UserStore { return user.NewStore(database) }},
+	}
+	for _, impl := range impls {
+		t.Run(impl.name, func(t *testing.T) { test(t, impl.store(dbtest.New(t))) })
+	}
+}
+
+func TestStoreCreateAndGet(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store types.UserStore) {
+		ctx := context.Background()
+		err := store.CreateUser(ctx, types.User{
+			ID: 100, Email: "Dancer@Example.com", Phone: "13800000001", Password: "hashed",
+			FirstName: "Li", LastName: "Lei", Role: types.RoleAdmin, Locale: "en",
+		})
+		if err != nil {
+			t.Fatalf("CreateUser: %v", err)
+		}
+
+		u, err := store.GetUserByPhone(ctx, "13800000001")
+		if err != nil {
+			t.Fatalf("GetUserByPhone: %v", err)
+		}
+		// ID、角色和语言由存储决定，不使用传入的值
+		if u.ID != 1 || u.Role != types.RoleUser || u.Locale != "" || u.Password != "hashed" || u.FirstName != "Li" {
+			t.Errorf("GetUserByPhone = %+v", u)
+		}
+		if u.CreatedAt.IsZero() {
+			t.Error("CreatedAt is not set")
+		}
+
+		byID, err := store.GetUserByID(ctx, u.ID)
+		if err != nil || byID.Phone != u.Phone {
+			t.Errorf("GetUserByID = %+v, %v", byID, err)
+		}
+		byEmail, err := store.GetUserByEmail(ctx, "dancer@example.com")
+		if err != nil || byEmail.ID != u.ID {
+			t.Errorf("GetUserByEmail (case-insensitive) = %+v, %v", byEmail, err)
+		}
+	})
+}
+
+func TestStoreErrors(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store types.UserStore) {
+		ctx := context.Background()
+		if err := store.CreateUser(ctx, types.User{Phone: "13800000001", Password: "x", FirstName: "a", LastName: "b"}); err != nil {
+			t.Fatal(err)
+		}
+
+		err := store.CreateUser(ctx, types.User{Phone: "13800000001", Password: "y", FirstName: "c", LastName: "d"})
+		if !errors.Is(err, types.ErrConflict) {
+			t.Errorf("CreateUser with a duplicate phone: err = %v, want ErrConflict", err)
+		}
+
+		lookups := map[string]func() error{
+			"GetUserByID":    func() error { _, err := store.GetUserByID(ctx, 42); return err },
+			"GetUserByPhone": func() error { _, err := store.GetUserByPhone(ctx, "13900000000"); return err },
+			"GetUserByEmail": func() error { _, err := store.GetUserByEmail(ctx, "nobody@example.com"); return err },
+		}
+		for name, lookup := range lookups {
+			if err := lookup(); !errors.Is(err, types.ErrNotFound) || err.Error() != "user not found" {
+				t.Errorf("%s of a missing user: err = %v, want %q", name, err, "user not found")
+			}
+		}
+	})
+}
+
+func TestStoreUpdateLocale(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store types.UserStore) {
+		ctx := context.Background()
+		if err := store.CreateUser(ctx, types.User{Phone: "13800000001", Password: "x", FirstName: "a", LastName: "b"}); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := store.UpdateLocale(ctx, 1, "en"); err != nil {
+			t.Fatalf("UpdateLocale: %v", err)
+		}
+		if u, _ := store.GetUserByID(ctx, 1); u.Locale != "en" {
+			t.Errorf("Locale = %q, want en", u.Locale)
+		}
+
+		// 用户不存在时不报错（调用方已经查到了用户）
+		if err := store.UpdateLocale(ctx, 42, "en"); err != nil {
+			t.Errorf("UpdateLocale of a missing user: %v", err)
+		}
+	})
+}
@@ -0,0 +1,31 @@
+package video
+
+import (
+	"context"
+
+	"github.com/Albert-tru/DanceMirror/types"
+)
+
+// CanView 判断用户是否可以查看视频（只读）：
+//  1. 视频所有者
+//  2. 视频被布置到用户所在的班级
+//  3. 视频是学生的练习录像，且其参考视频布置在该老师的班级中
+func CanView(ctx context.Context, classStore types.ClassStore, video *types.Video, userID int) (bool, error) {
+	if video.UserID == userID {
+		return true, nil
+	}
+	if classStore == nil {
+		return false, nil
+	}
+
+	assigned, err := classStore.IsVideoAssignedToUser(ctx, userID, video.ID)
+	if err != nil || assigned {
+		return assigned, err
+	}
+
+	if video.ReferenceID != 0 {
+		return classStore.IsTeacherOfAssignment(ctx, userID, video.UserID, video.ReferenceID)
+	}
+
+	return false, nil
+}
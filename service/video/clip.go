@@ -0,0 +1,168 @@
+package video
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/Albert-tru/DanceMirror/config"
+	"github.com/Albert-tru/DanceMirror/service/auth"
+	"github.com/Albert-tru/DanceMirror/service/job"
+	"github.com/Albert-tru/DanceMirror/service/quota"
+	"github.com/Albert-tru/DanceMirror/types"
+	"github.com/Albert-tru/DanceMirror/utils"
+)
+
+// handleCreateClip 从视频中截取一段（可裁剪画面）生成新视频。
+// 剪辑在后台任务中执行，返回 202 和任务，通过 GET /jobs/{id} 查询结果
+func (h *Handler) handleCreateClip(w http.ResponseWriter, r *http.Request) {
+	if h.transcoder == nil {
+		utils.WriteError(w, http.StatusServiceUnavailable, utils.NewError(utils.CodeUnavailable, "clipping is not available"))
+		return
+	}
+
+	source, ok := h.loadViewableVideo(w, r)
+	if !ok {
+		return
+	}
+
+	var payload types.CreateClipPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, utils.InvalidPayload(err))
+		return
+	}
+
+	// 按源文件的实际时长和分辨率检查剪辑范围
+	info, err := h.transcoder.Probe(r.Context(), source.FilePath)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if info.Duration > 0 && payload.End > info.Duration {
+		utils.WriteError(w, http.StatusBadRequest, utils.NewError(utils.CodeBadRequest, "end exceeds video duration (%.3f)", info.Duration))
+		return
+	}
+	if c := payload.Crop; c != nil && info.Width > 0 && info.Height > 0 {
+		if c.X+c.Width > info.Width || c.Y+c.Height > info.Height {
+			utils.WriteError(w, http.StatusBadRequest, utils.NewError(utils.CodeBadRequest, "crop exceeds video size (%dx%d)", info.Width, info.Height))
+			return
+		}
+	}
+
+	if payload.Title == "" {
+		payload.Title = fmt.Sprintf("%s (%s-%s)", source.Title, formatClock(payload.Start), formatClock(payload.End))
+	}
+
+	userID := auth.GetUserIDFromContext(r.Context())
+	j, err := h.jobs.Enqueue(r.Context(), types.JobClip, userID, source.ID, payload)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusAccepted, j)
+}
+
+// handleGetClips 获取当前用户从该视频剪辑出的视频
+func (h *Handler) handleGetClips(w http.ResponseWriter, r *http.Request) {
+	source, ok := h.loadViewableVideo(w, r)
+	if !ok {
+		return
+	}
+
+	userID := auth.GetUserIDFromContext(r.Context())
+	clips, err := h.store.GetClips(r.Context(), userID, source.ID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, clips)
+}
+
+// HandleClip 执行 types.JobClip 任务：剪出片段，按上传流程登记为发起用户的新视频，
+// 任务结果为 {"videoId": ...}
+func (h *Handler) HandleClip(ctx context.Context, j *types.Job) error {
+	if h.transcoder == nil {
+		return job.Permanent(fmt.Errorf("clipping is not available"))
+	}
+
+	source, err := h.store.GetVideoByID(ctx, j.VideoID)
+	if errors.Is(err, types.ErrNotFound) {
+		// 源视频已被删除
+		return job.Permanent(err)
+	}
+	if err != nil {
+		return err
+	}
+
+	var payload types.CreateClipPayload
+	if err := json.Unmarshal(j.Payload, &payload); err != nil {
+		return job.Permanent(fmt.Errorf("invalid clip payload: %v", err))
+	}
+
+	// 在上传目录中生成，完成后可以直接移动到正式位置
+	if err := os.MkdirAll(config.Envs.UploadDir, os.ModePerm); err != nil {
+		return err
+	}
+	workDir, err := os.MkdirTemp(config.Envs.UploadDir, ".clip-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workDir)
+
+	tmp := filepath.Join(workDir, "clip.mp4")
+	opts := types.ClipOptions{Start: payload.Start, End: payload.End, Crop: payload.Crop}
+	if err := h.transcoder.Clip(ctx, source.FilePath, tmp, opts); err != nil {
+		return err
+	}
+	info, err := h.transcoder.Probe(ctx, tmp)
+	if err != nil {
+		return err
+	}
+	contentHash, size, err := hashFile(tmp)
+	if err != nil {
+		return err
+	}
+
+	if err := h.quota.Reserve(ctx, j.UserID, size); err != nil {
+		if errors.Is(err, quota.ErrExceeded) {
+			return job.Permanent(err)
+		}
+		return err
+	}
+
+	video, created, err := h.ingest(ctx, tmp, &types.Video{
+		UserID:      j.UserID,
+		Title:       payload.Title,
+		Description: payload.Description,
+		FileSize:    size,
+		Duration:    info.Duration,
+		ContentHash: contentHash,
+		MimeType:    "video/mp4",
+		SourceID:    source.ID,
+	})
+	if err != nil || !created {
+		h.quota.Release(ctx, j.UserID, size)
+	}
+	if err != nil {
+		return err
+	}
+
+	j.Result, err = json.Marshal(map[string]int{"videoId": video.ID})
+	return err
+}
+
+// formatClock 把秒数格式化为 mm:ss
+func formatClock(seconds float64) string {
+	total := int(seconds)
+	return fmt.Sprintf("%02d:%02d", total/60, total%60)
+}
@@ -0,0 +1,99 @@
+package video_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Albert-tru/DanceMirror/cmd/api/apitest"
+	"github.com/Albert-tru/DanceMirror/types"
+)
+
+// waitJob 等待后台任务结束
+func waitJob(t *testing.T, s *apitest.Server, token string, id int) *types.Job {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		var j types.Job
+		s.Do(t, http.MethodGet, fmt.Sprintf("/api/v1/jobs/%d", id), token, nil, http.StatusOK, &j)
+		if j.Status == types.JobSucceeded || j.Status == types.JobFailed {
+			return &j
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %d is still %s", id, j.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// 剪辑范围和裁剪区域按探测到的时长和分辨率检查（Fake 转码：10 秒，1280x720）
+func TestClipValidation(t *testing.T) {
+	s := apitest.Start(t)
+	token := createUser(t, s, "13800000001")
+	video := upload(t, s, token, apitest.FakeMP4(1024))
+	path := fmt.Sprintf("/api/v1/videos/%d/clips", video.ID)
+
+	tests := []struct {
+		name    string
+		payload any
+		status  int
+	}{
+		{"whole video", types.CreateClipPayload{Start: 0, End: 10}, http.StatusAccepted},
+		{"end exceeds duration", types.CreateClipPayload{Start: 5, End: 10.5}, http.StatusBadRequest},
+		{"negative start", types.CreateClipPayload{Start: -1, End: 2}, http.StatusBadRequest},
+		{"end before start", types.CreateClipPayload{Start: 3, End: 2}, http.StatusBadRequest},
+		{"empty range", types.CreateClipPayload{Start: 2, End: 2}, http.StatusBadRequest},
+		{"crop inside the frame", types.CreateClipPayload{Start: 1, End: 2, Crop: &types.CropRect{X: 640, Y: 360, Width: 640, Height: 360}}, http.StatusAccepted},
+		{"crop too wide", types.CreateClipPayload{Start: 1, End: 2, Crop: &types.CropRect{X: 641, Y: 0, Width: 640, Height: 360}}, http.StatusBadRequest},
+		{"crop too tall", types.CreateClipPayload{Start: 1, End: 2, Crop: &types.CropRect{X: 0, Y: 361, Width: 640, Height: 360}}, http.StatusBadRequest},
+		{"crop too small", types.CreateClipPayload{Start: 1, End: 2, Crop: &types.CropRect{Width: 8, Height: 8}}, http.StatusBadRequest},
+		{"negative crop offset", types.CreateClipPayload{Start: 1, End: 2, Crop: &types.CropRect{X: -1, Width: 640, Height: 360}}, http.StatusBadRequest},
+		{"malformed body", "{", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s.Do(t, http.MethodPost, path, token, tt.payload, tt.status, nil)
+		})
+	}
+}
+
+// 能查看视频的用户可以剪辑，剪出的视频属于剪辑的人
+func TestClipAccess(t *testing.T) {
+	s := apitest.Start(t)
+	c := s.NewClassroom(t)
+	path := fmt.Sprintf("/api/v1/videos/%d/clips", c.Video.ID)
+	payload := types.CreateClipPayload{Start: 1, End: 3}
+
+	s.Do(t, http.MethodPost, path, c.Outsider, payload, http.StatusForbidden, nil)
+	s.Do(t, http.MethodGet, path, c.Outsider, nil, http.StatusForbidden, nil)
+	s.Do(t, http.MethodPost, "/api/v1/videos/999/clips", c.Student, payload, http.StatusNotFound, nil)
+	s.Do(t, http.MethodPost, "/api/v1/videos/abc/clips", c.Student, payload, http.StatusBadRequest, nil)
+
+	var j types.Job
+	s.Do(t, http.MethodPost, path, c.Student, payload, http.StatusAccepted, &j)
+	done := waitJob(t, s, c.Student, j.ID)
+	var result struct {
+		VideoID int `json:"videoId"`
+	}
+	if done.Status != types.JobSucceeded || json.Unmarshal(done.Result, &result) != nil {
+		t.Fatalf("clip job = %s %s %s", done.Status, done.Result, done.LastError)
+	}
+
+	var clip types.Video
+	s.Do(t, http.MethodGet, fmt.Sprintf("/api/v1/videos/%d", result.VideoID), c.Student, nil, http.StatusOK, &clip)
+	if clip.UserID != c.StudentID || clip.SourceID != c.Video.ID || clip.Title != "routine (00:01-00:03)" {
+		t.Errorf("clip = %+v, want the student's clip of video %d", clip, c.Video.ID)
+	}
+
+	// 剪辑列表只包含自己剪出的视频
+	var clips []*types.Video
+	s.Do(t, http.MethodGet, path, c.Student, nil, http.StatusOK, &clips)
+	if len(clips) != 1 || clips[0].ID != result.VideoID {
+		t.Errorf("student's clips = %+v, want video %d", clips, result.VideoID)
+	}
+	s.Do(t, http.MethodGet, path, c.Teacher, nil, http.StatusOK, &clips)
+	if len(clips) != 0 {
+		t.Errorf("teacher's clips = %d, want none", len(clips))
+	}
+}
@@ -0,0 +1,87 @@
+package video
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"time"
+
+	"github.com/Albert-tru/DanceMirror/types"
+)
+
+const (
+	deletePollInterval = 30 * time.Second
+	deleteBatchSize    = 50
+	deleteRetryBase    = time.Minute
+	deleteRetryMax     = 6 * time.Hour
+)
+
+// FileDeleter 后台删除 file_deletions 中登记的文件，失败后按指数退避重试。
+// 数据库记录和待删除文件在同一事务中写入，进程中途退出也不会漏删文件。
+type FileDeleter struct {
+	store types.FileDeletionStore
+}
+
+func NewFileDeleter(store types.FileDeletionStore) *FileDeleter {
+	return &FileDeleter{store: store}
+}
+
+// Run 定时删除到期的文件，直到 ctx 被取消
+func (d *FileDeleter) Run(ctx context.Context) {
+	ticker := time.NewTicker(deletePollInterval)
+	defer ticker.Stop()
+
+	for {
+		d.DeleteDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeleteDue 删除一批到期的文件
+func (d *FileDeleter) DeleteDue(ctx context.Context) {
+	deletions, err := d.store.GetDueFileDeletions(ctx, time.Now(), deleteBatchSize)
+	if err != nil {
+		log.Printf("files: failed to load pending deletions: %v", err)
+		return
+	}
+
+	for _, deletion := range deletions {
+		if ctx.Err() != nil {
+			return
+		}
+
+		// 文件已经不存在也算删除成功
+		err := os.Remove(deletion.FilePath)
+		if err == nil || errors.Is(err, fs.ErrNotExist) {
+			if err := d.store.CompleteFileDeletion(ctx, deletion.ID); err != nil {
+				log.Printf("files: failed to complete deletion %d: %v", deletion.ID, err)
+			}
+			continue
+		}
+
+		log.Printf("files: failed to delete %s (attempt %d): %v", deletion.FilePath, deletion.Attempts+1, err)
+		next := time.Now().Add(deleteBackoff(deletion.Attempts + 1))
+		if err := d.store.RetryFileDeletion(ctx, deletion.ID, err.Error(), next); err != nil {
+			log.Printf("files: failed to reschedule deletion %d: %v", deletion.ID, err)
+		}
+	}
+}
+
+// deleteBackoff 第 n 次失败后的等待时间：1 分钟起每次翻倍，最多 6 小时
+func deleteBackoff(attempts int) time.Duration {
+	delay := deleteRetryBase
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= deleteRetryMax {
+			return deleteRetryMax
+		}
+	}
+	return delay
+}
@@ -0,0 +1,98 @@
+package video
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+
+	"github.com/Albert-tru/DanceMirror/service/auth"
+	"github.com/Albert-tru/DanceMirror/service/transcode"
+	"github.com/Albert-tru/DanceMirror/types"
+	"github.com/Albert-tru/DanceMirror/utils"
+)
+
+// maxDerivedPerVideo 每个视频最多保留多少种派生输出。派生输出可以由任何能查看视频的人请求，
+// 不计入任何人的存储配额，用数量上限控制占用的空间
+const maxDerivedPerVideo = 6
+
+// handleCreateDerived 请求生成镜像/变速版本，供离线练习下载。
+// 相同参数的输出只生成一次：已完成时直接返回 200，否则返回 202 和处理中的输出，
+// 完成后通过 /videos/{id}/renditions/{kind}?download=1 下载
+func (h *Handler) handleCreateDerived(w http.ResponseWriter, r *http.Request) {
+	if h.transcoder == nil {
+		utils.WriteError(w, http.StatusServiceUnavailable, utils.NewError(utils.CodeUnavailable, "transcoding is not available"))
+		return
+	}
+
+	video, ok := h.loadViewableVideo(w, r)
+	if !ok {
+		return
+	}
+
+	var payload types.CreateDerivedPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, utils.InvalidPayload(err))
+		return
+	}
+
+	// 速度保留两位小数，避免 0.75 和 0.7500001 生成两份
+	opts := types.DerivedOptions{
+		Mirror: payload.Mirror,
+		Speed:  math.Round(payload.Speed*100) / 100,
+	}
+	kind := transcode.DerivedKind(opts)
+	if kind == "" {
+		utils.WriteError(w, http.StatusBadRequest, utils.NewError(utils.CodeBadRequest, "mirror or speed is required"))
+		return
+	}
+
+	rendition, err := h.renditions.GetRendition(r.Context(), video.ID, kind)
+	if err != nil && !errors.Is(err, types.ErrNotFound) {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if err == nil && rendition.Status != types.RenditionFailed {
+		status := http.StatusAccepted
+		if rendition.Status == types.RenditionReady {
+			status = http.StatusOK
+		}
+		utils.WriteJSON(w, status, rendition)
+		return
+	}
+
+	// 先登记为 pending，重复请求不会再次提交任务；数量检查和登记在同一个事务中
+	rendition = &types.Rendition{
+		VideoID: video.ID,
+		Kind:    kind,
+		Status:  types.RenditionPending,
+	}
+	saved, err := h.renditions.SaveDerivedRendition(r.Context(), rendition, maxDerivedPerVideo)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if !saved {
+		utils.WriteError(w, http.StatusConflict, utils.NewError(utils.CodeQuotaExceeded, "this video already has %d derived versions", maxDerivedPerVideo))
+		return
+	}
+
+	userID := auth.GetUserIDFromContext(r.Context())
+	if _, err := h.jobs.Enqueue(r.Context(), types.JobDerive, userID, video.ID, opts); err != nil {
+		// 标记为失败，下次请求时重新提交（请求已取消时也要标记）
+		rendition.Status = types.RenditionFailed
+		rendition.Error = err.Error()
+		if err := h.renditions.SaveRendition(context.WithoutCancel(r.Context()), rendition); err != nil {
+			log.Printf("video: failed to mark %s rendition of video %d as failed: %v", kind, video.ID, err)
+		}
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusAccepted, rendition)
+}
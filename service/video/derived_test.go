@@ -0,0 +1,91 @@
+package video_test
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/Albert-tru/DanceMirror/cmd/api/apitest"
+	"github.com/Albert-tru/DanceMirror/types"
+)
+
+// 每个视频最多 6 种派生输出，并发请求也不会超过
+func TestDerivedCap(t *testing.T) {
+	s := apitest.Start(t)
+	token := createUser(t, s, "13800000001")
+	video := upload(t, s, token, apitest.FakeMP4(1024))
+	path := fmt.Sprintf("/api/v1/videos/%d/derived", video.ID)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	statuses := map[int]int{}
+	for i := 0; i < 12; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := s.Request(http.MethodPost, path, token, types.CreateDerivedPayload{Speed: 0.25 + 0.05*float64(i)})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			resp.Body.Close()
+			mu.Lock()
+			statuses[resp.StatusCode]++
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	if statuses[http.StatusAccepted] != 6 || statuses[http.StatusConflict] != 6 {
+		t.Errorf("statuses = %v, want 6 accepted and 6 conflicts", statuses)
+	}
+
+	var renditions []*types.Rendition
+	s.Do(t, http.MethodGet, fmt.Sprintf("/api/v1/videos/%d/renditions", video.ID), token, nil, http.StatusOK, &renditions)
+	derived := 0
+	for _, r := range renditions {
+		if r.Kind != types.RenditionMP4 && r.Kind != types.RenditionHLS && r.Kind != types.RenditionThumbnails {
+			derived++
+		}
+	}
+	if derived != 6 {
+		t.Errorf("derived renditions = %d, want 6", derived)
+	}
+
+	// 上限之后新的参数被拒绝
+	s.Do(t, http.MethodPost, path, token, types.CreateDerivedPayload{Mirror: true}, http.StatusConflict, nil)
+}
+
+func TestDerivedValidation(t *testing.T) {
+	s := apitest.Start(t)
+	c := s.NewClassroom(t)
+	path := fmt.Sprintf("/api/v1/videos/%d/derived", c.Video.ID)
+
+	tests := []struct {
+		name    string
+		token   string
+		path    string
+		payload any
+		status  int
+	}{
+		{"outsider", c.Outsider, path, types.CreateDerivedPayload{Mirror: true}, http.StatusForbidden},
+		{"missing video", c.Student, "/api/v1/videos/999/derived", types.CreateDerivedPayload{Mirror: true}, http.StatusNotFound},
+		{"nothing to derive", c.Student, path, types.CreateDerivedPayload{Speed: 1}, http.StatusBadRequest},
+		{"too slow", c.Student, path, types.CreateDerivedPayload{Speed: 0.2}, http.StatusBadRequest},
+		{"too fast", c.Student, path, types.CreateDerivedPayload{Speed: 2.5}, http.StatusBadRequest},
+		{"class member", c.Student, path, types.CreateDerivedPayload{Mirror: true}, http.StatusAccepted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s.Do(t, http.MethodPost, tt.path, tt.token, tt.payload, tt.status, nil)
+		})
+	}
+
+	// 相同参数只生成一次，完成后直接返回
+	waitRendition(t, s, c.Teacher, c.Video.ID, "mirror")
+	var rendition types.Rendition
+	s.Do(t, http.MethodPost, path, c.Teacher, types.CreateDerivedPayload{Mirror: true, Speed: 1}, http.StatusOK, &rendition)
+	if rendition.Kind != "mirror" || rendition.Status != types.RenditionReady {
+		t.Errorf("rendition = %+v, want the ready mirror", rendition)
+	}
+}
@@ -0,0 +1,123 @@
+package video
+
+import (
+	"io"
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Albert-tru/DanceMirror/config"
+	"github.com/Albert-tru/DanceMirror/service/auth"
+	"github.com/Albert-tru/DanceMirror/service/transcode"
+	"github.com/Albert-tru/DanceMirror/types"
+	"github.com/Albert-tru/DanceMirror/utils"
+	"github.com/gorilla/mux"
+)
+
+const hlsPlaylistType = "application/vnd.apple.mpegurl"
+
+// HLS 码率目录和文件名只允许简单字符，防止路径穿越
+var hlsNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+(\.[A-Za-z0-9]+)?$`)
+
+// handleHLSMaster 返回 master 播放列表（与视频详情相同的权限检查），
+// 其中各码率播放列表的地址替换为带签名的链接
+func (h *Handler) handleHLSMaster(w http.ResponseWriter, r *http.Request) {
+	video, ok := h.loadViewableVideo(w, r)
+	if !ok {
+		return
+	}
+
+	rendition, ok := h.loadReadyRendition(w, r, video.ID, types.RenditionHLS)
+	if !ok {
+		return
+	}
+
+	// 相对地址以 master 所在目录为基准
+	base := strings.TrimSuffix(r.URL.Path, "master.m3u8")
+	h.servePlaylist(w, r, rendition.FilePath, base)
+}
+
+// handleHLSFile 返回某个码率的播放列表或分片，只接受签名链接；
+// 播放列表中的分片地址同样替换为签名链接
+func (h *Handler) handleHLSFile(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	variant, file := vars["variant"], vars["file"]
+	if !hlsNamePattern.MatchString(variant) || !hlsNamePattern.MatchString(file) {
+		utils.WriteError(w, http.StatusBadRequest, utils.NewError(utils.CodeBadRequest, "invalid hls path"))
+		return
+	}
+
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, utils.NewError(utils.CodeInvalidID, "invalid video id"))
+		return
+	}
+
+	// 视频进入回收站后签名链接随即失效
+	video, err := h.store.GetVideoByID(r.Context(), id)
+	if err != nil {
+		utils.WriteStoreError(w, err)
+		return
+	}
+	rendition, ok := h.loadReadyRendition(w, r, video.ID, types.RenditionHLS)
+	if !ok {
+		return
+	}
+
+	key := transcode.RenditionKey(video.ID, "hls/"+variant+"/"+file)
+	if strings.HasSuffix(file, ".m3u8") {
+		h.servePlaylist(w, r, key, path.Dir(r.URL.Path)+"/")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	h.serveStored(w, r, key, "video/mp2t", rendition.UpdatedAt)
+}
+
+// servePlaylist 读取播放列表，把其中的相对地址替换为 base 下的签名链接
+func (h *Handler) servePlaylist(w http.ResponseWriter, r *http.Request, key, base string) {
+	f, err := h.storage.Open(r.Context(), key)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, utils.NewError(utils.CodeNotFound, "playlist not found"))
+		return
+	}
+	defer f.Close()
+
+	body, err := io.ReadAll(f)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	expires := time.Now().Add(time.Duration(config.Envs.SignedURLTTL) * time.Second)
+	lines := strings.Split(string(body), "\n")
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines[i] = auth.SignURL(base+line, expires)
+	}
+
+	// 播放列表包含有时效的签名，不能被缓存
+	w.Header().Set("Content-Type", hlsPlaylistType)
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write([]byte(strings.Join(lines, "\n")))
+}
+
+// loadReadyRendition 读取已完成的转码输出，失败时已写入响应
+func (h *Handler) loadReadyRendition(w http.ResponseWriter, r *http.Request, videoID int, kind string) (*types.Rendition, bool) {
+	rendition, err := h.renditions.GetRendition(r.Context(), videoID, kind)
+	if err != nil {
+		utils.WriteStoreError(w, err)
+		return nil, false
+	}
+	if rendition.Status != types.RenditionReady {
+		utils.WriteError(w, http.StatusConflict, utils.NewError(utils.CodeConflict, "rendition is %s", rendition.Status))
+		return nil, false
+	}
+	return rendition, true
+}
@@ -0,0 +1,159 @@
+package video_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Albert-tru/DanceMirror/cmd/api/apitest"
+	"github.com/Albert-tru/DanceMirror/service/auth"
+	"github.com/Albert-tru/DanceMirror/types"
+)
+
+// waitRendition 等待后台任务生成 kind 类型的转码输出
+func waitRendition(t *testing.T, s *apitest.Server, token string, videoID int, kind string) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		var renditions []*types.Rendition
+		s.Do(t, http.MethodGet, fmt.Sprintf("/api/v1/videos/%d/renditions", videoID), token, nil, http.StatusOK, &renditions)
+		for _, r := range renditions {
+			if r.Kind == kind && r.Status == types.RenditionReady {
+				return
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s rendition of video %d is not ready", kind, videoID)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// get 不带 Authorization 请求 path（签名链接或 ?token= 鉴权），返回状态码、响应头和内容
+func get(t *testing.T, s *apitest.Server, path string) (int, http.Header, []byte) {
+	t.Helper()
+	resp, err := s.Client().Get(s.URL + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, resp.Header, body
+}
+
+// links 播放列表或轨道中的签名链接（去掉 #xywh= 片段）
+func links(body []byte) []string {
+	var list []string
+	for _, line := range strings.Split(string(body), "\n") {
+		if strings.Contains(line, "signature=") {
+			link, _, _ := strings.Cut(line, "#")
+			list = append(list, link)
+		}
+	}
+	return list
+}
+
+// tampered 篡改签名链接的各种方式，都应返回 403
+func tampered(t *testing.T, link string) map[string]string {
+	t.Helper()
+	u, err := url.Parse(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	sig, exp := q.Get("signature"), q.Get("expires")
+	flip := "0"
+	if sig[0] == '0' {
+		flip = "1"
+	}
+
+	with := func(path, exp, sig string) string {
+		v := url.Values{}
+		v.Set("expires", exp)
+		v.Set("signature", sig)
+		return path + "?" + v.Encode()
+	}
+	var later int64
+	fmt.Sscan(exp, &later)
+	return map[string]string{
+		"unsigned":           u.Path,
+		"modified signature": with(u.Path, exp, flip+sig[1:]),
+		"extended expiry":    with(u.Path, fmt.Sprint(later+3600), sig),
+		"invalid expiry":     with(u.Path, "soon", sig),
+		"other path":         with(strings.Replace(u.Path, "/videos/", "/videos/1", 1), exp, sig),
+		"expired":            auth.SignURL(u.Path, time.Now().Add(-time.Second)),
+	}
+}
+
+func TestHLS(t *testing.T) {
+	s := apitest.Start(t)
+	owner := createUser(t, s, "13800000001")
+	other := createUser(t, s, "13800000002")
+	content := apitest.FakeMP4(4096)
+	video := upload(t, s, owner, content)
+	waitRendition(t, s, owner, video.ID, types.RenditionHLS)
+	master := fmt.Sprintf("/api/v1/videos/%d/hls/master.m3u8", video.ID)
+
+	// master 播放列表和视频详情的权限相同
+	for token, want := range map[string]int{owner: http.StatusOK, other: http.StatusForbidden, "": http.StatusForbidden} {
+		if status, _, _ := get(t, s, master+"?token="+token); status != want {
+			t.Errorf("master with token %q = %d, want %d", token, status, want)
+		}
+	}
+
+	status, header, body := get(t, s, master+"?token="+owner)
+	if status != http.StatusOK || header.Get("Content-Type") != "application/vnd.apple.mpegurl" || header.Get("Cache-Control") != "no-store" {
+		t.Fatalf("master = %d %v", status, header)
+	}
+	variants := links(body)
+	if len(variants) == 0 {
+		t.Fatalf("master playlist has no signed variants:\n%s", body)
+	}
+
+	// 码率播放列表中的分片同样是签名链接，分片内容是源文件（Fake 转码）
+	status, _, body = get(t, s, variants[0])
+	segments := links(body)
+	if status != http.StatusOK || len(segments) != 2 {
+		t.Fatalf("variant playlist = %d:\n%s", status, body)
+	}
+	status, header, body = get(t, s, segments[0])
+	if status != http.StatusOK || header.Get("Content-Type") != "video/mp2t" || !bytes.Equal(body, content) {
+		t.Errorf("segment = %d %s, %d bytes", status, header.Get("Content-Type"), len(body))
+	}
+
+	for _, link := range []string{variants[0], segments[0]} {
+		for name, path := range tampered(t, link) {
+			if status, _, _ := get(t, s, path); status != http.StatusForbidden {
+				t.Errorf("%s %s = %d, want 403", name, path, status)
+			}
+		}
+	}
+
+	// 签名有效但路径不合法或文件不存在
+	base := fmt.Sprintf("/api/v1/videos/%d/hls/", video.ID)
+	expires := time.Now().Add(time.Minute)
+	invalid := map[string]int{
+		base + "720p/.ts":                          http.StatusBadRequest,
+		base + "720p/seg.a.ts":                     http.StatusBadRequest,
+		base + "720p/missing.ts":                   http.StatusNotFound,
+		"/api/v1/videos/999/hls/720p/seg_00000.ts": http.StatusNotFound,
+	}
+	for path, want := range invalid {
+		if status, _, _ := get(t, s, auth.SignURL(path, expires)); status != want {
+			t.Errorf("signed %s = %d, want %d", path, status, want)
+		}
+	}
+
+	// 视频进入回收站后已签发的链接失效
+	s.Do(t, http.MethodDelete, fmt.Sprintf("/api/v1/videos/%d", video.ID), owner, nil, http.StatusOK, nil)
+	if status, _, _ := get(t, s, segments[0]); status != http.StatusNotFound {
+		t.Errorf("segment of a trashed video = %d, want 404", status)
+	}
+}
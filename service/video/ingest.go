@@ -0,0 +1,104 @@
+package video
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Albert-tru/DanceMirror/config"
+	"github.com/Albert-tru/DanceMirror/types"
+)
+
+// ingest 把上传目录中的临时文件登记为视频（上传和剪辑共用）。
+// video 需要填好 UserID、ContentHash、FileSize、MimeType 等字段；
+// 同一用户已有相同内容时删除临时文件并返回已有视频，created 为 false。
+// 存储配额由调用方负责
+func (h *Handler) ingest(ctx context.Context, tmpPath string, video *types.Video) (result *types.Video, created bool, err error) {
+	// 同一用户重复上传相同内容，直接返回已有的视频
+	existing, err := h.store.GetVideoByHash(ctx, video.UserID, video.ContentHash)
+	if err == nil {
+		os.Remove(tmpPath)
+		return existing, false, nil
+	}
+	if !errors.Is(err, types.ErrNotFound) {
+		os.Remove(tmpPath)
+		return nil, false, err
+	}
+
+	fileName, err := newFileName(video.UserID, containerExt[video.MimeType])
+	if err != nil {
+		os.Remove(tmpPath)
+		return nil, false, err
+	}
+	filePath := filepath.Join(config.Envs.UploadDir, fileName)
+
+	// 移动到正式位置后登记文件；其他用户上传过相同内容时共用已有文件
+	if err := os.Rename(tmpPath, filePath); err != nil {
+		os.Remove(tmpPath)
+		return nil, false, err
+	}
+
+	storedPath, err := h.store.AcquireBlob(ctx, video.ContentHash, filePath, video.FileSize)
+	if err != nil {
+		os.Remove(filePath)
+		return nil, false, err
+	}
+	if storedPath != filePath {
+		os.Remove(filePath)
+		filePath = storedPath
+		fileName = filepath.Base(storedPath)
+	}
+
+	video.FilePath = filePath
+	video.FileName = fileName
+	if err := h.store.CreateVideo(ctx, video); err != nil {
+		// 如果数据库保存失败，释放文件引用（最后一个引用时由 FileDeleter 删除文件；
+		// 释放失败时文件会成为孤儿文件，由一致性检查发现）。请求已取消时也要释放
+		if err := h.store.ReleaseBlob(context.WithoutCancel(ctx), video.ContentHash); err != nil {
+			log.Printf("video: failed to release blob %s: %v", video.ContentHash, err)
+		}
+		return nil, false, err
+	}
+
+	h.events.Publish(video.UserID, types.EventVideoUploaded, video)
+
+	// 转码在后台进行，完成后推送 video.processed（视频已登记，请求取消时也要提交）
+	if _, err := h.jobs.Enqueue(context.WithoutCancel(ctx), types.JobTranscode, video.UserID, video.ID, nil); err != nil {
+		log.Printf("video: failed to enqueue transcoding for video %d: %v", video.ID, err)
+	}
+
+	return video, true, nil
+}
+
+// newFileName 生成唯一文件名（加随机后缀，避免同一秒内的上传互相覆盖）
+func newFileName(userID int, ext string) (string, error) {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d_%s_%s%s", userID, time.Now().Format("20060102_150405"), hex.EncodeToString(suffix), ext), nil
+}
+
+// hashFile 计算文件内容的 SHA-256 和大小
+func hashFile(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	hasher := sha256.New()
+	size, err := io.Copy(hasher, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), size, nil
+}
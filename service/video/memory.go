@@ -0,0 +1,368 @@
+package video
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Albert-tru/DanceMirror/types"
+)
+
+// MemoryStore 内存中的 types.VideoStore 和 types.FileDeletionStore 实现（用于测试），
+// 查询结果和错误与数据库实现一致，返回的都是副本。
+// 没有外键级联：删除视频不会删除练习记录、转码输出等关联数据
+type MemoryStore struct {
+	mu             sync.RWMutex
+	nextID         int
+	videos         map[int]*types.Video
+	blobs          map[string]*memoryBlob
+	nextDeletionID int
+	deletions      map[int]*types.FileDeletion
+}
+
+type memoryBlob struct {
+	filePath string
+	size     int64
+	refCount int
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		nextID:         1,
+		videos:         map[int]*types.Video{},
+		blobs:          map[string]*memoryBlob{},
+		nextDeletionID: 1,
+		deletions:      map[int]*types.FileDeletion{},
+	}
+}
+
+func (s *MemoryStore) GetVideoByID(ctx context.Context, id int) (*types.Video, error) {
+	return s.findVideo(func(v *types.Video) bool { return v.ID == id && v.DeletedAt == nil })
+}
+
+func (s *MemoryStore) GetVideos(ctx context.Context, userID int) ([]*types.Video, error) {
+	return s.filterVideos(newestFirst, func(v *types.Video) bool { return v.UserID == userID && v.DeletedAt == nil }), nil
+}
+
+func (s *MemoryStore) GetRecordings(ctx context.Context, userID, referenceID int) ([]*types.Video, error) {
+	return s.filterVideos(newestFirst, func(v *types.Video) bool {
+		return v.UserID == userID && v.ReferenceID == referenceID && v.DeletedAt == nil
+	}), nil
+}
+
+func (s *MemoryStore) GetClips(ctx context.Context, userID, sourceID int) ([]*types.Video, error) {
+	return s.filterVideos(newestFirst, func(v *types.Video) bool {
+		return v.UserID == userID && v.SourceID == sourceID && v.DeletedAt == nil
+	}), nil
+}
+
+func (s *MemoryStore) GetVideoByHash(ctx context.Context, userID int, hash string) (*types.Video, error) {
+	return s.findVideo(func(v *types.Video) bool {
+		return v.UserID == userID && v.ContentHash == hash && v.DeletedAt == nil
+	})
+}
+
+func (s *MemoryStore) GetTrashedVideos(ctx context.Context, userID int) ([]*types.Video, error) {
+	return s.filterVideos(recentlyDeletedFirst, func(v *types.Video) bool { return v.UserID == userID && v.DeletedAt != nil }), nil
+}
+
+func (s *MemoryStore) GetTrashedVideoByID(ctx context.Context, id int) (*types.Video, error) {
+	return s.findVideo(func(v *types.Video) bool { return v.ID == id && v.DeletedAt != nil })
+}
+
+func (s *MemoryStore) GetExpiredTrash(ctx context.Context, before time.Time, limit int) ([]*types.Video, error) {
+	videos := s.filterVideos(func(a, b *types.Video) bool { return a.DeletedAt.Before(*b.DeletedAt) }, func(v *types.Video) bool {
+		return v.DeletedAt != nil && v.DeletedAt.Before(before)
+	})
+	if len(videos) > limit {
+		videos = videos[:limit]
+	}
+	return videos, nil
+}
+
+func (s *MemoryStore) GetAllVideos(ctx context.Context) ([]*types.Video, error) {
+	return s.filterVideos(func(a, b *types.Video) bool { return a.ID < b.ID }, func(*types.Video) bool { return true }), nil
+}
+
+func (s *MemoryStore) CreateVideo(ctx context.Context, video *types.Video) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 和数据库一样只回填 ID，创建和更新时间只保存在记录中
+	video.ID = s.nextID
+	s.nextID++
+
+	stored := copyVideo(video)
+	stored.CreatedAt = time.Now().Truncate(time.Second)
+	stored.UpdatedAt = stored.CreatedAt
+	stored.DeletedAt = nil
+	stored.SyncOffset = nil
+	stored.SyncConfidence = 0
+	stored.Renditions = nil
+	s.videos[stored.ID] = stored
+	return nil
+}
+
+func (s *MemoryStore) UpdateVideo(ctx context.Context, video *types.Video) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if v, ok := s.videos[video.ID]; ok {
+		v.Title = video.Title
+		v.Description = video.Description
+		v.Duration = video.Duration
+		v.Thumbnail = video.Thumbnail
+		v.UpdatedAt = time.Now().Truncate(time.Second)
+	}
+	return nil
+}
+
+func (s *MemoryStore) UpdateSync(ctx context.Context, videoID int, offset, confidence float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if v, ok := s.videos[videoID]; ok {
+		v.SyncOffset = &offset
+		v.SyncConfidence = confidence
+		v.UpdatedAt = time.Now().Truncate(time.Second)
+	}
+	return nil
+}
+
+func (s *MemoryStore) DeleteVideo(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.deleteVideo(id, func(*types.Video) bool { return true })
+	return err
+}
+
+func (s *MemoryStore) TrashVideo(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if v, ok := s.videos[id]; ok && v.DeletedAt == nil {
+		now := time.Now().Truncate(time.Second)
+		v.DeletedAt = &now
+		v.UpdatedAt = now
+	}
+	return nil
+}
+
+func (s *MemoryStore) RestoreVideo(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if v, ok := s.videos[id]; ok {
+		v.DeletedAt = nil
+		v.UpdatedAt = time.Now().Truncate(time.Second)
+	}
+	return nil
+}
+
+func (s *MemoryStore) PurgeVideo(ctx context.Context, id int, before time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.deleteVideo(id, func(v *types.Video) bool { return v.DeletedAt != nil && v.DeletedAt.Before(before) })
+}
+
+// deleteVideo 删除符合条件的视频并释放文件，调用方持有写锁
+func (s *MemoryStore) deleteVideo(id int, match func(*types.Video) bool) (bool, error) {
+	v, ok := s.videos[id]
+	if !ok || !match(v) {
+		return false, nil
+	}
+
+	if v.ContentHash != "" {
+		if err := s.releaseBlob(v.ContentHash); err != nil {
+			return false, err
+		}
+	} else {
+		s.enqueueFileDeletion(v.FilePath)
+	}
+	delete(s.videos, id)
+	return true, nil
+}
+
+func (s *MemoryStore) AcquireBlob(ctx context.Context, hash, filePath string, size int64) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if blob, ok := s.blobs[hash]; ok {
+		blob.refCount++
+		return blob.filePath, nil
+	}
+
+	s.blobs[hash] = &memoryBlob{filePath: filePath, size: size, refCount: 1}
+	return filePath, nil
+}
+
+func (s *MemoryStore) ReleaseBlob(ctx context.Context, hash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.releaseBlob(hash)
+}
+
+// releaseBlob 减少引用计数，调用方持有写锁
+func (s *MemoryStore) releaseBlob(hash string) error {
+	blob, ok := s.blobs[hash]
+	if !ok {
+		return fmt.Errorf("blob %w", types.ErrNotFound)
+	}
+
+	if blob.refCount > 1 {
+		blob.refCount--
+		return nil
+	}
+
+	delete(s.blobs, hash)
+	s.enqueueFileDeletion(blob.filePath)
+	return nil
+}
+
+func (s *MemoryStore) GetKnownFilePaths(ctx context.Context) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	seen := map[string]bool{}
+	paths := []string{}
+	add := func(path string) {
+		if !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+	for _, v := range s.videos {
+		add(v.FilePath)
+	}
+	for _, blob := range s.blobs {
+		add(blob.filePath)
+	}
+	for _, d := range s.deletions {
+		add(d.FilePath)
+	}
+
+	return paths, nil
+}
+
+func (s *MemoryStore) EnqueueFileDeletion(ctx context.Context, filePath string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.enqueueFileDeletion(filePath)
+	return nil
+}
+
+// enqueueFileDeletion 登记待删除的文件，调用方持有写锁
+func (s *MemoryStore) enqueueFileDeletion(filePath string) {
+	now := time.Now().Truncate(time.Second)
+	s.deletions[s.nextDeletionID] = &types.FileDeletion{
+		ID:            s.nextDeletionID,
+		FilePath:      filePath,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}
+	s.nextDeletionID++
+}
+
+func (s *MemoryStore) GetDueFileDeletions(ctx context.Context, now time.Time, limit int) ([]*types.FileDeletion, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	deletions := []*types.FileDeletion{}
+	for _, d := range s.deletions {
+		if !d.NextAttemptAt.After(now) {
+			copied := *d
+			deletions = append(deletions, &copied)
+		}
+	}
+	sort.Slice(deletions, func(i, j int) bool {
+		if !deletions[i].NextAttemptAt.Equal(deletions[j].NextAttemptAt) {
+			return deletions[i].NextAttemptAt.Before(deletions[j].NextAttemptAt)
+		}
+		return deletions[i].ID < deletions[j].ID
+	})
+	if len(deletions) > limit {
+		deletions = deletions[:limit]
+	}
+
+	return deletions, nil
+}
+
+func (s *MemoryStore) CompleteFileDeletion(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.deletions, id)
+	return nil
+}
+
+func (s *MemoryStore) RetryFileDeletion(ctx context.Context, id int, lastError string, next time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if d, ok := s.deletions[id]; ok {
+		d.Attempts++
+		d.LastError = lastError
+		d.NextAttemptAt = next
+	}
+	return nil
+}
+
+// findVideo 返回 ID 最小的符合条件的视频
+func (s *MemoryStore) findVideo(match func(*types.Video) bool) (*types.Video, error) {
+	videos := s.filterVideos(func(a, b *types.Video) bool { return a.ID < b.ID }, match)
+	if len(videos) == 0 {
+		return nil, fmt.Errorf("video %w", types.ErrNotFound)
+	}
+	return videos[0], nil
+}
+
+func (s *MemoryStore) filterVideos(less func(a, b *types.Video) bool, match func(*types.Video) bool) []*types.Video {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	videos := []*types.Video{}
+	for _, v := range s.videos {
+		if match(v) {
+			videos = append(videos, copyVideo(v))
+		}
+	}
+	sort.Slice(videos, func(i, j int) bool { return less(videos[i], videos[j]) })
+	return videos
+}
+
+// newestFirst 按创建时间倒序（同一秒内按 ID 倒序）
+func newestFirst(a, b *types.Video) bool {
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.After(b.CreatedAt)
+	}
+	return a.ID > b.ID
+}
+
+// recentlyDeletedFirst 按移入回收站的时间倒序
+func recentlyDeletedFirst(a, b *types.Video) bool {
+	if !a.DeletedAt.Equal(*b.DeletedAt) {
+		return a.DeletedAt.After(*b.DeletedAt)
+	}
+	return a.ID > b.ID
+}
+
+// copyVideo 复制视频记录，指针字段也复制一份
+func copyVideo(v *types.Video) *types.Video {
+	copied := *v
+	if v.DeletedAt != nil {
+		deletedAt := *v.DeletedAt
+		copied.DeletedAt = &deletedAt
+	}
+	if v.SyncOffset != nil {
+		offset := *v.SyncOffset
+		copied.SyncOffset = &offset
+	}
+	copied.Renditions = nil
+	return &copied
+}
@@ -0,0 +1,82 @@
+package video
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Albert-tru/DanceMirror/service/quota"
+	"github.com/Albert-tru/DanceMirror/types"
+)
+
+const (
+	purgeInterval  = time.Hour
+	purgeBatchSize = 100
+)
+
+// Purger 后台清理回收站：彻底删除超过保留期的视频记录并释放存储配额，
+// 文件由 FileDeleter 删除
+type Purger struct {
+	store     types.VideoStore
+	quota     *quota.Manager
+	retention time.Duration
+}
+
+func NewPurger(store types.VideoStore, quota *quota.Manager, retention time.Duration) *Purger {
+	return &Purger{
+		store:     store,
+		quota:     quota,
+		retention: retention,
+	}
+}
+
+// Run 定时清理，直到 ctx 被取消
+func (p *Purger) Run(ctx context.Context) {
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+
+	for {
+		p.Purge(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Purge 清理一轮过期的视频，返回彻底删除的数量
+func (p *Purger) Purge(ctx context.Context) int {
+	before := time.Now().Add(-p.retention)
+	purged := 0
+
+	for ctx.Err() == nil {
+		videos, err := p.store.GetExpiredTrash(ctx, before, purgeBatchSize)
+		if err != nil {
+			log.Printf("trash: failed to load expired videos: %v", err)
+			return purged
+		}
+
+		for _, video := range videos {
+			ok, err := p.store.PurgeVideo(ctx, video.ID, before)
+			if err != nil {
+				log.Printf("trash: failed to purge video %d: %v", video.ID, err)
+				return purged
+			}
+			// 已被恢复
+			if !ok {
+				continue
+			}
+
+			p.quota.Release(ctx, video.UserID, video.FileSize)
+			purged++
+		}
+
+		if len(videos) < purgeBatchSize {
+			break
+		}
+	}
+
+	return purged
+}
@@ -0,0 +1,196 @@
+package video_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Albert-tru/DanceMirror/db/dbtest"
+	"github.com/Albert-tru/DanceMirror/service/quota"
+	"github.com/Albert-tru/DanceMirror/service/user"
+	"github.com/Albert-tru/DanceMirror/service/video"
+	"github.com/Albert-tru/DanceMirror/types"
+)
+
+// trash 回收站测试用的数据库：一个用户，存储配额记录和视频记录一致
+type trash struct {
+	db     *sql.DB
+	store  *video.Store
+	quota  *quota.Manager
+	usage  *quota.Store
+	userID int
+	dir    string
+}
+
+func newTrash(t *testing.T) *trash {
+	t.Helper()
+	database := dbtest.New(t)
+	usage := quota.NewStore(database)
+	tr := &trash{
+		db:     database,
+		store:  video.NewStore(database),
+		quota:  quota.NewManager(usage, user.NewStore(database)),
+		usage:  usage,
+		userID: dbtest.CreateUser(t, database, "13800000001"),
+		dir:    t.TempDir(),
+	}
+
+	// 不限配额，只检查用量
+	maxBytes, maxVideos := int64(quota.Unlimited), quota.Unlimited
+	if err := usage.SetQuotaOverride(context.Background(), &types.QuotaOverride{UserID: tr.userID, MaxBytes: &maxBytes, MaxVideos: &maxVideos}); err != nil {
+		t.Fatal(err)
+	}
+	return tr
+}
+
+// upload 像上传一样预占配额并保存视频，hash 不为空时共用同一个文件
+func (tr *trash) upload(t *testing.T, name, hash string, size int64) *types.Video {
+	t.Helper()
+	ctx := context.Background()
+	if err := tr.quota.Reserve(ctx, tr.userID, size); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(tr.dir, name)
+	if hash != "" {
+		var err error
+		if path, err = tr.store.AcquireBlob(ctx, hash, path, size); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(path, make([]byte, size), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	v := &types.Video{UserID: tr.userID, Title: name, FilePath: path, FileName: name, FileSize: size, ContentHash: hash, MimeType: "video/mp4"}
+	if err := tr.store.CreateVideo(ctx, v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+// trashDaysAgo 把视频移入回收站，删除时间改为 days 天前
+func (tr *trash) trashDaysAgo(t *testing.T, v *types.Video, days int) {
+	t.Helper()
+	if err := tr.store.TrashVideo(context.Background(), v.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tr.db.Exec("UPDATE videos SET deletedAt = datetime('now', ?) WHERE id = ?", fmt.Sprintf("-%d days", days), v.ID); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// checkUsage 配额中记录的用量
+func (tr *trash) checkUsage(t *testing.T, bytes int64, videos int) {
+	t.Helper()
+	usedBytes, videoCount, err := tr.usage.GetUsage(context.Background(), tr.userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usedBytes != bytes || videoCount != videos {
+		t.Errorf("usage = %d bytes, %d videos; want %d bytes, %d videos", usedBytes, videoCount, bytes, videos)
+	}
+}
+
+// checkFiles 执行一轮文件删除，检查哪些文件还在
+func (tr *trash) checkFiles(t *testing.T, exist map[string]bool) {
+	t.Helper()
+	video.NewFileDeleter(tr.store).DeleteDue(context.Background())
+	for name, want := range exist {
+		_, err := os.Stat(filepath.Join(tr.dir, name))
+		if got := err == nil; got != want {
+			t.Errorf("%s exists = %v, want %v", name, got, want)
+		}
+	}
+}
+
+// 只彻底删除超过保留期的视频，释放配额；共用的文件在最后一个引用释放后才删除
+func TestPurge(t *testing.T) {
+	tr := newTrash(t)
+	ctx := context.Background()
+	purger := video.NewPurger(tr.store, tr.quota, 7*24*time.Hour)
+
+	shared := tr.upload(t, "shared.mp4", "hash-shared", 100)
+	copied := tr.upload(t, "copy.mp4", "hash-shared", 100)
+	plain := tr.upload(t, "plain.mp4", "", 200)
+	recent := tr.upload(t, "recent.mp4", "", 300)
+	restored := tr.upload(t, "restored.mp4", "", 400)
+	kept := tr.upload(t, "kept.mp4", "", 500)
+	tr.checkUsage(t, 1600, 6)
+
+	tr.trashDaysAgo(t, shared, 8)
+	tr.trashDaysAgo(t, plain, 8)
+	tr.trashDaysAgo(t, recent, 6)
+	tr.trashDaysAgo(t, restored, 30)
+	if err := tr.store.RestoreVideo(ctx, restored.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := purger.Purge(ctx); n != 2 {
+		t.Errorf("Purge = %d, want 2", n)
+	}
+	for _, v := range []*types.Video{shared, plain} {
+		if _, err := tr.store.GetTrashedVideoByID(ctx, v.ID); !errors.Is(err, types.ErrNotFound) {
+			t.Errorf("video %s after purge: err = %v, want ErrNotFound", v.Title, err)
+		}
+	}
+	if _, err := tr.store.GetTrashedVideoByID(ctx, recent.ID); err != nil {
+		t.Errorf("video within the retention period: %v", err)
+	}
+	for _, v := range []*types.Video{copied, restored, kept} {
+		if _, err := tr.store.GetVideoByID(ctx, v.ID); err != nil {
+			t.Errorf("video %s: %v", v.Title, err)
+		}
+	}
+	tr.checkUsage(t, 1300, 4)
+	// copy.mp4 还引用着 shared.mp4 的文件
+	tr.checkFiles(t, map[string]bool{"shared.mp4": true, "plain.mp4": false, "recent.mp4": true, "restored.mp4": true})
+
+	// 再清理一次没有可删除的
+	if n := purger.Purge(ctx); n != 0 {
+		t.Errorf("second Purge = %d, want 0", n)
+	}
+
+	tr.trashDaysAgo(t, copied, 8)
+	if n := purger.Purge(ctx); n != 1 {
+		t.Errorf("Purge of the last reference = %d, want 1", n)
+	}
+	if err := tr.store.ReleaseBlob(ctx, "hash-shared"); !errors.Is(err, types.ErrNotFound) {
+		t.Errorf("ReleaseBlob after the last reference was purged: err = %v, want ErrNotFound", err)
+	}
+	tr.checkUsage(t, 1200, 3)
+	tr.checkFiles(t, map[string]bool{"shared.mp4": false, "kept.mp4": true})
+}
+
+// 超过一批的过期视频在一轮中全部清理
+func TestPurgeBatches(t *testing.T) {
+	tr := newTrash(t)
+	for i := 0; i < 120; i++ {
+		v := tr.upload(t, "video.mp4", "hash", 1)
+		tr.trashDaysAgo(t, v, 2)
+	}
+
+	if n := video.NewPurger(tr.store, tr.quota, 24*time.Hour).Purge(context.Background()); n != 120 {
+		t.Errorf("Purge = %d, want 120", n)
+	}
+	tr.checkUsage(t, 0, 0)
+	tr.checkFiles(t, map[string]bool{"video.mp4": false})
+}
+
+// 已取消的 ctx 不清理
+func TestPurgeCanceled(t *testing.T) {
+	tr := newTrash(t)
+	tr.trashDaysAgo(t, tr.upload(t, "old.mp4", "", 10), 2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if n := video.NewPurger(tr.store, tr.quota, 24*time.Hour).Purge(ctx); n != 0 {
+		t.Errorf("Purge with a canceled context = %d, want 0", n)
+	}
+	tr.checkUsage(t, 10, 1)
+}
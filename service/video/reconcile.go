@@ -0,0 +1,130 @@
+package video
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Albert-tru/DanceMirror/service/quota"
+	"github.com/Albert-tru/DanceMirror/service/storage"
+	"github.com/Albert-tru/DanceMirror/types"
+)
+
+// ReconcileReport 一致性检查的结果
+type ReconcileReport struct {
+	OrphanFiles   []string       `json:"orphanFiles"`  // 磁盘上有但没有任何记录引用的文件
+	MissingFiles  []*types.Video `json:"missingFiles"` // 文件已丢失的视频记录
+	Repaired      bool           `json:"repaired"`
+	SkippedRecent int            `json:"skippedRecent"` // 最近修改、可能仍在上传中的文件数
+}
+
+// Reconciler 对比上传目录和数据库记录，找出孤儿文件和丢失文件的视频
+type Reconciler struct {
+	store     types.VideoStore
+	files     types.FileDeletionStore
+	quota     *quota.Manager
+	uploadDir string
+	grace     time.Duration
+}
+
+// NewReconciler grace 内修改过的文件不算孤儿文件（上传过程中的临时文件）
+func NewReconciler(store types.VideoStore, files types.FileDeletionStore, quota *quota.Manager, uploadDir string, grace time.Duration) *Reconciler {
+	return &Reconciler{
+		store:     store,
+		files:     files,
+		quota:     quota,
+		uploadDir: uploadDir,
+		grace:     grace,
+	}
+}
+
+// Run 执行检查；repair 为 true 时把孤儿文件登记为待删除，并删除文件已丢失的视频记录
+func (r *Reconciler) Run(ctx context.Context, repair bool) (*ReconcileReport, error) {
+	report := &ReconcileReport{
+		OrphanFiles:  []string{},
+		MissingFiles: []*types.Video{},
+	}
+
+	// 先读取数据库，再扫描磁盘：扫描期间新上传的文件在 grace 内，不会被误判
+	known, err := r.store.GetKnownFilePaths(ctx)
+	if err != nil {
+		return nil, err
+	}
+	knownSet := make(map[string]struct{}, len(known))
+	for _, path := range known {
+		knownSet[absPath(path)] = struct{}{}
+	}
+
+	videos, err := r.store.GetAllVideos(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// 转码输出按视频存放在存储层的 renditions/<videoId> 下
+	for _, video := range videos {
+		knownSet[absPath(filepath.Join(r.uploadDir, storage.RenditionPrefix(video.ID)))] = struct{}{}
+	}
+
+	cutoff := time.Now().Add(-r.grace)
+	err = filepath.WalkDir(r.uploadDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if _, ok := knownSet[absPath(path)]; ok {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.ModTime().After(cutoff) {
+			report.SkippedRecent++
+			return nil
+		}
+		report.OrphanFiles = append(report.OrphanFiles, path)
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	for _, video := range videos {
+		if _, err := os.Stat(video.FilePath); os.IsNotExist(err) {
+			report.MissingFiles = append(report.MissingFiles, video)
+		}
+	}
+
+	if !repair {
+		return report, nil
+	}
+
+	for _, path := range report.OrphanFiles {
+		if err := r.files.EnqueueFileDeletion(ctx, path); err != nil {
+			return report, err
+		}
+	}
+	for _, video := range report.MissingFiles {
+		if err := r.store.DeleteVideo(ctx, video.ID); err != nil {
+			return report, err
+		}
+		r.quota.Release(ctx, video.UserID, video.FileSize)
+	}
+	report.Repaired = true
+
+	return report, nil
+}
+
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
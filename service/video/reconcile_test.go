@@ -0,0 +1,158 @@
+package video_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/Albert-tru/DanceMirror/service/storage"
+	"github.com/Albert-tru/DanceMirror/service/video"
+)
+
+// file 在上传目录中写入文件，old 为 true 时修改时间改为两小时前
+func (tr *trash) file(t *testing.T, name string, old bool) string {
+	t.Helper()
+	path := filepath.Join(tr.dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("data"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if old {
+		past := time.Now().Add(-2 * time.Hour)
+		if err := os.Chtimes(path, past, past); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return path
+}
+
+func TestReconcile(t *testing.T) {
+	tr := newTrash(t)
+	ctx := context.Background()
+
+	present := tr.upload(t, "present.mp4", "", 100)
+	tr.upload(t, "shared.mp4", "hash", 100)
+	missing := tr.upload(t, "missing.mp4", "", 200)
+	trashed := tr.upload(t, "trashed.mp4", "", 300)
+	tr.trashDaysAgo(t, trashed, 1)
+	if err := os.Remove(missing.FilePath); err != nil {
+		t.Fatal(err)
+	}
+	// 已登记待删除的文件不算孤儿
+	pending := tr.file(t, "pending.mp4", true)
+	if err := tr.store.EnqueueFileDeletion(ctx, pending); err != nil {
+		t.Fatal(err)
+	}
+	// 存在的视频的转码输出不算孤儿，已不存在的视频的算
+	tr.file(t, filepath.Join(storage.RenditionPrefix(present.ID), "720p.mp4"), true)
+	orphans := []string{
+		tr.file(t, "orphan.mp4", true),
+		tr.file(t, filepath.Join(storage.RenditionPrefix(999), "720p.mp4"), true),
+	}
+	sort.Strings(orphans)
+	tr.file(t, "uploading.mp4", false)
+
+	reconciler := video.NewReconciler(tr.store, tr.store, tr.quota, tr.dir, time.Hour)
+	check := func(repair bool) {
+		t.Helper()
+		report, err := reconciler.Run(ctx, repair)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sort.Strings(report.OrphanFiles)
+		if !equal(report.OrphanFiles, orphans) {
+			t.Errorf("orphan files = %v, want %v", report.OrphanFiles, orphans)
+		}
+		if !equal(videoIDs(report.MissingFiles), []int{missing.ID}) {
+			t.Errorf("missing files = %v, want video %d", videoIDs(report.MissingFiles), missing.ID)
+		}
+		if report.SkippedRecent != 1 || report.Repaired != repair {
+			t.Errorf("report = %+v, want 1 recent file skipped and repaired = %v", report, repair)
+		}
+	}
+
+	// 只检查时不做任何修改
+	check(false)
+	if due, _ := tr.store.GetDueFileDeletions(ctx, time.Now().Add(time.Hour), 10); !equal(filePaths(due), []string{pending}) {
+		t.Errorf("deletions after a dry run = %v, want only %s", filePaths(due), pending)
+	}
+	if _, err := tr.store.GetVideoByID(ctx, missing.ID); err != nil {
+		t.Errorf("video with a missing file after a dry run: %v", err)
+	}
+	tr.checkUsage(t, 700, 4)
+
+	check(true)
+	if _, err := tr.store.GetVideoByID(ctx, missing.ID); err == nil {
+		t.Error("video with a missing file was not deleted")
+	}
+	tr.checkUsage(t, 500, 3)
+	tr.checkFiles(t, map[string]bool{
+		"orphan.mp4":    false,
+		"pending.mp4":   false,
+		"present.mp4":   true,
+		"shared.mp4":    true,
+		"trashed.mp4":   true,
+		"uploading.mp4": true,
+		filepath.Join(storage.RenditionPrefix(present.ID), "720p.mp4"): true,
+		filepath.Join(storage.RenditionPrefix(999), "720p.mp4"):        false,
+	})
+
+	// 修复之后没有需要处理的
+	report, err := reconciler.Run(ctx, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.OrphanFiles) != 0 || len(report.MissingFiles) != 0 {
+		t.Errorf("report after repair = %+v, want nothing to repair", report)
+	}
+}
+
+// 上传目录不存在时没有孤儿文件，只报告丢失的文件
+func TestReconcileMissingDir(t *testing.T) {
+	tr := newTrash(t)
+	v := tr.upload(t, "video.mp4", "", 100)
+
+	reconciler := video.NewReconciler(tr.store, tr.store, tr.quota, filepath.Join(tr.dir, "missing"), time.Hour)
+	report, err := reconciler.Run(context.Background(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.OrphanFiles) != 0 || len(report.MissingFiles) != 0 {
+		t.Errorf("report = %+v, want nothing for video %d", report, v.ID)
+	}
+}
+
+// 删除失败的文件按退避时间重试，文件不存在算删除成功
+func TestFileDeleterRetry(t *testing.T) {
+	tr := newTrash(t)
+	ctx := context.Background()
+
+	// 非空目录无法删除
+	busy := filepath.Join(tr.dir, "busy")
+	if err := os.MkdirAll(filepath.Join(busy, "file"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{busy, filepath.Join(tr.dir, "missing.mp4")} {
+		if err := tr.store.EnqueueFileDeletion(ctx, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	video.NewFileDeleter(tr.store).DeleteDue(ctx)
+
+	if due, _ := tr.store.GetDueFileDeletions(ctx, time.Now(), 10); len(due) != 0 {
+		t.Errorf("due deletions = %v, want none until the retry", filePaths(due))
+	}
+	due, err := tr.store.GetDueFileDeletions(ctx, time.Now().Add(2*time.Minute), 10)
+	if err != nil || len(due) != 1 {
+		t.Fatalf("due deletions after a minute = %v, %v; want the directory", filePaths(due), err)
+	}
+	if d := due[0]; d.FilePath != busy || d.Attempts != 1 || d.LastError == "" {
+		t.Errorf("deletion = %+v, want one failed attempt on %s", d, busy)
+	}
+}
@@ -1,225 +1,492 @@
-package video
-
-import (
-	"fmt"
-	"io"
-	"net/http"
-	"os"
-	"path/filepath"
-	"strconv"
-	"time"
-
-	"github.com/Albert-tru/DanceMirror/config"
-	"github.com/Albert-tru/DanceMirror/service/auth"
-	"github.com/Albert-tru/DanceMirror/types"
-	"github.com/Albert-tru/DanceMirror/utils"
-	"github.com/gorilla/mux"
-)
-
-type Handler struct {
-	store     types.VideoStore
-	userStore types.UserStore
-}
-
-func NewHandler(store types.VideoStore, userStore types.UserStore) *Handler {
-	return &Handler{
-		store:     store,
-		userStore: userStore,
-	}
-}
-
-func (h *Handler) RegisterRoutes(router *mux.Router) {
-	// API 路由
-	router.HandleFunc("/videos", auth.WithJWTAuth(h.handleGetVideos, h.userStore)).Methods(http.MethodGet)
-	router.HandleFunc("/videos", auth.WithJWTAuth(h.handleUpload, h.userStore)).Methods(http.MethodPost)
-	router.HandleFunc("/videos/{id}", auth.WithJWTAuth(h.handleGetVideo, h.userStore)).Methods(http.MethodGet)
-	router.HandleFunc("/videos/{id}", auth.WithJWTAuth(h.handleDeleteVideo, h.userStore)).Methods(http.MethodDelete)
-
-	// 静态文件服务 - 提供上传的视频文件访问
-	router.PathPrefix("/uploads/").Handler(http.StripPrefix("/uploads/", http.FileServer(http.Dir(config.Envs.UploadDir))))
-}
-
-func (h *Handler) handleGetVideos(w http.ResponseWriter, r *http.Request) {
-	userID := auth.GetUserIDFromContext(r.Context())
-	if userID == -1 {
-		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("unauthorized"))
-		return
-	}
-
-	videos, err := h.store.GetVideos(userID)
-	if err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, err)
-		return
-	}
-
-	utils.WriteJSON(w, http.StatusOK, videos)
-}
-
-func (h *Handler) handleGetVideo(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	idStr := vars["id"]
-
-	id, err := strconv.Atoi(idStr)
-	if err != nil {
-		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid video id"))
-		return
-	}
-
-	video, err := h.store.GetVideoByID(id)
-	if err != nil {
-		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("video not found"))
-		return
-	}
-
-	// 验证用户权限
-	userID := auth.GetUserIDFromContext(r.Context())
-	if video.UserID != userID {
-		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("permission denied"))
-		return
-	}
-
-	utils.WriteJSON(w, http.StatusOK, video)
-}
-
-func (h *Handler) handleUpload(w http.ResponseWriter, r *http.Request) {
-	userID := auth.GetUserIDFromContext(r.Context())
-	if userID == -1 {
-		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("unauthorized"))
-		return
-	}
-
-	// 限制文件大小
-	r.Body = http.MaxBytesReader(w, r.Body, config.Envs.MaxUploadSize)
-	if err := r.ParseMultipartForm(config.Envs.MaxUploadSize); err != nil {
-		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("file too large"))
-		return
-	}
-
-	// 获取表单数据
-	title := r.FormValue("title")
-	description := r.FormValue("description")
-
-	if title == "" {
-		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("title is required"))
-		return
-	}
-
-	// 获取文件 - 支持 "video" 和 "file" 两个字段名
-	file, header, err := r.FormFile("video")
-	if err != nil {
-		// 尝试使用 "file" 字段名（用于录制上传）
-		file, header, err = r.FormFile("file")
-		if err != nil {
-			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("failed to get file: %v", err))
-			return
-		}
-	}
-	defer file.Close()
-
-	// 验证文件类型
-	contentType := header.Header.Get("Content-Type")
-	if !isValidVideoType(contentType) {
-		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid file type: %s", contentType))
-		return
-	}
-
-	// 生成唯一文件名
-	ext := filepath.Ext(header.Filename)
-	fileName := fmt.Sprintf("%d_%s%s", userID, time.Now().Format("20060102_150405"), ext)
-	filePath := filepath.Join(config.Envs.UploadDir, fileName)
-
-	// 确保上传目录存在
-	if err := os.MkdirAll(config.Envs.UploadDir, os.ModePerm); err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, err)
-		return
-	}
-
-	// 保存文件
-	dst, err := os.Create(filePath)
-	if err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, err)
-		return
-	}
-	defer dst.Close()
-
-	if _, err := io.Copy(dst, file); err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, err)
-		return
-	}
-
-	// 创建视频记录
-	video := &types.Video{
-		UserID:      userID,
-		Title:       title,
-		Description: description,
-		FilePath:    filePath,
-		FileName:    fileName,
-		FileSize:    header.Size,
-	}
-
-	if err := h.store.CreateVideo(video); err != nil {
-		// 如果数据库保存失败，删除已上传的文件
-		os.Remove(filePath)
-		utils.WriteError(w, http.StatusInternalServerError, err)
-		return
-	}
-
-	utils.WriteJSON(w, http.StatusCreated, video)
-}
-
-func (h *Handler) handleDeleteVideo(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	idStr := vars["id"]
-
-	id, err := strconv.Atoi(idStr)
-	if err != nil {
-		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid video id"))
-		return
-	}
-
-	video, err := h.store.GetVideoByID(id)
-	if err != nil {
-		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("video not found"))
-		return
-	}
-
-	// 验证用户权限
-	userID := auth.GetUserIDFromContext(r.Context())
-	if video.UserID != userID {
-		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("permission denied"))
-		return
-	}
-
-	// 删除数据库记录
-	if err := h.store.DeleteVideo(id); err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, err)
-		return
-	}
-
-	// 删除文件
-	if err := os.Remove(video.FilePath); err != nil {
-		// 记录错误但不返回失败（文件可能已被删除）
-		fmt.Printf("warning: failed to delete file %s: %v\n", video.FilePath, err)
-	}
-
-	utils.WriteJSON(w, http.StatusOK, map[string]string{"message": "video deleted successfully"})
-}
-
-func isValidVideoType(contentType string) bool {
-	validTypes := []string{
-		"video/mp4",
-		"video/mpeg",
-		"video/quicktime",
-		"video/x-msvideo",
-		"video/x-ms-wmv",
-		"video/webm",       // 添加 webm 支持
-		"video/x-matroska", // 添加 matroska 支持
-	}
-
-	for _, t := range validTypes {
-		if t == contentType {
-			return true
-		}
-	}
-	return false
-}
+package video
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/Albert-tru/DanceMirror/config"
+	"github.com/Albert-tru/DanceMirror/service/auth"
+	"github.com/Albert-tru/DanceMirror/service/quota"
+	"github.com/Albert-tru/DanceMirror/types"
+	"github.com/Albert-tru/DanceMirror/utils"
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	store      types.VideoStore
+	userStore  types.UserStore
+	classStore types.ClassStore
+	events     types.EventPublisher
+	quota      *quota.Manager
+	jobs       types.JobQueue
+	renditions types.RenditionStore
+	storage    types.Storage
+	transcoder types.Transcoder // 为 nil 时（没有 ffmpeg）不能剪辑
+}
+
+func NewHandler(store types.VideoStore, userStore types.UserStore, classStore types.ClassStore, events types.EventPublisher, quota *quota.Manager, jobs types.JobQueue, renditions types.RenditionStore, storage types.Storage, transcoder types.Transcoder) *Handler {
+	return &Handler{
+		store:      store,
+		userStore:  userStore,
+		classStore: classStore,
+		events:     events,
+		quota:      quota,
+		jobs:       jobs,
+		renditions: renditions,
+		storage:    storage,
+		transcoder: transcoder,
+	}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	// API 路由
+	router.HandleFunc("/videos", auth.WithJWTAuth(h.handleGetVideos, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/videos", auth.WithJWTAuth(h.handleUpload, h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/videos/{id}", auth.WithJWTAuth(h.handleGetVideo, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/videos/{id}", auth.WithJWTAuth(h.handleDeleteVideo, h.userStore)).Methods(http.MethodDelete)
+	router.HandleFunc("/videos/{id}/restore", auth.WithJWTAuth(h.handleRestoreVideo, h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/trash", auth.WithJWTAuth(h.handleGetTrash, h.userStore)).Methods(http.MethodGet)
+	// <video> 标签无法设置请求头，播放地址通过 ?token= 鉴权
+	router.HandleFunc("/videos/{id}/stream", auth.WithJWTAuthFromQuery(h.handleStreamVideo, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/videos/{id}/renditions", auth.WithJWTAuth(h.handleGetRenditions, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/videos/{id}/renditions/{kind}", auth.WithJWTAuthFromQuery(h.handleStreamRendition, h.userStore)).Methods(http.MethodGet)
+	// 封面图和拖动预览：封面和 WebVTT 轨道需要登录，轨道中的拼图使用签名链接
+	router.HandleFunc("/videos/{id}/thumbnail", auth.WithJWTAuthFromQuery(h.handleThumbnail, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/videos/{id}/thumbnails.vtt", auth.WithJWTAuthFromQuery(h.handleThumbnailsTrack, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/videos/{id}/thumbnails/{file}", auth.WithSignedURL(h.handleThumbnailFile)).Methods(http.MethodGet)
+	// 重新计算练习录像与参考视频的时间偏移（上传时会自动计算）
+	router.HandleFunc("/videos/{id}/sync", auth.WithJWTAuth(h.handleSyncRecording, h.userStore)).Methods(http.MethodPost)
+	// 镜像/变速版本（后台任务生成，相同参数复用）
+	router.HandleFunc("/videos/{id}/derived", auth.WithJWTAuth(h.handleCreateDerived, h.userStore)).Methods(http.MethodPost)
+	// 剪辑：截取片段生成新视频（后台任务）
+	router.HandleFunc("/videos/{id}/clips", auth.WithJWTAuth(h.handleCreateClip, h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/videos/{id}/clips", auth.WithJWTAuth(h.handleGetClips, h.userStore)).Methods(http.MethodGet)
+	// HLS：master 播放列表需要登录，其中的码率播放列表和分片使用签名链接
+	router.HandleFunc("/videos/{id}/hls/master.m3u8", auth.WithJWTAuthFromQuery(h.handleHLSMaster, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/videos/{id}/hls/{variant}/{file}", auth.WithSignedURL(h.handleHLSFile)).Methods(http.MethodGet)
+}
+
+func (h *Handler) handleGetVideos(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == -1 {
+		utils.WriteError(w, http.StatusUnauthorized, utils.NewError(utils.CodeUnauthorized, "unauthorized"))
+		return
+	}
+
+	videos, err := h.store.GetVideos(r.Context(), userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, videos)
+}
+
+func (h *Handler) handleGetVideo(w http.ResponseWriter, r *http.Request) {
+	video, ok := h.loadViewableVideo(w, r)
+	if !ok {
+		return
+	}
+
+	renditions, err := h.renditions.GetRenditions(r.Context(), video.ID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	video.Renditions = renditions
+
+	utils.WriteJSON(w, http.StatusOK, video)
+}
+
+// handleStreamVideo 播放视频文件，Content-Type 使用上传时识别出的类型，支持 Range 请求
+func (h *Handler) handleStreamVideo(w http.ResponseWriter, r *http.Request) {
+	video, ok := h.loadViewableVideo(w, r)
+	if !ok {
+		return
+	}
+
+	// 旧视频没有记录类型时，由 ServeContent 按扩展名推断
+	serveFile(w, r, video.FilePath, video.MimeType, video.UpdatedAt)
+}
+
+func (h *Handler) handleGetRenditions(w http.ResponseWriter, r *http.Request) {
+	video, ok := h.loadViewableVideo(w, r)
+	if !ok {
+		return
+	}
+
+	renditions, err := h.renditions.GetRenditions(r.Context(), video.ID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, renditions)
+}
+
+// handleStreamRendition 播放或下载转码输出（例如 /renditions/mp4），未完成时返回 409
+func (h *Handler) handleStreamRendition(w http.ResponseWriter, r *http.Request) {
+	video, ok := h.loadViewableVideo(w, r)
+	if !ok {
+		return
+	}
+
+	rendition, ok := h.loadReadyRendition(w, r, video.ID, mux.Vars(r)["kind"])
+	if !ok {
+		return
+	}
+
+	// ?download=1 时作为附件下载（离线练习）
+	if r.URL.Query().Get("download") != "" {
+		name := fmt.Sprintf("%s-%s%s", video.Title, rendition.Kind, path.Ext(rendition.FilePath))
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	}
+
+	h.serveStored(w, r, rendition.FilePath, rendition.MimeType, rendition.UpdatedAt)
+}
+
+// loadViewableVideo 读取路径中的视频并检查查看权限，失败时已写入响应
+func (h *Handler) loadViewableVideo(w http.ResponseWriter, r *http.Request) (*types.Video, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, utils.NewError(utils.CodeInvalidID, "invalid video id"))
+		return nil, false
+	}
+
+	video, err := h.store.GetVideoByID(r.Context(), id)
+	if err != nil {
+		utils.WriteStoreError(w, err)
+		return nil, false
+	}
+	// 回收站中的视频不能再查看和播放（GetVideoByID 本身不返回，这里再确认一次，文件要到彻底删除时才移除）
+	if video.DeletedAt != nil {
+		utils.WriteStoreError(w, fmt.Errorf("video %w", types.ErrNotFound))
+		return nil, false
+	}
+
+	// 验证用户权限（所有者或班级成员）
+	userID := auth.GetUserIDFromContext(r.Context())
+	ok, err := CanView(r.Context(), h.classStore, video, userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return nil, false
+	}
+	if !ok {
+		utils.WriteError(w, http.StatusForbidden, utils.NewError(utils.CodePermissionDenied, "permission denied"))
+		return nil, false
+	}
+
+	return video, true
+}
+
+// serveFile 输出文件，支持 Range 请求；mimeType 为空时按扩展名推断
+func serveFile(w http.ResponseWriter, r *http.Request, path, mimeType string, modTime time.Time) {
+	f, err := os.Open(path)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, utils.NewError(utils.CodeNotFound, "video file not found"))
+		return
+	}
+	defer f.Close()
+
+	if mimeType != "" {
+		w.Header().Set("Content-Type", mimeType)
+	}
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, filepath.Base(path), modTime, f)
+}
+
+// serveStored 输出存储层中的文件，支持 Range 请求
+func (h *Handler) serveStored(w http.ResponseWriter, r *http.Request, key, mimeType string, modTime time.Time) {
+	f, err := h.storage.Open(r.Context(), key)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, utils.NewError(utils.CodeNotFound, "file not found"))
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", mimeType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, path.Base(key), modTime, f)
+}
+
+func (h *Handler) handleUpload(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == -1 {
+		utils.WriteError(w, http.StatusUnauthorized, utils.NewError(utils.CodeUnauthorized, "unauthorized"))
+		return
+	}
+
+	// 限制文件大小
+	r.Body = http.MaxBytesReader(w, r.Body, config.Envs.MaxUploadSize)
+	if err := r.ParseMultipartForm(config.Envs.MaxUploadSize); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, utils.NewError(utils.CodePayloadTooLarge, "file too large"))
+		return
+	}
+
+	// 获取表单数据
+	title := r.FormValue("title")
+	description := r.FormValue("description")
+
+	if title == "" {
+		utils.WriteError(w, http.StatusBadRequest, utils.NewError(utils.CodeBadRequest, "title is required"))
+		return
+	}
+
+	// 练习录像可以关联一个参考视频（需要有查看权限）
+	var referenceID int
+	if ref := r.FormValue("referenceId"); ref != "" {
+		id, err := strconv.Atoi(ref)
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, utils.NewError(utils.CodeInvalidID, "invalid reference id"))
+			return
+		}
+
+		reference, err := h.store.GetVideoByID(r.Context(), id)
+		if errors.Is(err, types.ErrNotFound) {
+			utils.WriteError(w, http.StatusBadRequest, utils.NewError(utils.CodeBadRequest, "reference video not found"))
+			return
+		}
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+		ok, err := CanView(r.Context(), h.classStore, reference, userID)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if !ok {
+			utils.WriteError(w, http.StatusForbidden, utils.NewError(utils.CodePermissionDenied, "permission denied"))
+			return
+		}
+		referenceID = reference.ID
+	}
+
+	// 获取文件 - 支持 "video" 和 "file" 两个字段名
+	file, header, err := r.FormFile("video")
+	if err != nil {
+		// 尝试使用 "file" 字段名（用于录制上传）
+		file, header, err = r.FormFile("file")
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, utils.NewError(utils.CodeBadRequest, "failed to get file: %v", err))
+			return
+		}
+	}
+	defer file.Close()
+
+	// 根据文件头识别真实的容器格式，不信任客户端声明的类型
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		utils.WriteError(w, http.StatusBadRequest, utils.NewError(utils.CodeBadRequest, "failed to read file: %v", err))
+		return
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	mimeType := detectVideoType(head[:n])
+	if mimeType == "" {
+		utils.WriteError(w, http.StatusBadRequest, utils.NewError(utils.CodeUnsupportedMedia, "unsupported video format"))
+		return
+	}
+	contentType := header.Header.Get("Content-Type")
+	if !declaredTypeMatches(contentType, mimeType) {
+		utils.WriteError(w, http.StatusBadRequest, utils.NewError(utils.CodeUnsupportedMedia, "file type %s does not match content (%s)", contentType, mimeType))
+		return
+	}
+
+	// 写入文件前预占存储配额，之后任何一步失败都要释放
+	if err := h.quota.Reserve(r.Context(), userID, header.Size); err != nil {
+		if errors.Is(err, quota.ErrExceeded) {
+			utils.WriteError(w, http.StatusRequestEntityTooLarge, utils.NewError(utils.CodeQuotaExceeded, "storage quota exceeded"))
+			return
+		}
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	stored := false
+	defer func() {
+		if !stored {
+			h.quota.Release(r.Context(), userID, header.Size)
+		}
+	}()
+
+	// 确保上传目录存在
+	if err := os.MkdirAll(config.Envs.UploadDir, os.ModePerm); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// 先保存到临时文件，同时计算内容的 SHA-256
+	tmp, err := os.CreateTemp(config.Envs.UploadDir, ".upload-*")
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	hasher := sha256.New()
+	_, err = io.Copy(io.MultiWriter(tmp, hasher), file)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	video, created, err := h.ingest(r.Context(), tmp.Name(), &types.Video{
+		UserID:      userID,
+		Title:       title,
+		Description: description,
+		FileSize:    header.Size,
+		ReferenceID: referenceID,
+		ContentHash: hex.EncodeToString(hasher.Sum(nil)),
+		MimeType:    mimeType,
+	})
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if !created {
+		utils.WriteJSON(w, http.StatusOK, video)
+		return
+	}
+	stored = true
+
+	// 练习录像和参考视频的开始时间不同，在后台按音轨计算偏移（视频已登记，请求取消时也要提交）
+	if referenceID != 0 {
+		if _, err := h.jobs.Enqueue(context.WithoutCancel(r.Context()), types.JobSync, userID, video.ID, nil); err != nil {
+			log.Printf("video: failed to enqueue sync for video %d: %v", video.ID, err)
+		}
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, video)
+}
+
+func (h *Handler) handleDeleteVideo(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	idStr := vars["id"]
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, utils.NewError(utils.CodeInvalidID, "invalid video id"))
+		return
+	}
+
+	video, err := h.store.GetVideoByID(r.Context(), id)
+	if err != nil {
+		utils.WriteStoreError(w, err)
+		return
+	}
+
+	// 验证用户权限
+	userID := auth.GetUserIDFromContext(r.Context())
+	if video.UserID != userID {
+		utils.WriteError(w, http.StatusForbidden, utils.NewError(utils.CodePermissionDenied, "permission denied"))
+		return
+	}
+
+	// 移入回收站，文件和存储配额在保留期过后由 Purger 释放
+	if err := h.store.TrashVideo(r.Context(), id); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	h.events.Publish(userID, types.EventVideoDeleted, video)
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"message": "video moved to trash"})
+}
+
+func (h *Handler) handleGetTrash(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+
+	videos, err := h.store.GetTrashedVideos(r.Context(), userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, videos)
+}
+
+func (h *Handler) handleRestoreVideo(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, utils.NewError(utils.CodeInvalidID, "invalid video id"))
+		return
+	}
+
+	video, err := h.store.GetTrashedVideoByID(r.Context(), id)
+	if errors.Is(err, types.ErrNotFound) {
+		utils.WriteError(w, http.StatusNotFound, utils.NewError(utils.CodeNotFound, "video not found in trash"))
+		return
+	}
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	userID := auth.GetUserIDFromContext(r.Context())
+	if video.UserID != userID {
+		utils.WriteError(w, http.StatusForbidden, utils.NewError(utils.CodePermissionDenied, "permission denied"))
+		return
+	}
+
+	if err := h.store.RestoreVideo(r.Context(), id); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	video.DeletedAt = nil
+
+	h.events.Publish(userID, types.EventVideoRestored, video)
+
+	utils.WriteJSON(w, http.StatusOK, video)
+}
+
+// handleSyncRecording 提交对齐任务，返回 202 和任务；结果保存在视频的 syncOffset/syncConfidence
+func (h *Handler) handleSyncRecording(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, utils.NewError(utils.CodeInvalidID, "invalid video id"))
+		return
+	}
+
+	video, err := h.store.GetVideoByID(r.Context(), id)
+	if err != nil {
+		utils.WriteStoreError(w, err)
+		return
+	}
+
+	userID := auth.GetUserIDFromContext(r.Context())
+	if video.UserID != userID {
+		utils.WriteError(w, http.StatusForbidden, utils.NewError(utils.CodePermissionDenied, "permission denied"))
+		return
+	}
+	if video.ReferenceID == 0 {
+		utils.WriteError(w, http.StatusBadRequest, utils.NewError(utils.CodeBadRequest, "video has no reference video"))
+		return
+	}
+
+	j, err := h.jobs.Enqueue(r.Context(), types.JobSync, userID, video.ID, nil)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusAccepted, j)
+}
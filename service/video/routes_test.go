@@ -0,0 +1,207 @@
+package video_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Albert-tru/DanceMirror/cmd/api/apitest"
+	"github.com/Albert-tru/DanceMirror/types"
+)
+
+func createUser(t *testing.T, s *apitest.Server, phone string) string {
+	t.Helper()
+	_, token, err := s.CreateUser(phone, "password123", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func upload(t *testing.T, s *apitest.Server, token string, content []byte) *types.Video {
+	t.Helper()
+	resp, err := s.Upload(token, map[string]string{"title": "basics"}, "basics.mp4", content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var video types.Video
+	if err := apitest.DecodeJSON(resp, &video); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("upload = %d", resp.StatusCode)
+	}
+	return &video
+}
+
+func TestUpload(t *testing.T) {
+	apitest.EachStore(t, func(t *testing.T, s *apitest.Server) {
+		token := createUser(t, s, "13800000001")
+		duplicate := apitest.FakeMP4(1024)
+		first := upload(t, s, token, duplicate)
+
+		tests := []struct {
+			name    string
+			token   string
+			fields  map[string]string
+			content []byte
+			status  int
+		}{
+			{"ok", token, map[string]string{"title": "a", "description": "b"}, apitest.FakeMP4(1024), http.StatusCreated},
+			{"same content returns the existing video", token, map[string]string{"title": "again"}, duplicate, http.StatusOK},
+			{"missing title", token, map[string]string{}, apitest.FakeMP4(1024), http.StatusBadRequest},
+			{"not a video", token, map[string]string{"title": "a"}, []byte("hello, this is a text file"), http.StatusBadRequest},
+			{"audio only mp4", token, map[string]string{"title": "a"}, append([]byte("\x00\x00\x00\x18ftypM4A \x00\x00\x02\x00M4A isom"), make([]byte, 64)...), http.StatusBadRequest},
+			{"unknown reference", token, map[string]string{"title": "a", "referenceId": "999"}, apitest.FakeMP4(1024), http.StatusBadRequest},
+			{"not logged in", "", map[string]string{"title": "a"}, apitest.FakeMP4(1024), http.StatusForbidden},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				resp, err := s.Upload(tt.token, tt.fields, "a.mp4", tt.content)
+				if err != nil {
+					t.Fatal(err)
+				}
+				body, _ := io.ReadAll(resp.Body)
+				resp.Body.Close()
+				if resp.StatusCode != tt.status {
+					t.Fatalf("upload = %d %s, want %d", resp.StatusCode, body, tt.status)
+				}
+				if tt.status == http.StatusOK {
+					var video types.Video
+					json.Unmarshal(body, &video)
+					if video.ID != first.ID {
+						t.Errorf("duplicate upload returned video %d, want %d", video.ID, first.ID)
+					}
+				}
+			})
+		}
+	})
+}
+
+// 只有所有者可以查看、播放和删除视频；服务器上的文件路径不返回给客户端
+func TestOwnership(t *testing.T) {
+	apitest.EachStore(t, func(t *testing.T, s *apitest.Server) {
+		owner := createUser(t, s, "13800000001")
+		other := createUser(t, s, "13800000002")
+		video := upload(t, s, owner, apitest.FakeMP4(1024))
+
+		tests := []struct {
+			name   string
+			method string
+			path   string
+			token  string
+			status int
+		}{
+			{"owner gets", http.MethodGet, "/api/v1/videos/{id}", owner, http.StatusOK},
+			{"other gets", http.MethodGet, "/api/v1/videos/{id}", other, http.StatusForbidden},
+			{"owner streams", http.MethodGet, "/api/v1/videos/{id}/stream?token=" + owner, "", http.StatusOK},
+			{"other streams", http.MethodGet, "/api/v1/videos/{id}/stream?token=" + other, "", http.StatusForbidden},
+			{"other deletes", http.MethodDelete, "/api/v1/videos/{id}", other, http.StatusForbidden},
+			{"invalid id", http.MethodGet, "/api/v1/videos/abc", owner, http.StatusBadRequest},
+			{"missing video", http.MethodGet, "/api/v1/videos/999", owner, http.StatusNotFound},
+			{"public uploads directory", http.MethodGet, "/uploads/{id}", "", http.StatusNotFound},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				path := strings.ReplaceAll(tt.path, "{id}", strconv.Itoa(video.ID))
+				resp, err := s.Request(tt.method, path, tt.token, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				body, _ := io.ReadAll(resp.Body)
+				resp.Body.Close()
+				if resp.StatusCode != tt.status {
+					t.Errorf("%s %s = %d, want %d", tt.method, path, resp.StatusCode, tt.status)
+				}
+				if bytes.Contains(body, []byte(s.UploadDir)) || bytes.Contains(body, []byte(`"filePath"`)) {
+					t.Errorf("response exposes the file path: %s", body)
+				}
+			})
+		}
+
+		// 其他人的删除请求没有生效
+		resp, err := s.Request(http.MethodGet, fmt.Sprintf("/api/v1/videos/%d", video.ID), owner, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("video after another user's delete = %d, want 200", resp.StatusCode)
+		}
+	})
+}
+
+// 移入回收站的视频不能再查看和播放，恢复后可以
+func TestTrashedVideo(t *testing.T) {
+	s := apitest.Start(t)
+	token := createUser(t, s, "13800000001")
+	content := apitest.FakeMP4(4096)
+	video := upload(t, s, token, content)
+	stream := fmt.Sprintf("/api/v1/videos/%d/stream?token=%s", video.ID, token)
+
+	steps := []struct {
+		method string
+		path   string
+		status int
+	}{
+		{http.MethodGet, stream, http.StatusOK},
+		{http.MethodDelete, fmt.Sprintf("/api/v1/videos/%d", video.ID), http.StatusOK},
+		{http.MethodGet, stream, http.StatusNotFound},
+		{http.MethodGet, fmt.Sprintf("/api/v1/videos/%d", video.ID), http.StatusNotFound},
+		{http.MethodPost, fmt.Sprintf("/api/v1/videos/%d/restore", video.ID), http.StatusOK},
+		{http.MethodGet, stream, http.StatusOK},
+	}
+	for _, step := range steps {
+		resp, err := s.Request(step.method, step.path, token, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != step.status {
+			t.Fatalf("%s %s = %d %s, want %d", step.method, step.path, resp.StatusCode, body, step.status)
+		}
+		if step.path == stream && step.status == http.StatusOK {
+			if !bytes.Equal(body, content) || resp.Header.Get("Content-Type") != "video/mp4" {
+				t.Errorf("stream = %d bytes of %s, want the uploaded mp4", len(body), resp.Header.Get("Content-Type"))
+			}
+		}
+	}
+}
+
+// 只有上传者可以删除和恢复视频，回收站只列出自己的视频
+func TestTrashAccess(t *testing.T) {
+	s := apitest.Start(t)
+	owner := createUser(t, s, "13800000001")
+	other := createUser(t, s, "13800000002")
+	video := upload(t, s, owner, apitest.FakeMP4(1024))
+	path := fmt.Sprintf("/api/v1/videos/%d", video.ID)
+
+	s.Do(t, http.MethodDelete, path, other, nil, http.StatusForbidden, nil)
+	s.Do(t, http.MethodPost, path+"/restore", owner, nil, http.StatusNotFound, nil)
+	s.Do(t, http.MethodDelete, path, owner, nil, http.StatusOK, nil)
+	s.Do(t, http.MethodDelete, path, owner, nil, http.StatusNotFound, nil)
+
+	var trash []*types.Video
+	s.Do(t, http.MethodGet, "/api/v1/trash", other, nil, http.StatusOK, &trash)
+	if len(trash) != 0 {
+		t.Errorf("other user's trash = %d videos, want none", len(trash))
+	}
+	s.Do(t, http.MethodGet, "/api/v1/trash", owner, nil, http.StatusOK, &trash)
+	if len(trash) != 1 || trash[0].ID != video.ID || trash[0].DeletedAt == nil {
+		t.Errorf("trash = %+v, want the deleted video", trash)
+	}
+
+	s.Do(t, http.MethodPost, path+"/restore", other, nil, http.StatusForbidden, nil)
+	s.Do(t, http.MethodPost, "/api/v1/videos/abc/restore", owner, nil, http.StatusBadRequest, nil)
+	s.Do(t, http.MethodPost, path+"/restore", owner, nil, http.StatusOK, nil)
+	s.Do(t, http.MethodGet, "/api/v1/trash", owner, nil, http.StatusOK, &trash)
+	if len(trash) != 0 {
+		t.Errorf("trash after restore = %d videos, want none", len(trash))
+	}
+}
@@ -0,0 +1,175 @@
+package video
+
+import (
+	"bytes"
+	"encoding/binary"
+	"mime"
+	"strings"
+)
+
+// sniffLen 识别容器格式需要读取的文件头长度
+const sniffLen = 512
+
+// containerExt 识别出的容器类型对应的文件扩展名
+var containerExt = map[string]string{
+	"video/mp4":        ".mp4",
+	"video/quicktime":  ".mov",
+	"video/webm":       ".webm",
+	"video/x-matroska": ".mkv",
+	"video/x-msvideo":  ".avi",
+	"video/mpeg":       ".mpg",
+	"video/x-ms-wmv":   ".wmv",
+}
+
+// containerFamily 结构相同、客户端经常混用的类型视为一类（例如 .mov 被标成 video/mp4）
+var containerFamily = map[string]string{
+	"video/mp4":        "isobmff",
+	"video/quicktime":  "isobmff",
+	"video/webm":       "matroska",
+	"video/x-matroska": "matroska",
+	"video/x-msvideo":  "avi",
+	"video/mpeg":       "mpeg",
+	"video/x-ms-wmv":   "asf",
+}
+
+var (
+	ebmlMagic = []byte{0x1A, 0x45, 0xDF, 0xA3}
+	asfMagic  = []byte{0x30, 0x26, 0xB2, 0x75, 0x8E, 0x66, 0xCF, 0x11}
+	mpegPack  = []byte{0x00, 0x00, 0x01, 0xBA}
+	mpegSeq   = []byte{0x00, 0x00, 0x01, 0xB3}
+)
+
+// detectVideoType 根据文件头识别视频容器，无法识别时返回空字符串
+func detectVideoType(head []byte) string {
+	switch {
+	case len(head) >= 12 && string(head[4:8]) == "ftyp":
+		return isoBrandType(head)
+	case len(head) >= 8 && isQuickTimeFile(head):
+		// 老的 QuickTime 文件没有 ftyp，直接以 moov/mdat 等 atom 开头
+		return "video/quicktime"
+	case bytes.HasPrefix(head, ebmlMagic):
+		// EBML 头中的 DocType 区分 WebM 和 Matroska
+		if i := bytes.Index(head, []byte{0x42, 0x82}); i >= 0 && bytes.Contains(head[i:min(i+12, len(head))], []byte("webm")) {
+			return "video/webm"
+		}
+		return "video/x-matroska"
+	case len(head) >= 12 && string(head[0:4]) == "RIFF" && string(head[8:12]) == "AVI ":
+		return "video/x-msvideo"
+	case bytes.HasPrefix(head, mpegPack), bytes.HasPrefix(head, mpegSeq):
+		return "video/mpeg"
+	case bytes.HasPrefix(head, asfMagic):
+		return "video/x-ms-wmv"
+	}
+	return ""
+}
+
+// videoBrands ISO BMFF 中表示视频文件的品牌（3gp/3g2 按前缀匹配）
+var videoBrands = map[string]bool{
+	"isom": true, "iso2": true, "iso4": true, "iso5": true, "iso6": true,
+	"mp41": true, "mp42": true, "avc1": true, "qt  ": true,
+	"M4V ": true, "M4VH": true, "M4VP": true,
+}
+
+// nonVideoBrands 同样使用 ISO BMFF 的音频和图片格式。它们的兼容品牌里常有 isom、mp42，
+// 主品牌是这些时直接拒绝
+var nonVideoBrands = map[string]bool{
+	"M4A ": true, "M4B ": true, "M4P ": true, "F4A ": true, "F4B ": true,
+	"heic": true, "heix": true, "heim": true, "heis": true, "hevc": true, "hevx": true,
+	"mif1": true, "msf1": true, "avif": true, "avis": true, "crx ": true,
+}
+
+func isVideoBrand(brand string) bool {
+	return videoBrands[brand] || strings.HasPrefix(brand, "3gp") || strings.HasPrefix(brand, "3g2")
+}
+
+// isoBrandType 按 ftyp box 的主品牌和兼容品牌识别：主品牌是视频品牌，或者主品牌不是音频/图片
+// 且兼容品牌中有视频品牌。主品牌 "qt  " 是 QuickTime，其余按 MP4 处理
+func isoBrandType(head []byte) string {
+	// ftyp：size(4) "ftyp" 主品牌(4) 次版本(4) 兼容品牌(4*n)，整个 box 在文件头内
+	size := int(binary.BigEndian.Uint32(head[0:4]))
+	if size < 16 || size > len(head) || (size-16)%4 != 0 {
+		return ""
+	}
+
+	major := string(head[8:12])
+	video := isVideoBrand(major)
+	if !video && !nonVideoBrands[major] {
+		for i := 16; i < size; i += 4 {
+			if isVideoBrand(string(head[i : i+4])) {
+				video = true
+				break
+			}
+		}
+	}
+
+	switch {
+	case !video:
+		return ""
+	case major == "qt  ":
+		return "video/quicktime"
+	default:
+		return "video/mp4"
+	}
+}
+
+// isQuickTimeFile 文件头是否由一串合法的 QuickTime atom 组成：每个 atom 的类型已知、长度合法，
+// 在文件头范围内的下一个 atom 也是如此（只看类型名太容易碰上）
+func isQuickTimeFile(head []byte) bool {
+	for off := 0; off+8 <= len(head); {
+		size := uint64(binary.BigEndian.Uint32(head[off : off+4]))
+		name := string(head[off+4 : off+8])
+		if !isQuickTimeAtom(name) {
+			return false
+		}
+
+		switch size {
+		case 0:
+			// 长度 0 表示一直到文件末尾，只有 mdat 会这样
+			return name == "mdat"
+		case 1:
+			// 64 位长度紧跟在类型之后
+			if off+16 > len(head) {
+				return true
+			}
+			size = binary.BigEndian.Uint64(head[off+8 : off+16])
+			if size < 16 {
+				return false
+			}
+		default:
+			if size < 8 {
+				return false
+			}
+		}
+
+		if size >= uint64(len(head)-off) {
+			// 超出文件头的只能是 moov/mdat，free 等填充 atom 很短，后面的 atom 应该能看到
+			return name == "moov" || name == "mdat"
+		}
+		off += int(size)
+	}
+	// 剩下不到一个 atom 头
+	return true
+}
+
+func isQuickTimeAtom(name string) bool {
+	switch name {
+	case "moov", "mdat", "wide", "free", "skip", "pnot":
+		return true
+	}
+	return false
+}
+
+// declaredTypeMatches 检查客户端声明的 Content-Type 是否与识别结果一致。
+// 未声明或声明为 application/octet-stream 时以识别结果为准。
+func declaredTypeMatches(declared, detected string) bool {
+	mediaType, _, err := mime.ParseMediaType(declared)
+	if declared == "" || (err == nil && mediaType == "application/octet-stream") {
+		return true
+	}
+	if err != nil {
+		return false
+	}
+
+	family, ok := containerFamily[mediaType]
+	return ok && family == containerFamily[detected]
+}
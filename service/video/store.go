@@ -1,22 +1,68 @@
 package video
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"time"
 
+	"github.com/Albert-tru/DanceMirror/db/dialect"
+	"github.com/Albert-tru/DanceMirror/service/storage"
 	"github.com/Albert-tru/DanceMirror/types"
 )
 
+// videoColumns 和 scanRowIntoVideo 的顺序一致
+const videoColumns = "id, userId, title, description, filePath, fileName, fileSize, duration, thumbnail, " +
+	"createdAt, updatedAt, referenceId, contentHash, mimeType, deletedAt, sourceId, syncOffset, syncConfidence"
+
 type Store struct {
-	db *sql.DB
+	db      *sql.DB
+	dialect dialect.Dialect
 }
 
 func NewStore(db *sql.DB) *Store {
-	return &Store{db: db}
+	return &Store{db: db, dialect: dialect.Of(db)}
+}
+
+func (s *Store) GetVideoByID(ctx context.Context, id int) (*types.Video, error) {
+	return s.queryVideo(ctx, "SELECT "+videoColumns+" FROM videos WHERE id = ? AND deletedAt IS NULL", id)
+}
+
+func (s *Store) GetVideos(ctx context.Context, userID int) ([]*types.Video, error) {
+	return s.queryVideos(ctx, "SELECT "+videoColumns+" FROM videos WHERE userId = ? AND deletedAt IS NULL ORDER BY createdAt DESC", userID)
+}
+
+// GetRecordings 获取用户针对某个参考视频上传的练习录像
+func (s *Store) GetRecordings(ctx context.Context, userID, referenceID int) ([]*types.Video, error) {
+	return s.queryVideos(ctx, "SELECT "+videoColumns+" FROM videos WHERE userId = ? AND referenceId = ? AND deletedAt IS NULL ORDER BY createdAt DESC", userID, referenceID)
+}
+
+// GetClips 获取用户从某个源视频剪辑出的视频
+func (s *Store) GetClips(ctx context.Context, userID, sourceID int) ([]*types.Video, error) {
+	return s.queryVideos(ctx, "SELECT "+videoColumns+" FROM videos WHERE userId = ? AND sourceId = ? AND deletedAt IS NULL ORDER BY createdAt DESC", userID, sourceID)
+}
+
+// GetVideoByHash 查找用户已上传的相同内容的视频
+func (s *Store) GetVideoByHash(ctx context.Context, userID int, hash string) (*types.Video, error) {
+	return s.queryVideo(ctx, "SELECT "+videoColumns+" FROM videos WHERE userId = ? AND contentHash = ? AND deletedAt IS NULL ORDER BY id LIMIT 1", userID, hash)
+}
+
+// GetTrashedVideos 获取用户回收站中的视频，最近删除的在前
+func (s *Store) GetTrashedVideos(ctx context.Context, userID int) ([]*types.Video, error) {
+	return s.queryVideos(ctx, "SELECT "+videoColumns+" FROM videos WHERE userId = ? AND deletedAt IS NOT NULL ORDER BY deletedAt DESC", userID)
+}
+
+func (s *Store) GetTrashedVideoByID(ctx context.Context, id int) (*types.Video, error) {
+	return s.queryVideo(ctx, "SELECT "+videoColumns+" FROM videos WHERE id = ? AND deletedAt IS NOT NULL", id)
+}
+
+func (s *Store) GetExpiredTrash(ctx context.Context, before time.Time, limit int) ([]*types.Video, error) {
+	return s.queryVideos(ctx, "SELECT "+videoColumns+" FROM videos WHERE deletedAt IS NOT NULL AND deletedAt < ? ORDER BY deletedAt LIMIT ?", s.dialect.Time(before), limit)
 }
 
-func (s *Store) GetVideoByID(id int) (*types.Video, error) {
-	rows, err := s.db.Query("SELECT * FROM videos WHERE id = ?", id)
+func (s *Store) queryVideo(ctx context.Context, query string, args ...any) (*types.Video, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -31,14 +77,14 @@ func (s *Store) GetVideoByID(id int) (*types.Video, error) {
 	}
 
 	if v.ID == 0 {
-		return nil, fmt.Errorf("video not found")
+		return nil, fmt.Errorf("video %w", types.ErrNotFound)
 	}
 
 	return v, nil
 }
 
-func (s *Store) GetVideos(userID int) ([]*types.Video, error) {
-	rows, err := s.db.Query("SELECT * FROM videos WHERE userId = ? ORDER BY createdAt DESC", userID)
+func (s *Store) queryVideos(ctx context.Context, query string, args ...any) ([]*types.Video, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -56,12 +102,29 @@ func (s *Store) GetVideos(userID int) ([]*types.Video, error) {
 	return videos, nil
 }
 
-func (s *Store) CreateVideo(video *types.Video) error {
-	result, err := s.db.Exec(`
-INSERT INTO videos (userId, title, description, filePath, fileName, fileSize, duration, thumbnail) 
-VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+func (s *Store) CreateVideo(ctx context.Context, video *types.Video) error {
+	var referenceID sql.NullInt64
+	if video.ReferenceID != 0 {
+		referenceID = sql.NullInt64{Int64: int64(video.ReferenceID), Valid: true}
+	}
+	var contentHash sql.NullString
+	if video.ContentHash != "" {
+		contentHash = sql.NullString{String: video.ContentHash, Valid: true}
+	}
+	var mimeType sql.NullString
+	if video.MimeType != "" {
+		mimeType = sql.NullString{String: video.MimeType, Valid: true}
+	}
+	var sourceID sql.NullInt64
+	if video.SourceID != 0 {
+		sourceID = sql.NullInt64{Int64: int64(video.SourceID), Valid: true}
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+INSERT INTO videos (userId, title, description, filePath, fileName, fileSize, duration, thumbnail, referenceId, contentHash, mimeType, sourceId) 
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		video.UserID, video.Title, video.Description, video.FilePath,
-		video.FileName, video.FileSize, video.Duration, video.Thumbnail)
+		video.FileName, video.FileSize, video.Duration, video.Thumbnail, referenceID, contentHash, mimeType, sourceID)
 	if err != nil {
 		return err
 	}
@@ -75,17 +138,221 @@ VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
 	return nil
 }
 
-func (s *Store) UpdateVideo(video *types.Video) error {
-	_, err := s.db.Exec(`
+func (s *Store) UpdateVideo(ctx context.Context, video *types.Video) error {
+	_, err := s.db.ExecContext(ctx, `
 UPDATE videos 
-SET title = ?, description = ?, duration = ?, thumbnail = ?, updatedAt = NOW() 
+SET title = ?, description = ?, duration = ?, thumbnail = ?, updatedAt = CURRENT_TIMESTAMP 
 WHERE id = ?`,
 		video.Title, video.Description, video.Duration, video.Thumbnail, video.ID)
 	return err
 }
 
-func (s *Store) DeleteVideo(id int) error {
-	_, err := s.db.Exec("DELETE FROM videos WHERE id = ?", id)
+func (s *Store) UpdateSync(ctx context.Context, videoID int, offset, confidence float64) error {
+	_, err := s.db.ExecContext(ctx, "UPDATE videos SET syncOffset = ?, syncConfidence = ? WHERE id = ?", offset, confidence, videoID)
+	return err
+}
+
+func (s *Store) DeleteVideo(ctx context.Context, id int) error {
+	_, err := s.deleteVideo(ctx, "DELETE FROM videos WHERE id = ?", id)
+	return err
+}
+
+func (s *Store) TrashVideo(ctx context.Context, id int) error {
+	_, err := s.db.ExecContext(ctx, "UPDATE videos SET deletedAt = CURRENT_TIMESTAMP WHERE id = ? AND deletedAt IS NULL", id)
+	return err
+}
+
+func (s *Store) RestoreVideo(ctx context.Context, id int) error {
+	_, err := s.db.ExecContext(ctx, "UPDATE videos SET deletedAt = NULL WHERE id = ?", id)
+	return err
+}
+
+func (s *Store) PurgeVideo(ctx context.Context, id int, before time.Time) (bool, error) {
+	// 带上 deletedAt 条件，避免删除刚被恢复的视频
+	return s.deleteVideo(ctx, "DELETE FROM videos WHERE id = ? AND deletedAt IS NOT NULL AND deletedAt < ?", id, s.dialect.Time(before))
+}
+
+// deleteVideo 在一个事务中删除视频记录并释放文件：
+// 有内容哈希的减少引用计数，否则直接登记文件待删除
+func (s *Store) deleteVideo(ctx context.Context, query string, id int, args ...any) (bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var filePath string
+	var contentHash sql.NullString
+	err = tx.QueryRowContext(ctx, "SELECT filePath, contentHash FROM videos WHERE id = ?"+s.dialect.ForUpdate(), id).Scan(&filePath, &contentHash)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	// 转码输出的记录随视频记录一起删除（外键级联），文件由后台任务从存储层删除
+	var renditions int
+	if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM video_renditions WHERE videoId = ?", id).Scan(&renditions); err != nil {
+		return false, err
+	}
+
+	result, err := tx.ExecContext(ctx, query, append([]any{id}, args...)...)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if n == 0 {
+		return false, nil
+	}
+
+	if contentHash.Valid {
+		err = s.releaseBlob(ctx, tx, contentHash.String)
+	} else {
+		err = enqueueFileDeletion(ctx, tx, filePath)
+	}
+	if err != nil {
+		return false, err
+	}
+	if renditions > 0 {
+		payload, err := json.Marshal(storage.DeletePayload{Prefix: storage.RenditionPrefix(id)})
+		if err != nil {
+			return false, err
+		}
+		if _, err := tx.ExecContext(ctx, "INSERT INTO jobs (type, payload) VALUES (?, ?)", types.JobDeleteStorage, payload); err != nil {
+			return false, err
+		}
+	}
+
+	return true, tx.Commit()
+}
+
+func (s *Store) AcquireBlob(ctx context.Context, hash, filePath string, size int64) (string, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+INSERT INTO video_blobs (hash, filePath, fileSize, refCount) VALUES (?, ?, ?, 1)
+`+s.dialect.OnConflict("hash", "refCount = refCount + 1"), hash, filePath, size)
+	if err != nil {
+		return "", err
+	}
+
+	var path string
+	if err := tx.QueryRowContext(ctx, "SELECT filePath FROM video_blobs WHERE hash = ?", hash).Scan(&path); err != nil {
+		return "", err
+	}
+
+	return path, tx.Commit()
+}
+
+func (s *Store) ReleaseBlob(ctx context.Context, hash string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := s.releaseBlob(ctx, tx, hash); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *Store) releaseBlob(ctx context.Context, tx *sql.Tx, hash string) error {
+	var path string
+	var refCount int
+	err := tx.QueryRowContext(ctx, "SELECT filePath, refCount FROM video_blobs WHERE hash = ?"+s.dialect.ForUpdate(), hash).Scan(&path, &refCount)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("blob %w", types.ErrNotFound)
+	}
+	if err != nil {
+		return err
+	}
+
+	if refCount > 1 {
+		_, err = tx.ExecContext(ctx, "UPDATE video_blobs SET refCount = refCount - 1 WHERE hash = ?", hash)
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM video_blobs WHERE hash = ?", hash); err != nil {
+		return err
+	}
+	return enqueueFileDeletion(ctx, tx, path)
+}
+
+func (s *Store) GetAllVideos(ctx context.Context) ([]*types.Video, error) {
+	return s.queryVideos(ctx, "SELECT "+videoColumns+" FROM videos ORDER BY id")
+}
+
+func (s *Store) GetKnownFilePaths(ctx context.Context) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT filePath FROM videos
+UNION SELECT filePath FROM video_blobs
+UNION SELECT filePath FROM file_deletions`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	paths := []string{}
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+
+	return paths, rows.Err()
+}
+
+func (s *Store) EnqueueFileDeletion(ctx context.Context, filePath string) error {
+	_, err := s.db.ExecContext(ctx, "INSERT INTO file_deletions (filePath) VALUES (?)", filePath)
+	return err
+}
+
+func enqueueFileDeletion(ctx context.Context, tx *sql.Tx, filePath string) error {
+	_, err := tx.ExecContext(ctx, "INSERT INTO file_deletions (filePath) VALUES (?)", filePath)
+	return err
+}
+
+func (s *Store) GetDueFileDeletions(ctx context.Context, now time.Time, limit int) ([]*types.FileDeletion, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT id, filePath, attempts, lastError, nextAttemptAt, createdAt FROM file_deletions
+WHERE nextAttemptAt <= ? ORDER BY nextAttemptAt LIMIT ?`, s.dialect.Time(now), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deletions := []*types.FileDeletion{}
+	for rows.Next() {
+		d := new(types.FileDeletion)
+		var lastError sql.NullString
+		if err := rows.Scan(&d.ID, &d.FilePath, &d.Attempts, &lastError, &d.NextAttemptAt, &d.CreatedAt); err != nil {
+			return nil, err
+		}
+		d.LastError = lastError.String
+		deletions = append(deletions, d)
+	}
+
+	return deletions, nil
+}
+
+func (s *Store) CompleteFileDeletion(ctx context.Context, id int) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM file_deletions WHERE id = ?", id)
+	return err
+}
+
+func (s *Store) RetryFileDeletion(ctx context.Context, id int, lastError string, next time.Time) error {
+	_, err := s.db.ExecContext(ctx, "UPDATE file_deletions SET attempts = attempts + 1, lastError = ?, nextAttemptAt = ? WHERE id = ?", lastError, s.dialect.Time(next), id)
 	return err
 }
 
@@ -94,6 +361,12 @@ func scanRowIntoVideo(rows *sql.Rows) (*types.Video, error) {
 
 	var duration sql.NullFloat64
 	var thumbnail sql.NullString
+	var referenceID sql.NullInt64
+	var contentHash sql.NullString
+	var mimeType sql.NullString
+	var deletedAt sql.NullTime
+	var sourceID sql.NullInt64
+	var syncOffset, syncConfidence sql.NullFloat64
 	err := rows.Scan(
 		&video.ID,
 		&video.UserID,
@@ -106,6 +379,13 @@ func scanRowIntoVideo(rows *sql.Rows) (*types.Video, error) {
 		&thumbnail,
 		&video.CreatedAt,
 		&video.UpdatedAt,
+		&referenceID,
+		&contentHash,
+		&mimeType,
+		&deletedAt,
+		&sourceID,
+		&syncOffset,
+		&syncConfidence,
 	)
 	if err != nil {
 		return nil, err
@@ -117,6 +397,25 @@ func scanRowIntoVideo(rows *sql.Rows) (*types.Video, error) {
 	if thumbnail.Valid {
 		video.Thumbnail = thumbnail.String
 	}
+	if referenceID.Valid {
+		video.ReferenceID = int(referenceID.Int64)
+	}
+	if contentHash.Valid {
+		video.ContentHash = contentHash.String
+	}
+	if mimeType.Valid {
+		video.MimeType = mimeType.String
+	}
+	if deletedAt.Valid {
+		video.DeletedAt = &deletedAt.Time
+	}
+	if sourceID.Valid {
+		video.SourceID = int(sourceID.Int64)
+	}
+	if syncOffset.Valid {
+		video.SyncOffset = &syncOffset.Float64
+		video.SyncConfidence = syncConfidence.Float64
+	}
 
 	return video, nil
 }
@@ -0,0 +1,315 @@
+package video_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/Albert-tru/DanceMirror/db/dbtest"
+	"github.com/Albert-tru/DanceMirror/service/video"
+	"github.com/Albert-tru/DanceMirror/types"
+)
+
+// store 视频存储同时实现了待删除文件的队列
+type store interface {
+	types.VideoStore
+	types.FileDeletionStore
+}
+
+// 内存存储和数据库存储执行同样的操作，结果和错误应该一致。
+// 数据库中先建好 alice 和 bob 两个用户（视频的外键），内存存储不检查
+func forEachStore(t *testing.T, test func(t *testing.T, s store, alice, bob int)) {
+	impls := []struct {
+		name  string
+		store func(database *sql.DB) store
+	}{
+		{"memory", func(*sql.DB) store { return video.NewMemoryStore() }},
+		{"sqlite", func(database *sql.DB) store { return video.NewStore(database) }},
+	}
+	for _, impl := range impls {
+		t.Run(impl.name, func(t *testing.T) {
+			database := dbtest.New(t)
+			alice := dbtest.CreateUser(t, database, "13800000001")
+			bob := dbtest.CreateUser(t, database, "13800000002")
+			test(t, impl.store(database), alice, bob)
+		})
+	}
+}
+
+func createVideo(t *testing.T, s store, userID int, path, hash string) *types.Video {
+	t.Helper()
+	v := &types.Video{UserID: userID, Title: path, FilePath: path, FileName: path, FileSize: 10, ContentHash: hash, MimeType: "video/mp4"}
+	if err := s.CreateVideo(context.Background(), v); err != nil {
+		t.Fatalf("CreateVideo: %v", err)
+	}
+	return v
+}
+
+func videoIDs(videos []*types.Video) []int {
+	ids := []int{}
+	for _, v := range videos {
+		ids = append(ids, v.ID)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+func filePaths(deletions []*types.FileDeletion) []string {
+	paths := []string{}
+	for _, d := range deletions {
+		paths = append(paths, d.FilePath)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func equal[T comparable](a, b []T) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestStoreCreateAndQuery(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s store, alice, bob int) {
+		ctx := context.Background()
+		ref := createVideo(t, s, alice, "ref.mp4", "hash-ref")
+		rec := &types.Video{UserID: alice, Title: "rec", FilePath: "rec.mp4", FileName: "rec.mp4", ReferenceID: ref.ID}
+		if err := s.CreateVideo(ctx, rec); err != nil {
+			t.Fatal(err)
+		}
+		clip := &types.Video{UserID: alice, Title: "clip", FilePath: "clip.mp4", FileName: "clip.mp4", SourceID: ref.ID}
+		if err := s.CreateVideo(ctx, clip); err != nil {
+			t.Fatal(err)
+		}
+		createVideo(t, s, bob, "bob.mp4", "hash-ref")
+
+		got, err := s.GetVideoByID(ctx, ref.ID)
+		if err != nil {
+			t.Fatalf("GetVideoByID: %v", err)
+		}
+		if got.UserID != alice || got.FilePath != "ref.mp4" || got.ContentHash != "hash-ref" || got.MimeType != "video/mp4" ||
+			got.DeletedAt != nil || got.SyncOffset != nil || got.CreatedAt.IsZero() {
+			t.Errorf("GetVideoByID = %+v", got)
+		}
+
+		queries := []struct {
+			name  string
+			query func() ([]*types.Video, error)
+			want  []int
+		}{
+			{"GetVideos", func() ([]*types.Video, error) { return s.GetVideos(ctx, alice) }, []int{ref.ID, rec.ID, clip.ID}},
+			{"GetRecordings", func() ([]*types.Video, error) { return s.GetRecordings(ctx, alice, ref.ID) }, []int{rec.ID}},
+			{"GetClips", func() ([]*types.Video, error) { return s.GetClips(ctx, alice, ref.ID) }, []int{clip.ID}},
+			{"GetVideos of another user", func() ([]*types.Video, error) { return s.GetVideos(ctx, bob) }, []int{4}},
+			{"GetAllVideos", func() ([]*types.Video, error) { return s.GetAllVideos(ctx) }, []int{1, 2, 3, 4}},
+		}
+		for _, q := range queries {
+			videos, err := q.query()
+			if err != nil || !equal(videoIDs(videos), q.want) {
+				t.Errorf("%s = %v, %v; want %v", q.name, videoIDs(videos), err, q.want)
+			}
+		}
+
+		// 内容哈希按用户区分
+		byHash, err := s.GetVideoByHash(ctx, bob, "hash-ref")
+		if err != nil || byHash.UserID != bob {
+			t.Errorf("GetVideoByHash = %+v, %v", byHash, err)
+		}
+		if _, err := s.GetVideoByHash(ctx, alice, "hash-none"); !errors.Is(err, types.ErrNotFound) {
+			t.Errorf("GetVideoByHash of a missing hash: err = %v, want ErrNotFound", err)
+		}
+		if _, err := s.GetVideoByID(ctx, 42); !errors.Is(err, types.ErrNotFound) || err.Error() != "video not found" {
+			t.Errorf("GetVideoByID of a missing video: err = %v, want %q", err, "video not found")
+		}
+	})
+}
+
+func TestStoreUpdate(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s store, alice, bob int) {
+		ctx := context.Background()
+		v := createVideo(t, s, alice, "a.mp4", "")
+
+		v.Title, v.Description, v.Duration, v.Thumbnail = "new", "desc", 12.5, "thumbs/1.jpg"
+		v.FilePath = "ignored.mp4"
+		if err := s.UpdateVideo(ctx, v); err != nil {
+			t.Fatalf("UpdateVideo: %v", err)
+		}
+		if err := s.UpdateSync(ctx, v.ID, 1.5, 0.8); err != nil {
+			t.Fatalf("UpdateSync: %v", err)
+		}
+
+		got, err := s.GetVideoByID(ctx, v.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Title != "new" || got.Description != "desc" || got.Duration != 12.5 || got.Thumbnail != "thumbs/1.jpg" || got.FilePath != "a.mp4" {
+			t.Errorf("after UpdateVideo = %+v", got)
+		}
+		if got.SyncOffset == nil || *got.SyncOffset != 1.5 || got.SyncConfidence != 0.8 {
+			t.Errorf("after UpdateSync offset = %v, confidence = %v", got.SyncOffset, got.SyncConfidence)
+		}
+
+		// 返回的是副本
+		got.Title = "changed"
+		if again, _ := s.GetVideoByID(ctx, v.ID); again.Title != "new" {
+			t.Errorf("modifying a returned video changed the store: %q", again.Title)
+		}
+	})
+}
+
+func TestStoreTrash(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s store, alice, bob int) {
+		ctx := context.Background()
+		kept := createVideo(t, s, alice, "kept.mp4", "")
+		trashed := createVideo(t, s, alice, "trashed.mp4", "")
+
+		if err := s.TrashVideo(ctx, trashed.ID); err != nil {
+			t.Fatalf("TrashVideo: %v", err)
+		}
+		if _, err := s.GetVideoByID(ctx, trashed.ID); !errors.Is(err, types.ErrNotFound) {
+			t.Errorf("GetVideoByID of a trashed video: err = %v, want ErrNotFound", err)
+		}
+		if videos, _ := s.GetVideos(ctx, alice); !equal(videoIDs(videos), []int{kept.ID}) {
+			t.Errorf("GetVideos = %v, want only %d", videoIDs(videos), kept.ID)
+		}
+		if videos, _ := s.GetTrashedVideos(ctx, alice); !equal(videoIDs(videos), []int{trashed.ID}) {
+			t.Errorf("GetTrashedVideos = %v, want %d", videoIDs(videos), trashed.ID)
+		}
+		got, err := s.GetTrashedVideoByID(ctx, trashed.ID)
+		if err != nil || got.DeletedAt == nil {
+			t.Errorf("GetTrashedVideoByID = %+v, %v", got, err)
+		}
+		if _, err := s.GetTrashedVideoByID(ctx, kept.ID); !errors.Is(err, types.ErrNotFound) {
+			t.Errorf("GetTrashedVideoByID of a video not in the trash: err = %v, want ErrNotFound", err)
+		}
+
+		// 还没过期的不会被彻底删除
+		if purged, err := s.PurgeVideo(ctx, trashed.ID, time.Now().Add(-time.Hour)); err != nil || purged {
+			t.Errorf("PurgeVideo before expiry = %v, %v; want false", purged, err)
+		}
+		if expired, _ := s.GetExpiredTrash(ctx, time.Now().Add(time.Hour), 10); !equal(videoIDs(expired), []int{trashed.ID}) {
+			t.Errorf("GetExpiredTrash = %v, want %d", videoIDs(expired), trashed.ID)
+		}
+
+		if err := s.RestoreVideo(ctx, trashed.ID); err != nil {
+			t.Fatalf("RestoreVideo: %v", err)
+		}
+		if _, err := s.GetVideoByID(ctx, trashed.ID); err != nil {
+			t.Errorf("GetVideoByID after restore: %v", err)
+		}
+		// 已恢复的视频不会被彻底删除
+		if purged, err := s.PurgeVideo(ctx, trashed.ID, time.Now().Add(time.Hour)); err != nil || purged {
+			t.Errorf("PurgeVideo of a restored video = %v, %v; want false", purged, err)
+		}
+
+		if err := s.TrashVideo(ctx, trashed.ID); err != nil {
+			t.Fatal(err)
+		}
+		if purged, err := s.PurgeVideo(ctx, trashed.ID, time.Now().Add(time.Hour)); err != nil || !purged {
+			t.Errorf("PurgeVideo of an expired video = %v, %v; want true", purged, err)
+		}
+		if videos, _ := s.GetAllVideos(ctx); !equal(videoIDs(videos), []int{kept.ID}) {
+			t.Errorf("GetAllVideos after purge = %v, want %d", videoIDs(videos), kept.ID)
+		}
+	})
+}
+
+func TestStoreBlobs(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s store, alice, bob int) {
+		ctx := context.Background()
+		later := time.Now().Add(time.Hour)
+
+		// 相同内容的文件只保存一份
+		path, err := s.AcquireBlob(ctx, "hash-a", "a1.mp4", 10)
+		if err != nil || path != "a1.mp4" {
+			t.Fatalf("AcquireBlob = %q, %v; want a1.mp4", path, err)
+		}
+		if path, err := s.AcquireBlob(ctx, "hash-a", "a2.mp4", 10); err != nil || path != "a1.mp4" {
+			t.Fatalf("AcquireBlob of an existing hash = %q, %v; want a1.mp4", path, err)
+		}
+		first := createVideo(t, s, alice, "a1.mp4", "hash-a")
+		second := createVideo(t, s, bob, "a1.mp4", "hash-a")
+		plain := createVideo(t, s, alice, "plain.mp4", "")
+
+		// 还有引用时不删除文件
+		if err := s.DeleteVideo(ctx, first.ID); err != nil {
+			t.Fatalf("DeleteVideo: %v", err)
+		}
+		if due, _ := s.GetDueFileDeletions(ctx, later, 10); len(due) != 0 {
+			t.Errorf("file deletions after releasing one of two references = %v, want none", filePaths(due))
+		}
+
+		if err := s.DeleteVideo(ctx, second.ID); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.DeleteVideo(ctx, plain.ID); err != nil {
+			t.Fatal(err)
+		}
+		// 删除不存在的视频不报错
+		if err := s.DeleteVideo(ctx, plain.ID); err != nil {
+			t.Errorf("DeleteVideo of a missing video: %v", err)
+		}
+		due, err := s.GetDueFileDeletions(ctx, later, 10)
+		if err != nil || !equal(filePaths(due), []string{"a1.mp4", "plain.mp4"}) {
+			t.Fatalf("GetDueFileDeletions = %v, %v; want [a1.mp4 plain.mp4]", filePaths(due), err)
+		}
+
+		if err := s.ReleaseBlob(ctx, "hash-a"); !errors.Is(err, types.ErrNotFound) {
+			t.Errorf("ReleaseBlob of a released hash: err = %v, want ErrNotFound", err)
+		}
+
+		paths, err := s.GetKnownFilePaths(ctx)
+		sort.Strings(paths)
+		if err != nil || !equal(paths, []string{"a1.mp4", "plain.mp4"}) {
+			t.Errorf("GetKnownFilePaths = %v, %v", paths, err)
+		}
+	})
+}
+
+func TestStoreFileDeletions(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s store, alice, bob int) {
+		ctx := context.Background()
+		later := time.Now().Add(time.Hour)
+		for _, path := range []string{"x.mp4", "y.mp4"} {
+			if err := s.EnqueueFileDeletion(ctx, path); err != nil {
+				t.Fatalf("EnqueueFileDeletion: %v", err)
+			}
+		}
+
+		due, err := s.GetDueFileDeletions(ctx, later, 1)
+		if err != nil || len(due) != 1 || due[0].FilePath != "x.mp4" {
+			t.Fatalf("GetDueFileDeletions with limit 1 = %v, %v; want [x.mp4]", filePaths(due), err)
+		}
+
+		// 重试时间还没到的不返回
+		if err := s.RetryFileDeletion(ctx, due[0].ID, "busy", later.Add(time.Hour)); err != nil {
+			t.Fatalf("RetryFileDeletion: %v", err)
+		}
+		due, _ = s.GetDueFileDeletions(ctx, later, 10)
+		if !equal(filePaths(due), []string{"y.mp4"}) {
+			t.Errorf("GetDueFileDeletions after retry = %v, want [y.mp4]", filePaths(due))
+		}
+		retried, _ := s.GetDueFileDeletions(ctx, later.Add(2*time.Hour), 10)
+		for _, d := range retried {
+			if d.FilePath == "x.mp4" && (d.Attempts != 1 || d.LastError != "busy") {
+				t.Errorf("retried deletion = %+v, want 1 attempt with the error", d)
+			}
+		}
+
+		if err := s.CompleteFileDeletion(ctx, due[0].ID); err != nil {
+			t.Fatalf("CompleteFileDeletion: %v", err)
+		}
+		if due, _ := s.GetDueFileDeletions(ctx, later, 10); len(due) != 0 {
+			t.Errorf("GetDueFileDeletions after complete = %v, want none", filePaths(due))
+		}
+	})
+}
@@ -0,0 +1,108 @@
+package video
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Albert-tru/DanceMirror/config"
+	"github.com/Albert-tru/DanceMirror/service/auth"
+	"github.com/Albert-tru/DanceMirror/service/transcode"
+	"github.com/Albert-tru/DanceMirror/types"
+	"github.com/Albert-tru/DanceMirror/utils"
+	"github.com/gorilla/mux"
+)
+
+// handleThumbnail 返回封面图（<img> 无法设置请求头，通过 ?token= 鉴权）
+func (h *Handler) handleThumbnail(w http.ResponseWriter, r *http.Request) {
+	video, ok := h.loadViewableVideo(w, r)
+	if !ok {
+		return
+	}
+	if video.Thumbnail == "" {
+		utils.WriteError(w, http.StatusNotFound, utils.NewError(utils.CodeNotFound, "thumbnail not found"))
+		return
+	}
+
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	h.serveStored(w, r, video.Thumbnail, "image/jpeg", video.UpdatedAt)
+}
+
+// handleThumbnailsTrack 返回 WebVTT 缩略图轨道，供播放器拖动进度条时显示预览；
+// 其中的拼图地址替换为签名链接
+func (h *Handler) handleThumbnailsTrack(w http.ResponseWriter, r *http.Request) {
+	video, ok := h.loadViewableVideo(w, r)
+	if !ok {
+		return
+	}
+
+	rendition, ok := h.loadReadyRendition(w, r, video.ID, types.RenditionThumbnails)
+	if !ok {
+		return
+	}
+
+	f, err := h.storage.Open(r.Context(), rendition.FilePath)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, utils.NewError(utils.CodeNotFound, "thumbnails not found"))
+		return
+	}
+	defer f.Close()
+
+	body, err := io.ReadAll(f)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// 拼图与轨道同名目录：.../thumbnails.vtt -> .../thumbnails/<file>
+	base := strings.TrimSuffix(r.URL.Path, ".vtt") + "/"
+	expires := time.Now().Add(time.Duration(config.Envs.SignedURLTTL) * time.Second)
+	signed := map[string]string{}
+	lines := strings.Split(string(body), "\n")
+	for i, line := range lines {
+		name, fragment, found := strings.Cut(line, "#xywh=")
+		if !found {
+			continue
+		}
+		if _, ok := signed[name]; !ok {
+			signed[name] = auth.SignURL(base+name, expires)
+		}
+		lines[i] = signed[name] + "#xywh=" + fragment
+	}
+
+	w.Header().Set("Content-Type", "text/vtt; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write([]byte(strings.Join(lines, "\n")))
+}
+
+// handleThumbnailFile 返回预览图拼图，只接受签名链接
+func (h *Handler) handleThumbnailFile(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	file := vars["file"]
+	if !hlsNamePattern.MatchString(file) || !strings.HasSuffix(file, ".jpg") {
+		utils.WriteError(w, http.StatusBadRequest, utils.NewError(utils.CodeBadRequest, "invalid thumbnail path"))
+		return
+	}
+
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, utils.NewError(utils.CodeInvalidID, "invalid video id"))
+		return
+	}
+
+	// 视频进入回收站后签名链接随即失效
+	video, err := h.store.GetVideoByID(r.Context(), id)
+	if err != nil {
+		utils.WriteStoreError(w, err)
+		return
+	}
+	rendition, ok := h.loadReadyRendition(w, r, video.ID, types.RenditionThumbnails)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	h.serveStored(w, r, transcode.RenditionKey(video.ID, "thumbnails/"+file), "image/jpeg", rendition.UpdatedAt)
+}
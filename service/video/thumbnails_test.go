@@ -0,0 +1,111 @@
+package video_test
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Albert-tru/DanceMirror/cmd/api/apitest"
+	"github.com/Albert-tru/DanceMirror/service/auth"
+	"github.com/Albert-tru/DanceMirror/types"
+)
+
+func TestThumbnails(t *testing.T) {
+	s := apitest.Start(t)
+	c := s.NewClassroom(t)
+	waitRendition(t, s, c.Teacher, c.Video.ID, types.RenditionThumbnails)
+	base := fmt.Sprintf("/api/v1/videos/%d/", c.Video.ID)
+
+	// 封面和轨道与视频详情的权限相同
+	for _, path := range []string{base + "thumbnail", base + "thumbnails.vtt"} {
+		for token, want := range map[string]int{c.Teacher: http.StatusOK, c.Student: http.StatusOK, c.Outsider: http.StatusForbidden, "": http.StatusForbidden} {
+			if status, _, _ := get(t, s, path+"?token="+token); status != want {
+				t.Errorf("%s with token %q = %d, want %d", path, token, status, want)
+			}
+		}
+	}
+	status, header, _ := get(t, s, base+"thumbnail?token="+c.Student)
+	if status != http.StatusOK || header.Get("Content-Type") != "image/jpeg" {
+		t.Errorf("thumbnail = %d %s", status, header.Get("Content-Type"))
+	}
+
+	// 轨道中每个预览都指向同一张拼图的签名链接
+	status, header, body := get(t, s, base+"thumbnails.vtt?token="+c.Student)
+	if status != http.StatusOK || header.Get("Content-Type") != "text/vtt; charset=utf-8" || header.Get("Cache-Control") != "no-store" {
+		t.Fatalf("track = %d %v", status, header)
+	}
+	if !strings.HasPrefix(string(body), "WEBVTT") || !strings.Contains(string(body), "#xywh=") {
+		t.Fatalf("track is not a WebVTT thumbnail track:\n%s", body)
+	}
+	sprites := links(body)
+	if len(sprites) == 0 {
+		t.Fatalf("track has no signed sprite links:\n%s", body)
+	}
+	for _, link := range sprites {
+		if link != sprites[0] {
+			t.Fatalf("sprite links differ: %s, %s", sprites[0], link)
+		}
+	}
+	if !strings.HasPrefix(sprites[0], base+"thumbnails/sprite.jpg?") {
+		t.Errorf("sprite link = %s, want under %sthumbnails/", sprites[0], base)
+	}
+
+	// 拼图不需要登录，只看签名（Fake 转码的拼图是源文件的副本）
+	status, header, body = get(t, s, sprites[0])
+	if status != http.StatusOK || header.Get("Content-Type") != "image/jpeg" || len(body) == 0 {
+		t.Errorf("sprite = %d %s, %d bytes", status, header.Get("Content-Type"), len(body))
+	}
+	_, _, poster := get(t, s, base+"thumbnail?token="+c.Teacher)
+	if !bytes.Equal(body, poster) {
+		t.Errorf("sprite = %d bytes, want the %d byte copy of the source", len(body), len(poster))
+	}
+
+	for name, path := range tampered(t, sprites[0]) {
+		if status, _, _ := get(t, s, path); status != http.StatusForbidden {
+			t.Errorf("%s %s = %d, want 403", name, path, status)
+		}
+	}
+
+	expires := time.Now().Add(time.Minute)
+	invalid := map[string]int{
+		base + "thumbnails/sprite.png":             http.StatusBadRequest,
+		base + "thumbnails/sprite.a.jpg":           http.StatusBadRequest,
+		base + "thumbnails/missing.jpg":            http.StatusNotFound,
+		"/api/v1/videos/999/thumbnails/sprite.jpg": http.StatusNotFound,
+	}
+	for path, want := range invalid {
+		if status, _, _ := get(t, s, auth.SignURL(path, expires)); status != want {
+			t.Errorf("signed %s = %d, want %d", path, status, want)
+		}
+	}
+
+	// 视频进入回收站后拼图链接失效
+	s.Do(t, http.MethodDelete, fmt.Sprintf("/api/v1/videos/%d", c.Video.ID), c.Teacher, nil, http.StatusOK, nil)
+	if status, _, _ := get(t, s, sprites[0]); status != http.StatusNotFound {
+		t.Errorf("sprite of a trashed video = %d, want 404", status)
+	}
+}
+
+// 预览图还在生成时轨道和拼图返回 409
+func TestThumbnailsNotReady(t *testing.T) {
+	s := apitest.Start(t)
+	token := createUser(t, s, "13800000001")
+	video := upload(t, s, token, apitest.FakeMP4(1024))
+	waitRendition(t, s, token, video.ID, types.RenditionThumbnails)
+
+	// 重新登记为处理中
+	if err := s.Stores.Renditions.SaveRendition(t.Context(), &types.Rendition{VideoID: video.ID, Kind: types.RenditionThumbnails, Status: types.RenditionProcessing}); err != nil {
+		t.Fatal(err)
+	}
+	path := fmt.Sprintf("/api/v1/videos/%d/thumbnails.vtt?token=%s", video.ID, token)
+	if status, _, _ := get(t, s, path); status != http.StatusConflict {
+		t.Errorf("track while processing = %d, want 409", status)
+	}
+	sprite := auth.SignURL(fmt.Sprintf("/api/v1/videos/%d/thumbnails/sprite.jpg", video.ID), time.Now().Add(time.Minute))
+	if status, _, _ := get(t, s, sprite); status != http.StatusConflict {
+		t.Errorf("sprite while processing = %d, want 409", status)
+	}
+}
@@ -0,0 +1,86 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+
+	"github.com/Albert-tru/DanceMirror/utils"
+)
+
+const forbiddenAddressMessage = "webhook url must not point to a loopback, private or link-local address"
+
+// ErrForbiddenAddress Webhook 地址指向本机、内网、链路本地（含云服务器元数据）等地址
+var ErrForbiddenAddress = errors.New(forbiddenAddressMessage)
+
+// 运营商级 NAT 地址段，IsPrivate 不包含
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// forbiddenAddr 不允许投递的地址
+func forbiddenAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return !addr.IsValid() ||
+		addr.IsUnspecified() ||
+		addr.IsLoopback() ||
+		addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() ||
+		sharedAddressSpace.Contains(addr)
+}
+
+// CheckURL 检查 Webhook 地址：只能是 http/https，主机名解析出的所有地址都不能是内网地址
+// （allowPrivate 时不检查地址，用于本地开发和测试）
+func (d *Dispatcher) CheckURL(ctx context.Context, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return utils.NewError(utils.CodeBadRequest, "url must use http or https")
+	}
+	if d.allowPrivate {
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", u.Hostname())
+	if err != nil {
+		return utils.NewError(utils.CodeBadRequest, "failed to resolve webhook host")
+	}
+	for _, addr := range addrs {
+		if forbiddenAddr(addr) {
+			return utils.NewError(utils.CodeBadRequest, forbiddenAddressMessage)
+		}
+	}
+	return nil
+}
+
+// newHTTPClient 投递用的 HTTP 客户端。创建后 DNS 可能改变（包括重定向到其他主机），
+// 所以在建立连接时按实际连接的地址再检查一次；不使用代理，否则检查的是代理的地址
+func newHTTPClient(allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: requestTimeout}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, c syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if forbiddenAddr(addrPort.Addr()) {
+				return ErrForbiddenAddress
+			}
+			return nil
+		}
+	}
+
+	return &http.Client{
+		Timeout: requestTimeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: requestTimeout,
+			IdleConnTimeout:     90 * time.Second,
+		},
+	}
+}
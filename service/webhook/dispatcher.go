@@ -0,0 +1,231 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Albert-tru/DanceMirror/types"
+)
+
+const (
+	pollInterval   = 5 * time.Second
+	batchSize      = 20
+	requestTimeout = 10 * time.Second
+	retryBase      = 30 * time.Second
+	retryMax       = 6 * time.Hour
+)
+
+// 投递请求头
+const (
+	HeaderEvent     = "X-DanceMirror-Event"
+	HeaderDelivery  = "X-DanceMirror-Delivery"
+	HeaderSignature = "X-DanceMirror-Signature"
+)
+
+// Dispatcher 后台投递 Webhook：监听事件总线创建投递记录，定时发送到期的投递，
+// 失败后按指数退避重试，直到成功或达到最大次数
+type Dispatcher struct {
+	store        types.WebhookStore
+	client       *http.Client
+	maxAttempts  int
+	allowPrivate bool // 允许投递到本机和内网地址
+	wake         chan struct{}
+}
+
+// NewDispatcher 创建投递器；allowPrivate 为 false 时拒绝投递到本机、内网和链路本地地址
+func NewDispatcher(store types.WebhookStore, maxAttempts int, allowPrivate bool) *Dispatcher {
+	return &Dispatcher{
+		store:        store,
+		client:       newHTTPClient(allowPrivate),
+		maxAttempts:  maxAttempts,
+		allowPrivate: allowPrivate,
+		wake:         make(chan struct{}, 1),
+	}
+}
+
+// HandleEvent 事件总线监听器：为用户订阅了该事件的每个 Webhook 创建一条投递
+func (d *Dispatcher) HandleEvent(e *types.Event) {
+	// 事件发布时请求可能已经结束，不使用请求的 ctx
+	ctx := context.Background()
+	webhooks, err := d.store.GetActiveWebhooks(ctx, e.UserID)
+	if err != nil {
+		log.Printf("webhook: failed to load webhooks of user %d: %v", e.UserID, err)
+		return
+	}
+
+	for _, w := range webhooks {
+		if !subscribed(w, e.Type) {
+			continue
+		}
+		if _, err := d.Enqueue(ctx, w.ID, e.Type, e.Data); err != nil {
+			log.Printf("webhook: failed to enqueue %s for webhook %d: %v", e.Type, w.ID, err)
+		}
+	}
+}
+
+// Enqueue 创建一条立即到期的投递并唤醒后台任务
+func (d *Dispatcher) Enqueue(ctx context.Context, webhookID int, event string, payload json.RawMessage) (*types.WebhookDelivery, error) {
+	now := time.Now()
+	delivery := &types.WebhookDelivery{
+		WebhookID:     webhookID,
+		Event:         event,
+		Payload:       payload,
+		Status:        types.DeliveryPending,
+		NextAttemptAt: &now,
+	}
+	if err := d.store.CreateDelivery(ctx, delivery); err != nil {
+		return nil, err
+	}
+
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+
+	return delivery, nil
+}
+
+// Run 运行投递循环，直到 ctx 结束
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		d.deliverDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+func (d *Dispatcher) deliverDue(ctx context.Context) {
+	deliveries, err := d.store.GetDueDeliveries(ctx, time.Now(), batchSize)
+	if err != nil {
+		log.Printf("webhook: failed to load due deliveries: %v", err)
+		return
+	}
+
+	for _, delivery := range deliveries {
+		if ctx.Err() != nil {
+			return
+		}
+		d.deliver(ctx, delivery)
+	}
+}
+
+// deliver 发送一次投递并记录结果
+func (d *Dispatcher) deliver(ctx context.Context, delivery *types.WebhookDelivery) {
+	webhook, err := d.store.GetWebhookByID(ctx, delivery.WebhookID)
+	if err != nil {
+		log.Printf("webhook: delivery %d has no webhook: %v", delivery.ID, err)
+		return
+	}
+
+	start := time.Now()
+	status, sendErr := d.send(ctx, webhook, delivery)
+	attempt := &types.WebhookAttempt{
+		DeliveryID:     delivery.ID,
+		ResponseStatus: status,
+		DurationMs:     int(time.Since(start).Milliseconds()),
+	}
+	if sendErr != nil {
+		attempt.Error = sendErr.Error()
+	}
+	if err := d.store.CreateAttempt(ctx, attempt); err != nil {
+		log.Printf("webhook: failed to record attempt of delivery %d: %v", delivery.ID, err)
+	}
+
+	delivery.Attempts++
+	switch {
+	case sendErr == nil:
+		delivery.Status = types.DeliverySucceeded
+		delivery.NextAttemptAt = nil
+	case delivery.Attempts >= d.maxAttempts:
+		delivery.Status = types.DeliveryFailed
+		delivery.NextAttemptAt = nil
+	default:
+		next := time.Now().Add(backoff(delivery.Attempts))
+		delivery.NextAttemptAt = &next
+	}
+
+	if err := d.store.UpdateDelivery(ctx, delivery); err != nil {
+		log.Printf("webhook: failed to update delivery %d: %v", delivery.ID, err)
+	}
+}
+
+// send 发送签名后的请求，返回响应状态码；非 2xx 视为失败
+func (d *Dispatcher) send(ctx context.Context, webhook *types.Webhook, delivery *types.WebhookDelivery) (int, error) {
+	body, err := json.Marshal(map[string]any{
+		"id":        delivery.ID,
+		"event":     delivery.Event,
+		"timestamp": time.Now().Unix(),
+		"data":      delivery.Payload,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "DanceMirror-Webhook/1.0")
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDelivery, strconv.Itoa(delivery.ID))
+	req.Header.Set(HeaderSignature, "sha256="+Sign(webhook.Secret, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// Sign 计算请求体的 HMAC-SHA256 签名（十六进制），接收方用同一个 secret 校验
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// backoff 第 n 次失败后的等待时间：30s、1m、2m、4m……最多 6h
+func backoff(attempts int) time.Duration {
+	delay := retryBase
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= retryMax {
+			return retryMax
+		}
+	}
+	return delay
+}
+
+func subscribed(webhook *types.Webhook, event string) bool {
+	for _, e := range webhook.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,244 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/Albert-tru/DanceMirror/db/dbtest"
+	"github.com/Albert-tru/DanceMirror/types"
+)
+
+// received 接收方收到的一次请求
+type received struct {
+	header http.Header
+	body   []byte
+}
+
+// receiver 本地接收方，按顺序返回 statuses 中的状态码（用完后返回 200）
+func receiver(t *testing.T, statuses ...int) (*httptest.Server, chan received) {
+	t.Helper()
+
+	requests := make(chan received, 16)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- received{header: r.Header.Clone(), body: body}
+		status := http.StatusOK
+		if len(statuses) > 0 {
+			status, statuses = statuses[0], statuses[1:]
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, requests
+}
+
+// setup 创建数据库、投递器（允许内网地址）和指向 url 的 Webhook
+func setup(t *testing.T, url string, maxAttempts int) (*Dispatcher, *Store, *types.Webhook) {
+	t.Helper()
+
+	database := dbtest.New(t)
+	store := NewStore(database)
+	webhook := &types.Webhook{
+		UserID: dbtest.CreateUser(t, database, "13800000001"),
+		URL:    url,
+		Secret: "0123456789abcdef",
+		Events: []string{"video.uploaded"},
+		Active: true,
+	}
+	if err := store.CreateWebhook(context.Background(), webhook); err != nil {
+		t.Fatal(err)
+	}
+	return NewDispatcher(store, maxAttempts, true), store, webhook
+}
+
+func TestSignature(t *testing.T) {
+	srv, requests := receiver(t)
+	d, store, webhook := setup(t, srv.URL, 3)
+	ctx := context.Background()
+
+	delivery, err := d.Enqueue(ctx, webhook.ID, "video.uploaded", json.RawMessage(`{"id":7}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.deliverDue(ctx)
+
+	req := <-requests
+	mac := hmac.New(sha256.New, []byte(webhook.Secret))
+	mac.Write(req.body)
+	if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); req.header.Get(HeaderSignature) != want {
+		t.Errorf("%s = %q, want %q", HeaderSignature, req.header.Get(HeaderSignature), want)
+	}
+	if req.header.Get(HeaderEvent) != "video.uploaded" {
+		t.Errorf("%s = %q", HeaderEvent, req.header.Get(HeaderEvent))
+	}
+
+	var body struct {
+		ID    int             `json:"id"`
+		Event string          `json:"event"`
+		Data  json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(req.body, &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.ID != delivery.ID || body.Event != "video.uploaded" || string(body.Data) != `{"id":7}` {
+		t.Errorf("body = %s", req.body)
+	}
+
+	got, err := store.GetDeliveryByID(ctx, delivery.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != types.DeliverySucceeded || got.Attempts != 1 || got.NextAttemptAt != nil {
+		t.Errorf("delivery = %+v, want succeeded after one attempt", got)
+	}
+}
+
+// 5xx 记录一次尝试并按退避时间安排下一次，达到最大次数后失败
+func TestRetry(t *testing.T) {
+	srv, requests := receiver(t, http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable)
+	d, store, webhook := setup(t, srv.URL, 3)
+	ctx := context.Background()
+
+	delivery, err := d.Enqueue(ctx, webhook.ID, "video.uploaded", json.RawMessage(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		status int
+		delay  time.Duration // 到下一次尝试的时间，0 表示不再重试
+		want   string
+	}{
+		{http.StatusInternalServerError, 30 * time.Second, types.DeliveryPending},
+		{http.StatusBadGateway, time.Minute, types.DeliveryPending},
+		{http.StatusServiceUnavailable, 0, types.DeliveryFailed},
+	}
+	for i, step := range steps {
+		start := time.Now()
+		d.deliverDue(ctx)
+		<-requests
+
+		got, err := store.GetDeliveryByID(ctx, delivery.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Status != step.want || got.Attempts != i+1 {
+			t.Fatalf("attempt %d: delivery = %+v, want %s", i+1, got, step.want)
+		}
+		if step.delay == 0 {
+			if got.NextAttemptAt != nil {
+				t.Errorf("attempt %d: next attempt at %v, want none", i+1, got.NextAttemptAt)
+			}
+		} else if got.NextAttemptAt == nil || got.NextAttemptAt.Sub(start) < step.delay-time.Second || got.NextAttemptAt.Sub(start) > step.delay+time.Second {
+			t.Errorf("attempt %d: next attempt at %v, want about %v after %v", i+1, got.NextAttemptAt, step.delay, start)
+		}
+
+		attempts, err := store.GetAttempts(ctx, delivery.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(attempts) != i+1 || attempts[i].ResponseStatus != step.status || attempts[i].Error == "" {
+			t.Errorf("attempt %d: log = %+v, want status %d with an error", i+1, attempts, step.status)
+		}
+
+		// 还没到期的投递不会发送
+		d.deliverDue(ctx)
+		select {
+		case <-requests:
+			t.Fatalf("attempt %d: delivery sent again before it was due", i+1)
+		default:
+		}
+
+		// 把下一次尝试提前到现在
+		if got.NextAttemptAt != nil {
+			now := time.Now().Add(-time.Second)
+			got.NextAttemptAt = &now
+			if err := store.UpdateDelivery(ctx, got); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{5, 8 * time.Minute},
+		{10, 256 * time.Minute},
+		{11, retryMax},
+		{100, retryMax},
+	}
+	for _, tt := range tests {
+		if got := backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestForbiddenAddr(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"127.0.0.1", true},
+		{"::1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"fe80::1", true},
+		{"fc00::1", true},
+		{"100.64.0.1", true},
+		{"0.0.0.0", true},
+		{"::ffff:127.0.0.1", true},
+		{"224.0.0.1", true},
+		{"8.8.8.8", false},
+		{"2606:4700:4700::1111", false},
+	}
+	for _, tt := range tests {
+		if got := forbiddenAddr(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("forbiddenAddr(%s) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
+
+// 投递时按实际连接的地址检查，即使地址在登记之后才变成内网地址
+func TestDialRejectsPrivateAddress(t *testing.T) {
+	srv, requests := receiver(t)
+	_, store, webhook := setup(t, srv.URL, 3)
+	d := NewDispatcher(store, 3, false)
+	ctx := context.Background()
+
+	delivery, err := d.Enqueue(ctx, webhook.ID, "video.uploaded", json.RawMessage(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.deliverDue(ctx)
+
+	select {
+	case <-requests:
+		t.Fatal("delivery reached a loopback address")
+	default:
+	}
+	attempts, err := store.GetAttempts(ctx, delivery.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attempts) != 1 || attempts[0].ResponseStatus != 0 || attempts[0].Error == "" {
+		t.Errorf("attempts = %+v, want one failed attempt without a response", attempts)
+	}
+}
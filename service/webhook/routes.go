@@ -0,0 +1,228 @@
+package webhook
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/Albert-tru/DanceMirror/service/auth"
+	"github.com/Albert-tru/DanceMirror/types"
+	"github.com/Albert-tru/DanceMirror/utils"
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	store      types.WebhookStore
+	dispatcher *Dispatcher
+	userStore  types.UserStore
+}
+
+func NewHandler(store types.WebhookStore, dispatcher *Dispatcher, userStore types.UserStore) *Handler {
+	return &Handler{
+		store:      store,
+		dispatcher: dispatcher,
+		userStore:  userStore,
+	}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/webhooks", auth.WithJWTAuth(h.handleGetWebhooks, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/webhooks", auth.WithJWTAuth(h.handleCreateWebhook, h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/webhooks/{id}", auth.WithJWTAuth(h.handleGetWebhook, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/webhooks/{id}", auth.WithJWTAuth(h.handleDeleteWebhook, h.userStore)).Methods(http.MethodDelete)
+
+	// 投递记录与重新投递
+	router.HandleFunc("/webhooks/{id}/deliveries", auth.WithJWTAuth(h.handleGetDeliveries, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/webhooks/{id}/deliveries/{deliveryId}", auth.WithJWTAuth(h.handleGetDelivery, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/webhooks/{id}/deliveries/{deliveryId}/redeliver", auth.WithJWTAuth(h.handleRedeliver, h.userStore)).Methods(http.MethodPost)
+}
+
+func (h *Handler) handleGetWebhooks(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == -1 {
+		utils.WriteError(w, http.StatusUnauthorized, utils.NewError(utils.CodeUnauthorized, "unauthorized"))
+		return
+	}
+
+	webhooks, err := h.store.GetWebhooks(r.Context(), userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	for _, webhook := range webhooks {
+		webhook.Secret = ""
+	}
+
+	utils.WriteJSON(w, http.StatusOK, webhooks)
+}
+
+func (h *Handler) handleCreateWebhook(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == -1 {
+		utils.WriteError(w, http.StatusUnauthorized, utils.NewError(utils.CodeUnauthorized, "unauthorized"))
+		return
+	}
+
+	var payload types.CreateWebhookPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, utils.InvalidPayload(err))
+		return
+	}
+
+	if err := h.dispatcher.CheckURL(r.Context(), payload.URL); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// 未指定 secret 时自动生成，只在创建时返回一次
+	secret := payload.Secret
+	if secret == "" {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+		secret = hex.EncodeToString(b)
+	}
+
+	webhook := &types.Webhook{
+		UserID: userID,
+		URL:    payload.URL,
+		Secret: secret,
+		Events: payload.Events,
+		Active: true,
+	}
+	if err := h.store.CreateWebhook(r.Context(), webhook); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, webhook)
+}
+
+func (h *Handler) handleGetWebhook(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := h.loadOwnWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	webhook.Secret = ""
+	utils.WriteJSON(w, http.StatusOK, webhook)
+}
+
+func (h *Handler) handleDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := h.loadOwnWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.store.DeleteWebhook(r.Context(), webhook.ID); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"message": "webhook deleted successfully"})
+}
+
+func (h *Handler) handleGetDeliveries(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := h.loadOwnWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	deliveries, err := h.store.GetDeliveries(r.Context(), webhook.ID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, deliveries)
+}
+
+// handleGetDelivery 返回投递详情及每次尝试的结果
+func (h *Handler) handleGetDelivery(w http.ResponseWriter, r *http.Request) {
+	delivery, ok := h.loadOwnDelivery(w, r)
+	if !ok {
+		return
+	}
+
+	attempts, err := h.store.GetAttempts(r.Context(), delivery.ID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	delivery.AttemptLog = attempts
+
+	utils.WriteJSON(w, http.StatusOK, delivery)
+}
+
+// handleRedeliver 以相同的事件内容创建一条新的投递
+func (h *Handler) handleRedeliver(w http.ResponseWriter, r *http.Request) {
+	delivery, ok := h.loadOwnDelivery(w, r)
+	if !ok {
+		return
+	}
+
+	redelivery, err := h.dispatcher.Enqueue(r.Context(), delivery.WebhookID, delivery.Event, delivery.Payload)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusAccepted, redelivery)
+}
+
+// loadOwnWebhook 读取路径中的 Webhook 并校验归属，失败时已写入响应
+func (h *Handler) loadOwnWebhook(w http.ResponseWriter, r *http.Request) (*types.Webhook, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, utils.NewError(utils.CodeInvalidID, "invalid webhook id"))
+		return nil, false
+	}
+
+	webhook, err := h.store.GetWebhookByID(r.Context(), id)
+	if err != nil {
+		utils.WriteStoreError(w, err)
+		return nil, false
+	}
+
+	if webhook.UserID != auth.GetUserIDFromContext(r.Context()) {
+		utils.WriteError(w, http.StatusForbidden, utils.NewError(utils.CodePermissionDenied, "permission denied"))
+		return nil, false
+	}
+
+	return webhook, true
+}
+
+// loadOwnDelivery 读取路径中的投递记录，必须属于路径中的 Webhook
+func (h *Handler) loadOwnDelivery(w http.ResponseWriter, r *http.Request) (*types.WebhookDelivery, bool) {
+	webhook, ok := h.loadOwnWebhook(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	deliveryID, err := strconv.Atoi(mux.Vars(r)["deliveryId"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, utils.NewError(utils.CodeInvalidID, "invalid delivery id"))
+		return nil, false
+	}
+
+	delivery, err := h.store.GetDeliveryByID(r.Context(), deliveryID)
+	if err == nil && delivery.WebhookID != webhook.ID {
+		err = fmt.Errorf("delivery %w", types.ErrNotFound)
+	}
+	if err != nil {
+		utils.WriteStoreError(w, err)
+		return nil, false
+	}
+
+	return delivery, true
+}
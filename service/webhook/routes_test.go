@@ -0,0 +1,129 @@
+package webhook_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Albert-tru/DanceMirror/cmd/api/apitest"
+	"github.com/Albert-tru/DanceMirror/config"
+	"github.com/Albert-tru/DanceMirror/types"
+)
+
+// allowPrivate 在测试期间允许投递到本机地址（测试服务器创建时读取配置）
+func allowPrivate(t *testing.T, allow bool) {
+	prev := config.Envs.WebhookAllowPrivate
+	config.Envs.WebhookAllowPrivate = allow
+	t.Cleanup(func() { config.Envs.WebhookAllowPrivate = prev })
+}
+
+// 登记时拒绝本机、内网和链路本地地址
+func TestCreateRejectsPrivateURL(t *testing.T) {
+	allowPrivate(t, false)
+	s := apitest.Start(t)
+	_, token, err := s.CreateUser("13800000001", "password123", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		url    string
+		status int
+	}{
+		{"http://127.0.0.1:8080/hook", http.StatusBadRequest},
+		{"http://localhost/hook", http.StatusBadRequest},
+		{"http://[::1]/hook", http.StatusBadRequest},
+		{"http://10.0.0.5/hook", http.StatusBadRequest},
+		{"http://192.168.1.10/hook", http.StatusBadRequest},
+		{"http://169.254.169.254/latest/meta-data", http.StatusBadRequest},
+		{"http://100.64.0.1/hook", http.StatusBadRequest},
+		{"ftp://8.8.8.8/hook", http.StatusBadRequest},
+		{"http://8.8.8.8/hook", http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			payload := types.CreateWebhookPayload{URL: tt.url, Events: []string{"video.uploaded"}}
+			s.Do(t, http.MethodPost, "/api/v1/webhooks", token, payload, tt.status, nil)
+		})
+	}
+}
+
+// 上传视频触发投递，重新投递以相同的事件内容再发送一次
+func TestRedeliver(t *testing.T) {
+	allowPrivate(t, true)
+	requests := make(chan []byte, 4)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- body
+	}))
+	defer receiver.Close()
+
+	s := apitest.Start(t)
+	_, owner, err := s.CreateUser("13800000001", "password123", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, other, err := s.CreateUser("13800000002", "password123", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var hook types.Webhook
+	s.Do(t, http.MethodPost, "/api/v1/webhooks", owner, types.CreateWebhookPayload{URL: receiver.URL, Events: []string{"video.uploaded"}}, http.StatusCreated, &hook)
+	resp, err := s.Upload(owner, map[string]string{"title": "routine"}, "routine.mp4", apitest.FakeMP4(1024))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	first := receive(t, requests)
+
+	deliveries := fmt.Sprintf("/api/v1/webhooks/%d/deliveries", hook.ID)
+	var list []types.WebhookDelivery
+	s.Do(t, http.MethodGet, deliveries, owner, nil, http.StatusOK, &list)
+	if len(list) != 1 {
+		t.Fatalf("deliveries = %+v, want one", list)
+	}
+	redeliver := fmt.Sprintf("%s/%d/redeliver", deliveries, list[0].ID)
+
+	s.Do(t, http.MethodPost, redeliver, other, nil, http.StatusForbidden, nil)
+	s.Do(t, http.MethodPost, fmt.Sprintf("%s/999/redeliver", deliveries), owner, nil, http.StatusNotFound, nil)
+
+	var redelivery types.WebhookDelivery
+	s.Do(t, http.MethodPost, redeliver, owner, nil, http.StatusAccepted, &redelivery)
+	second := receive(t, requests)
+
+	if redelivery.ID == list[0].ID || second.ID != redelivery.ID {
+		t.Errorf("redelivery id = %d (sent %d), want a new delivery after %d", redelivery.ID, second.ID, list[0].ID)
+	}
+	if second.Event != first.Event || string(second.Data) != string(first.Data) {
+		t.Errorf("redelivered %s %s, want %s %s", second.Event, second.Data, first.Event, first.Data)
+	}
+}
+
+// event 投递的请求体
+type event struct {
+	ID    int             `json:"id"`
+	Event string          `json:"event"`
+	Data  json.RawMessage `json:"data"`
+}
+
+// receive 等待接收方收到下一次投递
+func receive(t *testing.T, requests chan []byte) event {
+	t.Helper()
+
+	select {
+	case body := <-requests:
+		var e event
+		if err := json.Unmarshal(body, &e); err != nil {
+			t.Fatal(err)
+		}
+		return e
+	case <-time.After(5 * time.Second):
+		t.Fatal("no webhook request within 5s")
+		return event{}
+	}
+}
@@ -0,0 +1,250 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Albert-tru/DanceMirror/db/dialect"
+	"github.com/Albert-tru/DanceMirror/types"
+)
+
+// webhookColumns、deliveryColumns 和对应的 scanRowInto 函数顺序一致
+const (
+	webhookColumns  = "id, userId, url, secret, events, active, createdAt"
+	deliveryColumns = "id, webhookId, event, payload, status, attempts, nextAttemptAt, createdAt, updatedAt"
+)
+
+type Store struct {
+	db      *sql.DB
+	dialect dialect.Dialect
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db, dialect: dialect.Of(db)}
+}
+
+func (s *Store) CreateWebhook(ctx context.Context, webhook *types.Webhook) error {
+	result, err := s.db.ExecContext(ctx, "INSERT INTO webhooks (userId, url, secret, events, active) VALUES (?, ?, ?, ?, ?)",
+		webhook.UserID, webhook.URL, webhook.Secret, strings.Join(webhook.Events, ","), webhook.Active)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	webhook.ID = int(id)
+	return nil
+}
+
+func (s *Store) GetWebhooks(ctx context.Context, userID int) ([]*types.Webhook, error) {
+	return s.queryWebhooks(ctx, "SELECT "+webhookColumns+" FROM webhooks WHERE userId = ? ORDER BY createdAt DESC", userID)
+}
+
+func (s *Store) GetActiveWebhooks(ctx context.Context, userID int) ([]*types.Webhook, error) {
+	return s.queryWebhooks(ctx, "SELECT "+webhookColumns+" FROM webhooks WHERE userId = ? AND active = TRUE", userID)
+}
+
+func (s *Store) GetWebhookByID(ctx context.Context, id int) (*types.Webhook, error) {
+	webhooks, err := s.queryWebhooks(ctx, "SELECT "+webhookColumns+" FROM webhooks WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(webhooks) == 0 {
+		return nil, fmt.Errorf("webhook %w", types.ErrNotFound)
+	}
+
+	return webhooks[0], nil
+}
+
+func (s *Store) DeleteWebhook(ctx context.Context, id int) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM webhooks WHERE id = ?", id)
+	return err
+}
+
+func (s *Store) CreateDelivery(ctx context.Context, delivery *types.WebhookDelivery) error {
+	result, err := s.db.ExecContext(ctx, "INSERT INTO webhook_deliveries (webhookId, event, payload, status, nextAttemptAt) VALUES (?, ?, ?, ?, ?)",
+		delivery.WebhookID, delivery.Event, string(delivery.Payload), delivery.Status, s.nullTime(delivery.NextAttemptAt))
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	delivery.ID = int(id)
+	return nil
+}
+
+// GetDeliveries 获取 Webhook 最近的投递记录
+func (s *Store) GetDeliveries(ctx context.Context, webhookID int) ([]*types.WebhookDelivery, error) {
+	return s.queryDeliveries(ctx, "SELECT "+deliveryColumns+" FROM webhook_deliveries WHERE webhookId = ? ORDER BY id DESC LIMIT 100", webhookID)
+}
+
+func (s *Store) GetDeliveryByID(ctx context.Context, id int) (*types.WebhookDelivery, error) {
+	deliveries, err := s.queryDeliveries(ctx, "SELECT "+deliveryColumns+" FROM webhook_deliveries WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(deliveries) == 0 {
+		return nil, fmt.Errorf("delivery %w", types.ErrNotFound)
+	}
+
+	return deliveries[0], nil
+}
+
+// GetDueDeliveries 获取到期需要投递的记录
+func (s *Store) GetDueDeliveries(ctx context.Context, now time.Time, limit int) ([]*types.WebhookDelivery, error) {
+	return s.queryDeliveries(ctx, `
+SELECT `+deliveryColumns+` FROM webhook_deliveries
+WHERE status = ? AND nextAttemptAt <= ?
+ORDER BY nextAttemptAt
+LIMIT ?`, types.DeliveryPending, s.dialect.Time(now), limit)
+}
+
+func (s *Store) UpdateDelivery(ctx context.Context, delivery *types.WebhookDelivery) error {
+	_, err := s.db.ExecContext(ctx, "UPDATE webhook_deliveries SET status = ?, attempts = ?, nextAttemptAt = ? WHERE id = ?",
+		delivery.Status, delivery.Attempts, s.nullTime(delivery.NextAttemptAt), delivery.ID)
+	return err
+}
+
+func (s *Store) CreateAttempt(ctx context.Context, attempt *types.WebhookAttempt) error {
+	result, err := s.db.ExecContext(ctx, "INSERT INTO webhook_attempts (deliveryId, responseStatus, error, durationMs) VALUES (?, ?, ?, ?)",
+		attempt.DeliveryID, attempt.ResponseStatus, attempt.Error, attempt.DurationMs)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	attempt.ID = int(id)
+	return nil
+}
+
+func (s *Store) GetAttempts(ctx context.Context, deliveryID int) ([]*types.WebhookAttempt, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT id, deliveryId, responseStatus, error, durationMs, createdAt FROM webhook_attempts WHERE deliveryId = ? ORDER BY id", deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attempts := []*types.WebhookAttempt{}
+	for rows.Next() {
+		a := new(types.WebhookAttempt)
+		var errText sql.NullString
+		if err := rows.Scan(&a.ID, &a.DeliveryID, &a.ResponseStatus, &errText, &a.DurationMs, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		a.Error = errText.String
+		attempts = append(attempts, a)
+	}
+
+	return attempts, nil
+}
+
+// nullTime 转换可以为空的时间参数
+func (s *Store) nullTime(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return s.dialect.Time(*t)
+}
+
+func (s *Store) queryWebhooks(ctx context.Context, query string, args ...any) ([]*types.Webhook, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := []*types.Webhook{}
+	for rows.Next() {
+		w, err := scanRowIntoWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, w)
+	}
+
+	return webhooks, nil
+}
+
+func (s *Store) queryDeliveries(ctx context.Context, query string, args ...any) ([]*types.WebhookDelivery, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []*types.WebhookDelivery{}
+	for rows.Next() {
+		d, err := scanRowIntoDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	return deliveries, nil
+}
+
+func scanRowIntoWebhook(rows *sql.Rows) (*types.Webhook, error) {
+	webhook := new(types.Webhook)
+
+	var events string
+	err := rows.Scan(
+		&webhook.ID,
+		&webhook.UserID,
+		&webhook.URL,
+		&webhook.Secret,
+		&events,
+		&webhook.Active,
+		&webhook.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	webhook.Events = strings.Split(events, ",")
+	return webhook, nil
+}
+
+func scanRowIntoDelivery(rows *sql.Rows) (*types.WebhookDelivery, error) {
+	delivery := new(types.WebhookDelivery)
+
+	var payload []byte
+	var nextAttemptAt sql.NullTime
+	err := rows.Scan(
+		&delivery.ID,
+		&delivery.WebhookID,
+		&delivery.Event,
+		&payload,
+		&delivery.Status,
+		&delivery.Attempts,
+		&nextAttemptAt,
+		&delivery.CreatedAt,
+		&delivery.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery.Payload = payload
+	if nextAttemptAt.Valid {
+		delivery.NextAttemptAt = &nextAttemptAt.Time
+	}
+
+	return delivery, nil
+}
@@ -169,6 +169,11 @@ const DanceMirrorAPI = (function() {
             return await request(`/videos/${id}`, { method: 'DELETE' });
         },
 
+        // 视频播放地址（<video> 无法设置请求头，令牌放在查询参数中）
+        streamURL: function(video) {
+            return `${config.apiBase}/videos/${video.id}/stream?token=${encodeURIComponent(getToken() || '')}`;
+        },
+
         // 下载文件
         downloadFile: function(path) {
             window.open(path, '_blank');
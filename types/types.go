@@ -1,6 +1,12 @@
 package types
 
-import "time"
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+)
 
 // User 用户结构
 type User struct {
@@ -11,8 +17,17 @@ type User struct {
 	FirstName string    `json:"firstName"`
 	LastName  string    `json:"lastName"`
 	CreatedAt time.Time `json:"createdAt"`
+	Role      string    `json:"role"`
+	Locale    string    `json:"locale"` // 接口返回信息的语言，空表示按 Accept-Language
 }
 
+// 用户角色
+const (
+	RoleUser    = "user"
+	RoleTeacher = "teacher"
+	RoleAdmin   = "admin"
+)
+
 // RegisterUserPayload 用户注册请求
 type RegisterUserPayload struct {
 	Phone     string `json:"phone" validate:"required,min=11,max=11"`
@@ -21,6 +36,11 @@ type RegisterUserPayload struct {
 	LastName  string `json:"lastName" validate:"required"`
 }
 
+// UpdateLocalePayload 设置接口返回信息的语言，空字符串表示按 Accept-Language
+type UpdateLocalePayload struct {
+	Locale string `json:"locale" validate:"omitempty,oneof=zh-CN en"`
+}
+
 // LoginUserPayload 用户登录请求
 type LoginUserPayload struct {
 	Phone    string `json:"phone" validate:"required"`
@@ -29,17 +49,25 @@ type LoginUserPayload struct {
 
 // Video 视频结构
 type Video struct {
-	ID          int       `json:"id"`
-	UserID      int       `json:"userId"`
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	FilePath    string    `json:"filePath"`
-	FileName    string    `json:"fileName"`
-	FileSize    int64     `json:"fileSize"`
-	Duration    float64   `json:"duration,omitempty"`  // 视频时长（秒）
-	Thumbnail   string    `json:"thumbnail,omitempty"` // 缩略图路径
-	CreatedAt   time.Time `json:"createdAt"`
-	UpdatedAt   time.Time `json:"updatedAt"`
+	ID             int          `json:"id"`
+	UserID         int          `json:"userId"`
+	Title          string       `json:"title"`
+	Description    string       `json:"description"`
+	FilePath       string       `json:"-"` // 服务器上的文件路径，不返回给客户端（通过 /videos/{id}/stream 播放）
+	FileName       string       `json:"-"`
+	FileSize       int64        `json:"fileSize"`
+	Duration       float64      `json:"duration,omitempty"`       // 视频时长（秒）
+	Thumbnail      string       `json:"thumbnail,omitempty"`      // 封面图在存储层中的 key，通过 /videos/{id}/thumbnail 访问
+	ReferenceID    int          `json:"referenceId,omitempty"`    // 练习录像对应的参考视频 ID
+	ContentHash    string       `json:"contentHash,omitempty"`    // 文件内容的 SHA-256
+	MimeType       string       `json:"mimeType,omitempty"`       // 根据文件头识别的 MIME 类型
+	DeletedAt      *time.Time   `json:"deletedAt,omitempty"`      // 移入回收站的时间
+	SourceID       int          `json:"sourceId,omitempty"`       // 剪辑生成的视频对应的源视频 ID
+	SyncOffset     *float64     `json:"syncOffset,omitempty"`     // 练习录像相对参考视频的偏移：录像 t 秒对应参考视频 t+syncOffset 秒
+	SyncConfidence float64      `json:"syncConfidence,omitempty"` // syncOffset 的可信度，0~1
+	Renditions     []*Rendition `json:"renditions,omitempty"`     // 转码输出（仅详情接口返回）
+	CreatedAt      time.Time    `json:"createdAt"`
+	UpdatedAt      time.Time    `json:"updatedAt"`
 }
 
 // UploadVideoPayload 视频上传请求
@@ -67,27 +95,608 @@ type CreatePracticePayload struct {
 	Notes    string  `json:"notes"`
 }
 
+// Class 班级结构
+type Class struct {
+	ID          int       `json:"id"`
+	TeacherID   int       `json:"teacherId"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	JoinCode    string    `json:"joinCode,omitempty"` // 邀请码，仅老师可见
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// ClassMember 班级成员
+type ClassMember struct {
+	ClassID   int       `json:"classId"`
+	UserID    int       `json:"userId"`
+	FirstName string    `json:"firstName"`
+	LastName  string    `json:"lastName"`
+	JoinedAt  time.Time `json:"joinedAt"`
+}
+
+// ClassAssignment 班级作业（布置给班级的参考视频）
+type ClassAssignment struct {
+	ID        int       `json:"id"`
+	ClassID   int       `json:"classId"`
+	VideoID   int       `json:"videoId"`
+	Note      string    `json:"note"`
+	Video     *Video    `json:"video,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// StudentActivity 学生在某个班级作业上的练习情况（老师查看）
+type StudentActivity struct {
+	VideoID    int         `json:"videoId"`
+	Practices  []*Practice `json:"practices"`
+	Recordings []*Video    `json:"recordings"`
+}
+
+// CreateClassPayload 创建班级请求
+type CreateClassPayload struct {
+	Name        string `json:"name" validate:"required,max=255"`
+	Description string `json:"description"`
+}
+
+// JoinClassPayload 加入班级请求
+type JoinClassPayload struct {
+	JoinCode string `json:"joinCode" validate:"required"`
+}
+
+// AssignVideoPayload 布置视频请求
+type AssignVideoPayload struct {
+	VideoID int    `json:"videoId" validate:"required"`
+	Note    string `json:"note"`
+}
+
+// Comment 视频评论（带时间点的反馈，支持回复）
+type Comment struct {
+	ID        int        `json:"id"`
+	VideoID   int        `json:"videoId"`
+	UserID    int        `json:"userId"`
+	ParentID  int        `json:"parentId,omitempty"`
+	VideoTime float64    `json:"videoTime"` // 评论对应的视频时间点（秒）
+	Body      string     `json:"body"`
+	FirstName string     `json:"firstName"`
+	LastName  string     `json:"lastName"`
+	Replies   []*Comment `json:"replies,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+}
+
+// CreateCommentPayload 创建评论请求
+type CreateCommentPayload struct {
+	VideoTime float64 `json:"videoTime" validate:"min=0"`
+	Body      string  `json:"body" validate:"required,max=2000"`
+	ParentID  int     `json:"parentId"`
+}
+
+// UpdateCommentPayload 修改评论请求
+type UpdateCommentPayload struct {
+	VideoTime *float64 `json:"videoTime" validate:"omitempty,min=0"`
+	Body      string   `json:"body" validate:"required,max=2000"`
+}
+
+// 片段对齐方式
+const (
+	SnapNone  = ""
+	SnapBeat  = "beat"  // 最近的一拍
+	SnapBar   = "bar"   // 最近的小节第一拍
+	SnapEight = "eight" // 最近的八拍起点
+)
+
+// Segment 用户保存的练习片段（AB 循环区间）
+type Segment struct {
+	ID        int            `json:"id"`
+	VideoID   int            `json:"videoId"`
+	UserID    int            `json:"userId"`
+	Name      string         `json:"name"`
+	Start     float64        `json:"start"`            // 起点（秒）
+	End       float64        `json:"end"`              // 终点（秒）
+	Counts    *SegmentCounts `json:"counts,omitempty"` // 视频有节拍信息时返回
+	CreatedAt time.Time      `json:"createdAt"`
+	UpdatedAt time.Time      `json:"updatedAt"`
+}
+
+// BeatPosition 某一拍在节拍网格中的位置
+type BeatPosition struct {
+	Time  float64 `json:"time"`
+	Bar   int     `json:"bar"`   // 第几小节，从 1 开始（弱起为 0）
+	Beat  int     `json:"beat"`  // 小节内第几拍
+	Eight int     `json:"eight"` // 第几个八拍，从 1 开始（弱起为 0）
+	Count int     `json:"count"` // 八拍中的第几拍，1~8
+}
+
+// SegmentCounts 片段对应的拍数，Label 为舞蹈常用的"八拍-拍"写法，例如 "2-1 ~ 3-8"
+type SegmentCounts struct {
+	Start BeatPosition `json:"start"` // 片段的第一拍
+	End   BeatPosition `json:"end"`   // 片段的最后一拍
+	Beats int          `json:"beats"`
+	Label string       `json:"label"`
+}
+
+// CreateSegmentPayload 创建片段请求，Snap 不为空时把起止时间对齐到节拍
+type CreateSegmentPayload struct {
+	Name  string  `json:"name" validate:"max=100"`
+	Start float64 `json:"start" validate:"min=0"`
+	End   float64 `json:"end" validate:"gtfield=Start"`
+	Snap  string  `json:"snap" validate:"omitempty,oneof=beat bar eight"`
+}
+
+// UpdateSegmentPayload 修改片段请求
+type UpdateSegmentPayload struct {
+	Name  string  `json:"name" validate:"max=100"`
+	Start float64 `json:"start" validate:"min=0"`
+	End   float64 `json:"end" validate:"gtfield=Start"`
+	Snap  string  `json:"snap" validate:"omitempty,oneof=beat bar eight"`
+}
+
+// CreateRoomPayload 创建同步练习房间请求
+type CreateRoomPayload struct {
+	VideoID int `json:"videoId" validate:"required"`
+}
+
+// StorageUsage 用户存储用量及配额（Max* 为空表示不限）
+type StorageUsage struct {
+	UserID          int    `json:"userId"`
+	Role            string `json:"role"`
+	UsedBytes       int64  `json:"usedBytes"`
+	VideoCount      int    `json:"videoCount"`
+	MaxBytes        *int64 `json:"maxBytes"`
+	MaxVideos       *int   `json:"maxVideos"`
+	RemainingBytes  *int64 `json:"remainingBytes"`
+	RemainingVideos *int   `json:"remainingVideos"`
+}
+
+// QuotaOverride 管理员为单个用户设置的配额，字段为空表示沿用角色默认值，负数表示不限
+type QuotaOverride struct {
+	UserID    int    `json:"userId"`
+	MaxBytes  *int64 `json:"maxBytes"`
+	MaxVideos *int   `json:"maxVideos"`
+}
+
+// SetQuotaPayload 设置用户配额请求。字段为 null 或不传表示沿用角色默认值，
+// 0 表示不允许再上传，-1 表示不限
+type SetQuotaPayload struct {
+	MaxBytes  *int64 `json:"maxBytes" validate:"omitempty,min=-1"`
+	MaxVideos *int   `json:"maxVideos" validate:"omitempty,min=-1"`
+}
+
+// 实时事件类型
+const (
+	EventVideoUploaded     = "video.uploaded"
+	EventVideoProcessed    = "video.processed"
+	EventVideoFailed       = "video.failed"
+	EventVideoDeleted      = "video.deleted"
+	EventVideoRestored     = "video.restored"
+	EventPracticeCreated   = "practice.created"
+	EventCommentCreated    = "comment.created"
+	EventAssignmentCreated = "assignment.created"
+)
+
+// Event 推送给用户的实时事件
+type Event struct {
+	ID        int64           `json:"id"`
+	UserID    int             `json:"userId"`
+	Type      string          `json:"type"`
+	Data      json.RawMessage `json:"data"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+// Webhook 投递状态
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// Webhook 用户配置的回调地址
+type Webhook struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"userId"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret,omitempty"` // 仅在创建时返回
+	Events    []string  `json:"events"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// WebhookDelivery 一次事件投递（失败后按指数退避重试）
+type WebhookDelivery struct {
+	ID            int               `json:"id"`
+	WebhookID     int               `json:"webhookId"`
+	Event         string            `json:"event"`
+	Payload       json.RawMessage   `json:"payload"`
+	Status        string            `json:"status"`
+	Attempts      int               `json:"attempts"`
+	NextAttemptAt *time.Time        `json:"nextAttemptAt,omitempty"`
+	AttemptLog    []*WebhookAttempt `json:"attemptLog,omitempty"`
+	CreatedAt     time.Time         `json:"createdAt"`
+	UpdatedAt     time.Time         `json:"updatedAt"`
+}
+
+// WebhookAttempt 一次投递尝试的结果
+type WebhookAttempt struct {
+	ID             int       `json:"id"`
+	DeliveryID     int       `json:"deliveryId"`
+	ResponseStatus int       `json:"responseStatus"`
+	Error          string    `json:"error,omitempty"`
+	DurationMs     int       `json:"durationMs"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+// CreateWebhookPayload 创建 Webhook 请求
+type CreateWebhookPayload struct {
+	URL    string   `json:"url" validate:"required,url,max=2048"`
+	Events []string `json:"events" validate:"required,min=1,dive,oneof=video.uploaded video.deleted video.restored practice.created"`
+	Secret string   `json:"secret" validate:"omitempty,min=16,max=128"`
+}
+
+// 后台任务类型
+const (
+	JobTranscode     = "transcode"
+	JobHLS           = "hls"
+	JobThumbnails    = "thumbnails"
+	JobBeats         = "beats"
+	JobSync          = "sync"
+	JobClip          = "clip"
+	JobDerive        = "derive"         // payload: DerivedOptions
+	JobDeleteStorage = "storage.delete" // payload: {"prefix": "..."}
+)
+
+// 后台任务状态
+const (
+	JobPending   = "pending"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+)
+
+// Job 后台任务（失败后按指数退避重试，超过 MaxAttempts 次后标记为失败）
+type Job struct {
+	ID          int             `json:"id"`
+	Type        string          `json:"type"`
+	UserID      int             `json:"userId,omitempty"`
+	VideoID     int             `json:"videoId,omitempty"`
+	Payload     json.RawMessage `json:"payload,omitempty"`
+	Result      json.RawMessage `json:"result,omitempty"` // 处理函数设置，完成时保存
+	Status      string          `json:"status"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"maxAttempts"`
+	LastError   string          `json:"lastError,omitempty"`
+	RunAt       time.Time       `json:"runAt"`
+	CreatedAt   time.Time       `json:"createdAt"`
+	UpdatedAt   time.Time       `json:"updatedAt"`
+}
+
+// 转码输出规格
+const (
+	RenditionMP4        = "mp4"        // H.264/AAC MP4，所有浏览器和手机都能播放
+	RenditionHLS        = "hls"        // 多码率 HLS，FilePath 为 master 播放列表
+	RenditionThumbnails = "thumbnails" // 拖动预览图，FilePath 为 WebVTT 缩略图轨道，宽高为单张预览图尺寸
+)
+
+// 转码输出状态
+const (
+	RenditionPending    = "pending"
+	RenditionProcessing = "processing"
+	RenditionReady      = "ready"
+	RenditionFailed     = "failed"
+)
+
+// Rendition 视频的一种转码输出
+type Rendition struct {
+	ID        int       `json:"id"`
+	VideoID   int       `json:"videoId"`
+	Kind      string    `json:"kind"`
+	Status    string    `json:"status"`
+	FilePath  string    `json:"-"` // 存储层中的 key
+	MimeType  string    `json:"mimeType,omitempty"`
+	Width     int       `json:"width,omitempty"`
+	Height    int       `json:"height,omitempty"`
+	Duration  float64   `json:"duration,omitempty"`
+	FileSize  int64     `json:"fileSize,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// TempoMap 视频音乐的节拍信息，用于把 AB 循环对齐到拍子
+type TempoMap struct {
+	VideoID     int       `json:"videoId"`
+	BPM         float64   `json:"bpm"`
+	BeatsPerBar int       `json:"beatsPerBar"`
+	Beats       []float64 `json:"beats"`      // 每一拍的时间（秒）
+	Downbeats   []float64 `json:"downbeats"`  // 每小节第一拍的时间
+	Confidence  float64   `json:"confidence"` // 节奏的明显程度，0~1
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// SpriteSheet 预览图拼图：每隔 Interval 秒截一帧，按 Columns 列 Rows 行拼成一张图
+type SpriteSheet struct {
+	Interval   float64 `json:"interval"`
+	Count      int     `json:"count"`
+	Columns    int     `json:"columns"`
+	Rows       int     `json:"rows"`
+	TileWidth  int     `json:"tileWidth"`
+	TileHeight int     `json:"tileHeight"`
+}
+
+// HLSVariant HLS 的一种码率
+type HLSVariant struct {
+	Name         string `json:"name"`         // 例如 "720p"，也是子目录名
+	Height       int    `json:"height"`       // 输出高度，宽度按比例
+	VideoBitrate int    `json:"videoBitrate"` // kbps
+	AudioBitrate int    `json:"audioBitrate"` // kbps
+}
+
+// CropRect 裁剪区域（源视频像素坐标）
+type CropRect struct {
+	X      int `json:"x" validate:"min=0"`
+	Y      int `json:"y" validate:"min=0"`
+	Width  int `json:"width" validate:"required,min=16"`
+	Height int `json:"height" validate:"required,min=16"`
+}
+
+// ClipOptions 剪辑参数：截取 [Start, End) 秒，Crop 不为空时裁剪画面
+type ClipOptions struct {
+	Start float64   `json:"start"`
+	End   float64   `json:"end"`
+	Crop  *CropRect `json:"crop,omitempty"`
+}
+
+// DerivedOptions 派生输出参数：水平翻转和/或变速（音频保持音调）
+type DerivedOptions struct {
+	Mirror bool    `json:"mirror"`
+	Speed  float64 `json:"speed"` // 0 或 1 表示不变速
+}
+
+// CreateDerivedPayload 请求生成派生输出（例如镜像的 0.75 倍速版本）
+type CreateDerivedPayload struct {
+	Mirror bool    `json:"mirror"`
+	Speed  float64 `json:"speed" validate:"omitempty,min=0.25,max=2"`
+}
+
+// CreateClipPayload 创建剪辑请求
+type CreateClipPayload struct {
+	Start       float64   `json:"start" validate:"min=0"`
+	End         float64   `json:"end" validate:"gtfield=Start"`
+	Crop        *CropRect `json:"crop"`
+	Title       string    `json:"title" validate:"max=255"`
+	Description string    `json:"description"`
+}
+
+// MediaInfo 媒体文件信息（ffprobe 结果）
+type MediaInfo struct {
+	Duration   float64 `json:"duration"`
+	Width      int     `json:"width"`
+	Height     int     `json:"height"`
+	VideoCodec string  `json:"videoCodec"`
+	AudioCodec string  `json:"audioCodec,omitempty"`
+}
+
+// 存储层的错误，用 errors.Is 判断（实现会包装成 "video not found" 等具体信息）
+var (
+	// ErrNotFound 记录不存在
+	ErrNotFound = errors.New("not found")
+	// ErrConflict 违反唯一约束（手机号已注册、已是班级成员等）
+	ErrConflict = errors.New("conflict")
+)
+
 // UserStore 用户存储接口
 type UserStore interface {
-	GetUserByEmail(email string) (*User, error)
-	GetUserByPhone(phone string) (*User, error)
-	GetUserByID(id int) (*User, error)
-	CreateUser(User) error
+	GetUserByEmail(ctx context.Context, email string) (*User, error)
+	GetUserByPhone(ctx context.Context, phone string) (*User, error)
+	GetUserByID(ctx context.Context, id int) (*User, error)
+	CreateUser(ctx context.Context, user User) error
+	// UpdateLocale 保存用户设置的语言
+	UpdateLocale(ctx context.Context, userID int, locale string) error
 }
 
 // VideoStore 视频存储接口
 type VideoStore interface {
-	GetVideos(userID int) ([]*Video, error)
-	GetVideoByID(id int) (*Video, error)
-	CreateVideo(video *Video) error
-	UpdateVideo(video *Video) error
-	DeleteVideo(id int) error
+	GetVideos(ctx context.Context, userID int) ([]*Video, error)
+	GetVideoByID(ctx context.Context, id int) (*Video, error)
+	GetRecordings(ctx context.Context, userID, referenceID int) ([]*Video, error)
+	GetVideoByHash(ctx context.Context, userID int, hash string) (*Video, error)
+	// GetClips 获取用户从某个源视频剪辑出的视频
+	GetClips(ctx context.Context, userID, sourceID int) ([]*Video, error)
+	// UpdateSync 保存练习录像与参考视频的时间偏移
+	UpdateSync(ctx context.Context, videoID int, offset, confidence float64) error
+	CreateVideo(ctx context.Context, video *Video) error
+	UpdateVideo(ctx context.Context, video *Video) error
+	DeleteVideo(ctx context.Context, id int) error
+
+	// 回收站：GetVideos/GetVideoByID 等查询不包含回收站中的视频
+	GetTrashedVideos(ctx context.Context, userID int) ([]*Video, error)
+	GetTrashedVideoByID(ctx context.Context, id int) (*Video, error)
+	TrashVideo(ctx context.Context, id int) error
+	RestoreVideo(ctx context.Context, id int) error
+	// GetExpiredTrash 获取在 before 之前移入回收站的视频
+	GetExpiredTrash(ctx context.Context, before time.Time, limit int) ([]*Video, error)
+	// PurgeVideo 彻底删除仍在回收站中且已过期的视频记录，记录已被恢复时返回 false。
+	// DeleteVideo 和 PurgeVideo 在同一事务中释放文件引用并登记待删除的文件
+	PurgeVideo(ctx context.Context, id int, before time.Time) (bool, error)
+
+	// AcquireBlob 登记一份内容为 hash 的文件并增加引用计数，
+	// 返回该内容实际使用的文件路径（已存在时为已有文件）
+	AcquireBlob(ctx context.Context, hash, filePath string, size int64) (string, error)
+	// ReleaseBlob 减少引用计数，最后一个引用释放时在同一事务中登记文件待删除
+	ReleaseBlob(ctx context.Context, hash string) error
+
+	// 一致性检查：包含回收站中的视频；已知文件包括视频、去重文件和待删除的文件
+	GetAllVideos(ctx context.Context) ([]*Video, error)
+	GetKnownFilePaths(ctx context.Context) ([]string, error)
+}
+
+// FileDeletion 待删除的文件（删除数据库记录时写入，后台任务删除文件，失败后重试）
+type FileDeletion struct {
+	ID            int       `json:"id"`
+	FilePath      string    `json:"filePath"`
+	Attempts      int       `json:"attempts"`
+	LastError     string    `json:"lastError,omitempty"`
+	NextAttemptAt time.Time `json:"nextAttemptAt"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+type FileDeletionStore interface {
+	EnqueueFileDeletion(ctx context.Context, filePath string) error
+	GetDueFileDeletions(ctx context.Context, now time.Time, limit int) ([]*FileDeletion, error)
+	CompleteFileDeletion(ctx context.Context, id int) error
+	RetryFileDeletion(ctx context.Context, id int, lastError string, next time.Time) error
+}
+
+// TempoMapStore 节拍信息存储接口
+type TempoMapStore interface {
+	// SaveTempoMap 保存视频的节拍信息，已存在时覆盖
+	SaveTempoMap(ctx context.Context, tempo *TempoMap) error
+	GetTempoMap(ctx context.Context, videoID int) (*TempoMap, error)
 }
 
 // PracticeStore 练习记录存储接口
 type PracticeStore interface {
-	GetPractices(userID int) ([]*Practice, error)
-	GetPracticeByID(id int) (*Practice, error)
-	CreatePractice(practice *Practice) error
-	DeletePractice(id int) error
+	GetPractices(ctx context.Context, userID int) ([]*Practice, error)
+	GetPracticeByID(ctx context.Context, id int) (*Practice, error)
+	GetPracticesByVideo(ctx context.Context, userID, videoID int) ([]*Practice, error)
+	CreatePractice(ctx context.Context, practice *Practice) error
+	DeletePractice(ctx context.Context, id int) error
+}
+
+// ClassStore 班级存储接口
+type ClassStore interface {
+	CreateClass(ctx context.Context, class *Class) error
+	GetClassByID(ctx context.Context, id int) (*Class, error)
+	GetClassByJoinCode(ctx context.Context, code string) (*Class, error)
+	GetClassesForUser(ctx context.Context, userID int) ([]*Class, error)
+	DeleteClass(ctx context.Context, id int) error
+
+	AddMember(ctx context.Context, classID, userID int) error
+	RemoveMember(ctx context.Context, classID, userID int) error
+	GetMembers(ctx context.Context, classID int) ([]*ClassMember, error)
+	IsMember(ctx context.Context, classID, userID int) (bool, error)
+
+	CreateAssignment(ctx context.Context, assignment *ClassAssignment) error
+	GetAssignments(ctx context.Context, classID int) ([]*ClassAssignment, error)
+	IsAssigned(ctx context.Context, classID, videoID int) (bool, error)
+	DeleteAssignment(ctx context.Context, classID, videoID int) error
+
+	// IsVideoAssignedToUser 用户所在的某个班级是否布置了该视频
+	IsVideoAssignedToUser(ctx context.Context, userID, videoID int) (bool, error)
+	// IsTeacherOfAssignment 老师是否有一个班级：学生是成员且布置了该视频
+	IsTeacherOfAssignment(ctx context.Context, teacherID, studentID, videoID int) (bool, error)
+}
+
+// CommentStore 评论存储接口
+type CommentStore interface {
+	GetComments(ctx context.Context, videoID int) ([]*Comment, error)
+	GetCommentByID(ctx context.Context, id int) (*Comment, error)
+	CreateComment(ctx context.Context, comment *Comment) error
+	UpdateComment(ctx context.Context, comment *Comment) error
+	DeleteComment(ctx context.Context, id int) error
+}
+
+// SegmentStore 练习片段存储接口
+type SegmentStore interface {
+	GetSegments(ctx context.Context, userID, videoID int) ([]*Segment, error)
+	GetSegmentByID(ctx context.Context, id int) (*Segment, error)
+	CreateSegment(ctx context.Context, segment *Segment) error
+	UpdateSegment(ctx context.Context, segment *Segment) error
+	DeleteSegment(ctx context.Context, id int) error
+}
+
+// EventStore 事件日志存储接口
+type EventStore interface {
+	CreateEvent(ctx context.Context, event *Event) error
+	GetEventsAfter(ctx context.Context, userID int, afterID int64) ([]*Event, error)
+	PruneEvents(ctx context.Context, userID int, keep int) error
+}
+
+// EventPublisher 事件发布接口
+type EventPublisher interface {
+	Publish(userID int, eventType string, data any)
+}
+
+// WebhookStore Webhook 存储接口
+type WebhookStore interface {
+	CreateWebhook(ctx context.Context, webhook *Webhook) error
+	GetWebhooks(ctx context.Context, userID int) ([]*Webhook, error)
+	GetWebhookByID(ctx context.Context, id int) (*Webhook, error)
+	GetActiveWebhooks(ctx context.Context, userID int) ([]*Webhook, error)
+	DeleteWebhook(ctx context.Context, id int) error
+
+	CreateDelivery(ctx context.Context, delivery *WebhookDelivery) error
+	GetDeliveries(ctx context.Context, webhookID int) ([]*WebhookDelivery, error)
+	GetDeliveryByID(ctx context.Context, id int) (*WebhookDelivery, error)
+	GetDueDeliveries(ctx context.Context, now time.Time, limit int) ([]*WebhookDelivery, error)
+	UpdateDelivery(ctx context.Context, delivery *WebhookDelivery) error
+
+	CreateAttempt(ctx context.Context, attempt *WebhookAttempt) error
+	GetAttempts(ctx context.Context, deliveryID int) ([]*WebhookAttempt, error)
+}
+
+// QuotaStore 存储用量和配额存储接口
+type QuotaStore interface {
+	GetUsage(ctx context.Context, userID int) (usedBytes int64, videoCount int, err error)
+	// Reserve 在不超过限制的前提下原子地增加用量，超出时返回 false（limit < 0 表示不限）
+	Reserve(ctx context.Context, userID int, bytes int64, maxBytes int64, maxVideos int) (bool, error)
+	Release(ctx context.Context, userID int, bytes int64) error
+	GetQuotaOverride(ctx context.Context, userID int) (*QuotaOverride, error)
+	SetQuotaOverride(ctx context.Context, override *QuotaOverride) error
+}
+
+// JobStore 后台任务存储接口
+type JobStore interface {
+	CreateJob(ctx context.Context, job *Job) error
+	// ClaimJob 领取一个到期的任务并标记为 running，没有任务时返回 nil
+	ClaimJob(ctx context.Context, now time.Time) (*Job, error)
+	CompleteJob(ctx context.Context, id int, result json.RawMessage) error
+	RetryJob(ctx context.Context, id int, lastError string, runAt time.Time) error
+	FailJob(ctx context.Context, id int, lastError string) error
+	// ResetRunningJobs 把上次进程退出时未完成的任务放回队列
+	ResetRunningJobs(ctx context.Context) error
+	GetJobByID(ctx context.Context, id int) (*Job, error)
+	GetJobsByVideo(ctx context.Context, videoID int) ([]*Job, error)
+}
+
+// JobQueue 提交后台任务（userID 为发起任务的用户）
+type JobQueue interface {
+	Enqueue(ctx context.Context, jobType string, userID, videoID int, payload any) (*Job, error)
+}
+
+// RenditionStore 转码输出存储接口
+type RenditionStore interface {
+	// SaveRendition 按 (videoId, kind) 插入或更新
+	SaveRendition(ctx context.Context, rendition *Rendition) error
+	// SaveDerivedRendition 视频的派生输出（不含失败的和同类型的）少于 limit 个时才保存，
+	// 返回 false 表示已达上限
+	SaveDerivedRendition(ctx context.Context, rendition *Rendition, limit int) (bool, error)
+	GetRendition(ctx context.Context, videoID int, kind string) (*Rendition, error)
+	GetRenditions(ctx context.Context, videoID int) ([]*Rendition, error)
+}
+
+// Transcoder 视频转码接口（ffmpeg 实现，测试时使用 Fake）
+type Transcoder interface {
+	Probe(ctx context.Context, path string) (*MediaInfo, error)
+	// TranscodeMP4 把 src 转成 H.264/AAC MP4 写入 dst
+	TranscodeMP4(ctx context.Context, src, dst string) error
+	// TranscodeHLS 把 src 切成一种码率的 HLS，在 dir 中生成 index.m3u8 和分片
+	TranscodeHLS(ctx context.Context, src, dir string, variant HLSVariant) error
+	// Poster 截取 at 秒处的一帧，缩放到 width 宽输出 JPEG 到 dst
+	Poster(ctx context.Context, src, dst string, at float64, width int) error
+	// Sprite 按 sheet 生成预览图拼图，输出 JPEG 到 dst
+	Sprite(ctx context.Context, src, dst string, sheet SpriteSheet) error
+	// DecodeAudio 把音轨解码为单声道 float32 PCM
+	DecodeAudio(ctx context.Context, src string, sampleRate int) ([]float32, error)
+	// Clip 截取（并裁剪）src 的一段，输出 H.264/AAC MP4 到 dst
+	Clip(ctx context.Context, src, dst string, opts ClipOptions) error
+	// Derive 生成镜像/变速版本，输出 H.264/AAC MP4 到 dst
+	Derive(ctx context.Context, src, dst string, opts DerivedOptions) error
+}
+
+// Storage 文件存储层，key 为 "/" 分隔的相对路径
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader) error
+	Open(ctx context.Context, key string) (io.ReadSeekCloser, error)
+	// Delete 删除 key，key 是目录前缀时删除其下所有文件；不存在时不报错
+	Delete(ctx context.Context, key string) error
 }
@@ -0,0 +1,120 @@
+// Package i18n 接口返回信息的多语言目录。目录以英文原文（fmt 格式串）为键，
+// locales/ 下每种语言一个 JSON 文件；新增返回给客户端的信息时每个文件都要加上
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 支持的语言
+const (
+	ZhCN = "zh-CN"
+	En   = "en"
+)
+
+// Locales 支持的语言，和 locales/ 下的文件对应
+var Locales = []string{ZhCN, En}
+
+//go:embed locales/*.json
+var files embed.FS
+
+var (
+	catalog       = load()
+	defaultLocale = ZhCN
+)
+
+func load() map[string]map[string]string {
+	c := make(map[string]map[string]string, len(Locales))
+	for _, locale := range Locales {
+		data, err := files.ReadFile("locales/" + locale + ".json")
+		if err != nil {
+			panic(err)
+		}
+		var bundle map[string]string
+		if err := json.Unmarshal(data, &bundle); err != nil {
+			panic(fmt.Sprintf("i18n: locales/%s.json: %v", locale, err))
+		}
+		c[locale] = bundle
+	}
+	return c
+}
+
+// Init 设置默认语言（客户端没有指定语言、指定的语言都不支持时使用），不支持的语言忽略
+func Init(locale string) {
+	if l, ok := Match(locale); ok {
+		defaultLocale = l
+	}
+}
+
+// Default 默认语言
+func Default() string {
+	return defaultLocale
+}
+
+// Match 把语言标签匹配到支持的语言：zh、zh-Hans、zh_CN 等匹配 zh-CN，en、en-US 等匹配 en
+func Match(tag string) (string, bool) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	primary, _, _ := strings.Cut(strings.ReplaceAll(tag, "_", "-"), "-")
+	switch primary {
+	case "zh":
+		return ZhCN, true
+	case "en":
+		return En, true
+	}
+	return "", false
+}
+
+// Negotiate 按 Accept-Language 请求头（含 q 权重）选择语言，都不支持时返回默认语言
+func Negotiate(acceptLanguage string) string {
+	type candidate struct {
+		locale string
+		q      float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		locale, ok := Match(tag)
+		if !ok {
+			continue
+		}
+		q := 1.0
+		if v, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q > 0 {
+			candidates = append(candidates, candidate{locale, q})
+		}
+	}
+	if len(candidates) == 0 {
+		return defaultLocale
+	}
+
+	// 权重相同时保持请求头中的顺序
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	return candidates[0].locale
+}
+
+// T 翻译一条信息，目录中没有时原样返回（比如数据库返回的错误）
+func T(locale, msg string) string {
+	if s, ok := catalog[locale][msg]; ok {
+		return s
+	}
+	return msg
+}
+
+// Sprintf 翻译格式串后再格式化
+func Sprintf(locale, format string, args ...any) string {
+	if len(args) == 0 {
+		return T(locale, format)
+	}
+	return fmt.Sprintf(T(locale, format), args...)
+}
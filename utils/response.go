@@ -1,125 +1,211 @@
 package utils
 
 import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
+
+	"github.com/Albert-tru/DanceMirror/utils/i18n"
 )
 
-// Response 统一的 API 响应结构
+// Response /api/v2 统一的响应结构：成功时 code 为 "ok"，data 为响应内容；
+// 失败时 code 为错误码，message 为错误信息，请求体校验失败时 details 列出每个字段的问题。
+// /api/v1 保持原来的响应格式（成功时直接返回内容，失败时为 {"error": "..."}）
 type Response struct {
-	Code    int         `json:"code"`
-	Message string      `json:"message"`
-	Data    interface{} `json:"data,omitempty"`
-	Error   string      `json:"error,omitempty"`
+	Code      string       `json:"code"`
+	Message   string       `json:"message,omitempty"`
+	Data      any          `json:"data,omitempty"`
+	Details   []FieldError `json:"details,omitempty"`
+	RequestID string       `json:"requestId,omitempty"`
+}
+
+// FieldError 单个字段的校验错误，field 为 JSON 字段名
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
 }
 
-// 业务状态码定义
+// 错误码：客户端按错误码判断错误类型，不要解析 message。已发布的错误码不能修改
 const (
-	CodeSuccess         = 200
-	CodeBadRequest      = 400
-	CodeUnauthorized    = 401
-	CodeForbidden       = 403
-	CodeNotFound        = 404
-	CodeConflict        = 409
-	CodeValidationError = 422
-	CodeTooManyRequests = 429
-	CodeInternalError   = 500
+	CodeOK               = "ok"
+	CodeBadRequest       = "bad_request"
+	CodeValidationFailed = "validation_failed"
+	CodeInvalidID        = "invalid_id"
+	CodeUnauthorized     = "unauthorized"
+	CodePermissionDenied = "permission_denied"
+	CodeNotFound         = "not_found"
+	CodeConflict         = "conflict"
+	CodePayloadTooLarge  = "payload_too_large"
+	CodeUnsupportedMedia = "unsupported_media"
+	CodeQuotaExceeded    = "quota_exceeded"
+	CodeTooManyRequests  = "too_many_requests"
+	CodeUnavailable      = "unavailable"
+	CodeInternalError    = "internal_error"
 )
 
-// Success 成功响应
-func Success(w http.ResponseWriter, data interface{}, message string) {
-	if message == "" {
-		message = "Success"
+// Error 带错误码的错误，没有错误码的错误按状态码推断（见 ErrorCode）。
+// 返回给客户端时按请求的语言翻译 format（见 i18n 包）
+type Error struct {
+	Code    string
+	Message string
+
+	format     string
+	args       []any
+	validation error // 请求体校验失败时的校验错误，details 和 message 按语言生成
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// NewError 创建带错误码的错误，format 同时是信息目录的键
+func NewError(code, format string, args ...any) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...), format: format, args: args}
+}
+
+// InvalidPayload 请求体校验失败，details 按字段列出 Validate.Struct 返回的错误
+func InvalidPayload(err error) *Error {
+	return &Error{
+		Code:       CodeValidationFailed,
+		Message:    fmt.Sprintf("invalid payload: %v", err),
+		validation: err,
 	}
-	WriteJSON(w, http.StatusOK, Response{
-		Code:    CodeSuccess,
-		Message: message,
-		Data:    data,
-	})
 }
 
-// Error 错误响应
-func Error(w http.ResponseWriter, code int, message string, err error) {
-	var errorDetail string
-	if err != nil {
-		errorDetail = err.Error()
+// localize 按语言生成错误信息和字段校验详情；不是 *Error 的错误按 Error() 查找信息目录
+func localize(err error, locale string) (string, []FieldError) {
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		return i18n.T(locale, err.Error()), nil
+	}
+	if apiErr.validation != nil {
+		message := i18n.Sprintf(locale, "invalid payload: %s", FormatValidationError(apiErr.validation, locale))
+		return message, ValidationDetails(apiErr.validation, locale)
 	}
+	return i18n.Sprintf(locale, apiErr.format, apiErr.args...), nil
+}
 
-	httpStatus := code
-	if code >= 500 {
-		httpStatus = http.StatusInternalServerError
-	} else if code >= 400 && code < 500 {
-		httpStatus = code
-	} else {
-		httpStatus = http.StatusBadRequest
+// ErrorCode 返回错误的错误码：err 带错误码时使用它，否则按状态码推断
+func ErrorCode(status int, err error) string {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Code
 	}
 
-	WriteJSON(w, httpStatus, Response{
-		Code:    code,
-		Message: message,
-		Error:   errorDetail,
-	})
+	switch status {
+	case http.StatusBadRequest:
+		return CodeBadRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodePermissionDenied
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusRequestEntityTooLarge:
+		return CodePayloadTooLarge
+	case http.StatusUnsupportedMediaType:
+		return CodeUnsupportedMedia
+	case http.StatusTooManyRequests:
+		return CodeTooManyRequests
+	case http.StatusServiceUnavailable:
+		return CodeUnavailable
+	}
+	if status >= 500 {
+		return CodeInternalError
+	}
+	return CodeBadRequest
 }
 
-// BadRequest 400 错误
-func BadRequest(w http.ResponseWriter, message string, err error) {
-	if message == "" {
-		message = "请求参数错误"
-	}
-	Error(w, CodeBadRequest, message, err)
+// responseWriter 记录 WriteJSON/WriteError 需要的请求信息：
+// 是否使用统一的响应结构（/api/v2）、请求 ID 和返回信息的语言
+type responseWriter struct {
+	http.ResponseWriter
+	envelope  bool
+	requestID string
+	locale    string
 }
 
-// Unauthorized 401 错误
-func Unauthorized(w http.ResponseWriter, message string) {
-	if message == "" {
-		message = "未授权访问"
+// Flush 支持 SSE
+func (w *responseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
 	}
-	Error(w, CodeUnauthorized, message, nil)
 }
 
-// Forbidden 403 错误
-func Forbidden(w http.ResponseWriter, message string) {
-	if message == "" {
-		message = "禁止访问"
-	}
-	Error(w, CodeForbidden, message, nil)
+// Hijack 支持 WebSocket 升级
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
 }
 
-// NotFound 404 错误
-func NotFound(w http.ResponseWriter, message string) {
-	if message == "" {
-		message = "资源不存在"
-	}
-	Error(w, CodeNotFound, message, nil)
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
 }
 
-// Conflict 409 错误
-func Conflict(w http.ResponseWriter, message string) {
-	if message == "" {
-		message = "资源冲突"
+// withState 找到 w 中的 responseWriter，没有时包装一个新的
+func withState(w http.ResponseWriter) (http.ResponseWriter, *responseWriter) {
+	if rw := stateOf(w); rw != nil {
+		return w, rw
 	}
-	Error(w, CodeConflict, message, nil)
+	rw := &responseWriter{ResponseWriter: w, locale: i18n.Default()}
+	return rw, rw
 }
 
-// ValidationError 422 参数验证错误
-func ValidationError(w http.ResponseWriter, message string, err error) {
-	if message == "" {
-		message = "参数验证失败"
+// stateOf 沿包装链查找 responseWriter，没有时返回 nil
+func stateOf(w http.ResponseWriter) *responseWriter {
+	for {
+		switch rw := w.(type) {
+		case *responseWriter:
+			return rw
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = rw.Unwrap()
+		default:
+			return nil
+		}
 	}
-	Error(w, CodeValidationError, message, err)
 }
 
-// TooManyRequests 429 请求过于频繁
-func TooManyRequests(w http.ResponseWriter, message string) {
-	if message == "" {
-		message = "请求过于频繁，请稍后再试"
+// WithEnvelope 中间件：WriteJSON 和 WriteError 的输出改为 Response 结构。
+// 需要放在 WithRequestID 之后；文件、播放列表、SSE 等非 JSON 响应不受影响
+func WithEnvelope(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w, rw := withState(w)
+		rw.envelope = true
+		rw.requestID = RequestID(r.Context())
+		next.ServeHTTP(w, r)
+	})
+}
+
+// WithLocale 中间件：按 Accept-Language 选择返回信息的语言，
+// 登录用户设置过语言时由 SetLocale 覆盖
+func WithLocale(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w, rw := withState(w)
+		rw.locale = i18n.Negotiate(r.Header.Get("Accept-Language"))
+		w.Header().Set("Content-Language", rw.locale)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// SetLocale 使用用户设置的语言，locale 为空或不支持时不修改
+func SetLocale(w http.ResponseWriter, locale string) {
+	l, ok := i18n.Match(locale)
+	rw := stateOf(w)
+	if !ok || rw == nil {
+		return
 	}
-	Error(w, CodeTooManyRequests, message, nil)
+	rw.locale = l
+	w.Header().Set("Content-Language", l)
 }
 
-// InternalError 500 服务器内部错误
-func InternalError(w http.ResponseWriter, message string, err error) {
-	if message == "" {
-		message = "服务器内部错误"
+// Locale 返回信息使用的语言，没有经过 WithLocale 时为默认语言
+func Locale(w http.ResponseWriter) string {
+	if rw := stateOf(w); rw != nil {
+		return rw.locale
 	}
-	Error(w, CodeInternalError, message, err)
+	return i18n.Default()
 }
@@ -2,29 +2,122 @@ package utils
 
 import (
 	"encoding/json"
-	"fmt"
+	"errors"
+	"log"
 	"net/http"
+	"reflect"
+	"strings"
 
+	"github.com/Albert-tru/DanceMirror/types"
 	"github.com/go-playground/validator/v10"
 )
 
-var Validate = validator.New()
+var Validate = newValidator()
 
+// newValidator 校验错误中使用 JSON 字段名，和请求体中的一致
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return f.Name
+		}
+		return name
+	})
+	return v
+}
+
+// ParseJSON 解析 JSON 请求体，失败时返回错误码为 bad_request 的 *Error
 func ParseJSON(r *http.Request, payload any) error {
 	if r.Body == nil {
-		return fmt.Errorf("missing request body")
+		return NewError(CodeBadRequest, "missing request body")
+	}
+	if err := json.NewDecoder(r.Body).Decode(payload); err != nil {
+		return NewError(CodeBadRequest, "invalid request body: %v", err)
 	}
-	return json.NewDecoder(r.Body).Decode(payload)
+	return nil
 }
 
+// WriteJSON 返回 JSON，/api/v2 的请求包装为 Response{Code: "ok", Data: v}
 func WriteJSON(w http.ResponseWriter, status int, v any) error {
+	if rw := stateOf(w); rw != nil && rw.envelope {
+		v = Response{Code: CodeOK, Data: v, RequestID: rw.requestID}
+	}
+	return writeJSON(w, status, v)
+}
+
+// WriteError 返回错误，信息按请求的语言翻译（见 WithLocale）；
+// /api/v2 的请求返回带错误码的 Response（见 ErrorCode）。
+// 5xx 的错误不是 *Error 时（数据库错误等）只返回通用信息，原文按请求 ID 记录在日志中
+func WriteError(w http.ResponseWriter, status int, err error) {
+	var apiErr *Error
+	if status >= http.StatusInternalServerError && !errors.As(err, &apiErr) {
+		log.Printf("request %s: %d: %v", w.Header().Get(RequestIDHeader), status, err)
+		err = NewError(ErrorCode(status, nil), "internal server error")
+	}
+
+	message, details := localize(err, Locale(w))
+
+	rw := stateOf(w)
+	if rw == nil || !rw.envelope {
+		writeJSON(w, status, map[string]string{"error": message})
+		return
+	}
+
+	writeJSON(w, status, Response{
+		Code:      ErrorCode(status, err),
+		Message:   message,
+		Details:   details,
+		RequestID: rw.requestID,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) error {
 	w.Header().Add("Content-Type", "application/json")
 	w.WriteHeader(status)
 	return json.NewEncoder(w).Encode(v)
 }
 
-func WriteError(w http.ResponseWriter, status int, err error) {
-	WriteJSON(w, status, map[string]string{"error": err.Error()})
+// StoreErrorStatus 按存储层返回的错误选择状态码：记录不存在 404，
+// 违反唯一约束 409，其他错误（比如数据库不可用）500
+func StoreErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, types.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, types.ErrConflict):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// WriteStoreError 按 StoreErrorStatus 的状态码和对应的错误码返回存储层错误。
+// 违反唯一约束的错误中有驱动的原始信息，记录到日志，只返回通用信息
+func WriteStoreError(w http.ResponseWriter, err error) {
+	status := StoreErrorStatus(err)
+	switch status {
+	case http.StatusNotFound:
+		// 存储层的信息是 "video not found" 这样的固定文本，可以直接翻译
+		WriteError(w, status, &Error{Code: CodeNotFound, Message: err.Error(), format: err.Error()})
+	case http.StatusConflict:
+		log.Printf("request %s: %v", w.Header().Get(RequestIDHeader), err)
+		WriteError(w, status, NewError(CodeConflict, "conflict"))
+	default:
+		WriteError(w, status, err)
+	}
+}
+
+// WriteUnauthorized 没有登录、令牌无效或已过期。/api/v2 返回 401 unauthorized，客户端据此重新登录；
+// /api/v1 的旧客户端按原来的 403 permission denied 处理，保持不变
+func WriteUnauthorized(w http.ResponseWriter) {
+	if rw := stateOf(w); rw != nil && rw.envelope {
+		WriteError(w, http.StatusUnauthorized, NewError(CodeUnauthorized, "unauthorized"))
+		return
+	}
+	WriteError(w, http.StatusForbidden, NewError(CodePermissionDenied, "permission denied"))
 }
 
 func GetTokenFromRequest(r *http.Request) string {
@@ -1,7 +1,10 @@
 package utils
 
 import (
-"fmt"
+"errors"
+"reflect"
+
+"github.com/Albert-tru/DanceMirror/utils/i18n"
 "github.com/go-playground/validator/v10"
 )
 
@@ -10,8 +13,8 @@ func ValidateStruct(s interface{}) error {
 return Validate.Struct(s)
 }
 
-// FormatValidationError 格式化验证错误为中文提示
-func FormatValidationError(err error) string {
+// FormatValidationError 格式化验证错误，locale 为提示的语言（见 i18n 包）
+func FormatValidationError(err error, locale string) string {
 if err == nil {
 return ""
 }
@@ -21,31 +24,80 @@ if !ok {
 return err.Error()
 }
 
+if len(validationErrors) > 0 {
+return fieldMessage(validationErrors[0], locale)
+}
+
+return i18n.T(locale, "validation failed")
+}
+
+// ValidationDetails 把校验错误按字段列出（/api/v2 响应中的 details），不是校验错误时返回 nil
+func ValidationDetails(err error, locale string) []FieldError {
+var validationErrors validator.ValidationErrors
+if !errors.As(err, &validationErrors) {
+return nil
+}
+
+details := make([]FieldError, 0, len(validationErrors))
 for _, e := range validationErrors {
+details = append(details, FieldError{
+Field:   e.Field(),
+Rule:    e.Tag(),
+Message: fieldMessage(e, locale),
+})
+}
+return details
+}
+
+// fieldMessage 单个字段校验错误的提示；min、max、len 按字段类型区分长度、项数和数值
+func fieldMessage(e validator.FieldError, locale string) string {
 field := e.Field()
 tag := e.Tag()
 
 switch tag {
 case "required":
-return fmt.Sprintf("%s 不能为空", field)
+return i18n.Sprintf(locale, "%s is required", field)
 case "email":
-return fmt.Sprintf("%s 邮箱格式不正确", field)
-case "min":
-return fmt.Sprintf("%s 长度不能小于 %s", field, e.Param())
-case "max":
-return fmt.Sprintf("%s 长度不能大于 %s", field, e.Param())
-case "len":
-return fmt.Sprintf("%s 长度必须为 %s", field, e.Param())
+return i18n.Sprintf(locale, "%s must be a valid email address", field)
+case "url":
+return i18n.Sprintf(locale, "%s must be a valid URL", field)
 case "numeric":
-return fmt.Sprintf("%s 必须为数字", field)
+return i18n.Sprintf(locale, "%s must be numeric", field)
 case "alphanum":
-return fmt.Sprintf("%s 只能包含字母和数字", field)
+return i18n.Sprintf(locale, "%s must contain only letters and digits", field)
+case "oneof":
+return i18n.Sprintf(locale, "%s must be one of: %s", field, e.Param())
+case "gtfield":
+return i18n.Sprintf(locale, "%s must be greater than %s", field, e.Param())
+case "min", "max", "len":
+return i18n.Sprintf(locale, boundFormat(tag, e.Kind()), field, e.Param())
 default:
-return fmt.Sprintf("%s 验证失败: %s", field, tag)
+return i18n.Sprintf(locale, "%s failed validation: %s", field, tag)
 }
 }
 
-return "参数验证失败"
+// boundFormat min、max、len 的提示格式
+func boundFormat(tag string, kind reflect.Kind) string {
+switch kind {
+case reflect.String:
+return map[string]string{
+"min": "%s must be at least %s characters long",
+"max": "%s must be at most %s characters long",
+"len": "%s must be exactly %s characters long",
+}[tag]
+case reflect.Slice, reflect.Array, reflect.Map:
+return map[string]string{
+"min": "%s must contain at least %s items",
+"max": "%s must contain at most %s items",
+"len": "%s must contain exactly %s items",
+}[tag]
+default:
+return map[string]string{
+"min": "%s must be at least %s",
+"max": "%s must be at most %s",
+"len": "%s must be %s",
+}[tag]
+}
 }
 
 // ValidatePhone 验证手机号（中国大陆）